@@ -48,6 +48,34 @@ func main() {
 	// 注册示例AI Agent工厂
 	agentManager.RegisterFactory(&ai_agent.ExampleAIAgentFactory{})
 
+	// 注册内置类型的AI Agent工厂，实例通过ai_agents配置或环境变量创建
+	for _, factory := range ai_agent.BuiltinFactories() {
+		agentManager.RegisterFactory(factory)
+	}
+
+	// 设置了OPENAI_API_KEY时创建OpenAI兼容的AI Agent
+	if os.Getenv("OPENAI_API_KEY") != "" {
+		createAgent(agentManager, "openai", ai_agent.TypeOpenAI, map[string]interface{}{
+			"base_url": getEnv("OPENAI_BASE_URL", ai_agent.DefaultOpenAIBaseURL),
+			"api_key":  "${OPENAI_API_KEY}",
+		})
+	}
+
+	// 设置了ANTHROPIC_API_KEY时创建Anthropic Messages API的AI Agent
+	if os.Getenv("ANTHROPIC_API_KEY") != "" {
+		createAgent(agentManager, "anthropic", ai_agent.TypeAnthropic, map[string]interface{}{
+			"base_url": getEnv("ANTHROPIC_BASE_URL", ai_agent.DefaultAnthropicBaseURL),
+			"api_key":  "${ANTHROPIC_API_KEY}",
+		})
+	}
+
+	// 设置了OLLAMA_HOST时创建本地模型服务的AI Agent
+	if host := os.Getenv("OLLAMA_HOST"); host != "" {
+		createAgent(agentManager, "ollama", ai_agent.TypeOllama, map[string]interface{}{
+			"base_url": host,
+		})
+	}
+
 	// 创建MCP服务管理器
 	mcpManager := mcp.NewDefaultMCPServiceManager()
 
@@ -89,3 +117,18 @@ func main() {
 
 	logger.Info("Server shutdown completed")
 }
+
+// createAgent 创建AI Agent实例，失败时只记录日志
+func createAgent(manager ai_agent.AIAgentManager, name, factory string, config map[string]interface{}) {
+	if err := manager.CreateInstance(name, factory, config); err != nil {
+		log.GlobalLogger.Error("Failed to create AI agent", zap.String("name", name), zap.Error(err))
+	}
+}
+
+// getEnv 获取环境变量，不存在时返回默认值
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
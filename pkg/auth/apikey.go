@@ -0,0 +1,371 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"kai/kaigate/pkg/log"
+	"kai/kaigate/pkg/storage"
+)
+
+// APIKeyPrefix API Key明文前缀，便于识别和区分其他令牌
+const APIKeyPrefix = "kg_"
+
+// apiKeyStorePrefix API Key在键值存储中的键前缀
+const apiKeyStorePrefix = "apikey/"
+
+// API Key校验错误
+var (
+	ErrInvalidAPIKey  = errors.New("invalid api key")
+	ErrAPIKeyDisabled = errors.New("api key disabled")
+	ErrAPIKeyExpired  = errors.New("api key expired")
+	ErrAPIKeyNotFound = errors.New("api key not found")
+)
+
+// APIKey API Key元数据
+// 只保存明文的SHA-256摘要，明文仅在签发时返回一次
+type APIKey struct {
+	ID          string     `json:"id"`                     // Key唯一标识
+	Name        string     `json:"name"`                   // Key名称
+	Owner       string     `json:"owner"`                  // 所属者
+	Prefix      string     `json:"prefix"`                 // 明文前缀，用于展示
+	Hash        string     `json:"hash"`                   // 明文摘要
+	Roles       []string   `json:"roles,omitempty"`        // 角色列表
+	Groups      []string   `json:"groups,omitempty"`       // 所属团队/分组
+	Agents      []string   `json:"agents,omitempty"`       // 可调用的AI Agent
+	MCPServices []string   `json:"mcp_services,omitempty"` // 可调用的MCP服务
+	ProxyRoutes []string   `json:"proxy_routes,omitempty"` // 可访问的代理路由
+	RateLimit   int        `json:"rate_limit,omitempty"`   // 限流值(请求/秒)
+	Enabled     bool       `json:"enabled"`                // 是否启用
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`   // 过期时间
+	CreatedAt   time.Time  `json:"created_at"`             // 创建时间
+}
+
+// IssueAPIKeyRequest 签发API Key请求
+type IssueAPIKeyRequest struct {
+	Name        string   `json:"name" binding:"required"`
+	Owner       string   `json:"owner"`
+	Roles       []string `json:"roles"`
+	Groups      []string `json:"groups"`
+	Agents      []string `json:"agents"`
+	MCPServices []string `json:"mcp_services"`
+	ProxyRoutes []string `json:"proxy_routes"`
+	RateLimit   int      `json:"rate_limit"`
+	// 有效期(秒)，0表示永不过期
+	TTL int64 `json:"ttl"`
+}
+
+// Expired 检查API Key是否已过期
+func (k *APIKey) Expired(now time.Time) bool {
+	return k.ExpiresAt != nil && now.After(*k.ExpiresAt)
+}
+
+// Identity 将API Key转换为调用方身份
+func (k *APIKey) Identity() *Identity {
+	return &Identity{
+		ID:          "apikey:" + k.ID,
+		Name:        k.Name,
+		Type:        IdentityTypeAPIKey,
+		Owner:       k.Owner,
+		Roles:       k.Roles,
+		Groups:      k.Groups,
+		Agents:      k.Agents,
+		MCPServices: k.MCPServices,
+		ProxyRoutes: k.ProxyRoutes,
+		RateLimit:   k.RateLimit,
+	}
+}
+
+// APIKeyManager API Key管理器
+// 负责API Key的签发、吊销、启停和校验
+type APIKeyManager struct {
+	store  storage.KV
+	keys   map[string]*APIKey // 按ID索引
+	hashes map[string]string  // 摘要到ID的索引
+	mutex  sync.RWMutex
+	logger log.Logger
+}
+
+// NewAPIKeyManager 创建APIKeyManager实例，并从存储中加载已有的Key
+func NewAPIKeyManager(store storage.KV) (*APIKeyManager, error) {
+	if store == nil {
+		return nil, errors.New("api key store cannot be nil")
+	}
+
+	m := &APIKeyManager{
+		store:  store,
+		keys:   make(map[string]*APIKey),
+		hashes: make(map[string]string),
+		logger: log.GlobalLogger,
+	}
+
+	if err := m.load(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// load 从存储中加载所有API Key
+func (m *APIKeyManager) load() error {
+	ids, err := m.store.List(apiKeyStorePrefix)
+	if err != nil {
+		return fmt.Errorf("list api keys failed: %w", err)
+	}
+
+	for _, storeKey := range ids {
+		data, err := m.store.Get(storeKey)
+		if err != nil {
+			return fmt.Errorf("load api key failed: %w", err)
+		}
+
+		var key APIKey
+		if err := json.Unmarshal(data, &key); err != nil {
+			m.logger.Warn("Skipping malformed api key record", zap.String("key", storeKey), zap.Error(err))
+			continue
+		}
+		m.keys[key.ID] = &key
+		m.hashes[key.Hash] = key.ID
+	}
+
+	m.logger.Info("API keys loaded", zap.Int("count", len(m.keys)))
+	return nil
+}
+
+// Issue 签发新的API Key，返回明文和元数据
+func (m *APIKeyManager) Issue(req IssueAPIKeyRequest) (string, *APIKey, error) {
+	if req.Name == "" {
+		return "", nil, errors.New("api key name cannot be empty")
+	}
+
+	secret, err := randomHex(24)
+	if err != nil {
+		return "", nil, err
+	}
+	id, err := randomHex(6)
+	if err != nil {
+		return "", nil, err
+	}
+
+	plaintext := APIKeyPrefix + secret
+	now := time.Now()
+	key := &APIKey{
+		ID:          id,
+		Name:        req.Name,
+		Owner:       req.Owner,
+		Prefix:      plaintext[:len(APIKeyPrefix)+6],
+		Hash:        hashAPIKey(plaintext),
+		Roles:       req.Roles,
+		Groups:      req.Groups,
+		Agents:      req.Agents,
+		MCPServices: req.MCPServices,
+		ProxyRoutes: req.ProxyRoutes,
+		RateLimit:   req.RateLimit,
+		Enabled:     true,
+		CreatedAt:   now,
+	}
+	if req.TTL > 0 {
+		expiresAt := now.Add(time.Duration(req.TTL) * time.Second)
+		key.ExpiresAt = &expiresAt
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if err := m.save(key); err != nil {
+		return "", nil, err
+	}
+	m.keys[key.ID] = key
+	m.hashes[key.Hash] = key.ID
+
+	m.logger.Info("API key issued", zap.String("id", key.ID), zap.String("name", key.Name))
+	return plaintext, key, nil
+}
+
+// Revoke 吊销API Key
+func (m *APIKeyManager) Revoke(id string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	key, exists := m.keys[id]
+	if !exists {
+		return ErrAPIKeyNotFound
+	}
+
+	if err := m.store.Delete(apiKeyStorePrefix + id); err != nil {
+		return fmt.Errorf("delete api key failed: %w", err)
+	}
+	delete(m.keys, id)
+	delete(m.hashes, key.Hash)
+
+	m.logger.Info("API key revoked", zap.String("id", id))
+	return nil
+}
+
+// SetEnabled 启用或禁用API Key
+func (m *APIKeyManager) SetEnabled(id string, enabled bool) (*APIKey, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	key, exists := m.keys[id]
+	if !exists {
+		return nil, ErrAPIKeyNotFound
+	}
+
+	updated := *key
+	updated.Enabled = enabled
+	if err := m.save(&updated); err != nil {
+		return nil, err
+	}
+	m.keys[id] = &updated
+
+	m.logger.Info("API key state changed", zap.String("id", id), zap.Bool("enabled", enabled))
+	return &updated, nil
+}
+
+// Get 获取API Key元数据
+func (m *APIKeyManager) Get(id string) (*APIKey, bool) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	key, exists := m.keys[id]
+	return key, exists
+}
+
+// List 列出所有API Key元数据，按创建时间排序
+func (m *APIKeyManager) List() []*APIKey {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	keys := make([]*APIKey, 0, len(m.keys))
+	for _, key := range m.keys {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+	return keys
+}
+
+// Validate 校验API Key明文，返回对应的元数据
+func (m *APIKeyManager) Validate(plaintext string) (*APIKey, error) {
+	if !strings.HasPrefix(plaintext, APIKeyPrefix) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	m.mutex.RLock()
+	id, exists := m.hashes[hashAPIKey(plaintext)]
+	var key *APIKey
+	if exists {
+		key = m.keys[id]
+	}
+	m.mutex.RUnlock()
+
+	if key == nil {
+		return nil, ErrInvalidAPIKey
+	}
+	if !key.Enabled {
+		return nil, ErrAPIKeyDisabled
+	}
+	if key.Expired(time.Now()) {
+		return nil, ErrAPIKeyExpired
+	}
+	return key, nil
+}
+
+// save 持久化API Key元数据，调用方需持有写锁
+func (m *APIKeyManager) save(key *APIKey) error {
+	data, err := json.Marshal(key)
+	if err != nil {
+		return fmt.Errorf("marshal api key failed: %w", err)
+	}
+	if err := m.store.Put(apiKeyStorePrefix+key.ID, data); err != nil {
+		return fmt.Errorf("save api key failed: %w", err)
+	}
+	return nil
+}
+
+// APIKeyAuthenticator 基于API Key的认证器
+type APIKeyAuthenticator struct {
+	manager *APIKeyManager
+	header  string
+}
+
+// NewAPIKeyAuthenticator 创建APIKeyAuthenticator实例
+// header为空时使用默认的X-API-Key请求头
+func NewAPIKeyAuthenticator(manager *APIKeyManager, header string) *APIKeyAuthenticator {
+	if header == "" {
+		header = "X-API-Key"
+	}
+	return &APIKeyAuthenticator{
+		manager: manager,
+		header:  header,
+	}
+}
+
+// Name 获取认证器名称
+func (a *APIKeyAuthenticator) Name() string {
+	return "api_key"
+}
+
+// Authenticate 从请求中提取并校验API Key
+// 依次检查自定义请求头、Authorization: Bearer和api_key查询参数
+func (a *APIKeyAuthenticator) Authenticate(r *http.Request) (*Identity, error) {
+	plaintext := r.Header.Get(a.header)
+	if plaintext == "" {
+		if token := bearerToken(r); strings.HasPrefix(token, APIKeyPrefix) {
+			plaintext = token
+		}
+	}
+	if plaintext == "" {
+		// WebSocket客户端无法自定义请求头，允许通过查询参数传递
+		plaintext = r.URL.Query().Get("api_key")
+	}
+	if plaintext == "" {
+		return nil, ErrNoCredentials
+	}
+
+	key, err := a.manager.Validate(plaintext)
+	if err != nil {
+		return nil, err
+	}
+	return key.Identity(), nil
+}
+
+// StripCredentials 移除请求中的API Key：自定义请求头、Bearer形式的Key和api_key查询参数
+// Authorization中的其他令牌（如JWT）保持不变
+func (a *APIKeyAuthenticator) StripCredentials(r *http.Request) {
+	r.Header.Del(a.header)
+	if strings.HasPrefix(bearerToken(r), APIKeyPrefix) {
+		r.Header.Del("Authorization")
+	}
+	if query := r.URL.Query(); query.Has("api_key") {
+		query.Del("api_key")
+		r.URL.RawQuery = query.Encode()
+	}
+}
+
+// hashAPIKey 计算API Key明文的摘要
+func hashAPIKey(plaintext string) string {
+	sum := sha256.Sum256([]byte(plaintext))
+	return hex.EncodeToString(sum[:])
+}
+
+// randomHex 生成指定字节数的随机十六进制字符串
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate random bytes failed: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
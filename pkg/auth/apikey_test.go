@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"kai/kaigate/pkg/storage"
+)
+
+func TestMiddlewareStripsAPIKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	manager, err := NewAPIKeyManager(storage.NewMemoryKV())
+	if err != nil {
+		t.Fatalf("create manager: %v", err)
+	}
+	plaintext, key, err := manager.Issue(IssueAPIKeyRequest{Name: "team-a"})
+	if err != nil {
+		t.Fatalf("issue key: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		prepare   func(r *http.Request)
+		wantAuth  string
+		wantQuery string
+	}{
+		{
+			name:    "header",
+			prepare: func(r *http.Request) { r.Header.Set("X-API-Key", plaintext) },
+		},
+		{
+			name:    "bearer",
+			prepare: func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+plaintext) },
+		},
+		{
+			name: "query",
+			prepare: func(r *http.Request) {
+				r.URL.RawQuery = "api_key=" + plaintext + "&stream=true"
+			},
+			wantQuery: "stream=true",
+		},
+		{
+			// 使用请求头认证时，Authorization中的其他令牌保持不变
+			name: "other bearer token kept",
+			prepare: func(r *http.Request) {
+				r.Header.Set("X-API-Key", plaintext)
+				r.Header.Set("Authorization", "Bearer upstream-token")
+			},
+			wantAuth: "Bearer upstream-token",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(Middleware(nil, true, nil, NewAPIKeyAuthenticator(manager, "")))
+			router.GET("/proxy", func(c *gin.Context) {
+				identity, _ := GetIdentity(c)
+				if identity == nil || identity.Name != key.Name || identity.Type != IdentityTypeAPIKey {
+					t.Errorf("identity = %+v, want key %s", identity, key.Name)
+				}
+				if got := c.Request.Header.Get("X-API-Key"); got != "" {
+					t.Errorf("X-API-Key forwarded: %q", got)
+				}
+				if got := c.Request.Header.Get("Authorization"); got != tt.wantAuth {
+					t.Errorf("Authorization = %q, want %q", got, tt.wantAuth)
+				}
+				if got := c.Request.URL.RawQuery; got != tt.wantQuery {
+					t.Errorf("query = %q, want %q", got, tt.wantQuery)
+				}
+				c.Status(http.StatusNoContent)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/proxy", nil)
+			tt.prepare(req)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != http.StatusNoContent {
+				t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+			}
+		})
+	}
+}
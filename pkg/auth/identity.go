@@ -0,0 +1,134 @@
+package auth
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 身份类型
+const (
+	IdentityTypeAnonymous = "anonymous" // 匿名调用方
+	IdentityTypeAPIKey    = "api_key"   // API Key认证
+)
+
+// ContextKeyIdentity gin上下文中保存调用方身份的键
+const ContextKeyIdentity = "identity"
+
+// identityContextKey 请求上下文中保存调用方身份的键类型
+type identityContextKey struct{}
+
+// Identity 调用方身份
+// 由认证器解析得到，在日志、限流、授权和用量统计中使用
+type Identity struct {
+	ID     string   `json:"id"`     // 身份唯一标识
+	Name   string   `json:"name"`   // 身份名称
+	Type   string   `json:"type"`   // 身份类型
+	Owner  string   `json:"owner"`  // 所属者
+	Roles  []string `json:"roles"`  // 角色列表
+	Groups []string `json:"groups"` // 所属团队/分组
+	Scopes []string `json:"scopes"` // 授权范围
+	// 可访问的资源范围，为空表示不限制
+	Agents      []string `json:"agents,omitempty"`       // 可调用的AI Agent
+	MCPServices []string `json:"mcp_services,omitempty"` // 可调用的MCP服务
+	ProxyRoutes []string `json:"proxy_routes,omitempty"` // 可访问的代理路由
+	// 限流配置(请求/秒)，0表示使用默认值
+	RateLimit int `json:"rate_limit,omitempty"`
+	// 令牌声明，仅JWT认证时存在
+	Claims map[string]interface{} `json:"claims,omitempty"`
+	// 需要转发给上游服务的请求头
+	ForwardHeaders map[string]string `json:"-"`
+}
+
+// AnonymousIdentity 创建匿名身份
+func AnonymousIdentity(remoteAddr string) *Identity {
+	return &Identity{
+		ID:   "anonymous:" + remoteAddr,
+		Name: "anonymous",
+		Type: IdentityTypeAnonymous,
+	}
+}
+
+// IsAnonymous 是否为匿名身份
+func (i *Identity) IsAnonymous() bool {
+	return i == nil || i.Type == IdentityTypeAnonymous
+}
+
+// AllowsAgent 检查是否允许调用指定的AI Agent
+func (i *Identity) AllowsAgent(agentID string) bool {
+	if i == nil {
+		return true
+	}
+	return matchScope(i.Agents, agentID)
+}
+
+// AllowsMCPService 检查是否允许调用指定的MCP服务
+func (i *Identity) AllowsMCPService(serviceID string) bool {
+	if i == nil {
+		return true
+	}
+	return matchScope(i.MCPServices, serviceID)
+}
+
+// AllowsProxyRoute 检查是否允许访问指定的代理路由
+func (i *Identity) AllowsProxyRoute(path string) bool {
+	if i == nil {
+		return true
+	}
+	return matchScope(i.ProxyRoutes, path)
+}
+
+// HasRole 检查是否拥有指定角色
+func (i *Identity) HasRole(role string) bool {
+	if i == nil {
+		return false
+	}
+	for _, r := range i.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// matchScope 检查资源是否在授权范围内
+// 范围为空表示不限制；支持"*"通配和"prefix*"前缀匹配
+func matchScope(scopes []string, resource string) bool {
+	if len(scopes) == 0 {
+		return true
+	}
+	for _, scope := range scopes {
+		if scope == "*" || scope == resource {
+			return true
+		}
+		if strings.HasSuffix(scope, "*") && strings.HasPrefix(resource, strings.TrimSuffix(scope, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// WithIdentity 将调用方身份写入请求上下文
+func WithIdentity(ctx context.Context, identity *Identity) context.Context {
+	return context.WithValue(ctx, identityContextKey{}, identity)
+}
+
+// IdentityFromContext 从请求上下文中获取调用方身份
+func IdentityFromContext(ctx context.Context) (*Identity, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	identity, ok := ctx.Value(identityContextKey{}).(*Identity)
+	return identity, ok && identity != nil
+}
+
+// GetIdentity 从gin上下文中获取调用方身份
+func GetIdentity(c *gin.Context) (*Identity, bool) {
+	value, exists := c.Get(ContextKeyIdentity)
+	if !exists {
+		return nil, false
+	}
+	identity, ok := value.(*Identity)
+	return identity, ok && identity != nil
+}
@@ -0,0 +1,332 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"kai/kaigate/pkg/log"
+)
+
+// jwksMinRefreshInterval 遇到未知kid时强制刷新JWKS的最小间隔，防止被恶意令牌放大请求
+const jwksMinRefreshInterval = 10 * time.Second
+
+// JSONWebKey JWKS中的单个密钥
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA公钥参数
+	N string `json:"n"`
+	E string `json:"e"`
+	// EC公钥参数
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	// 对称密钥参数
+	K string `json:"k"`
+}
+
+// JSONWebKeySet JWKS文档
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// PublicKey 将JWK转换为可用于验签的密钥
+// RSA返回*rsa.PublicKey，EC返回*ecdsa.PublicKey，oct返回[]byte
+func (k JSONWebKey) PublicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("decode rsa modulus failed: %w", err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("decode rsa exponent failed: %w", err)
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported ec curve: %s", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("decode ec x failed: %w", err)
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("decode ec y failed: %w", err)
+		}
+		key := &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}
+		if !key.Curve.IsOnCurve(key.X, key.Y) {
+			return nil, errors.New("ec point is not on curve")
+		}
+		return key, nil
+
+	case "oct":
+		secret, err := base64.RawURLEncoding.DecodeString(k.K)
+		if err != nil {
+			return nil, fmt.Errorf("decode symmetric key failed: %w", err)
+		}
+		return secret, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported key type: %s", k.Kty)
+	}
+}
+
+// KeySet 验签密钥集合
+// 由静态密钥和远程JWKS组成，远程JWKS按间隔缓存，遇到未知kid时刷新以支持密钥轮换
+type KeySet struct {
+	staticKeys      map[string]interface{} // kid到密钥的映射，空kid表示默认密钥
+	jwksURL         string
+	refreshInterval time.Duration
+	client          *http.Client
+	remoteKeys      map[string]interface{}
+	lastRefresh     time.Time
+	mutex           sync.RWMutex
+	refreshMutex    sync.Mutex
+	logger          log.Logger
+}
+
+// NewKeySet 创建KeySet实例
+func NewKeySet(jwksURL string, refreshInterval time.Duration) *KeySet {
+	if refreshInterval <= 0 {
+		refreshInterval = 5 * time.Minute
+	}
+	return &KeySet{
+		staticKeys:      make(map[string]interface{}),
+		jwksURL:         jwksURL,
+		refreshInterval: refreshInterval,
+		client:          &http.Client{Timeout: 10 * time.Second},
+		remoteKeys:      make(map[string]interface{}),
+		logger:          log.GlobalLogger,
+	}
+}
+
+// AddStaticKey 添加静态密钥
+func (ks *KeySet) AddStaticKey(kid string, key interface{}) {
+	ks.mutex.Lock()
+	ks.staticKeys[kid] = key
+	ks.mutex.Unlock()
+}
+
+// AddPEMFile 从PEM文件加载公钥或证书，kid为空时作为默认密钥
+func (ks *KeySet) AddPEMFile(kid, file string) error {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("read key file failed: %w", err)
+	}
+
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return fmt.Errorf("no pem data found in %s", file)
+	}
+
+	var key interface{}
+	switch block.Type {
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return fmt.Errorf("parse certificate failed: %w", err)
+		}
+		key = cert.PublicKey
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+		if err != nil {
+			return fmt.Errorf("parse rsa public key failed: %w", err)
+		}
+	default:
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return fmt.Errorf("parse public key failed: %w", err)
+		}
+	}
+
+	ks.AddStaticKey(kid, key)
+	return nil
+}
+
+// Lookup 根据kid查找验签密钥
+func (ks *KeySet) Lookup(ctx context.Context, kid string) (interface{}, error) {
+	if key, ok := ks.lookupCached(kid, false); ok {
+		return key, nil
+	}
+	if ks.jwksURL == "" {
+		// 未配置JWKS时，带kid的令牌也可以使用默认静态密钥验签
+		if key, ok := ks.lookupCached("", false); ok {
+			return key, nil
+		}
+		return nil, fmt.Errorf("no key found for kid %q", kid)
+	}
+
+	// 缓存过期或出现未知kid时刷新JWKS
+	if err := ks.refresh(ctx, false); err != nil {
+		// 刷新失败时退回使用过期的缓存密钥，避免身份提供方短暂不可用导致全部拒绝
+		if key, ok := ks.lookupCached(kid, true); ok {
+			ks.logger.Warn("Using stale JWKS after refresh failure", zap.Error(err))
+			return key, nil
+		}
+		return nil, err
+	}
+	if key, ok := ks.lookupCached(kid, false); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("no key found for kid %q", kid)
+}
+
+// lookupCached 从已加载的密钥中查找
+func (ks *KeySet) lookupCached(kid string, allowStale bool) (interface{}, bool) {
+	ks.mutex.RLock()
+	defer ks.mutex.RUnlock()
+
+	if key, ok := ks.staticKeys[kid]; ok {
+		return key, true
+	}
+
+	// 缓存过期后不再使用远程密钥，强制刷新
+	if !allowStale && time.Since(ks.lastRefresh) > ks.refreshInterval {
+		return nil, false
+	}
+	if key, ok := ks.remoteKeys[kid]; ok {
+		return key, true
+	}
+	// 未指定kid且远程只有一个密钥时直接使用
+	if kid == "" && len(ks.remoteKeys) == 1 {
+		for _, key := range ks.remoteKeys {
+			return key, true
+		}
+	}
+	return nil, false
+}
+
+// Refresh 立即刷新远程JWKS
+func (ks *KeySet) Refresh(ctx context.Context) error {
+	return ks.refresh(ctx, true)
+}
+
+// refresh 刷新远程JWKS，非强制刷新时受最小间隔限制
+func (ks *KeySet) refresh(ctx context.Context, force bool) error {
+	ks.refreshMutex.Lock()
+	defer ks.refreshMutex.Unlock()
+
+	ks.mutex.RLock()
+	lastRefresh := ks.lastRefresh
+	ks.mutex.RUnlock()
+	if !force && time.Since(lastRefresh) < jwksMinRefreshInterval {
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ks.jwksURL, nil)
+	if err != nil {
+		return fmt.Errorf("create jwks request failed: %w", err)
+	}
+	resp, err := ks.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("fetch jwks failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetch jwks failed: status %d", resp.StatusCode)
+	}
+
+	var set JSONWebKeySet
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&set); err != nil {
+		return fmt.Errorf("decode jwks failed: %w", err)
+	}
+
+	keys := make(map[string]interface{}, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.PublicKey()
+		if err != nil {
+			ks.logger.Warn("Skipping unsupported JWK", zap.String("kid", jwk.Kid), zap.Error(err))
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+
+	ks.mutex.Lock()
+	ks.remoteKeys = keys
+	ks.lastRefresh = time.Now()
+	ks.mutex.Unlock()
+
+	ks.logger.Info("JWKS refreshed", zap.String("url", ks.jwksURL), zap.Int("keys", len(keys)))
+	return nil
+}
+
+// DiscoverJWKSURL 通过OIDC发现文档获取JWKS地址
+// 发现文档中的issuer必须与配置的签发者一致，防止使用其他签发者的密钥
+func DiscoverJWKSURL(ctx context.Context, issuer string) (string, error) {
+	discoveryURL := strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, discoveryURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("create discovery request failed: %w", err)
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("fetch discovery document failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("fetch discovery document failed: status %d", resp.StatusCode)
+	}
+
+	var document struct {
+		Issuer  string `json:"issuer"`
+		JWKSURI string `json:"jwks_uri"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&document); err != nil {
+		return "", fmt.Errorf("decode discovery document failed: %w", err)
+	}
+	if strings.TrimSuffix(document.Issuer, "/") != strings.TrimSuffix(issuer, "/") {
+		return "", fmt.Errorf("discovery document issuer %q does not match %q", document.Issuer, issuer)
+	}
+	if document.JWKSURI == "" {
+		return "", errors.New("discovery document has no jwks_uri")
+	}
+	return document.JWKSURI, nil
+}
+
+// hashForAlgorithm 获取签名算法对应的摘要算法
+func hashForAlgorithm(alg string) (crypto.Hash, bool) {
+	switch alg {
+	case "HS256", "RS256", "ES256":
+		return crypto.SHA256, true
+	default:
+		return 0, false
+	}
+}
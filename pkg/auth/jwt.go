@@ -0,0 +1,387 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// IdentityTypeJWT JWT令牌认证
+const IdentityTypeJWT = "jwt"
+
+// JWT校验错误
+var (
+	ErrMalformedToken       = errors.New("malformed token")
+	ErrUnsupportedAlg       = errors.New("unsupported signing algorithm")
+	ErrInvalidSignature     = errors.New("invalid token signature")
+	ErrTokenExpired         = errors.New("token expired")
+	ErrTokenNotYetValid     = errors.New("token not yet valid")
+	ErrInvalidIssuer        = errors.New("invalid token issuer")
+	ErrInvalidAudience      = errors.New("invalid token audience")
+	ErrKeyAlgorithmMismatch = errors.New("key type does not match signing algorithm")
+)
+
+// Claims JWT声明
+type Claims map[string]interface{}
+
+// String 获取字符串类型的声明
+func (c Claims) String(name string) string {
+	value, _ := c[name].(string)
+	return value
+}
+
+// Strings 获取字符串列表类型的声明
+// 同时支持JSON数组和以空格分隔的字符串（如OAuth2的scope声明）
+func (c Claims) Strings(name string) []string {
+	switch value := c[name].(type) {
+	case string:
+		return strings.Fields(value)
+	case []interface{}:
+		result := make([]string, 0, len(value))
+		for _, item := range value {
+			if str, ok := item.(string); ok {
+				result = append(result, str)
+			}
+		}
+		return result
+	default:
+		return nil
+	}
+}
+
+// maxNumericDate NumericDate声明允许的最大绝对值，超过后无法精确转换为时间
+const maxNumericDate = 1 << 53
+
+// Time 获取NumericDate类型的声明，声明不存在时ok为false
+// NumericDate可以带小数部分；声明存在但不是有效数字时返回错误，避免格式错误的exp被当作未设置
+func (c Claims) Time(name string) (t time.Time, ok bool, err error) {
+	raw, exists := c[name]
+	if !exists || raw == nil {
+		return time.Time{}, false, nil
+	}
+
+	var seconds float64
+	switch value := raw.(type) {
+	case float64:
+		seconds = value
+	case json.Number:
+		if seconds, err = value.Float64(); err != nil {
+			return time.Time{}, false, fmt.Errorf("%w: invalid %s claim", ErrMalformedToken, name)
+		}
+	default:
+		return time.Time{}, false, fmt.Errorf("%w: invalid %s claim", ErrMalformedToken, name)
+	}
+	if math.IsNaN(seconds) || math.Abs(seconds) > maxNumericDate {
+		return time.Time{}, false, fmt.Errorf("%w: invalid %s claim", ErrMalformedToken, name)
+	}
+
+	whole, fraction := math.Modf(seconds)
+	return time.Unix(int64(whole), int64(fraction*float64(time.Second))), true, nil
+}
+
+// JWTConfig JWT认证配置
+type JWTConfig struct {
+	Algorithms []string      // 允许的签名算法
+	Issuer     string        // 期望的签发者，为空不校验
+	Audiences  []string      // 期望的受众，任一匹配即可，为空不校验
+	Leeway     time.Duration // 时间校验容差
+	HMACSecret []byte        // HS256共享密钥
+
+	// 声明到身份字段的映射
+	SubjectClaim     string
+	NameClaim        string
+	RolesClaim       string
+	GroupsClaim      string
+	ScopesClaim      string
+	AgentsClaim      string
+	MCPServicesClaim string
+
+	// 需要转发给上游服务的声明，声明名到请求头的映射
+	ForwardClaims map[string]string
+}
+
+// JWTAuthenticator 基于JWT的认证器
+// 支持HS256/RS256/ES256签名，密钥来自静态配置或JWKS
+type JWTAuthenticator struct {
+	config     JWTConfig
+	keys       *KeySet
+	algorithms map[string]bool
+}
+
+// NewJWTAuthenticator 创建JWTAuthenticator实例
+func NewJWTAuthenticator(config JWTConfig, keys *KeySet) *JWTAuthenticator {
+	if len(config.Algorithms) == 0 {
+		config.Algorithms = []string{"RS256", "ES256"}
+	}
+	if config.SubjectClaim == "" {
+		config.SubjectClaim = "sub"
+	}
+	if config.ScopesClaim == "" {
+		config.ScopesClaim = "scope"
+	}
+	if keys == nil {
+		keys = NewKeySet("", 0)
+	}
+
+	algorithms := make(map[string]bool, len(config.Algorithms))
+	for _, alg := range config.Algorithms {
+		algorithms[alg] = true
+	}
+
+	return &JWTAuthenticator{
+		config:     config,
+		keys:       keys,
+		algorithms: algorithms,
+	}
+}
+
+// Name 获取认证器名称
+func (a *JWTAuthenticator) Name() string {
+	return "jwt"
+}
+
+// ForwardedHeaders 获取由该认证器设置的上游请求头
+// 认证中间件会先清除客户端传入的同名请求头，防止伪造
+func (a *JWTAuthenticator) ForwardedHeaders() []string {
+	headers := make([]string, 0, len(a.config.ForwardClaims))
+	for _, header := range a.config.ForwardClaims {
+		headers = append(headers, header)
+	}
+	return headers
+}
+
+// Authenticate 从请求中提取并校验JWT
+// 令牌来自Authorization: Bearer请求头，WebSocket连接也可使用access_token查询参数
+func (a *JWTAuthenticator) Authenticate(r *http.Request) (*Identity, error) {
+	token := bearerToken(r)
+	if token == "" {
+		token = r.URL.Query().Get("access_token")
+	}
+	// 非JWT格式的令牌交给其他认证器处理
+	if token == "" || strings.Count(token, ".") != 2 {
+		return nil, ErrNoCredentials
+	}
+
+	claims, err := a.Verify(r.Context(), token)
+	if err != nil {
+		return nil, err
+	}
+	return a.identityFromClaims(claims), nil
+}
+
+// Verify 校验JWT签名和时间、签发者、受众声明
+func (a *JWTAuthenticator) Verify(ctx context.Context, token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformedToken
+	}
+
+	// 解析头部
+	headerBytes, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, ErrMalformedToken
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := json.Unmarshal(headerBytes, &header); err != nil {
+		return nil, ErrMalformedToken
+	}
+	if !a.algorithms[header.Alg] {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAlg, header.Alg)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrMalformedToken
+	}
+
+	// 校验签名
+	signingInput := parts[0] + "." + parts[1]
+	if err := a.verifySignature(ctx, header.Alg, header.Kid, signingInput, signature); err != nil {
+		return nil, err
+	}
+
+	// 解析声明
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrMalformedToken
+	}
+	decoder := json.NewDecoder(strings.NewReader(string(payload)))
+	decoder.UseNumber()
+	var claims Claims
+	if err := decoder.Decode(&claims); err != nil {
+		return nil, ErrMalformedToken
+	}
+
+	if err := a.validateClaims(claims, time.Now()); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// verifySignature 按算法校验签名
+func (a *JWTAuthenticator) verifySignature(ctx context.Context, alg, kid, signingInput string, signature []byte) error {
+	digest := sha256.Sum256([]byte(signingInput))
+
+	if alg == "HS256" {
+		secret := a.config.HMACSecret
+		if len(secret) == 0 {
+			// 未配置共享密钥时尝试使用JWKS中的对称密钥
+			key, err := a.keys.Lookup(ctx, kid)
+			if err != nil {
+				return err
+			}
+			var ok bool
+			if secret, ok = key.([]byte); !ok {
+				return ErrKeyAlgorithmMismatch
+			}
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(signingInput))
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return ErrInvalidSignature
+		}
+		return nil
+	}
+
+	key, err := a.keys.Lookup(ctx, kid)
+	if err != nil {
+		return err
+	}
+
+	hash, _ := hashForAlgorithm(alg)
+	switch alg {
+	case "RS256":
+		publicKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return ErrKeyAlgorithmMismatch
+		}
+		if err := rsa.VerifyPKCS1v15(publicKey, hash, digest[:], signature); err != nil {
+			return ErrInvalidSignature
+		}
+		return nil
+
+	case "ES256":
+		publicKey, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return ErrKeyAlgorithmMismatch
+		}
+		// JWS中的ECDSA签名为定长的r||s
+		if len(signature) != 64 {
+			return ErrInvalidSignature
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(publicKey, digest[:], r, s) {
+			return ErrInvalidSignature
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupportedAlg, alg)
+	}
+}
+
+// validateClaims 校验时间、签发者和受众声明
+func (a *JWTAuthenticator) validateClaims(claims Claims, now time.Time) error {
+	exp, ok, err := claims.Time("exp")
+	if err != nil {
+		return err
+	}
+	if ok && now.After(exp.Add(a.config.Leeway)) {
+		return ErrTokenExpired
+	}
+	nbf, ok, err := claims.Time("nbf")
+	if err != nil {
+		return err
+	}
+	if ok && now.Add(a.config.Leeway).Before(nbf) {
+		return ErrTokenNotYetValid
+	}
+	// iat不参与有效期判断，但格式错误说明令牌不可信
+	if _, _, err := claims.Time("iat"); err != nil {
+		return err
+	}
+
+	if a.config.Issuer != "" && claims.String("iss") != a.config.Issuer {
+		return ErrInvalidIssuer
+	}
+
+	if len(a.config.Audiences) > 0 {
+		matched := false
+		for _, aud := range claims.Strings("aud") {
+			for _, expected := range a.config.Audiences {
+				if aud == expected {
+					matched = true
+				}
+			}
+		}
+		if !matched {
+			return ErrInvalidAudience
+		}
+	}
+	return nil
+}
+
+// identityFromClaims 将JWT声明映射为调用方身份
+func (a *JWTAuthenticator) identityFromClaims(claims Claims) *Identity {
+	subject := claims.String(a.config.SubjectClaim)
+
+	identity := &Identity{
+		ID:     "jwt:" + subject,
+		Name:   subject,
+		Type:   IdentityTypeJWT,
+		Owner:  claims.String("iss"),
+		Scopes: claims.Strings(a.config.ScopesClaim),
+		Claims: claims,
+	}
+	if a.config.NameClaim != "" {
+		if name := claims.String(a.config.NameClaim); name != "" {
+			identity.Name = name
+		}
+	}
+	if a.config.RolesClaim != "" {
+		identity.Roles = claims.Strings(a.config.RolesClaim)
+	}
+	if a.config.GroupsClaim != "" {
+		identity.Groups = claims.Strings(a.config.GroupsClaim)
+	}
+	if a.config.AgentsClaim != "" {
+		identity.Agents = claims.Strings(a.config.AgentsClaim)
+	}
+	if a.config.MCPServicesClaim != "" {
+		identity.MCPServices = claims.Strings(a.config.MCPServicesClaim)
+	}
+
+	// 生成转发给上游服务的请求头
+	if len(a.config.ForwardClaims) > 0 {
+		identity.ForwardHeaders = make(map[string]string, len(a.config.ForwardClaims))
+		for claim, header := range a.config.ForwardClaims {
+			switch value := claims[claim].(type) {
+			case nil:
+				continue
+			case string:
+				identity.ForwardHeaders[header] = value
+			case []interface{}:
+				identity.ForwardHeaders[header] = strings.Join(claims.Strings(claim), ",")
+			default:
+				identity.ForwardHeaders[header] = fmt.Sprint(value)
+			}
+		}
+	}
+
+	return identity
+}
@@ -0,0 +1,315 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"kai/kaigate/pkg/log"
+)
+
+func TestMain(m *testing.M) {
+	if err := log.InitLogger("error", "json", "", false); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// jwksServer 测试用的JWKS服务，可以在运行中替换密钥
+type jwksServer struct {
+	*httptest.Server
+	mutex    sync.Mutex
+	keys     []JSONWebKey
+	requests int
+}
+
+func newJWKSServer(t *testing.T, keys ...JSONWebKey) *jwksServer {
+	t.Helper()
+	s := &jwksServer{keys: keys}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		s.requests++
+		json.NewEncoder(w).Encode(JSONWebKeySet{Keys: s.keys})
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *jwksServer) setKeys(keys ...JSONWebKey) {
+	s.mutex.Lock()
+	s.keys = keys
+	s.mutex.Unlock()
+}
+
+func encodeSegment(t *testing.T, value interface{}) string {
+	t.Helper()
+	data, err := json.Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// signToken 按算法签发令牌，key为[]byte、*rsa.PrivateKey或*ecdsa.PrivateKey
+func signToken(t *testing.T, alg, kid string, key interface{}, claims map[string]interface{}) string {
+	t.Helper()
+	header := map[string]string{"alg": alg, "typ": "JWT"}
+	if kid != "" {
+		header["kid"] = kid
+	}
+	signingInput := encodeSegment(t, header) + "." + encodeSegment(t, claims)
+	digest := sha256.Sum256([]byte(signingInput))
+
+	var signature []byte
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(signingInput))
+		signature = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		var err error
+		if signature, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:]); err != nil {
+			t.Fatal(err)
+		}
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		signature = make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+	default:
+		t.Fatalf("unsupported key type %T", key)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func rsaJWK(t *testing.T, kid string) (*rsa.PrivateKey, JSONWebKey) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, JSONWebKey{
+		Kty: "RSA",
+		Kid: kid,
+		Use: "sig",
+		Alg: "RS256",
+		N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func ecJWK(t *testing.T, kid string) (*ecdsa.PrivateKey, JSONWebKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, JSONWebKey{
+		Kty: "EC",
+		Kid: kid,
+		Use: "sig",
+		Alg: "ES256",
+		Crv: "P-256",
+		X:   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+		Y:   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32))),
+	}
+}
+
+func validClaims() map[string]interface{} {
+	now := time.Now()
+	return map[string]interface{}{
+		"sub": "alice",
+		"iss": "https://issuer.example.com",
+		"aud": "kaigate",
+		"iat": now.Unix(),
+		"nbf": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(time.Hour).Unix(),
+	}
+}
+
+func TestVerifyHS256(t *testing.T) {
+	secret := []byte("test-secret")
+	authenticator := NewJWTAuthenticator(JWTConfig{Algorithms: []string{"HS256"}, HMACSecret: secret}, nil)
+
+	claims, err := authenticator.Verify(context.Background(), signToken(t, "HS256", "", secret, validClaims()))
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if claims.String("sub") != "alice" {
+		t.Errorf("sub = %q, want alice", claims.String("sub"))
+	}
+
+	_, err = authenticator.Verify(context.Background(), signToken(t, "HS256", "", []byte("other-secret"), validClaims()))
+	if !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Verify() with wrong secret error = %v, want %v", err, ErrInvalidSignature)
+	}
+}
+
+func TestVerifyJWKS(t *testing.T) {
+	rsaKey, rsaPublic := rsaJWK(t, "rsa-1")
+	ecKey, ecPublic := ecJWK(t, "ec-1")
+	server := newJWKSServer(t, rsaPublic, ecPublic)
+
+	authenticator := NewJWTAuthenticator(JWTConfig{Algorithms: []string{"RS256", "ES256"}}, NewKeySet(server.URL, time.Hour))
+
+	tests := []struct {
+		name string
+		alg  string
+		kid  string
+		key  interface{}
+	}{
+		{name: "RS256", alg: "RS256", kid: "rsa-1", key: rsaKey},
+		{name: "ES256", alg: "ES256", kid: "ec-1", key: ecKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := signToken(t, tt.alg, tt.kid, tt.key, validClaims())
+			if _, err := authenticator.Verify(context.Background(), token); err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+		})
+	}
+
+	// 算法与密钥类型不一致时拒绝
+	token := signToken(t, "ES256", "rsa-1", ecKey, validClaims())
+	if _, err := authenticator.Verify(context.Background(), token); !errors.Is(err, ErrKeyAlgorithmMismatch) {
+		t.Errorf("Verify() with mismatched key error = %v, want %v", err, ErrKeyAlgorithmMismatch)
+	}
+
+	// 未允许的算法直接拒绝
+	token = signToken(t, "HS256", "", []byte("secret"), validClaims())
+	if _, err := authenticator.Verify(context.Background(), token); !errors.Is(err, ErrUnsupportedAlg) {
+		t.Errorf("Verify() with HS256 error = %v, want %v", err, ErrUnsupportedAlg)
+	}
+}
+
+func TestVerifyJWKSKeyRotation(t *testing.T) {
+	oldKey, oldPublic := rsaJWK(t, "old")
+	server := newJWKSServer(t, oldPublic)
+	keys := NewKeySet(server.URL, time.Hour)
+	authenticator := NewJWTAuthenticator(JWTConfig{Algorithms: []string{"RS256"}}, keys)
+
+	if _, err := authenticator.Verify(context.Background(), signToken(t, "RS256", "old", oldKey, validClaims())); err != nil {
+		t.Fatalf("Verify() with old key error = %v", err)
+	}
+
+	newKey, newPublic := rsaJWK(t, "new")
+	server.setKeys(newPublic)
+
+	// 最小刷新间隔内遇到未知kid不会请求JWKS
+	if _, err := authenticator.Verify(context.Background(), signToken(t, "RS256", "new", newKey, validClaims())); err == nil {
+		t.Fatal("Verify() with new key succeeded within min refresh interval")
+	}
+	if server.requests != 1 {
+		t.Errorf("jwks requests = %d, want 1", server.requests)
+	}
+
+	// 超过最小刷新间隔后未知kid触发刷新
+	keys.mutex.Lock()
+	keys.lastRefresh = time.Now().Add(-jwksMinRefreshInterval)
+	keys.mutex.Unlock()
+	if _, err := authenticator.Verify(context.Background(), signToken(t, "RS256", "new", newKey, validClaims())); err != nil {
+		t.Fatalf("Verify() with rotated key error = %v", err)
+	}
+	if server.requests != 2 {
+		t.Errorf("jwks requests = %d, want 2", server.requests)
+	}
+
+	// 轮换后旧密钥不再可用
+	if _, err := authenticator.Verify(context.Background(), signToken(t, "RS256", "old", oldKey, validClaims())); err == nil {
+		t.Error("Verify() with removed key succeeded")
+	}
+}
+
+func TestValidateClaims(t *testing.T) {
+	secret := []byte("test-secret")
+	authenticator := NewJWTAuthenticator(JWTConfig{
+		Algorithms: []string{"HS256"},
+		HMACSecret: secret,
+		Issuer:     "https://issuer.example.com",
+		Audiences:  []string{"kaigate", "other"},
+		Leeway:     30 * time.Second,
+	}, nil)
+	now := time.Now()
+
+	tests := []struct {
+		name   string
+		modify func(claims map[string]interface{})
+		want   error
+	}{
+		{name: "valid", modify: func(map[string]interface{}) {}},
+		{name: "expired", modify: func(c map[string]interface{}) { c["exp"] = now.Add(-time.Minute).Unix() }, want: ErrTokenExpired},
+		{name: "expired within leeway", modify: func(c map[string]interface{}) { c["exp"] = now.Add(-10 * time.Second).Unix() }},
+		{name: "fractional exp expired", modify: func(c map[string]interface{}) { c["exp"] = float64(now.Add(-time.Minute).Unix()) + 0.5 }, want: ErrTokenExpired},
+		{name: "fractional exp valid", modify: func(c map[string]interface{}) { c["exp"] = float64(now.Add(time.Hour).Unix()) + 0.5 }},
+		{name: "string exp", modify: func(c map[string]interface{}) { c["exp"] = "tomorrow" }, want: ErrMalformedToken},
+		{name: "huge exp", modify: func(c map[string]interface{}) { c["exp"] = 1e300 }, want: ErrMalformedToken},
+		{name: "not yet valid", modify: func(c map[string]interface{}) { c["nbf"] = now.Add(time.Minute).Unix() }, want: ErrTokenNotYetValid},
+		{name: "malformed nbf", modify: func(c map[string]interface{}) { c["nbf"] = true }, want: ErrMalformedToken},
+		{name: "malformed iat", modify: func(c map[string]interface{}) { c["iat"] = "now" }, want: ErrMalformedToken},
+		{name: "no exp", modify: func(c map[string]interface{}) { delete(c, "exp") }},
+		{name: "wrong issuer", modify: func(c map[string]interface{}) { c["iss"] = "https://evil.example.com" }, want: ErrInvalidIssuer},
+		{name: "wrong audience", modify: func(c map[string]interface{}) { c["aud"] = "someone-else" }, want: ErrInvalidAudience},
+		{name: "audience list", modify: func(c map[string]interface{}) { c["aud"] = []string{"someone-else", "other"} }},
+		{name: "no audience", modify: func(c map[string]interface{}) { delete(c, "aud") }, want: ErrInvalidAudience},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := validClaims()
+			tt.modify(claims)
+			_, err := authenticator.Verify(context.Background(), signToken(t, "HS256", "", secret, claims))
+			if tt.want == nil && err != nil {
+				t.Fatalf("Verify() error = %v, want nil", err)
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Fatalf("Verify() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiscoverJWKSURL(t *testing.T) {
+	var issuer string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/.well-known/openid-configuration" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"issuer": issuer, "jwks_uri": issuer + "/jwks"})
+	}))
+	defer server.Close()
+
+	issuer = server.URL
+	jwksURL, err := DiscoverJWKSURL(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("DiscoverJWKSURL() error = %v", err)
+	}
+	if jwksURL != server.URL+"/jwks" {
+		t.Errorf("jwks url = %q, want %q", jwksURL, server.URL+"/jwks")
+	}
+
+	// 发现文档声明的签发者与配置不一致时拒绝
+	issuer = "https://evil.example.com"
+	if _, err := DiscoverJWKSURL(context.Background(), server.URL); err == nil {
+		t.Error("DiscoverJWKSURL() with mismatched issuer succeeded")
+	}
+}
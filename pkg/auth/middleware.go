@@ -0,0 +1,128 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"kai/kaigate/pkg/log"
+)
+
+// ErrNoCredentials 请求未携带该认证器可识别的凭证
+var ErrNoCredentials = errors.New("no credentials provided")
+
+// Authenticator 认证器接口
+// 从HTTP请求中解析调用方身份
+type Authenticator interface {
+	// 获取认证器名称
+	Name() string
+
+	// 认证请求，未携带凭证时返回ErrNoCredentials
+	Authenticate(r *http.Request) (*Identity, error)
+}
+
+// HeaderForwarder 会向上游转发请求头的认证器
+type HeaderForwarder interface {
+	// 获取该认证器可能设置的请求头名称
+	ForwardedHeaders() []string
+}
+
+// CredentialStripper 认证后需要从请求中移除凭证的认证器
+// 网关自身签发的凭证只用于访问网关，不能随请求转发给代理目标等上游服务
+type CredentialStripper interface {
+	// 从请求中移除该认证器识别的凭证
+	StripCredentials(r *http.Request)
+}
+
+// Middleware 创建认证中间件
+// 依次尝试各认证器，成功后将身份写入gin上下文和请求上下文；
+// required为true时，未携带凭证的请求（skipPaths除外）将被拒绝
+func Middleware(logger log.Logger, required bool, skipPaths []string, authenticators ...Authenticator) gin.HandlerFunc {
+	if logger == nil {
+		logger = log.GlobalLogger
+	}
+
+	skip := make(map[string]bool, len(skipPaths))
+	for _, path := range skipPaths {
+		skip[path] = true
+	}
+
+	// 收集需要转发给上游的请求头，这些请求头只能由网关设置
+	forwardedHeaders := []string{}
+	strippers := []CredentialStripper{}
+	for _, authenticator := range authenticators {
+		if forwarder, ok := authenticator.(HeaderForwarder); ok {
+			forwardedHeaders = append(forwardedHeaders, forwarder.ForwardedHeaders()...)
+		}
+		if stripper, ok := authenticator.(CredentialStripper); ok {
+			strippers = append(strippers, stripper)
+		}
+	}
+
+	return func(c *gin.Context) {
+		var identity *Identity
+
+		// 清除客户端伪造的转发请求头
+		for _, header := range forwardedHeaders {
+			c.Request.Header.Del(header)
+		}
+
+		for _, authenticator := range authenticators {
+			id, err := authenticator.Authenticate(c.Request)
+			if errors.Is(err, ErrNoCredentials) {
+				continue
+			}
+			if err != nil {
+				logger.Warn("Authentication failed",
+					zap.String("authenticator", authenticator.Name()),
+					zap.String("path", c.Request.URL.Path),
+					zap.String("remote_addr", c.ClientIP()),
+					zap.Error(err),
+				)
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+				return
+			}
+			identity = id
+			break
+		}
+
+		if identity == nil {
+			if required && !skip[c.Request.URL.Path] {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: missing credentials"})
+				return
+			}
+			identity = AnonymousIdentity(c.ClientIP())
+		}
+
+		// 移除网关凭证，避免转发给上游服务
+		for _, stripper := range strippers {
+			stripper.StripCredentials(c.Request)
+		}
+
+		// 将身份声明转发给上游服务
+		for header, value := range identity.ForwardHeaders {
+			c.Request.Header.Set(header, value)
+		}
+
+		SetIdentity(c, identity)
+		c.Next()
+	}
+}
+
+// SetIdentity 将调用方身份写入gin上下文和请求上下文
+func SetIdentity(c *gin.Context, identity *Identity) {
+	c.Set(ContextKeyIdentity, identity)
+	c.Request = c.Request.WithContext(WithIdentity(c.Request.Context(), identity))
+}
+
+// bearerToken 从Authorization请求头中提取Bearer令牌
+func bearerToken(r *http.Request) string {
+	header := r.Header.Get("Authorization")
+	if len(header) > 7 && strings.EqualFold(header[:7], "Bearer ") {
+		return strings.TrimSpace(header[7:])
+	}
+	return ""
+}
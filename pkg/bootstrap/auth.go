@@ -0,0 +1,252 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"kai/kaigate/pkg/auth"
+	"kai/kaigate/pkg/config"
+	"kai/kaigate/pkg/storage"
+)
+
+// setupAuth 根据配置初始化认证组件
+// 返回的中间件为nil表示未启用认证
+func (s *Server) setupAuth() gin.HandlerFunc {
+	authConfig := config.GlobalConfig.Auth
+	if !authConfig.Enable {
+		s.logger.Info("Authentication disabled")
+		return nil
+	}
+
+	authenticators := []auth.Authenticator{}
+
+	// 初始化API Key认证
+	if authConfig.APIKey.Enable {
+		if s.apiKeyManager == nil {
+			store, err := storage.NewKV(authConfig.APIKey.Store, authConfig.APIKey.StoreDir)
+			if err == nil {
+				s.apiKeyManager, err = auth.NewAPIKeyManager(store)
+			}
+			if err != nil {
+				s.logger.Error("Failed to initialize API key manager", zap.Error(err))
+				s.authErrors = append(s.authErrors, fmt.Errorf("api key: %w", err))
+			}
+		}
+		if s.apiKeyManager != nil {
+			authenticators = append(authenticators, auth.NewAPIKeyAuthenticator(s.apiKeyManager, authConfig.APIKey.Header))
+		}
+	}
+
+	// 初始化JWT认证
+	if authConfig.JWT.Enable {
+		authenticator, err := s.newJWTAuthenticator()
+		if err != nil {
+			s.logger.Error("Failed to initialize JWT authenticator", zap.Error(err))
+			s.authErrors = append(s.authErrors, fmt.Errorf("jwt: %w", err))
+		} else {
+			authenticators = append(authenticators, authenticator)
+		}
+	}
+
+	s.logger.Info("Authentication enabled",
+		zap.Bool("required", authConfig.Required),
+		zap.Int("authenticators", len(authenticators)),
+	)
+	return auth.Middleware(s.logger, authConfig.Required, authConfig.SkipPaths, authenticators...)
+}
+
+// authError 合并各认证器初始化的错误，没有错误时返回nil
+func (s *Server) authError() error {
+	if len(s.authErrors) == 0 {
+		return nil
+	}
+	return fmt.Errorf("failed to initialize authentication: %w", errors.Join(s.authErrors...))
+}
+
+// newJWTAuthenticator 根据配置创建JWT认证器
+func (s *Server) newJWTAuthenticator() (*auth.JWTAuthenticator, error) {
+	jwtConfig := config.GlobalConfig.Auth.JWT
+
+	// 通过OIDC发现文档获取JWKS地址
+	jwksURL := jwtConfig.JWKSURL
+	if jwksURL == "" && jwtConfig.OIDCDiscovery && jwtConfig.Issuer != "" {
+		ctx, cancel := context.WithTimeout(s.serverContext, 10*time.Second)
+		defer cancel()
+		discovered, err := auth.DiscoverJWKSURL(ctx, jwtConfig.Issuer)
+		if err != nil {
+			return nil, err
+		}
+		jwksURL = discovered
+	}
+
+	// 加载静态密钥，以文件名（不含扩展名）作为kid，第一个文件同时作为默认密钥
+	keys := auth.NewKeySet(jwksURL, time.Duration(jwtConfig.RefreshInterval)*time.Second)
+	for i, file := range jwtConfig.PublicKeyFiles {
+		kid := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+		if err := keys.AddPEMFile(kid, file); err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			if err := keys.AddPEMFile("", file); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// 预热JWKS缓存，失败时在首次验签时重试
+	if jwksURL != "" {
+		go func() {
+			ctx, cancel := context.WithTimeout(s.serverContext, 10*time.Second)
+			defer cancel()
+			if err := keys.Refresh(ctx); err != nil {
+				s.logger.Warn("Failed to prefetch JWKS", zap.String("url", jwksURL), zap.Error(err))
+			}
+		}()
+	}
+
+	claims := jwtConfig.Claims
+	return auth.NewJWTAuthenticator(auth.JWTConfig{
+		Algorithms:       jwtConfig.Algorithms,
+		Issuer:           jwtConfig.Issuer,
+		Audiences:        jwtConfig.Audiences,
+		Leeway:           time.Duration(jwtConfig.Leeway) * time.Second,
+		HMACSecret:       []byte(jwtConfig.HMACSecret),
+		SubjectClaim:     claims["subject"],
+		NameClaim:        claims["name"],
+		RolesClaim:       claims["roles"],
+		GroupsClaim:      claims["groups"],
+		ScopesClaim:      claims["scopes"],
+		AgentsClaim:      claims["agents"],
+		MCPServicesClaim: claims["mcp_services"],
+		ForwardClaims:    jwtConfig.ForwardClaims,
+	}, keys), nil
+}
+
+// registerAPIKeyRoutes 注册API Key管理接口
+func (s *Server) registerAPIKeyRoutes(router *gin.Engine) {
+	keys := router.Group("/api-keys")
+	{
+		keys.GET("", s.handleListAPIKeys)
+		keys.POST("", s.handleIssueAPIKey)
+		keys.GET("/:id", s.handleGetAPIKey)
+		keys.DELETE("/:id", s.handleRevokeAPIKey)
+		keys.POST("/:id/enable", s.handleSetAPIKeyEnabled(true))
+		keys.POST("/:id/disable", s.handleSetAPIKeyEnabled(false))
+	}
+}
+
+// requireAPIKeyManager 检查API Key管理器是否可用
+func (s *Server) requireAPIKeyManager(c *gin.Context) bool {
+	if s.apiKeyManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "API key authentication is not enabled"})
+		return false
+	}
+	return true
+}
+
+// handleListAPIKeys 处理API Key列表请求
+func (s *Server) handleListAPIKeys(c *gin.Context) {
+	if !s.requireAPIKeyManager(c) {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"api_keys": s.apiKeyManager.List()})
+}
+
+// handleIssueAPIKey 处理API Key签发请求
+func (s *Server) handleIssueAPIKey(c *gin.Context) {
+	if !s.requireAPIKeyManager(c) {
+		return
+	}
+
+	var request auth.IssueAPIKeyRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	plaintext, key, err := s.apiKeyManager.Issue(request)
+	if err != nil {
+		s.logger.Error("Failed to issue API key", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue API key: " + err.Error()})
+		return
+	}
+
+	s.logger.Audit("issue_api_key", c.ClientIP(), key.ID, true, zap.String("name", key.Name))
+
+	// 明文只在签发时返回一次
+	c.JSON(http.StatusCreated, gin.H{
+		"key":     plaintext,
+		"api_key": key,
+	})
+}
+
+// handleGetAPIKey 处理API Key详情请求
+func (s *Server) handleGetAPIKey(c *gin.Context) {
+	if !s.requireAPIKeyManager(c) {
+		return
+	}
+
+	key, exists := s.apiKeyManager.Get(c.Param("id"))
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+		return
+	}
+	c.JSON(http.StatusOK, key)
+}
+
+// handleRevokeAPIKey 处理API Key吊销请求
+func (s *Server) handleRevokeAPIKey(c *gin.Context) {
+	if !s.requireAPIKeyManager(c) {
+		return
+	}
+
+	id := c.Param("id")
+	if err := s.apiKeyManager.Revoke(id); err != nil {
+		if errors.Is(err, auth.ErrAPIKeyNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+			return
+		}
+		s.logger.Error("Failed to revoke API key", zap.String("id", id), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke API key: " + err.Error()})
+		return
+	}
+
+	s.logger.Audit("revoke_api_key", c.ClientIP(), id, true)
+	c.JSON(http.StatusOK, gin.H{"message": "API key revoked"})
+}
+
+// handleSetAPIKeyEnabled 创建API Key启用/禁用处理函数
+func (s *Server) handleSetAPIKeyEnabled(enabled bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !s.requireAPIKeyManager(c) {
+			return
+		}
+
+		id := c.Param("id")
+		key, err := s.apiKeyManager.SetEnabled(id, enabled)
+		if err != nil {
+			if errors.Is(err, auth.ErrAPIKeyNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+				return
+			}
+			s.logger.Error("Failed to update API key", zap.String("id", id), zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update API key: " + err.Error()})
+			return
+		}
+
+		action := "disable_api_key"
+		if enabled {
+			action = "enable_api_key"
+		}
+		s.logger.Audit(action, c.ClientIP(), id, true)
+		c.JSON(http.StatusOK, key)
+	}
+}
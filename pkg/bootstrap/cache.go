@@ -0,0 +1,87 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"kai/kaigate/pkg/cache"
+	"kai/kaigate/pkg/config"
+	"kai/kaigate/pkg/service/ai_agent"
+)
+
+// setupCache 根据配置初始化AI响应缓存，未启用时返回nil
+func (s *Server) setupCache() *cache.Cache {
+	cacheConfig := config.GetConfig().Cache
+	if !cacheConfig.Enable || s.agentManager == nil {
+		return nil
+	}
+
+	options := []cache.Option{cache.WithLogger(s.logger)}
+	if semantic := cacheConfig.Semantic; semantic.Enable {
+		if semantic.Agent == "" {
+			s.logger.Error("Semantic cache requires an embedding agent, semantic matching disabled")
+		} else {
+			options = append(options, cache.WithSemantic(s.semanticEmbedder(semantic.Agent, semantic.Model), semantic.Threshold))
+		}
+	}
+
+	s.responseCache = cache.NewCache(options...)
+	s.logger.Info("AI response cache enabled",
+		zap.Int("ttl", cacheConfig.TTL),
+		zap.Int("max_entries", cacheConfig.MaxEntries),
+		zap.String("scope", s.responseCache.Scope()),
+		zap.Bool("semantic", s.responseCache.Semantic()),
+	)
+	return s.responseCache
+}
+
+// semanticEmbedder 使用指定的AI Agent生成语义缓存的嵌入向量
+func (s *Server) semanticEmbedder(agentID, model string) cache.Embedder {
+	return func(ctx context.Context, text string) ([]float64, error) {
+		agent, err := s.agentManager.GetAIAgent(agentID, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := agent.Embedding(ctx, ai_agent.EmbeddingRequest{Model: model, Input: []string{text}})
+		if err != nil {
+			return nil, err
+		}
+		if len(resp.Data) == 0 {
+			return nil, errors.New("embedding response is empty")
+		}
+		return resp.Data[0].Embedding, nil
+	}
+}
+
+// registerCacheRoutes 注册缓存管理接口
+func (s *Server) registerCacheRoutes(router *gin.Engine) {
+	router.GET("/cache", func(c *gin.Context) {
+		if s.responseCache == nil {
+			c.JSON(http.StatusOK, gin.H{"enabled": false})
+			return
+		}
+		total, agents := s.responseCache.Stats()
+		c.JSON(http.StatusOK, gin.H{
+			"enabled":  true,
+			"semantic": s.responseCache.Semantic(),
+			"agents":   s.responseCache.Agents(),
+			"entries":  s.responseCache.Len(),
+			"total":    total,
+			"by_agent": agents,
+		})
+	})
+
+	router.DELETE("/cache", func(c *gin.Context) {
+		if s.responseCache == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Cache is not enabled"})
+			return
+		}
+		purged := s.responseCache.Purge()
+		s.logger.Audit("purge_cache", c.ClientIP(), "cache", true)
+		c.JSON(http.StatusOK, gin.H{"message": "Cache purged", "purged": purged})
+	})
+}
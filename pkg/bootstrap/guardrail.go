@@ -0,0 +1,79 @@
+package bootstrap
+
+import (
+	"net/http"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"kai/kaigate/pkg/config"
+	"kai/kaigate/pkg/guardrail"
+)
+
+// setupGuardrail 根据配置创建AI Agent调用防护中间件，未启用或配置无效时返回nil
+func (s *Server) setupGuardrail() *guardrail.Guard {
+	guardConfig := config.GetConfig().Guardrail
+	if !guardConfig.Enable {
+		return nil
+	}
+
+	var detectors []guardrail.Detector
+	if len(guardConfig.Detectors) > 0 {
+		builtin, err := guardrail.BuiltinDetectors(guardConfig.Detectors...)
+		if err != nil {
+			s.logger.Error("Invalid guardrail detectors, guardrail disabled", zap.Error(err))
+			return nil
+		}
+		detectors = builtin
+	}
+	for _, custom := range guardConfig.Custom {
+		detector, err := guardrail.NewDetector(custom.Name, custom.Pattern)
+		if err != nil {
+			s.logger.Error("Invalid guardrail detector, guardrail disabled", zap.Error(err))
+			return nil
+		}
+		detectors = append(detectors, detector)
+	}
+
+	patterns := make([]*regexp.Regexp, 0, len(guardConfig.Deny.Patterns))
+	for _, pattern := range guardConfig.Deny.Patterns {
+		regex, err := regexp.Compile(pattern)
+		if err != nil {
+			s.logger.Error("Invalid guardrail deny pattern, guardrail disabled",
+				zap.String("pattern", pattern),
+				zap.Error(err),
+			)
+			return nil
+		}
+		patterns = append(patterns, regex)
+	}
+
+	s.guard = guardrail.NewGuard(
+		guardrail.WithDetectors(detectors...),
+		guardrail.WithDenyKeywords(guardConfig.Deny.Keywords...),
+		guardrail.WithDenyPatterns(patterns...),
+		guardrail.WithAgents(guardConfig.Agents),
+		guardrail.WithTargetResolver(s.routeTargets),
+		guardrail.WithRestore(guardConfig.Restore),
+		guardrail.WithMaskResponses(guardConfig.MaskResponses),
+		guardrail.WithLogger(s.logger),
+	)
+	s.logger.Info("Guardrail enabled",
+		zap.Int("detectors", len(detectors)),
+		zap.Int("deny_rules", len(guardConfig.Deny.Keywords)+len(patterns)),
+		zap.Bool("restore", guardConfig.Restore),
+	)
+	return s.guard
+}
+
+// registerGuardrailRoutes 注册调用防护统计接口
+func (s *Server) registerGuardrailRoutes(router *gin.Engine) {
+	router.GET("/guardrail", func(c *gin.Context) {
+		if s.guard == nil {
+			c.JSON(http.StatusOK, gin.H{"enabled": false})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"enabled": true, "stats": s.guard.Stats()})
+	})
+}
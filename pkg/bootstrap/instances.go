@@ -0,0 +1,233 @@
+package bootstrap
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"kai/kaigate/pkg/config"
+	"kai/kaigate/pkg/service/ai_agent"
+	"kai/kaigate/pkg/service/mcp"
+)
+
+// removedServiceFactory 已从配置中删除的MCP服务实例，调用时返回错误
+type removedServiceFactory struct {
+	name string
+}
+
+// Create 实现MCPServiceFactory接口的Create方法
+func (f *removedServiceFactory) Create() (mcp.MCPService, error) {
+	return nil, fmt.Errorf("MCP service instance removed from config: %s", f.name)
+}
+
+// Name 实现MCPServiceFactory接口的Name方法
+func (f *removedServiceFactory) Name() string {
+	return f.name
+}
+
+// setupInstances 按配置创建AI Agent实例、实例池和MCP服务实例
+func (s *Server) setupInstances() {
+	s.agentInstances = make(map[string]config.AIAgentInstanceConfig)
+	s.agentPools = make(map[string]config.AgentPoolConfig)
+	s.serviceInstances = make(map[string]config.MCPServiceInstanceConfig)
+	s.reloadInstances()
+}
+
+// reloadInstances 从当前配置重新加载实例
+// 配置有变更的实例按新配置重新创建，创建失败时保留原实例；配置中删除的AI Agent实例被删除，MCP服务实例保留名称，调用时返回错误
+func (s *Server) reloadInstances() {
+	cfg := config.GetConfig()
+	if s.agentManager != nil {
+		s.applyAgentInstances(cfg.AIAgents)
+		s.applyAgentPools(cfg.AgentPools)
+	}
+	if s.mcpManager != nil {
+		s.applyServiceInstances(cfg.MCPServices)
+	}
+}
+
+// applyAgentInstances 应用AI Agent实例配置
+func (s *Server) applyAgentInstances(instances []config.AIAgentInstanceConfig) {
+	configured := make(map[string]bool, len(instances))
+	for _, instance := range instances {
+		if configured[instance.Name] {
+			s.logger.Error("Duplicate AI agent instance in config, skipped", zap.String("name", instance.Name))
+			continue
+		}
+		configured[instance.Name] = true
+
+		if previous, exists := s.agentInstances[instance.Name]; exists && reflect.DeepEqual(previous, instance) {
+			continue
+		}
+		if err := s.agentManager.CreateInstance(instance.Name, instance.Type, agentInstanceConfig(instance)); err != nil {
+			s.logger.Error("Failed to create AI agent instance", zap.String("name", instance.Name), zap.Error(err))
+			continue
+		}
+		s.agentInstances[instance.Name] = instance
+		s.logger.Info("AI agent instance configured",
+			zap.String("name", instance.Name),
+			zap.String("type", instance.Type),
+		)
+	}
+
+	for name := range s.agentInstances {
+		if configured[name] {
+			continue
+		}
+		s.agentManager.RemoveInstance(name)
+		delete(s.agentInstances, name)
+		s.logger.Info("AI agent instance removed", zap.String("name", name))
+	}
+}
+
+// applyAgentPools 应用AI Agent实例池配置，配置有变更的实例池重新创建，配置中删除的实例池被删除
+func (s *Server) applyAgentPools(pools []config.AgentPoolConfig) {
+	configured := make(map[string]bool, len(pools))
+	for _, pool := range pools {
+		if configured[pool.Name] {
+			s.logger.Error("Duplicate AI agent pool in config, skipped", zap.String("name", pool.Name))
+			continue
+		}
+		configured[pool.Name] = true
+
+		if previous, exists := s.agentPools[pool.Name]; exists && reflect.DeepEqual(previous, pool) {
+			continue
+		}
+		interval := pool.HealthCheckInterval
+		if interval <= 0 {
+			interval = config.DefaultAgentPoolHealthCheckInterval
+		}
+		if err := s.agentManager.CreatePool(pool.Name, pool.Members, time.Duration(interval)*time.Second); err != nil {
+			s.logger.Error("Failed to create AI agent pool", zap.String("name", pool.Name), zap.Error(err))
+			continue
+		}
+		s.agentPools[pool.Name] = pool
+	}
+
+	for name := range s.agentPools {
+		if configured[name] {
+			continue
+		}
+		s.agentManager.RemovePool(name)
+		delete(s.agentPools, name)
+	}
+}
+
+// applyServiceInstances 应用MCP服务实例配置
+func (s *Server) applyServiceInstances(instances []config.MCPServiceInstanceConfig) {
+	configured := make(map[string]bool, len(instances))
+	for _, instance := range instances {
+		if configured[instance.Name] {
+			s.logger.Error("Duplicate MCP service instance in config, skipped", zap.String("name", instance.Name))
+			continue
+		}
+		configured[instance.Name] = true
+
+		if previous, exists := s.serviceInstances[instance.Name]; exists && reflect.DeepEqual(previous, instance) {
+			continue
+		}
+		factory, err := mcp.NewFactory(instance.Type, instance.Name, serviceInstanceConfig(instance))
+		if err != nil {
+			s.logger.Error("Invalid MCP service instance config", zap.String("name", instance.Name), zap.Error(err))
+			continue
+		}
+
+		if err := s.mcpManager.ReplaceFactory(factory); err != nil {
+			s.logger.Error("Failed to register MCP service instance", zap.String("name", instance.Name), zap.Error(err))
+			continue
+		}
+		s.serviceInstances[instance.Name] = instance
+
+		if _, err := s.mcpManager.GetMCPService(instance.Name, nil); err != nil {
+			s.logger.Error("Failed to create MCP service instance", zap.String("name", instance.Name), zap.Error(err))
+			continue
+		}
+		s.logger.Info("MCP service instance configured",
+			zap.String("name", instance.Name),
+			zap.String("type", instance.Type),
+		)
+	}
+
+	for name := range s.serviceInstances {
+		if configured[name] {
+			continue
+		}
+		s.mcpManager.ReplaceFactory(&removedServiceFactory{name: name})
+		delete(s.serviceInstances, name)
+		s.logger.Info("MCP service instance removed", zap.String("name", name))
+	}
+}
+
+// registerInstanceRoutes 注册实例查询接口
+func (s *Server) registerInstanceRoutes(router *gin.Engine) {
+	// 列出AI Agent实例及其工厂和健康状态
+	router.GET("/ai-agents", func(c *gin.Context) {
+		if s.agentManager == nil {
+			c.JSON(http.StatusOK, gin.H{"agents": []interface{}{}})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"agents": s.agentManager.ListAgentInstances()})
+	})
+
+	// 列出实例池及各成员的健康状态和进行中请求数
+	router.GET("/ai-agent-pools", func(c *gin.Context) {
+		if s.agentManager == nil {
+			c.JSON(http.StatusOK, gin.H{"pools": []ai_agent.AgentPoolStatus{}})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"pools": s.agentManager.ListPools()})
+	})
+}
+
+// agentInstanceConfig 将实例配置转换为AI Agent的配置项，Options中的同名配置项会被覆盖
+func agentInstanceConfig(instance config.AIAgentInstanceConfig) map[string]interface{} {
+	values := make(map[string]interface{}, len(instance.Options)+8)
+	for key, value := range instance.Options {
+		values[key] = value
+	}
+	setString(values, "base_url", instance.BaseURL)
+	setString(values, "api_key", instance.APIKey)
+	setString(values, "organization", instance.Organization)
+	setString(values, "default_model", instance.DefaultModel)
+	if len(instance.Headers) > 0 {
+		values["headers"] = instance.Headers
+	}
+	if len(instance.ModelMap) > 0 {
+		values["model_map"] = instance.ModelMap
+	}
+	if len(instance.Models) > 0 {
+		values["models"] = instance.Models
+	}
+	if instance.Timeout > 0 {
+		values["timeout"] = instance.Timeout
+	}
+	if instance.MaxConcurrency > 0 {
+		values["max_concurrency"] = instance.MaxConcurrency
+	}
+	return values
+}
+
+// serviceInstanceConfig 将实例配置转换为MCP服务的配置项
+func serviceInstanceConfig(instance config.MCPServiceInstanceConfig) map[string]interface{} {
+	values := make(map[string]interface{}, len(instance.Options)+3)
+	for key, value := range instance.Options {
+		values[key] = value
+	}
+	setString(values, "endpoint", instance.Endpoint)
+	setString(values, "api_key", instance.APIKey)
+	if instance.Timeout > 0 {
+		values["timeout"] = instance.Timeout
+	}
+	return values
+}
+
+// setString 非空时设置字符串配置项
+func setString(values map[string]interface{}, key, value string) {
+	if value != "" {
+		values[key] = value
+	}
+}
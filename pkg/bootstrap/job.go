@@ -0,0 +1,137 @@
+package bootstrap
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"kai/kaigate/pkg/config"
+	"kai/kaigate/pkg/job"
+	"kai/kaigate/pkg/netutil"
+	"kai/kaigate/pkg/storage"
+)
+
+// setupJobs 根据配置初始化异步任务管理器，未启用或初始化失败时返回nil
+func (s *Server) setupJobs() *job.Manager {
+	jobsConfig := config.GetConfig().Jobs
+	if !jobsConfig.Enable || (s.agentManager == nil && s.mcpManager == nil) {
+		return nil
+	}
+
+	store, err := storage.NewKV(jobsConfig.Store, jobsConfig.StoreDir)
+	if err != nil {
+		s.logger.Error("Failed to initialize job store", zap.Error(err))
+		return nil
+	}
+
+	guard, err := callbackGuard()
+	if err != nil {
+		s.logger.Error("Invalid job callback allow list", zap.Error(err))
+		store.Close()
+		return nil
+	}
+
+	options := []job.Option{
+		job.WithCallbackGuard(guard),
+		job.WithWorkers(jobsConfig.Workers),
+		job.WithQueueSize(jobsConfig.QueueSize),
+		job.WithTTL(time.Duration(jobsConfig.TTL) * time.Second),
+		job.WithTimeout(time.Duration(jobsConfig.Timeout) * time.Second),
+		job.WithLogger(s.logger),
+	}
+	// 启用Webhook时回调经过签名并在失败时重试，任务结束事件发布给订阅方
+	if s.webhooks != nil {
+		options = append(options,
+			job.WithNotifier(jobCallbackNotifier{dispatcher: s.webhooks}),
+			job.WithListener(s.publishJobEvent),
+		)
+	} else {
+		options = append(options, job.WithNotifier(job.NewHTTPNotifier(time.Duration(jobsConfig.CallbackTimeout)*time.Second, guard)))
+	}
+
+	manager, err := job.NewManager(store, s.agentManager, s.mcpManager, options...)
+	if err != nil {
+		s.logger.Error("Failed to initialize job manager", zap.Error(err))
+		store.Close()
+		return nil
+	}
+
+	s.jobs = manager
+	s.logger.Info("Async jobs enabled",
+		zap.String("store", jobsConfig.Store),
+		zap.Int("workers", jobsConfig.Workers),
+		zap.Int("queue_size", jobsConfig.QueueSize),
+	)
+	return manager
+}
+
+// callbackGuard 根据配置创建校验异步任务回调地址的Guard
+func callbackGuard() (*netutil.Guard, error) {
+	return netutil.NewGuard(config.GetConfig().Jobs.CallbackAllowedHosts)
+}
+
+// registerJobRoutes 注册异步任务管理接口
+func (s *Server) registerJobRoutes(router *gin.Engine) {
+	jobs := router.Group("/jobs")
+
+	// 列出任务，可以按owner和status过滤
+	jobs.GET("", func(c *gin.Context) {
+		if s.jobs == nil {
+			c.JSON(http.StatusOK, gin.H{"enabled": false})
+			return
+		}
+		list := s.jobs.List(c.Query("owner"))
+		if status := c.Query("status"); status != "" {
+			filtered := list[:0]
+			for _, j := range list {
+				if j.Status == status {
+					filtered = append(filtered, j)
+				}
+			}
+			list = filtered
+		}
+		c.JSON(http.StatusOK, gin.H{"enabled": true, "jobs": list})
+	})
+
+	jobs.GET("/:id", func(c *gin.Context) {
+		if s.jobs == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Jobs are not enabled"})
+			return
+		}
+		j, err := s.jobs.Get(c.Param("id"))
+		if err != nil {
+			c.JSON(jobErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, j)
+	})
+
+	// 取消任务
+	jobs.DELETE("/:id", func(c *gin.Context) {
+		if s.jobs == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Jobs are not enabled"})
+			return
+		}
+		j, err := s.jobs.Cancel(c.Param("id"))
+		s.logger.Audit("cancel_job", c.ClientIP(), c.Param("id"), err == nil)
+		if err != nil {
+			c.JSON(jobErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, j)
+	})
+}
+
+// jobErrorStatus 根据任务错误返回HTTP状态码
+func jobErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, job.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, job.ErrFinished):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,74 @@
+package bootstrap
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"kai/kaigate/pkg/config"
+	"kai/kaigate/pkg/service/middleware"
+	"kai/kaigate/pkg/tokenizer"
+)
+
+// setupMiddleware 根据配置向AI Agent和MCP服务管理器注册内置中间件
+// 执行顺序为耗时统计、日志、参数校验、用量统计、token计数、调用防护、敏感信息替换，校验失败的请求同样计入耗时统计和日志，不计入用量
+func (s *Server) setupMiddleware() {
+	middlewareConfig := config.GetConfig().Middleware
+
+	var stack []middleware.Middleware
+	if middlewareConfig.Timing.Enable {
+		s.timing = middleware.NewTiming()
+		stack = append(stack, s.timing)
+	}
+	if middlewareConfig.Logging.Enable {
+		stack = append(stack, middleware.NewLogging(s.logger))
+	}
+	if validation := middlewareConfig.Validation; validation.Enable {
+		stack = append(stack, middleware.NewValidation(
+			middleware.WithMaxMessages(validation.MaxMessages),
+			middleware.WithMaxInputChars(validation.MaxInputChars),
+		))
+	}
+	for _, m := range stack {
+		s.useMiddleware(m, true)
+	}
+
+	// 用量统计只作用于AI Agent
+	if usageMiddleware := s.setupUsage(); usageMiddleware != nil {
+		s.useMiddleware(usageMiddleware, false)
+	}
+
+	// token计数在用量统计之内执行，为缺少用量的响应补全Usage后再记录
+	if config.GetConfig().Tokenizer.Enable {
+		s.useMiddleware(tokenizer.NewMiddleware(s.tokenizers), false)
+	}
+
+	// 调用防护只作用于AI Agent，在敏感信息替换之前执行，以便还原响应中的占位符
+	if guard := s.setupGuardrail(); guard != nil {
+		s.useMiddleware(guard, false)
+	}
+	if pii := middlewareConfig.PIIRedaction; pii.Enable {
+		s.useMiddleware(middleware.NewPIIRedaction(pii.RedactResponses), true)
+	}
+}
+
+// useMiddleware 向AI Agent管理器注册中间件，withMCP为true时同时注册到MCP服务管理器
+func (s *Server) useMiddleware(m middleware.Middleware, withMCP bool) {
+	if s.agentManager != nil {
+		s.agentManager.Use(m)
+	}
+	if withMCP && s.mcpManager != nil {
+		s.mcpManager.Use(m)
+	}
+}
+
+// registerMiddlewareRoutes 注册中间件统计接口
+func (s *Server) registerMiddlewareRoutes(router *gin.Engine) {
+	router.GET("/middleware/timings", func(c *gin.Context) {
+		if s.timing == nil {
+			c.JSON(http.StatusOK, gin.H{"enabled": false})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"enabled": true, "timings": s.timing.Snapshot()})
+	})
+}
@@ -0,0 +1,74 @@
+package bootstrap
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"kai/kaigate/pkg/config"
+	gw_router "kai/kaigate/pkg/router"
+)
+
+// setupModelRoutes 根据配置初始化逻辑模型路由，路由名注册为AI Agent
+func (s *Server) setupModelRoutes() {
+	if s.agentManager == nil {
+		return
+	}
+	s.modelRouter = gw_router.NewModelRouter(s.agentManager)
+	s.modelRouteNames = make(map[string]bool)
+	if err := s.reloadModelRoutes(); err != nil {
+		s.logger.Error("Failed to load model routes", zap.Error(err))
+	}
+}
+
+// reloadModelRoutes 从当前配置加载逻辑模型路由
+// 与已有AI Agent同名的路由不会注册；配置中删除的路由保留名称，调用时返回路由不存在
+func (s *Server) reloadModelRoutes() error {
+	routes := config.GetConfig().ModelRoutes
+	if err := s.modelRouter.Load(routes); err != nil {
+		return err
+	}
+
+	agents := make(map[string]bool)
+	for _, name := range s.agentManager.ListAvailableAgents() {
+		agents[name] = true
+	}
+	for _, route := range routes {
+		if agents[route.Name] && !s.modelRouteNames[route.Name] {
+			s.logger.Error("Model route conflicts with an existing AI agent, skipped", zap.String("route", route.Name))
+			continue
+		}
+		if err := s.agentManager.RegisterFactory(gw_router.NewModelRouteFactory(route.Name, s.modelRouter)); err != nil {
+			s.logger.Error("Failed to register model route", zap.String("route", route.Name), zap.Error(err))
+			continue
+		}
+		s.modelRouteNames[route.Name] = true
+	}
+
+	s.logger.Info("Model routes loaded", zap.Int("routes", len(routes)))
+	return nil
+}
+
+// routeTargets 返回逻辑模型路由的目标AI Agent，供按AI Agent限定范围的防护和合并器展开路由名
+// 路由随配置重载更新，每次调用时读取当前路由
+func (s *Server) routeTargets(name string) []string {
+	if s.modelRouter == nil {
+		return nil
+	}
+	return s.modelRouter.TargetAgents(name)
+}
+
+// registerModelRouteRoutes 注册逻辑模型路由管理接口
+func (s *Server) registerModelRouteRoutes(router *gin.Engine) {
+	router.GET("/model-routes", func(c *gin.Context) {
+		if s.modelRouter == nil {
+			c.JSON(http.StatusOK, gin.H{"routes": []config.ModelRouteConfig{}})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"routes":  s.modelRouter.Routes(),
+			"targets": s.modelRouter.TargetStates(),
+		})
+	})
+}
@@ -0,0 +1,144 @@
+package bootstrap
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"kai/kaigate/pkg/auth"
+	"kai/kaigate/pkg/config"
+	"kai/kaigate/pkg/policy"
+)
+
+// setupPolicy 根据配置初始化授权策略引擎
+// 引擎始终创建，便于通过配置重载启用或禁用策略
+func (s *Server) setupPolicy() *policy.Engine {
+	s.policyEngine = policy.NewEngine()
+	if err := s.reloadPolicies(); err != nil {
+		// 规则无效时拒绝所有调用，避免在策略缺失的情况下放行
+		s.logger.Error("Failed to load policies, denying all requests", zap.Error(err))
+		s.policyEngine.Load(nil, policy.EffectDeny)
+		s.policyEngine.SetEnabled(true)
+	}
+	return s.policyEngine
+}
+
+// reloadPolicies 从当前配置加载授权策略
+func (s *Server) reloadPolicies() error {
+	return s.applyPolicies(config.GetConfig().Policy)
+}
+
+// applyPolicies 加载授权策略，规则无效时保留原有规则
+func (s *Server) applyPolicies(policyConfig config.PolicyConfig) error {
+	if err := s.policyEngine.Load(policyConfig.Rules, policyConfig.DefaultEffect); err != nil {
+		return err
+	}
+	s.policyEngine.SetEnabled(policyConfig.Enable)
+	return nil
+}
+
+// registerPolicyRoutes 注册授权策略管理接口
+func (s *Server) registerPolicyRoutes(router *gin.Engine) {
+	policies := router.Group("/policies")
+	{
+		policies.GET("", s.handleListPolicies)
+		policies.POST("/reload", s.handleReloadPolicies)
+		policies.POST("/dry-run", s.handleDryRunPolicy)
+	}
+}
+
+// handleListPolicies 处理策略列表请求
+func (s *Server) handleListPolicies(c *gin.Context) {
+	rules, defaultEffect := s.policyEngine.Rules()
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":        s.policyEngine.Enabled(),
+		"default_effect": defaultEffect,
+		"rules":          rules,
+	})
+}
+
+// handleReloadPolicies 处理策略重载请求
+// 重新读取配置文件，只更新其中的授权策略，其余配置保持不变；规则无效时不修改当前配置
+func (s *Server) handleReloadPolicies(c *gin.Context) {
+	policyConfig, err := config.LoadPolicyConfig()
+	if err != nil {
+		s.logger.Error("Failed to reload config", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reload config: " + err.Error()})
+		return
+	}
+
+	if err := s.applyPolicies(policyConfig); err != nil {
+		s.logger.Error("Failed to reload policies", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid policies: " + err.Error()})
+		return
+	}
+	config.SetPolicyConfig(policyConfig)
+
+	rules, defaultEffect := s.policyEngine.Rules()
+	s.logger.Audit("reload_policies", c.ClientIP(), "policies", true)
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Policies reloaded successfully",
+		"enabled":        s.policyEngine.Enabled(),
+		"default_effect": defaultEffect,
+		"rules":          len(rules),
+	})
+}
+
+// handleDryRunPolicy 处理策略试运行请求
+// 可以指定调用方身份或API Key ID；提供rules时使用候选规则评估，否则使用当前生效的规则
+func (s *Server) handleDryRunPolicy(c *gin.Context) {
+	var request struct {
+		Identity      *auth.Identity            `json:"identity"`
+		APIKeyID      string                    `json:"api_key_id"`
+		Resource      string                    `json:"resource" binding:"required"`
+		Target        string                    `json:"target" binding:"required"`
+		Model         string                    `json:"model"`
+		Rules         []config.PolicyRuleConfig `json:"rules"`
+		DefaultEffect string                    `json:"default_effect"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	// 使用API Key对应的身份
+	identity := request.Identity
+	if request.APIKeyID != "" {
+		if s.apiKeyManager == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "API key authentication is not enabled"})
+			return
+		}
+		key, exists := s.apiKeyManager.Get(request.APIKeyID)
+		if !exists {
+			c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+			return
+		}
+		identity = key.Identity()
+	}
+
+	input := policy.Input{
+		Identity: identity,
+		Resource: request.Resource,
+		Target:   request.Target,
+		Model:    request.Model,
+	}
+
+	// 未提供候选规则时使用当前规则评估（即使策略未启用）
+	rules := request.Rules
+	defaultEffect := request.DefaultEffect
+	if rules == nil {
+		rules, defaultEffect = s.policyEngine.Rules()
+	}
+
+	decision, err := policy.DryRun(rules, defaultEffect, input)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid policies: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"input":    input,
+		"decision": decision,
+	})
+}
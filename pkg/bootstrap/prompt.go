@@ -0,0 +1,167 @@
+package bootstrap
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"kai/kaigate/pkg/config"
+	"kai/kaigate/pkg/prompt"
+	"kai/kaigate/pkg/storage"
+)
+
+// setupPrompts 初始化提示模板注册表并加载配置中的模板，初始化失败时返回nil
+func (s *Server) setupPrompts() *prompt.Registry {
+	promptConfig := config.GetConfig().Prompts
+
+	store, err := storage.NewKV(promptConfig.Store, promptConfig.StoreDir)
+	if err != nil {
+		s.logger.Error("Failed to initialize prompt store", zap.Error(err))
+		return nil
+	}
+
+	registry, err := prompt.NewRegistry(store, prompt.WithLogger(s.logger))
+	if err != nil {
+		s.logger.Error("Failed to initialize prompt registry", zap.Error(err))
+		store.Close()
+		return nil
+	}
+	if err := registry.LoadConfig(promptConfig.Templates); err != nil {
+		s.logger.Error("Failed to load prompt templates", zap.Error(err))
+	}
+
+	s.prompts = registry
+	return registry
+}
+
+// reloadPrompts 从当前配置重新加载提示模板
+func (s *Server) reloadPrompts() error {
+	return s.prompts.LoadConfig(config.GetConfig().Prompts.Templates)
+}
+
+// registerPromptRoutes 注册提示模板管理接口
+func (s *Server) registerPromptRoutes(router *gin.Engine) {
+	prompts := router.Group("/prompts")
+
+	// 列出全部模板
+	prompts.GET("", func(c *gin.Context) {
+		if s.prompts == nil {
+			c.JSON(http.StatusOK, gin.H{"templates": []prompt.Info{}})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"templates": s.prompts.List()})
+	})
+
+	// 创建模板版本，未指定版本时使用最新版本号加1
+	prompts.POST("", func(c *gin.Context) {
+		if s.prompts == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Prompt registry is not available"})
+			return
+		}
+		var request prompt.Template
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format: " + err.Error()})
+			return
+		}
+		tmpl, err := s.prompts.Create(request)
+		s.logger.Audit("create_prompt_template", c.ClientIP(), request.Name, err == nil)
+		if err != nil {
+			c.JSON(promptErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, tmpl)
+	})
+
+	// 列出模板的全部版本
+	prompts.GET("/:name", func(c *gin.Context) {
+		if s.prompts == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": prompt.ErrNotFound.Error()})
+			return
+		}
+		versions, err := s.prompts.Versions(c.Param("name"))
+		if err != nil {
+			c.JSON(promptErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"name": c.Param("name"), "versions": versions})
+	})
+
+	// 获取模板版本，版本可以是v3、3或latest
+	prompts.GET("/:name/:version", func(c *gin.Context) {
+		tmpl, ok := s.promptVersion(c)
+		if !ok {
+			return
+		}
+		c.JSON(http.StatusOK, tmpl)
+	})
+
+	// 使用变量预览渲染结果
+	prompts.POST("/:name/:version/render", func(c *gin.Context) {
+		tmpl, ok := s.promptVersion(c)
+		if !ok {
+			return
+		}
+		var request struct {
+			Variables map[string]interface{} `json:"variables"`
+		}
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format: " + err.Error()})
+			return
+		}
+		messages, err := tmpl.Render(request.Variables)
+		if err != nil {
+			c.JSON(promptErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"template": tmpl.Ref(), "messages": messages})
+	})
+
+	// 删除管理接口创建的模板版本
+	prompts.DELETE("/:name/:version", func(c *gin.Context) {
+		tmpl, ok := s.promptVersion(c)
+		if !ok {
+			return
+		}
+		err := s.prompts.Delete(tmpl.Name, tmpl.Version)
+		s.logger.Audit("delete_prompt_template", c.ClientIP(), tmpl.Ref(), err == nil)
+		if err != nil {
+			c.JSON(promptErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Prompt template deleted"})
+	})
+}
+
+// promptVersion 获取路径参数指定的模板版本，失败时写入错误响应
+func (s *Server) promptVersion(c *gin.Context) (*prompt.Template, bool) {
+	if s.prompts == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": prompt.ErrNotFound.Error()})
+		return nil, false
+	}
+	name, version, err := prompt.ParseRef(c.Param("name") + "@" + c.Param("version"))
+	if err != nil {
+		c.JSON(promptErrorStatus(err), gin.H{"error": err.Error()})
+		return nil, false
+	}
+	tmpl, err := s.prompts.Get(name, version)
+	if err != nil {
+		c.JSON(promptErrorStatus(err), gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return tmpl, true
+}
+
+// promptErrorStatus 根据提示模板错误返回HTTP状态码
+func promptErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, prompt.ErrInvalid):
+		return http.StatusBadRequest
+	case errors.Is(err, prompt.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, prompt.ErrExists), errors.Is(err, prompt.ErrReadOnly):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
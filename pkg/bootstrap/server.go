@@ -12,12 +12,26 @@ import (
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 
+	"kai/kaigate/pkg/auth"
+	"kai/kaigate/pkg/cache"
 	"kai/kaigate/pkg/config"
+	"kai/kaigate/pkg/guardrail"
+	"kai/kaigate/pkg/job"
 	"kai/kaigate/pkg/log"
+	"kai/kaigate/pkg/policy"
+	"kai/kaigate/pkg/prompt"
 	http_protocol "kai/kaigate/pkg/protocol/http"
 	"kai/kaigate/pkg/protocol/websocket"
+	gw_router "kai/kaigate/pkg/router"
 	"kai/kaigate/pkg/service/ai_agent"
 	"kai/kaigate/pkg/service/mcp"
+	"kai/kaigate/pkg/service/middleware"
+	"kai/kaigate/pkg/service/orchestrator"
+	"kai/kaigate/pkg/session"
+	"kai/kaigate/pkg/tlsutil"
+	"kai/kaigate/pkg/tokenizer"
+	"kai/kaigate/pkg/usage"
+	"kai/kaigate/pkg/webhook"
 )
 
 // Server 服务器实例
@@ -34,6 +48,48 @@ type Server struct {
 	logger        log.Logger
 	agentManager  ai_agent.AIAgentManager
 	mcpManager    mcp.MCPServiceManager
+	// 认证与限流组件
+	apiKeyManager    *auth.APIKeyManager
+	rateLimitManager *gw_router.RateLimitManager
+	// 上游连接池管理器
+	transportManager *gw_router.TransportManager
+	// 授权策略引擎
+	policyEngine *policy.Engine
+	// 已按配置创建的AI Agent实例、实例池和MCP服务实例
+	agentInstances   map[string]config.AIAgentInstanceConfig
+	agentPools       map[string]config.AgentPoolConfig
+	serviceInstances map[string]config.MCPServiceInstanceConfig
+	// 逻辑模型路由器及已注册的路由名
+	modelRouter     *gw_router.ModelRouter
+	modelRouteNames map[string]bool
+	// AI响应缓存
+	responseCache *cache.Cache
+	// 嵌入向量请求合并器
+	embeddingBatcher *ai_agent.EmbeddingBatcher
+	// 调用耗时统计中间件
+	timing *middleware.Timing
+	// AI Agent调用防护中间件
+	guard *guardrail.Guard
+	// 对话会话管理器
+	sessions *session.Manager
+	// 提示模板注册表
+	prompts *prompt.Registry
+	// 用量账本
+	usage *usage.Ledger
+	// 异步任务管理器
+	jobs *job.Manager
+	// Webhook分发器
+	webhooks *webhook.Dispatcher
+	// 按模型选择的分词器
+	tokenizers *tokenizer.Registry
+	// HTTP路由选项，重载代理路由时复用
+	httpOptions []http_protocol.RouteOption
+	// 各监听器的证书，按监听器名称索引
+	certReloaders map[string]*tlsutil.CertReloader
+	// 启用了TLS但初始化失败的监听器错误
+	tlsErrors []error
+	// 启用了认证但初始化失败的认证器错误
+	authErrors []error
 	// 用于存储已注册的代理路由，便于更新
 	registeredProxyRoutes map[string]bool
 }
@@ -62,6 +118,13 @@ func WithMCPServiceManager(manager mcp.MCPServiceManager) ServerOption {
 	}
 }
 
+// WithAPIKeyManager 设置API Key管理器
+func WithAPIKeyManager(manager *auth.APIKeyManager) ServerOption {
+	return func(s *Server) {
+		s.apiKeyManager = manager
+	}
+}
+
 // NewServer 创建新的服务器实例
 func NewServer(options ...ServerOption) *Server {
 	// 创建服务器上下文
@@ -73,6 +136,7 @@ func NewServer(options ...ServerOption) *Server {
 		cancelFunc:            cancel,
 		logger:                log.GlobalLogger, // 使用默认日志记录器
 		registeredProxyRoutes: make(map[string]bool),
+		certReloaders:         make(map[string]*tlsutil.CertReloader),
 	}
 
 	// 应用选项
@@ -110,16 +174,101 @@ func NewServer(options ...ServerOption) *Server {
 		Handler: server.adminRouter,
 	}
 
+	// 初始化各监听器的TLS，WebSocket升级依赖HTTP/1.1，不启用HTTP/2
+	server.setupTLS("http", server.httpServer, config.GlobalConfig.Server.TLS.HTTP, true)
+	server.setupTLS("ws", server.wsServer, config.GlobalConfig.Server.TLS.WS, false)
+	server.setupTLS("admin", server.adminServer, config.GlobalConfig.Server.TLS.Admin, true)
+
 	// 定义一个回调函数来记录注册的路由
 	onRouteRegistered := func(path string) {
 		server.registeredProxyRoutes[path] = true
 	}
 
+	// 初始化认证组件
+	authMiddleware := server.setupAuth()
+	httpOptions := []http_protocol.RouteOption{}
+	wsOptions := []websocket.RouteOption{}
+	if authMiddleware != nil {
+		httpOptions = append(httpOptions, http_protocol.WithAuthMiddleware(authMiddleware))
+		wsOptions = append(wsOptions, websocket.WithAuthMiddleware(authMiddleware))
+	}
+
+	// 初始化按调用方限流
+	if config.GlobalConfig.Router.EnableRateLimit {
+		rate := config.GlobalConfig.Router.DefaultRateLimit
+		server.rateLimitManager = gw_router.NewRateLimitManager(rate, rate)
+		httpOptions = append(httpOptions, http_protocol.WithRateLimiter(server.rateLimitManager))
+		wsOptions = append(wsOptions, websocket.WithRateLimiter(server.rateLimitManager))
+	}
+
+	// 初始化上游连接池
+	server.transportManager = gw_router.NewTransportManager()
+	httpOptions = append(httpOptions, http_protocol.WithTransportManager(server.transportManager))
+
+	// 初始化授权策略
+	if engine := server.setupPolicy(); engine != nil {
+		httpOptions = append(httpOptions, http_protocol.WithPolicyEngine(engine))
+		wsOptions = append(wsOptions, websocket.WithPolicyEngine(engine))
+	}
+
+	// 按配置创建AI Agent和MCP服务实例
+	server.setupInstances()
+
+	// 加载分词器，供token计数中间件和会话使用
+	server.setupTokenizers()
+
+	// 注册AI Agent和MCP服务调用中间件
+	server.setupMiddleware()
+
+	// 初始化逻辑模型路由
+	server.setupModelRoutes()
+
+	// 初始化AI响应缓存
+	if responseCache := server.setupCache(); responseCache != nil {
+		httpOptions = append(httpOptions, http_protocol.WithCache(responseCache))
+	}
+
+	// 初始化嵌入向量请求合并
+	if batchConfig := config.GlobalConfig.EmbeddingBatch; batchConfig.Enable {
+		server.embeddingBatcher = ai_agent.NewEmbeddingBatcher(
+			ai_agent.WithBatchWindow(time.Duration(batchConfig.Window)*time.Millisecond),
+			ai_agent.WithMaxBatchSize(batchConfig.MaxBatchSize),
+			ai_agent.WithBatchAgents(batchConfig.Agents),
+			ai_agent.WithBatchTargetResolver(server.routeTargets),
+			ai_agent.WithBatcherLogger(server.logger),
+		)
+		httpOptions = append(httpOptions, http_protocol.WithEmbeddingBatcher(server.embeddingBatcher))
+	}
+
+	// 初始化对话会话
+	if sessions := server.setupSession(); sessions != nil {
+		httpOptions = append(httpOptions, http_protocol.WithSessionManager(sessions))
+	}
+
+	// 初始化提示模板
+	if prompts := server.setupPrompts(); prompts != nil {
+		httpOptions = append(httpOptions, http_protocol.WithPromptRegistry(prompts))
+	}
+
+	// 初始化Webhook，异步任务的回调和事件通过Webhook投递
+	server.setupWebhooks()
+
+	// 初始化异步任务
+	if jobs := server.setupJobs(); jobs != nil {
+		httpOptions = append(httpOptions, http_protocol.WithJobManager(jobs))
+	}
+
+	// 初始化工具调用编排
+	if config.GlobalConfig.Orchestrator.Enable && server.mcpManager != nil {
+		httpOptions = append(httpOptions, http_protocol.WithOrchestrator(orchestrator.NewOrchestrator(server.mcpManager, orchestrator.WithLogger(server.logger))))
+	}
+
 	// 注册HTTP处理器，传入管理器和路由注册回调
-	http_protocol.RegisterRoutes(server.httpRouter, server.logger, server.agentManager, server.mcpManager, onRouteRegistered)
+	server.httpOptions = httpOptions
+	http_protocol.RegisterRoutes(server.httpRouter, server.logger, server.agentManager, server.mcpManager, onRouteRegistered, httpOptions...)
 
 	// 注册WebSocket处理器，传入管理器
-	websocket.RegisterRoutes(server.wsRouter, server.logger, server.agentManager, server.mcpManager)
+	websocket.RegisterRoutes(server.wsRouter, server.logger, server.agentManager, server.mcpManager, wsOptions...)
 
 	// 注册管理接口处理器
 	server.registerAdminRoutes(server.adminRouter)
@@ -148,12 +297,63 @@ func (s *Server) ReloadProxyRoutes() error {
 	}
 
 	// 重新注册代理路由
-	http_protocol.RegisterProxyRoutesFromConfig(s.httpRouter, s.logger, onRouteRegistered)
+	http_protocol.RegisterProxyRoutesFromConfig(s.httpRouter, s.logger, onRouteRegistered, s.httpOptions...)
 	s.logger.Info("Proxy routes reloaded successfully")
 
+	// 应用配置中的其他变更
+	s.applyReloadedConfig()
+
 	return nil
 }
 
+// applyReloadedConfig 在配置重载后应用支持热更新的配置项
+func (s *Server) applyReloadedConfig() {
+	// 重新加载TLS证书
+	s.reloadCertificates()
+
+	// 按新的配置和证书重新创建上游连接池
+	if s.transportManager != nil {
+		s.transportManager.Reload()
+	}
+
+	// 重新加载授权策略
+	if s.policyEngine != nil {
+		if err := s.reloadPolicies(); err != nil {
+			s.logger.Error("Failed to reload policies, keeping previous rules", zap.Error(err))
+		}
+	}
+
+	// 重新创建有变更的AI Agent和MCP服务实例
+	s.reloadInstances()
+
+	// 重新加载逻辑模型路由
+	if s.modelRouter != nil {
+		if err := s.reloadModelRoutes(); err != nil {
+			s.logger.Error("Failed to reload model routes, keeping previous routes", zap.Error(err))
+		}
+	}
+
+	// 重新加载提示模板
+	if s.prompts != nil {
+		if err := s.reloadPrompts(); err != nil {
+			s.logger.Error("Failed to reload prompt templates, keeping previous templates", zap.Error(err))
+		}
+	}
+
+	// 更新模型价格
+	if s.usage != nil {
+		s.reloadUsagePricing()
+	}
+
+	// 更新配置中的Webhook订阅并通知订阅方
+	if s.webhooks != nil {
+		if err := s.reloadWebhooks(); err != nil {
+			s.logger.Error("Failed to reload webhook subscribers, keeping previous subscribers", zap.Error(err))
+		}
+		s.webhooks.Publish(webhook.EventConfigReloaded, nil)
+	}
+}
+
 // handleReloadConfig 处理配置重载请求
 func (s *Server) handleReloadConfig(c *gin.Context) {
 	if err := config.ReloadConfig(); err != nil {
@@ -165,6 +365,10 @@ func (s *Server) handleReloadConfig(c *gin.Context) {
 	}
 
 	s.logger.Info("Config reloaded successfully")
+
+	// 应用配置中的其他变更
+	s.applyReloadedConfig()
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Config reloaded successfully",
 	})
@@ -239,16 +443,85 @@ func (s *Server) registerAdminRoutes(router *gin.Engine) {
 
 	// 代理路由重载接口
 	router.POST("/reload-proxy-routes", s.handleReloadProxyRoutes)
+
+	// API Key管理接口
+	s.registerAPIKeyRoutes(router)
+
+	// 授权策略管理接口
+	s.registerPolicyRoutes(router)
+
+	// TLS证书管理接口
+	s.registerTLSRoutes(router)
+
+	// AI Agent实例接口
+	s.registerInstanceRoutes(router)
+
+	// 逻辑模型路由接口
+	s.registerModelRouteRoutes(router)
+
+	// 缓存管理接口
+	s.registerCacheRoutes(router)
+
+	// 中间件统计接口
+	s.registerMiddlewareRoutes(router)
+
+	// 调用防护统计接口
+	s.registerGuardrailRoutes(router)
+
+	// 会话管理接口
+	s.registerSessionRoutes(router)
+
+	// 提示模板管理接口
+	s.registerPromptRoutes(router)
+
+	// 用量查询接口
+	s.registerUsageRoutes(router)
+
+	// 分词器查询接口
+	s.registerTokenizerRoutes(router)
+
+	// 异步任务管理接口
+	s.registerJobRoutes(router)
+
+	// Webhook管理接口
+	s.registerWebhookRoutes(router)
+
+	// 嵌入向量请求合并统计接口
+	router.GET("/embedding-batches", func(c *gin.Context) {
+		if s.embeddingBatcher == nil {
+			c.JSON(http.StatusOK, gin.H{"enabled": false})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"enabled": true, "agents": s.embeddingBatcher.Stats()})
+	})
+
+	// 限流状态接口
+	router.GET("/rate-limits", func(c *gin.Context) {
+		if s.rateLimitManager == nil {
+			c.JSON(http.StatusOK, gin.H{"enabled": false})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"enabled": true, "rate_limiters": s.rateLimitManager.GetAllRateLimiters()})
+	})
 }
 
 // Start 启动服务器
 func (s *Server) Start() error {
+	// 启用了TLS的监听器初始化失败时拒绝启动
+	if err := s.tlsError(); err != nil {
+		return err
+	}
+	// 认证器初始化失败时拒绝启动，避免调用方被静默视为匿名
+	if err := s.authError(); err != nil {
+		return err
+	}
+
 	// 启动HTTP服务器
 	s.wg.Add(1)
 	go func() {
 		defer s.wg.Done()
-		s.logger.Info("Starting HTTP server", zap.String("addr", s.httpServer.Addr))
-		if err := s.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		s.logger.Info("Starting HTTP server", zap.String("addr", s.httpServer.Addr), zap.Bool("tls", s.httpServer.TLSConfig != nil))
+		if err := listenAndServe(s.httpServer); err != nil && err != http.ErrServerClosed {
 			s.logger.Error("HTTP server error", zap.Error(err))
 		}
 	}()
@@ -257,8 +530,8 @@ func (s *Server) Start() error {
 	s.wg.Add(1)
 	go func() {
 		defer s.wg.Done()
-		s.logger.Info("Starting WebSocket server", zap.String("addr", s.wsServer.Addr))
-		if err := s.wsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		s.logger.Info("Starting WebSocket server", zap.String("addr", s.wsServer.Addr), zap.Bool("tls", s.wsServer.TLSConfig != nil))
+		if err := listenAndServe(s.wsServer); err != nil && err != http.ErrServerClosed {
 			s.logger.Error("WebSocket server error", zap.Error(err))
 		}
 	}()
@@ -267,8 +540,8 @@ func (s *Server) Start() error {
 	s.wg.Add(1)
 	go func() {
 		defer s.wg.Done()
-		s.logger.Info("Starting admin server", zap.String("addr", s.adminServer.Addr))
-		if err := s.adminServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		s.logger.Info("Starting admin server", zap.String("addr", s.adminServer.Addr), zap.Bool("tls", s.adminServer.TLSConfig != nil))
+		if err := listenAndServe(s.adminServer); err != nil && err != http.ErrServerClosed {
 			s.logger.Error("Admin server error", zap.Error(err))
 		}
 	}()
@@ -328,6 +601,46 @@ func (s *Server) Stop() {
 		}
 	}
 
+	// 释放上游空闲连接
+	if s.transportManager != nil {
+		s.transportManager.CloseIdleConnections()
+	}
+
+	// 停止异步任务，排队中的任务在下次启动后继续执行
+	if s.jobs != nil {
+		if err := s.jobs.Close(); err != nil {
+			s.logger.Error("Job store close error", zap.Error(err))
+		}
+	}
+
+	// 停止Webhook投递，未完成的投递写入死信列表
+	if s.webhooks != nil {
+		if err := s.webhooks.Close(); err != nil {
+			s.logger.Error("Webhook store close error", zap.Error(err))
+		}
+	}
+
+	// 关闭会话存储
+	if s.sessions != nil {
+		if err := s.sessions.Close(); err != nil {
+			s.logger.Error("Session store close error", zap.Error(err))
+		}
+	}
+
+	// 关闭提示模板存储
+	if s.prompts != nil {
+		if err := s.prompts.Close(); err != nil {
+			s.logger.Error("Prompt store close error", zap.Error(err))
+		}
+	}
+
+	// 关闭用量记录文件
+	if s.usage != nil {
+		if err := s.usage.Close(); err != nil {
+			s.logger.Error("Usage sink close error", zap.Error(err))
+		}
+	}
+
 	// 等待所有goroutine完成
 	s.wg.Wait()
 
@@ -0,0 +1,104 @@
+package bootstrap
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"kai/kaigate/pkg/config"
+	"kai/kaigate/pkg/session"
+	"kai/kaigate/pkg/storage"
+)
+
+// setupSession 根据配置初始化对话会话管理器，未启用或初始化失败时返回nil
+func (s *Server) setupSession() *session.Manager {
+	sessionConfig := config.GetConfig().Session
+	if !sessionConfig.Enable || s.agentManager == nil {
+		return nil
+	}
+
+	store, err := storage.NewKV(sessionConfig.Store, sessionConfig.StoreDir)
+	if err != nil {
+		s.logger.Error("Failed to initialize session store", zap.Error(err))
+		return nil
+	}
+
+	manager, err := session.NewManager(store,
+		session.WithMaxTokens(sessionConfig.MaxTokens),
+		session.WithStrategy(sessionConfig.Strategy),
+		session.WithTTL(time.Duration(sessionConfig.TTL)*time.Second),
+		session.WithSummarizer(session.NewAgentSummarizer(s.agentManager, sessionConfig.SummaryAgent, sessionConfig.SummaryModel)),
+		session.WithTokenizers(s.tokenizers),
+		session.WithLogger(s.logger),
+	)
+	if err != nil {
+		s.logger.Error("Failed to initialize session manager", zap.Error(err))
+		store.Close()
+		return nil
+	}
+
+	s.sessions = manager
+	s.logger.Info("Conversation sessions enabled",
+		zap.String("store", sessionConfig.Store),
+		zap.String("strategy", sessionConfig.Strategy),
+		zap.Int("max_tokens", sessionConfig.MaxTokens),
+	)
+	return manager
+}
+
+// registerSessionRoutes 注册会话管理接口
+func (s *Server) registerSessionRoutes(router *gin.Engine) {
+	sessions := router.Group("/sessions")
+
+	// 列出会话，可以按owner过滤
+	sessions.GET("", func(c *gin.Context) {
+		if s.sessions == nil {
+			c.JSON(http.StatusOK, gin.H{"enabled": false})
+			return
+		}
+		infos, err := s.sessions.List(c.Query("owner"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"enabled": true, "sessions": infos})
+	})
+
+	sessions.GET("/:id", func(c *gin.Context) {
+		if s.sessions == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Sessions are not enabled"})
+			return
+		}
+		sess, err := s.sessions.Get(c.Param("id"))
+		if err != nil {
+			c.JSON(sessionErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, sess)
+	})
+
+	sessions.DELETE("/:id", func(c *gin.Context) {
+		if s.sessions == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Sessions are not enabled"})
+			return
+		}
+		err := s.sessions.Delete(c.Param("id"))
+		s.logger.Audit("delete_session", c.ClientIP(), c.Param("id"), err == nil)
+		if err != nil {
+			c.JSON(sessionErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Session deleted"})
+	})
+}
+
+// sessionErrorStatus 根据会话错误返回HTTP状态码
+func sessionErrorStatus(err error) int {
+	if errors.Is(err, session.ErrNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,110 @@
+package bootstrap
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"kai/kaigate/pkg/config"
+	"kai/kaigate/pkg/tlsutil"
+)
+
+// setupTLS 根据配置为监听器启用TLS
+// 初始化失败时记录错误，Start返回该错误拒绝启动，避免证书配置错误时以明文提供服务
+func (s *Server) setupTLS(name string, server *http.Server, tlsConfig config.TLSConfig, enableHTTP2 bool) {
+	if !tlsConfig.Enable {
+		return
+	}
+
+	serverTLSConfig, reloader, err := tlsutil.ServerConfig(tlsConfig, enableHTTP2)
+	if err != nil {
+		s.logger.Error("Failed to initialize TLS",
+			zap.String("listener", name),
+			zap.Error(err),
+		)
+		s.tlsErrors = append(s.tlsErrors, fmt.Errorf("%s listener: %w", name, err))
+		return
+	}
+
+	server.TLSConfig = serverTLSConfig
+	s.certReloaders[name] = reloader
+
+	// 定期检查证书文件变更
+	go reloader.Watch(s.serverContext, time.Duration(tlsConfig.ReloadInterval)*time.Second)
+
+	s.logger.Info("TLS enabled",
+		zap.String("listener", name),
+		zap.Int("certificates", len(reloader.Certificates())),
+		zap.Bool("mtls", tlsConfig.ClientCAFile != ""),
+	)
+}
+
+// tlsError 合并各监听器TLS初始化的错误，没有错误时返回nil
+func (s *Server) tlsError() error {
+	if len(s.tlsErrors) == 0 {
+		return nil
+	}
+	return fmt.Errorf("failed to initialize TLS: %w", errors.Join(s.tlsErrors...))
+}
+
+// listenAndServe 启动监听，配置了TLS时使用HTTPS
+func listenAndServe(server *http.Server) error {
+	if server.TLSConfig != nil {
+		// 证书由TLSConfig.GetCertificate提供
+		return server.ListenAndServeTLS("", "")
+	}
+	return server.ListenAndServe()
+}
+
+// reloadCertificates 从磁盘重新加载所有监听器的证书
+func (s *Server) reloadCertificates() map[string]string {
+	failures := make(map[string]string)
+	for name, reloader := range s.certReloaders {
+		if err := reloader.Reload(); err != nil {
+			s.logger.Error("Failed to reload TLS certificates", zap.String("listener", name), zap.Error(err))
+			failures[name] = err.Error()
+		}
+	}
+	return failures
+}
+
+// registerTLSRoutes 注册TLS证书管理接口
+func (s *Server) registerTLSRoutes(router *gin.Engine) {
+	router.GET("/tls", s.handleGetTLS)
+	router.POST("/tls/reload", s.handleReloadTLS)
+}
+
+// handleGetTLS 处理TLS状态请求
+func (s *Server) handleGetTLS(c *gin.Context) {
+	listeners := make(map[string]interface{}, len(s.certReloaders))
+	for name, reloader := range s.certReloaders {
+		listeners[name] = gin.H{
+			"loaded_at":    reloader.LoadedAt(),
+			"certificates": reloader.Certificates(),
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"listeners": listeners})
+}
+
+// handleReloadTLS 处理证书重载请求
+func (s *Server) handleReloadTLS(c *gin.Context) {
+	failures := s.reloadCertificates()
+	if len(failures) > 0 {
+		s.logger.Audit("reload_tls_certificates", c.ClientIP(), "tls", false)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":  "Failed to reload some certificates, previous certificates are kept",
+			"errors": failures,
+		})
+		return
+	}
+
+	s.logger.Audit("reload_tls_certificates", c.ClientIP(), "tls", true)
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Certificates reloaded successfully",
+		"listeners": len(s.certReloaders),
+	})
+}
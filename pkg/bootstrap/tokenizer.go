@@ -0,0 +1,68 @@
+package bootstrap
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"kai/kaigate/pkg/config"
+	"kai/kaigate/pkg/service/ai_agent"
+	"kai/kaigate/pkg/tokenizer"
+)
+
+// setupTokenizers 加载配置中的BPE词表，加载失败的词表对应的模型使用启发式估算
+func (s *Server) setupTokenizers() {
+	tokenizerConfig := config.GetConfig().Tokenizer
+	s.tokenizers = tokenizer.NewRegistry()
+
+	for _, encoding := range tokenizerConfig.Encodings {
+		bpe, err := tokenizer.LoadBPE(encoding.Name, encoding.File, encoding.Pattern)
+		if err != nil {
+			s.logger.Error("Failed to load tokenizer vocabulary, falling back to heuristic",
+				zap.String("name", encoding.Name),
+				zap.String("file", encoding.File),
+				zap.Error(err),
+			)
+			continue
+		}
+		s.tokenizers.Register(bpe, encoding.Models...)
+		if encoding.Name == tokenizerConfig.Default {
+			s.tokenizers.SetDefault(bpe)
+		}
+		s.logger.Info("Tokenizer vocabulary loaded",
+			zap.String("name", encoding.Name),
+			zap.Int("tokens", bpe.Size()),
+			zap.Strings("models", encoding.Models),
+		)
+	}
+}
+
+// registerTokenizerRoutes 注册分词器查询接口
+func (s *Server) registerTokenizerRoutes(router *gin.Engine) {
+	// 列出已加载的词表及适用的模型
+	router.GET("/tokenizers", func(c *gin.Context) {
+		encodings, fallback := s.tokenizers.List()
+		c.JSON(http.StatusOK, gin.H{"encodings": encodings, "default": fallback})
+	})
+
+	// 使用模型对应的分词器计算文本或消息的token数
+	router.POST("/tokenizers/count", func(c *gin.Context) {
+		var request struct {
+			Model    string             `json:"model"`
+			Text     string             `json:"text"`
+			Messages []ai_agent.Message `json:"messages"`
+		}
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format: " + err.Error()})
+			return
+		}
+
+		counter := s.tokenizers.For(request.Model)
+		tokens := counter.Count(request.Text)
+		if len(request.Messages) > 0 {
+			tokens += tokenizer.CountMessages(counter, request.Messages)
+		}
+		c.JSON(http.StatusOK, gin.H{"tokenizer": counter.Name(), "tokens": tokens})
+	})
+}
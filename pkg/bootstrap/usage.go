@@ -0,0 +1,123 @@
+package bootstrap
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"kai/kaigate/pkg/config"
+	"kai/kaigate/pkg/usage"
+)
+
+// setupUsage 根据配置创建用量账本和用量统计中间件，未启用或初始化失败时返回nil
+func (s *Server) setupUsage() *usage.Middleware {
+	usageConfig := config.GetConfig().Usage
+	if !usageConfig.Enable {
+		return nil
+	}
+
+	options := []usage.Option{
+		usage.WithPricing(usageConfig.Pricing, usageConfig.Currency),
+		usage.WithLogger(s.logger),
+	}
+	if usageConfig.RetentionDays > 0 {
+		options = append(options, usage.WithRetention(time.Duration(usageConfig.RetentionDays)*24*time.Hour))
+	}
+	switch usageConfig.Sink {
+	case "file":
+		sink, err := usage.NewFileSink(usageConfig.File)
+		if err != nil {
+			s.logger.Error("Failed to open usage file, usage accounting disabled", zap.Error(err))
+			return nil
+		}
+		options = append(options, usage.WithSink(sink))
+	case "none", "":
+	default:
+		s.logger.Error("Unknown usage sink, usage accounting disabled", zap.String("sink", usageConfig.Sink))
+		return nil
+	}
+
+	s.usage = usage.NewLedger(options...)
+	return usage.NewMiddleware(s.usage)
+}
+
+// reloadUsagePricing 从当前配置更新模型价格
+func (s *Server) reloadUsagePricing() {
+	usageConfig := config.GetConfig().Usage
+	s.usage.SetPricing(usageConfig.Pricing, usageConfig.Currency)
+}
+
+// registerUsageRoutes 注册用量查询接口
+func (s *Server) registerUsageRoutes(router *gin.Engine) {
+	// 按时间范围和维度汇总用量
+	router.GET("/usage", func(c *gin.Context) {
+		if s.usage == nil {
+			c.JSON(http.StatusOK, gin.H{"enabled": false})
+			return
+		}
+
+		query := usage.Query{
+			Caller: c.Query("caller"),
+			Agent:  c.Query("agent"),
+			Model:  c.Query("model"),
+		}
+		var err error
+		if query.From, err = parseUsageTime(c.Query("from")); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from: " + err.Error()})
+			return
+		}
+		if query.To, err = parseUsageTime(c.Query("to")); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to: " + err.Error()})
+			return
+		}
+		for _, dimension := range strings.Split(c.Query("group_by"), ",") {
+			if dimension = strings.TrimSpace(dimension); dimension != "" {
+				query.GroupBy = append(query.GroupBy, dimension)
+			}
+		}
+
+		report, err := s.usage.Query(query)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, usage.ErrInvalidQuery) {
+				status = http.StatusBadRequest
+			}
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, report)
+	})
+
+	// 查看模型价格
+	router.GET("/usage/pricing", func(c *gin.Context) {
+		if s.usage == nil {
+			c.JSON(http.StatusOK, gin.H{"enabled": false})
+			return
+		}
+		prices, currency := s.usage.Pricing()
+		c.JSON(http.StatusOK, gin.H{"currency": currency, "pricing": prices})
+	})
+}
+
+// parseUsageTime 解析查询时间，支持RFC3339、日期(2006-01-02，UTC)和Unix秒，为空时返回零值
+func parseUsageTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, errors.New("expected RFC3339, YYYY-MM-DD or unix seconds")
+	}
+	return time.Unix(seconds, 0), nil
+}
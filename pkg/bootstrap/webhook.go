@@ -0,0 +1,217 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"kai/kaigate/pkg/config"
+	"kai/kaigate/pkg/job"
+	"kai/kaigate/pkg/storage"
+	"kai/kaigate/pkg/webhook"
+)
+
+// setupWebhooks 根据配置初始化Webhook分发器，未启用或初始化失败时返回nil
+func (s *Server) setupWebhooks() *webhook.Dispatcher {
+	webhookConfig := config.GetConfig().Webhooks
+	if !webhookConfig.Enable {
+		return nil
+	}
+
+	store, err := storage.NewKV(webhookConfig.Store, webhookConfig.StoreDir)
+	if err != nil {
+		s.logger.Error("Failed to initialize webhook store", zap.Error(err))
+		return nil
+	}
+
+	guard, err := callbackGuard()
+	if err != nil {
+		s.logger.Error("Invalid job callback allow list", zap.Error(err))
+		store.Close()
+		return nil
+	}
+
+	dispatcher, err := webhook.NewDispatcher(store,
+		webhook.WithWorkers(webhookConfig.Workers),
+		webhook.WithQueueSize(webhookConfig.QueueSize),
+		webhook.WithTimeout(time.Duration(webhookConfig.Timeout)*time.Second),
+		webhook.WithMaxAttempts(webhookConfig.MaxAttempts),
+		webhook.WithBackoff(time.Duration(webhookConfig.InitialBackoff)*time.Second, time.Duration(webhookConfig.MaxBackoff)*time.Second),
+		webhook.WithCallbackSecret(webhookConfig.CallbackSecret),
+		webhook.WithCallbackGuard(guard),
+		webhook.WithLogger(s.logger),
+	)
+	if err != nil {
+		s.logger.Error("Failed to initialize webhook dispatcher", zap.Error(err))
+		store.Close()
+		return nil
+	}
+	s.webhooks = dispatcher
+
+	if err := s.reloadWebhooks(); err != nil {
+		s.logger.Error("Invalid webhook subscribers in config, ignoring them", zap.Error(err))
+	}
+	s.logger.Info("Webhooks enabled",
+		zap.String("store", webhookConfig.Store),
+		zap.Int("subscribers", len(webhookConfig.Subscribers)),
+	)
+	return dispatcher
+}
+
+// reloadWebhooks 从当前配置更新配置文件中定义的订阅
+func (s *Server) reloadWebhooks() error {
+	subscribers := make([]webhook.Subscriber, 0)
+	for _, sub := range config.GetConfig().Webhooks.Subscribers {
+		subscribers = append(subscribers, webhook.Subscriber{
+			Name:   sub.Name,
+			URL:    sub.URL,
+			Secret: sub.Secret,
+			Events: sub.Events,
+		})
+	}
+	return s.webhooks.SetConfigSubscribers(subscribers)
+}
+
+// publishJobEvent 发布异步任务结束事件
+// 订阅方不是任务的提交者，事件只包含任务摘要，请求和结果只通过提交者指定的回调地址发送
+func (s *Server) publishJobEvent(j job.Job) {
+	s.webhooks.Publish("job."+j.Status, j.Summary())
+}
+
+// jobCallbackNotifier 通过Webhook分发器发送异步任务回调，回调签名并在失败时重试
+type jobCallbackNotifier struct {
+	dispatcher *webhook.Dispatcher
+}
+
+// Notify 实现job.Notifier接口的Notify方法
+func (n jobCallbackNotifier) Notify(_ context.Context, url string, j *job.Job) error {
+	return n.dispatcher.Send(url, "job."+j.Status, j)
+}
+
+// registerWebhookRoutes 注册Webhook管理接口
+func (s *Server) registerWebhookRoutes(router *gin.Engine) {
+	webhooks := router.Group("/webhooks")
+
+	// 列出订阅和投递统计
+	webhooks.GET("", func(c *gin.Context) {
+		if s.webhooks == nil {
+			c.JSON(http.StatusOK, gin.H{"enabled": false})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"enabled": true, "subscribers": s.webhooks.Subscribers(), "stats": s.webhooks.Stats()})
+	})
+
+	// 创建或更新订阅，配置文件中定义的订阅不能修改
+	webhooks.POST("", func(c *gin.Context) {
+		if s.webhooks == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Webhooks are not enabled"})
+			return
+		}
+		var request struct {
+			Name   string   `json:"name" binding:"required"`
+			URL    string   `json:"url" binding:"required"`
+			Secret string   `json:"secret"`
+			Events []string `json:"events"`
+		}
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format: " + err.Error()})
+			return
+		}
+		sub := webhook.Subscriber{Name: request.Name, URL: request.URL, Secret: request.Secret, Events: request.Events}
+		err := s.webhooks.PutSubscriber(sub)
+		s.logger.Audit("put_webhook", c.ClientIP(), request.Name, err == nil)
+		if err != nil {
+			c.JSON(webhookErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		sub.Source = webhook.SourceAPI
+		c.JSON(http.StatusOK, sub.Info())
+	})
+
+	webhooks.DELETE("/:name", func(c *gin.Context) {
+		if s.webhooks == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Webhooks are not enabled"})
+			return
+		}
+		err := s.webhooks.DeleteSubscriber(c.Param("name"))
+		s.logger.Audit("delete_webhook", c.ClientIP(), c.Param("name"), err == nil)
+		if err != nil {
+			c.JSON(webhookErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Webhook deleted"})
+	})
+
+	// 死信列表
+	deadLetters := webhooks.Group("/dead-letters")
+
+	deadLetters.GET("", func(c *gin.Context) {
+		if s.webhooks == nil {
+			c.JSON(http.StatusOK, gin.H{"enabled": false})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"enabled": true, "dead_letters": s.webhooks.DeadLetters()})
+	})
+
+	// 重放全部死信
+	deadLetters.POST("/replay", func(c *gin.Context) {
+		if s.webhooks == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Webhooks are not enabled"})
+			return
+		}
+		count, err := s.webhooks.ReplayAll()
+		s.logger.Audit("replay_webhooks", c.ClientIP(), "*", err == nil)
+		if err != nil {
+			c.JSON(webhookErrorStatus(err), gin.H{"error": err.Error(), "replayed": count})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"replayed": count})
+	})
+
+	deadLetters.POST("/:id/replay", func(c *gin.Context) {
+		if s.webhooks == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Webhooks are not enabled"})
+			return
+		}
+		delivery, err := s.webhooks.Replay(c.Param("id"))
+		s.logger.Audit("replay_webhook", c.ClientIP(), c.Param("id"), err == nil)
+		if err != nil {
+			c.JSON(webhookErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, delivery)
+	})
+
+	deadLetters.DELETE("/:id", func(c *gin.Context) {
+		if s.webhooks == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Webhooks are not enabled"})
+			return
+		}
+		err := s.webhooks.DeleteDeadLetter(c.Param("id"))
+		s.logger.Audit("delete_dead_letter", c.ClientIP(), c.Param("id"), err == nil)
+		if err != nil {
+			c.JSON(webhookErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Dead letter deleted"})
+	})
+}
+
+// webhookErrorStatus 根据Webhook错误返回HTTP状态码
+func webhookErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, webhook.ErrInvalid):
+		return http.StatusBadRequest
+	case errors.Is(err, webhook.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, webhook.ErrReadOnly):
+		return http.StatusConflict
+	case errors.Is(err, webhook.ErrQueueFull):
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,134 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+
+	"go.uber.org/zap"
+
+	"kai/kaigate/pkg/service/ai_agent"
+)
+
+// CachedAgent 为AI Agent的Chat、Completion和Embedding添加缓存，其他方法直接调用原Agent
+// 流式请求不经过缓存
+type CachedAgent struct {
+	ai_agent.AIAgent
+	cache *Cache
+	name  string
+}
+
+// Wrap 为启用缓存的AI Agent添加缓存层，未启用时返回原Agent
+func (c *Cache) Wrap(name string, agent ai_agent.AIAgent) ai_agent.AIAgent {
+	if !c.Enabled(name) {
+		return agent
+	}
+	return &CachedAgent{AIAgent: agent, cache: c, name: name}
+}
+
+// Chat 实现AIAgent接口的Chat方法
+func (a *CachedAgent) Chat(ctx context.Context, req ai_agent.ChatRequest) (*ai_agent.ChatResponse, error) {
+	if req.Stream {
+		return a.AIAgent.Chat(ctx, req)
+	}
+
+	// 语义匹配范围为除消息外的全部参数，参数不同的请求不会相互命中
+	scoped := req
+	scoped.Messages = nil
+	return lookup(ctx, a, "chat", req, scoped, chatText(req.Messages), func() (*ai_agent.ChatResponse, error) {
+		return a.AIAgent.Chat(ctx, req)
+	})
+}
+
+// Completion 实现AIAgent接口的Completion方法
+func (a *CachedAgent) Completion(ctx context.Context, req ai_agent.CompletionRequest) (*ai_agent.CompletionResponse, error) {
+	if req.Stream {
+		return a.AIAgent.Completion(ctx, req)
+	}
+
+	scoped := req
+	scoped.Prompt = ""
+	return lookup(ctx, a, "completion", req, scoped, req.Prompt, func() (*ai_agent.CompletionResponse, error) {
+		return a.AIAgent.Completion(ctx, req)
+	})
+}
+
+// Embedding 实现AIAgent接口的Embedding方法，嵌入向量只做精确匹配
+func (a *CachedAgent) Embedding(ctx context.Context, req ai_agent.EmbeddingRequest) (*ai_agent.EmbeddingResponse, error) {
+	return lookup(ctx, a, "embedding", req, nil, "", func() (*ai_agent.EmbeddingResponse, error) {
+		return a.AIAgent.Embedding(ctx, req)
+	})
+}
+
+// lookup 依次精确匹配、语义匹配，未命中时调用call并写入缓存
+// scope为nil或text为空时不做语义匹配；响应以JSON保存，命中时返回新解码的副本，调用方修改响应不会影响缓存；
+// 精确匹配的键和语义匹配范围都包含调用方所在的缓存分区，调用方不会命中其他分区的响应
+func lookup[T any](ctx context.Context, a *CachedAgent, kind string, req, scope interface{}, text string, call func() (T, error)) (T, error) {
+	var zero T
+	c := a.cache
+	control, result := fromContext(ctx)
+
+	tenant := c.tenant(ctx)
+	key, err := requestKey(tenant, a.name, kind, req)
+	if err != nil {
+		c.logger.Warn("Failed to compute cache key", zap.String("agent", a.name), zap.Error(err))
+		return call()
+	}
+
+	if !control.NoCache {
+		if data, ok := c.Get(key); ok {
+			if response, err := decode[T](data); err == nil {
+				result.Status = StatusHit
+				c.record(a.name, func(s *Stats) { s.Hits++ })
+				return response, nil
+			}
+		}
+	}
+
+	// 计算语义匹配范围和请求文本的嵌入向量，用于语义匹配和写入缓存
+	scopeKey := ""
+	var vector []float64
+	if c.Semantic() && scope != nil && text != "" && !(control.NoCache && control.NoStore) {
+		if scopeKey, err = requestKey(tenant, a.name, kind, scope); err == nil {
+			vector, err = c.embedder(ctx, text)
+		}
+		if err != nil {
+			c.logger.Warn("Failed to compute semantic cache vector", zap.String("agent", a.name), zap.Error(err))
+			scopeKey, vector = "", nil
+		}
+	}
+
+	if control.NoCache {
+		result.Status = StatusBypass
+		c.record(a.name, func(s *Stats) { s.Bypasses++ })
+	} else {
+		if vector != nil {
+			if data, similarity, ok := c.Nearest(scopeKey, vector); ok {
+				if response, err := decode[T](data); err == nil {
+					result.Status, result.Similarity = StatusSemanticHit, similarity
+					c.record(a.name, func(s *Stats) { s.Hits++; s.SemanticHits++ })
+					return response, nil
+				}
+			}
+		}
+		result.Status = StatusMiss
+		c.record(a.name, func(s *Stats) { s.Misses++ })
+	}
+
+	response, err := call()
+	if err != nil {
+		return zero, err
+	}
+	if !control.NoStore {
+		if data, err := json.Marshal(response); err == nil {
+			c.Set(a.name, key, data, scopeKey, vector)
+		}
+	}
+	return response, nil
+}
+
+// decode 解码缓存的响应
+func decode[T any](data []byte) (T, error) {
+	var response T
+	err := json.Unmarshal(data, &response)
+	return response, err
+}
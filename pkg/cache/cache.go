@@ -0,0 +1,337 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"kai/kaigate/pkg/auth"
+	"kai/kaigate/pkg/config"
+	"kai/kaigate/pkg/log"
+)
+
+// 缓存共享范围
+const (
+	ScopeCaller = "caller" // 按调用方身份隔离
+	ScopeOwner  = "owner"  // 按身份所属者隔离，同一所属者的调用方共享，未设置所属者时按调用方隔离
+	ScopeGlobal = "global" // 全部调用方共享
+)
+
+// Embedder 生成文本的嵌入向量，用于语义匹配
+type Embedder func(ctx context.Context, text string) ([]float64, error)
+
+// Cache AI响应缓存
+// 按请求的规范化哈希精确匹配，可选按嵌入向量的余弦相似度语义匹配；条目超过有效期后失效，超出容量时淘汰最久未使用的条目
+type Cache struct {
+	ttl        time.Duration
+	maxEntries int
+	agents     map[string]bool // 启用缓存的AI Agent，为空表示全部
+	scope      string          // 共享范围，决定哪些调用方可以命中同一条目
+	embedder   Embedder
+	threshold  float64
+
+	entries map[string]*list.Element
+	scopes  map[string]map[string]*list.Element // 语义匹配范围到条目的索引
+	order   *list.List                          // 按最近使用排序，最近使用的在前
+	stats   map[string]*Stats                   // 按AI Agent统计
+	mutex   sync.Mutex
+	logger  log.Logger
+}
+
+// Option 缓存选项
+type Option func(*Cache)
+
+// Stats 缓存统计
+type Stats struct {
+	Hits         int64 `json:"hits"`          // 命中次数（含语义命中）
+	SemanticHits int64 `json:"semantic_hits"` // 语义命中次数
+	Misses       int64 `json:"misses"`        // 未命中次数
+	Bypasses     int64 `json:"bypasses"`      // 按请求头跳过查找的次数
+	Stores       int64 `json:"stores"`        // 写入次数
+	Evictions    int64 `json:"evictions"`     // 因容量淘汰的次数
+	Expirations  int64 `json:"expirations"`   // 因过期删除的次数
+}
+
+// entry 缓存条目
+type entry struct {
+	key       string
+	agent     string
+	scope     string    // 语义匹配范围，为空表示不参与语义匹配
+	vector    []float64 // 请求文本的嵌入向量
+	value     []byte    // JSON编码的响应
+	expiresAt time.Time
+}
+
+// NewCache 创建缓存，默认值来自配置
+func NewCache(options ...Option) *Cache {
+	cfg := config.GetConfig().Cache
+	c := &Cache{
+		ttl:        time.Duration(cfg.TTL) * time.Second,
+		maxEntries: cfg.MaxEntries,
+		agents:     make(map[string]bool),
+		scope:      cfg.Scope,
+		threshold:  cfg.Semantic.Threshold,
+		entries:    make(map[string]*list.Element),
+		scopes:     make(map[string]map[string]*list.Element),
+		order:      list.New(),
+		stats:      make(map[string]*Stats),
+		logger:     log.GlobalLogger,
+	}
+	for _, agent := range cfg.Agents {
+		c.agents[agent] = true
+	}
+	for _, option := range options {
+		option(c)
+	}
+	if c.ttl <= 0 {
+		c.ttl = config.DefaultCacheTTL * time.Second
+	}
+	if c.maxEntries <= 0 {
+		c.maxEntries = config.DefaultCacheMaxEntries
+	}
+	if c.scope != ScopeOwner && c.scope != ScopeGlobal {
+		c.scope = ScopeCaller
+	}
+	if c.threshold <= 0 || c.threshold > 1 {
+		c.threshold = config.DefaultSemanticThreshold
+	}
+	return c
+}
+
+// WithTTL 设置缓存有效期
+func WithTTL(ttl time.Duration) Option {
+	return func(c *Cache) {
+		c.ttl = ttl
+	}
+}
+
+// WithMaxEntries 设置最大缓存条目数
+func WithMaxEntries(maxEntries int) Option {
+	return func(c *Cache) {
+		c.maxEntries = maxEntries
+	}
+}
+
+// WithAgents 设置启用缓存的AI Agent，为空表示全部
+func WithAgents(agents []string) Option {
+	return func(c *Cache) {
+		c.agents = make(map[string]bool, len(agents))
+		for _, agent := range agents {
+			c.agents[agent] = true
+		}
+	}
+}
+
+// WithSemantic 启用语义匹配，相似度不低于threshold的请求视为命中
+func WithSemantic(embedder Embedder, threshold float64) Option {
+	return func(c *Cache) {
+		c.embedder = embedder
+		c.threshold = threshold
+	}
+}
+
+// WithScope 设置缓存共享范围
+func WithScope(scope string) Option {
+	return func(c *Cache) {
+		c.scope = scope
+	}
+}
+
+// WithLogger 设置日志记录器
+func WithLogger(logger log.Logger) Option {
+	return func(c *Cache) {
+		c.logger = logger
+	}
+}
+
+// Enabled 判断AI Agent是否启用缓存
+func (c *Cache) Enabled(agent string) bool {
+	return len(c.agents) == 0 || c.agents[agent]
+}
+
+// Scope 获取缓存共享范围
+func (c *Cache) Scope() string {
+	return c.scope
+}
+
+// tenant 获取调用方所在的缓存分区，同一分区的调用方才能命中彼此写入的条目
+func (c *Cache) tenant(ctx context.Context) string {
+	if c.scope == ScopeGlobal {
+		return ""
+	}
+	identity, ok := auth.IdentityFromContext(ctx)
+	if !ok || identity == nil {
+		return ""
+	}
+	if c.scope == ScopeOwner && identity.Owner != "" {
+		return "owner:" + identity.Owner
+	}
+	return identity.ID
+}
+
+// Semantic 判断是否启用语义匹配
+func (c *Cache) Semantic() bool {
+	return c.embedder != nil
+}
+
+// Get 精确匹配缓存条目
+func (c *Cache) Get(key string) ([]byte, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	e := element.Value.(*entry)
+	if time.Now().After(e.expiresAt) {
+		c.remove(element)
+		c.statsFor(e.agent).Expirations++
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return e.value, true
+}
+
+// Nearest 在同一语义匹配范围内查找相似度最高且不低于阈值的条目
+func (c *Cache) Nearest(scope string, vector []float64) ([]byte, float64, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var best *list.Element
+	bestScore := c.threshold
+	now := time.Now()
+	for _, element := range c.scopes[scope] {
+		e := element.Value.(*entry)
+		if now.After(e.expiresAt) {
+			c.remove(element)
+			c.statsFor(e.agent).Expirations++
+			continue
+		}
+		if score := cosine(vector, e.vector); score >= bestScore {
+			best, bestScore = element, score
+		}
+	}
+	if best == nil {
+		return nil, 0, false
+	}
+	c.order.MoveToFront(best)
+	return best.Value.(*entry).value, bestScore, true
+}
+
+// Set 写入缓存条目，scope和vector为空时只参与精确匹配
+func (c *Cache) Set(agent, key string, value []byte, scope string, vector []float64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		c.remove(element)
+	}
+
+	e := &entry{
+		key:       key,
+		agent:     agent,
+		value:     value,
+		expiresAt: time.Now().Add(c.ttl),
+	}
+	if scope != "" && len(vector) > 0 {
+		e.scope, e.vector = scope, vector
+	}
+	element := c.order.PushFront(e)
+	c.entries[key] = element
+	if e.scope != "" {
+		if c.scopes[e.scope] == nil {
+			c.scopes[e.scope] = make(map[string]*list.Element)
+		}
+		c.scopes[e.scope][key] = element
+	}
+	c.statsFor(agent).Stores++
+
+	// 淘汰最久未使用的条目
+	for c.order.Len() > c.maxEntries {
+		oldest := c.order.Back()
+		c.remove(oldest)
+		c.statsFor(oldest.Value.(*entry).agent).Evictions++
+	}
+}
+
+// Purge 清空缓存，返回删除的条目数
+func (c *Cache) Purge() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	count := c.order.Len()
+	c.entries = make(map[string]*list.Element)
+	c.scopes = make(map[string]map[string]*list.Element)
+	c.order.Init()
+	return count
+}
+
+// Len 返回当前条目数
+func (c *Cache) Len() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.order.Len()
+}
+
+// Stats 返回各AI Agent的统计和汇总
+func (c *Cache) Stats() (Stats, map[string]Stats) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	total := Stats{}
+	agents := make(map[string]Stats, len(c.stats))
+	for agent, stats := range c.stats {
+		agents[agent] = *stats
+		total.Hits += stats.Hits
+		total.SemanticHits += stats.SemanticHits
+		total.Misses += stats.Misses
+		total.Bypasses += stats.Bypasses
+		total.Stores += stats.Stores
+		total.Evictions += stats.Evictions
+		total.Expirations += stats.Expirations
+	}
+	return total, agents
+}
+
+// Agents 返回启用缓存的AI Agent，为空表示全部
+func (c *Cache) Agents() []string {
+	agents := make([]string, 0, len(c.agents))
+	for agent := range c.agents {
+		agents = append(agents, agent)
+	}
+	sort.Strings(agents)
+	return agents
+}
+
+// record 更新统计，调用方不需要持有锁
+func (c *Cache) record(agent string, update func(*Stats)) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	update(c.statsFor(agent))
+}
+
+// statsFor 获取AI Agent的统计，调用方需要持有锁
+func (c *Cache) statsFor(agent string) *Stats {
+	stats, ok := c.stats[agent]
+	if !ok {
+		stats = &Stats{}
+		c.stats[agent] = stats
+	}
+	return stats
+}
+
+// remove 删除条目，调用方需要持有锁
+func (c *Cache) remove(element *list.Element) {
+	e := element.Value.(*entry)
+	c.order.Remove(element)
+	delete(c.entries, e.key)
+	if e.scope != "" {
+		delete(c.scopes[e.scope], e.key)
+		if len(c.scopes[e.scope]) == 0 {
+			delete(c.scopes, e.scope)
+		}
+	}
+}
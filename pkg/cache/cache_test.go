@@ -0,0 +1,127 @@
+package cache
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"kai/kaigate/pkg/auth"
+	"kai/kaigate/pkg/log"
+	"kai/kaigate/pkg/service/ai_agent"
+)
+
+func TestMain(m *testing.M) {
+	if err := log.InitLogger("error", "json", "", false); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// countingAgent 记录调用次数，每次返回不同的回复
+type countingAgent struct {
+	ai_agent.AIAgent
+	calls int
+}
+
+func (a *countingAgent) Chat(ctx context.Context, req ai_agent.ChatRequest) (*ai_agent.ChatResponse, error) {
+	a.calls++
+	return &ai_agent.ChatResponse{ID: "resp-" + strconv.Itoa(a.calls)}, nil
+}
+
+// fixedEmbedder 所有文本返回相同的向量，任意两个请求都语义匹配
+func fixedEmbedder(ctx context.Context, text string) ([]float64, error) {
+	return []float64{1, 0}, nil
+}
+
+func callerContext(id, owner string) context.Context {
+	return auth.WithIdentity(context.Background(), &auth.Identity{ID: id, Owner: owner})
+}
+
+func TestCachePartitionsByIdentity(t *testing.T) {
+	alice := callerContext("apikey:alice", "team-a")
+	aliceBot := callerContext("apikey:alice-bot", "team-a")
+	bob := callerContext("apikey:bob", "team-b")
+	anonymous := context.Background()
+
+	tests := []struct {
+		scope     string
+		contexts  []context.Context
+		wantCalls int
+	}{
+		// 按调用方隔离：每个调用方各自未命中一次
+		{ScopeCaller, []context.Context{alice, alice, aliceBot, bob, anonymous, anonymous}, 4},
+		// 按所属者隔离：同一所属者的调用方共享
+		{ScopeOwner, []context.Context{alice, aliceBot, bob, bob}, 2},
+		{ScopeGlobal, []context.Context{alice, bob, anonymous}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.scope, func(t *testing.T) {
+			agent := &countingAgent{}
+			cached := NewCache(WithScope(tt.scope), WithTTL(time.Minute), WithMaxEntries(100)).Wrap("openai", agent)
+			req := ai_agent.ChatRequest{Model: "gpt-4o", Messages: []ai_agent.Message{{Role: "user", Content: "hello"}}}
+			for _, ctx := range tt.contexts {
+				if _, err := cached.Chat(ctx, req); err != nil {
+					t.Fatalf("Chat: %v", err)
+				}
+			}
+			if agent.calls != tt.wantCalls {
+				t.Errorf("upstream calls = %d, want %d", agent.calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestSemanticCachePartitionsByIdentity(t *testing.T) {
+	agent := &countingAgent{}
+	cache := NewCache(WithScope(ScopeCaller), WithSemantic(fixedEmbedder, 0.9), WithTTL(time.Minute), WithMaxEntries(100))
+	cached := cache.Wrap("openai", agent)
+	message := func(content string) ai_agent.ChatRequest {
+		return ai_agent.ChatRequest{Model: "gpt-4o", Messages: []ai_agent.Message{{Role: "user", Content: content}}}
+	}
+
+	alice := callerContext("apikey:alice", "")
+	if _, err := cached.Chat(alice, message("what is the weather")); err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+
+	// 同一调用方的相似请求语义命中
+	ctx, result := WithControl(alice, Control{})
+	if _, err := cached.Chat(ctx, message("how is the weather")); err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if result.Status != StatusSemanticHit || agent.calls != 1 {
+		t.Errorf("same caller: status = %s, calls = %d", result.Status, agent.calls)
+	}
+
+	// 其他调用方不会语义命中
+	ctx, result = WithControl(callerContext("apikey:bob", ""), Control{})
+	if _, err := cached.Chat(ctx, message("how is the weather")); err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if result.Status != StatusMiss || agent.calls != 2 {
+		t.Errorf("other caller: status = %s, calls = %d", result.Status, agent.calls)
+	}
+}
+
+func TestRequestKey(t *testing.T) {
+	req := ai_agent.ChatRequest{Model: "gpt-4o", Messages: []ai_agent.Message{{Role: "user", Content: "hello"}}}
+	base, err := requestKey("", "openai", "chat", req)
+	if err != nil {
+		t.Fatalf("requestKey: %v", err)
+	}
+	same, _ := requestKey("", "openai", "chat", req)
+	if same != base {
+		t.Error("same request produced different keys")
+	}
+	for name, key := range map[string]func() (string, error){
+		"tenant": func() (string, error) { return requestKey("apikey:alice", "openai", "chat", req) },
+		"agent":  func() (string, error) { return requestKey("", "ollama", "chat", req) },
+		"kind":   func() (string, error) { return requestKey("", "openai", "completion", req) },
+	} {
+		if other, _ := key(); other == base {
+			t.Errorf("different %s produced the same key", name)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package cache
+
+import (
+	"context"
+	"strings"
+)
+
+// StatusHeader 返回缓存结果的响应头
+const StatusHeader = "X-Kaigate-Cache"
+
+// 缓存结果
+const (
+	StatusHit         = "HIT"          // 精确命中
+	StatusSemanticHit = "SEMANTIC-HIT" // 语义命中
+	StatusMiss        = "MISS"         // 未命中，响应已写入缓存
+	StatusBypass      = "BYPASS"       // 按请求头跳过查找
+)
+
+// Control 请求的缓存控制
+type Control struct {
+	NoCache bool // 不使用缓存中的响应
+	NoStore bool // 不写入缓存
+}
+
+// Result 请求的缓存结果
+type Result struct {
+	Status     string
+	Similarity float64 // 语义命中时的相似度
+}
+
+// controlKey 缓存控制在上下文中的键
+type controlKey struct{}
+
+// requestState 上下文中的缓存控制和结果
+type requestState struct {
+	control Control
+	result  *Result
+}
+
+// ParseControl 解析请求头Cache-Control和Pragma
+// no-cache跳过查找，no-store不写入缓存
+func ParseControl(cacheControl, pragma string) Control {
+	control := Control{}
+	for _, directive := range strings.Split(cacheControl, ",") {
+		switch strings.ToLower(strings.TrimSpace(directive)) {
+		case "no-cache", "max-age=0":
+			control.NoCache = true
+		case "no-store":
+			control.NoStore = true
+		}
+	}
+	if strings.EqualFold(strings.TrimSpace(pragma), "no-cache") {
+		control.NoCache = true
+	}
+	return control
+}
+
+// WithControl 在上下文中放入缓存控制，返回记录缓存结果的Result
+func WithControl(ctx context.Context, control Control) (context.Context, *Result) {
+	result := &Result{}
+	return context.WithValue(ctx, controlKey{}, &requestState{control: control, result: result}), result
+}
+
+// ResultFromContext 获取请求的缓存结果，请求未经过缓存时返回false
+func ResultFromContext(ctx context.Context) (*Result, bool) {
+	state, ok := ctx.Value(controlKey{}).(*requestState)
+	if !ok || state.result.Status == "" {
+		return nil, false
+	}
+	return state.result, true
+}
+
+// fromContext 获取缓存控制和结果
+func fromContext(ctx context.Context) (Control, *Result) {
+	if state, ok := ctx.Value(controlKey{}).(*requestState); ok {
+		return state.control, state.result
+	}
+	return Control{}, &Result{}
+}
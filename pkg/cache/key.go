@@ -0,0 +1,57 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"math"
+	"strings"
+
+	"kai/kaigate/pkg/service/ai_agent"
+)
+
+// requestKey 计算请求的规范化哈希
+// 请求先编码为JSON，结构体字段顺序固定、map按键排序，相同参数的请求得到相同的键；
+// tenant为调用方所在的缓存分区，不同分区的相同请求得到不同的键
+func requestKey(tenant, agent, kind string, request interface{}) (string, error) {
+	payload, err := json.Marshal(struct {
+		Tenant  string      `json:"tenant,omitempty"`
+		Agent   string      `json:"agent"`
+		Kind    string      `json:"kind"`
+		Request interface{} `json:"request"`
+	}{tenant, agent, kind, request})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// chatText 将聊天消息拼接为用于语义匹配的文本
+func chatText(messages []ai_agent.Message) string {
+	builder := strings.Builder{}
+	for _, message := range messages {
+		builder.WriteString(message.Role)
+		builder.WriteString(": ")
+		builder.WriteString(message.Content)
+		builder.WriteString("\n")
+	}
+	return builder.String()
+}
+
+// cosine 计算两个向量的余弦相似度，维度不同或为零向量时返回0
+func cosine(a, b []float64) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
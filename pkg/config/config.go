@@ -29,6 +29,13 @@ type Config struct {
 		Debug       bool   `yaml:"debug"`
 		ConnTimeout int    `yaml:"conn_timeout"`
 		RWTimeout   int    `yaml:"rw_timeout"`
+
+		// 各监听器的TLS配置
+		TLS struct {
+			HTTP  TLSConfig `yaml:"http"`  // HTTP服务
+			WS    TLSConfig `yaml:"ws"`    // WebSocket服务
+			Admin TLSConfig `yaml:"admin"` // 管理接口服务
+		} `yaml:"tls"`
 	} `yaml:"server"`
 
 	// 日志配置
@@ -54,11 +61,369 @@ type Config struct {
 	} `yaml:"router"`
 
 	// 代理路由配置
-	ProxyRoutes []struct {
-		Path      string `yaml:"path"`       // 代理路径
-		TargetURL string `yaml:"target_url"` // 目标URL
-		Enable    bool   `yaml:"enable"`     // 是否启用
-	} `yaml:"proxy_routes"`
+	ProxyRoutes []ProxyRouteConfig `yaml:"proxy_routes"`
+
+	// 认证配置
+	Auth struct {
+		Enable    bool     `yaml:"enable"`     // 是否启用认证
+		Required  bool     `yaml:"required"`   // 是否拒绝未携带凭证的请求
+		SkipPaths []string `yaml:"skip_paths"` // 无需认证的路径
+
+		// API Key认证配置
+		APIKey struct {
+			Enable   bool   `yaml:"enable"`    // 是否启用API Key认证
+			Header   string `yaml:"header"`    // 携带API Key的请求头
+			Store    string `yaml:"store"`     // 存储类型: memory, file
+			StoreDir string `yaml:"store_dir"` // 文件存储目录
+		} `yaml:"api_key"`
+
+		// JWT/OIDC认证配置
+		JWT struct {
+			Enable          bool              `yaml:"enable"`           // 是否启用JWT认证
+			Algorithms      []string          `yaml:"algorithms"`       // 允许的签名算法: HS256, RS256, ES256
+			HMACSecret      string            `yaml:"hmac_secret"`      // HS256共享密钥
+			PublicKeyFiles  []string          `yaml:"public_key_files"` // 静态公钥/证书PEM文件
+			JWKSURL         string            `yaml:"jwks_url"`         // JWKS地址
+			OIDCDiscovery   bool              `yaml:"oidc_discovery"`   // 是否通过签发者的发现文档获取JWKS地址
+			RefreshInterval int               `yaml:"refresh_interval"` // JWKS缓存时间(秒)
+			Issuer          string            `yaml:"issuer"`           // 期望的签发者
+			Audiences       []string          `yaml:"audiences"`        // 期望的受众
+			Leeway          int               `yaml:"leeway"`           // 时间校验容差(秒)
+			Claims          map[string]string `yaml:"claims"`           // 身份字段到声明名的映射
+			ForwardClaims   map[string]string `yaml:"forward_claims"`   // 声明名到上游请求头的映射
+		} `yaml:"jwt"`
+	} `yaml:"auth"`
+
+	// AI Agent实例配置，启动时按类型创建，配置重载时重新创建有变更的实例
+	AIAgents []AIAgentInstanceConfig `yaml:"ai_agents"`
+
+	// AI Agent实例池配置，一个名称对应多个实例
+	AgentPools []AgentPoolConfig `yaml:"agent_pools"`
+
+	// MCP服务实例配置
+	MCPServices []MCPServiceInstanceConfig `yaml:"mcp_services"`
+
+	// OpenAI兼容接口配置
+	OpenAI struct {
+		Enable       bool              `yaml:"enable"`        // 是否启用/v1兼容接口
+		DefaultAgent string            `yaml:"default_agent"` // 无法根据model确定AI Agent时使用的默认Agent
+		ModelMap     map[string]string `yaml:"model_map"`     // 模型名到AI Agent的映射
+	} `yaml:"openai"`
+
+	// 逻辑模型路由配置
+	ModelRoutes []ModelRouteConfig `yaml:"model_routes"`
+
+	// AI响应缓存配置
+	Cache struct {
+		Enable     bool     `yaml:"enable"`      // 是否启用缓存
+		TTL        int      `yaml:"ttl"`         // 缓存有效期(秒)
+		MaxEntries int      `yaml:"max_entries"` // 最大缓存条目数，超出时淘汰最久未使用的条目
+		Agents     []string `yaml:"agents"`      // 启用缓存的AI Agent或逻辑模型，为空表示全部
+		Scope      string   `yaml:"scope"`       // 缓存共享范围: caller按调用方隔离, owner按身份所属者隔离, global全部调用方共享
+
+		// 语义缓存配置，按嵌入向量的相似度匹配聊天和文本生成请求
+		Semantic struct {
+			Enable    bool    `yaml:"enable"`    // 是否启用语义匹配
+			Agent     string  `yaml:"agent"`     // 生成嵌入向量的AI Agent
+			Model     string  `yaml:"model"`     // 生成嵌入向量的模型
+			Threshold float64 `yaml:"threshold"` // 余弦相似度阈值
+		} `yaml:"semantic"`
+	} `yaml:"cache"`
+
+	// 嵌入向量请求合并配置
+	EmbeddingBatch struct {
+		Enable       bool     `yaml:"enable"`         // 是否合并并发的嵌入向量请求
+		Window       int      `yaml:"window"`         // 合并等待时间(毫秒)
+		MaxBatchSize int      `yaml:"max_batch_size"` // 单次上游调用的最大输入数
+		Agents       []string `yaml:"agents"`         // 启用合并的AI Agent或逻辑模型，为空表示全部
+	} `yaml:"embedding_batch"`
+
+	// AI Agent和MCP服务调用中间件配置，按耗时统计、日志、参数校验、敏感信息替换的顺序执行
+	Middleware struct {
+		Logging struct {
+			Enable bool `yaml:"enable"` // 是否记录每次调用的日志，只记录元数据不记录内容
+		} `yaml:"logging"`
+		Timing struct {
+			Enable bool `yaml:"enable"` // 是否统计每个Agent和服务的调用耗时
+		} `yaml:"timing"`
+		Validation struct {
+			Enable        bool `yaml:"enable"`          // 是否校验请求参数
+			MaxMessages   int  `yaml:"max_messages"`    // 单次聊天请求的最大消息数，0表示不限制
+			MaxInputChars int  `yaml:"max_input_chars"` // 单次请求输入文本的最大字符数，0表示不限制
+		} `yaml:"validation"`
+		PIIRedaction struct {
+			Enable          bool `yaml:"enable"`           // 是否替换请求中的个人敏感信息
+			RedactResponses bool `yaml:"redact_responses"` // 是否同时替换响应中的个人敏感信息
+		} `yaml:"pii_redaction"`
+	} `yaml:"middleware"`
+
+	// AI Agent调用防护配置
+	Guardrail struct {
+		Enable        bool                     `yaml:"enable"`         // 是否启用
+		Agents        []string                 `yaml:"agents"`         // 防护的AI Agent或逻辑模型，为空表示全部
+		Detectors     []string                 `yaml:"detectors"`      // 启用的内置检测器: email, phone, id_card, credit_card, ipv4
+		Custom        []GuardrailPatternConfig `yaml:"custom"`         // 自定义正则检测器
+		Restore       bool                     `yaml:"restore"`        // 是否将响应中的占位符还原为原文
+		MaskResponses bool                     `yaml:"mask_responses"` // 是否遮盖模型响应中出现的敏感信息
+
+		// 拒绝列表，输入匹配任一规则时拒绝请求
+		Deny struct {
+			Keywords []string `yaml:"keywords"` // 关键词，不区分大小写
+			Patterns []string `yaml:"patterns"` // 正则表达式
+		} `yaml:"deny"`
+	} `yaml:"guardrail"`
+
+	// 对话会话配置
+	Session struct {
+		Enable       bool   `yaml:"enable"`        // 是否启用
+		Store        string `yaml:"store"`         // 存储类型: memory, file
+		StoreDir     string `yaml:"store_dir"`     // 文件存储目录
+		MaxTokens    int    `yaml:"max_tokens"`    // 历史消息与本轮消息的token预算，0表示不限制
+		Strategy     string `yaml:"strategy"`      // 超出预算时的处理策略: sliding_window, summarize
+		SummaryAgent string `yaml:"summary_agent"` // 生成摘要的AI Agent，为空时使用会话当前的Agent
+		SummaryModel string `yaml:"summary_model"` // 生成摘要的模型
+		TTL          int    `yaml:"ttl"`           // 会话空闲过期时间(秒)，0表示不过期
+	} `yaml:"session"`
+
+	// 提示模板配置
+	Prompts struct {
+		Store     string                 `yaml:"store"`     // 管理接口创建的模板的存储类型: memory, file
+		StoreDir  string                 `yaml:"store_dir"` // 文件存储目录
+		Templates []PromptTemplateConfig `yaml:"templates"` // 配置文件中定义的模板
+	} `yaml:"prompts"`
+
+	// 用量统计配置
+	Usage struct {
+		Enable        bool                        `yaml:"enable"`         // 是否记录每次AI Agent调用的用量
+		Sink          string                      `yaml:"sink"`           // 用量记录的输出: file, none
+		File          string                      `yaml:"file"`           // 用量记录文件，每行一条JSON记录
+		RetentionDays int                         `yaml:"retention_days"` // 内存中按小时汇总的保留天数
+		Currency      string                      `yaml:"currency"`       // 价格使用的货币
+		Pricing       map[string]ModelPriceConfig `yaml:"pricing"`        // 模型价格，键为模型名，支持"prefix*"前缀匹配
+	} `yaml:"usage"`
+
+	// token计数配置
+	Tokenizer struct {
+		Enable    bool                      `yaml:"enable"`    // 是否为缺少用量的AI Agent响应估算token数
+		Default   string                    `yaml:"default"`   // 未匹配模型时使用的词表名称，为空时使用启发式估算
+		Encodings []TokenizerEncodingConfig `yaml:"encodings"` // BPE词表
+	} `yaml:"tokenizer"`
+
+	// 异步任务配置
+	Jobs struct {
+		Enable          bool   `yaml:"enable"`           // 是否允许通过/api/v1/jobs异步提交调用
+		Workers         int    `yaml:"workers"`          // 并发执行任务的worker数
+		QueueSize       int    `yaml:"queue_size"`       // 等待执行的任务队列长度，队列满时拒绝提交
+		Store           string `yaml:"store"`            // 存储类型: memory, file
+		StoreDir        string `yaml:"store_dir"`        // 文件存储目录
+		TTL             int    `yaml:"ttl"`              // 任务结束后结果的保留时间(秒)，0表示不过期
+		Timeout         int    `yaml:"timeout"`          // 单个任务的最长执行时间(秒)，0表示不限制
+		CallbackTimeout int    `yaml:"callback_timeout"` // 未启用webhooks时回调请求的超时时间(秒)
+		// 允许作为回调地址的内部主机名、IP或CIDR网段，其他解析到回环、私有、链路本地地址的回调地址都会被拒绝
+		CallbackAllowedHosts []string `yaml:"callback_allowed_hosts"`
+	} `yaml:"jobs"`
+
+	// Webhook配置
+	Webhooks struct {
+		Enable         bool                      `yaml:"enable"`          // 是否投递异步任务回调和网关事件
+		Workers        int                       `yaml:"workers"`         // 并发投递的worker数
+		QueueSize      int                       `yaml:"queue_size"`      // 等待投递的队列长度，队列满时投递直接进入死信列表
+		Timeout        int                       `yaml:"timeout"`         // 单次投递请求超时时间(秒)
+		MaxAttempts    int                       `yaml:"max_attempts"`    // 每个投递的最大请求次数，超过后进入死信列表
+		InitialBackoff int                       `yaml:"initial_backoff"` // 首次重试等待时间(秒)，之后每次翻倍
+		MaxBackoff     int                       `yaml:"max_backoff"`     // 最长重试等待时间(秒)
+		Store          string                    `yaml:"store"`           // 管理接口创建的订阅和死信的存储类型: memory, file
+		StoreDir       string                    `yaml:"store_dir"`       // 文件存储目录
+		CallbackSecret string                    `yaml:"callback_secret"` // 异步任务callback_url回调的签名密钥，支持${ENV}引用环境变量
+		Subscribers    []WebhookSubscriberConfig `yaml:"subscribers"`     // 事件订阅
+	} `yaml:"webhooks"`
+
+	// 工具调用编排配置
+	Orchestrator struct {
+		Enable      bool `yaml:"enable"`       // 是否允许聊天请求启用MCP工具
+		MaxSteps    int  `yaml:"max_steps"`    // 最大工具调用轮数
+		ToolTimeout int  `yaml:"tool_timeout"` // 单次工具调用超时时间(秒)
+	} `yaml:"orchestrator"`
+
+	// 授权策略配置
+	Policy PolicyConfig `yaml:"policy"`
+}
+
+// ProxyRouteConfig 代理路由配置
+type ProxyRouteConfig struct {
+	Path      string `yaml:"path"`       // 代理路径
+	TargetURL string `yaml:"target_url"` // 目标URL
+	Enable    bool   `yaml:"enable"`     // 是否启用
+
+	TLS       UpstreamTLSConfig `yaml:"tls"`       // 访问上游服务的TLS配置
+	Transport TransportConfig   `yaml:"transport"` // 上游连接池配置
+}
+
+// UpstreamTLSConfig 访问上游服务的TLS配置
+type UpstreamTLSConfig struct {
+	CAFile             string `yaml:"ca_file"`              // 校验上游证书的CA文件，为空使用系统CA
+	CertFile           string `yaml:"cert_file"`            // 客户端证书文件（mTLS）
+	KeyFile            string `yaml:"key_file"`             // 客户端私钥文件（mTLS）
+	ServerName         string `yaml:"server_name"`          // 覆盖SNI和证书校验使用的主机名
+	MinVersion         string `yaml:"min_version"`          // 最低TLS版本
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"` // 跳过证书校验，仅用于开发环境
+}
+
+// AIAgentInstanceConfig AI Agent实例配置
+type AIAgentInstanceConfig struct {
+	Name           string                 `yaml:"name" json:"name"`                       // 实例名称，作为agent_id和model使用
+	Type           string                 `yaml:"type" json:"type"`                       // 类型: openai, anthropic, ollama, example
+	BaseURL        string                 `yaml:"base_url" json:"base_url"`               // 接口地址，为空时使用类型的默认地址
+	APIKey         string                 `yaml:"api_key" json:"-"`                       // API密钥，支持${ENV}引用环境变量
+	Organization   string                 `yaml:"organization" json:"organization"`       // OpenAI组织ID
+	Headers        map[string]string      `yaml:"headers" json:"-"`                       // 附加请求头
+	DefaultModel   string                 `yaml:"default_model" json:"default_model"`     // 请求未指定模型时使用的模型
+	ModelMap       map[string]string      `yaml:"model_map" json:"model_map"`             // 模型别名到上游模型名的映射
+	Models         []string               `yaml:"models" json:"models"`                   // 静态模型列表
+	Timeout        int                    `yaml:"timeout" json:"timeout"`                 // 非流式请求超时时间(秒)
+	MaxConcurrency int                    `yaml:"max_concurrency" json:"max_concurrency"` // 最大并发调用数，0表示不限制
+	Options        map[string]interface{} `yaml:"options" json:"options"`                 // 类型特有的配置项，如anthropic的version、ollama的keep_alive
+}
+
+// AgentPoolConfig AI Agent实例池配置
+// 调用按进行中请求数最少的健康实例转发，健康检查失败的实例暂时移出轮换
+type AgentPoolConfig struct {
+	Name                string   `yaml:"name" json:"name"`                                   // 实例池名称，作为agent_id和model使用
+	Members             []string `yaml:"members" json:"members"`                             // 成员实例名称
+	HealthCheckInterval int      `yaml:"health_check_interval" json:"health_check_interval"` // 健康检查间隔(秒)，0使用默认值
+}
+
+// ModelPriceConfig 模型价格，单位为每百万token的价格
+type ModelPriceConfig struct {
+	Input  float64 `yaml:"input" json:"input"`   // 输入(提示)token价格
+	Output float64 `yaml:"output" json:"output"` // 输出(生成)token价格
+}
+
+// TokenizerEncodingConfig BPE词表配置
+type TokenizerEncodingConfig struct {
+	Name    string   `yaml:"name"`    // 词表名称，如cl100k_base
+	File    string   `yaml:"file"`    // tiktoken格式的词表文件
+	Pattern string   `yaml:"pattern"` // 预分词的正则表达式，为空时使用近似cl100k_base的规则
+	Models  []string `yaml:"models"`  // 使用该词表的模型，支持"prefix*"前缀匹配
+}
+
+// WebhookSubscriberConfig 事件订阅配置
+type WebhookSubscriberConfig struct {
+	Name   string   `yaml:"name"`   // 订阅名称
+	URL    string   `yaml:"url"`    // 投递地址
+	Secret string   `yaml:"secret"` // 签名密钥，支持${ENV}引用环境变量，为空时不签名
+	Events []string `yaml:"events"` // 订阅的事件类型，支持"prefix*"前缀匹配，为空表示全部事件
+}
+
+// MCPServiceInstanceConfig MCP服务实例配置
+type MCPServiceInstanceConfig struct {
+	Name     string                 `yaml:"name" json:"name"`         // 实例名称，作为service_id使用
+	Type     string                 `yaml:"type" json:"type"`         // 类型: example
+	Endpoint string                 `yaml:"endpoint" json:"endpoint"` // 服务地址
+	APIKey   string                 `yaml:"api_key" json:"-"`         // 访问凭证，支持${ENV}引用环境变量
+	Timeout  int                    `yaml:"timeout" json:"timeout"`   // 调用超时时间(秒)
+	Options  map[string]interface{} `yaml:"options" json:"options"`   // 类型特有的配置项
+}
+
+// ModelRouteConfig 逻辑模型路由配置
+// 逻辑模型名按顺序映射到多个目标，请求失败、超时或被限流时依次尝试下一个目标
+type ModelRouteConfig struct {
+	Name    string              `yaml:"name" json:"name"`       // 逻辑模型名，作为AI Agent名称使用
+	Timeout int                 `yaml:"timeout" json:"timeout"` // 单个目标的超时时间(秒)，流式请求为首个数据块的等待时间，0表示不限制
+	Targets []ModelTargetConfig `yaml:"targets" json:"targets"` // 目标列表
+}
+
+// ModelTargetConfig 模型路由目标
+type ModelTargetConfig struct {
+	Agent  string `yaml:"agent" json:"agent"`   // AI Agent名称
+	Model  string `yaml:"model" json:"model"`   // 传给Agent的模型名
+	Weight int    `yaml:"weight" json:"weight"` // 权重，按权重选择首个目标，为0的目标只作为后备
+}
+
+// PromptTemplateConfig 提示模板配置
+type PromptTemplateConfig struct {
+	Name        string                 `yaml:"name" json:"name"`               // 模板名称
+	Version     int                    `yaml:"version" json:"version"`         // 版本号，为0时视为1
+	Description string                 `yaml:"description" json:"description"` // 模板说明
+	Variables   []PromptVariableConfig `yaml:"variables" json:"variables"`     // 变量声明
+	Messages    []PromptMessageConfig  `yaml:"messages" json:"messages"`       // 模板消息
+}
+
+// PromptVariableConfig 提示模板变量
+type PromptVariableConfig struct {
+	Name        string `yaml:"name" json:"name"`               // 变量名
+	Required    bool   `yaml:"required" json:"required"`       // 是否必须由请求提供
+	Default     string `yaml:"default" json:"default"`         // 默认值
+	Description string `yaml:"description" json:"description"` // 变量说明
+}
+
+// PromptMessageConfig 提示模板消息
+type PromptMessageConfig struct {
+	Role    string `yaml:"role" json:"role"`       // 消息角色: system, developer, user, assistant
+	Content string `yaml:"content" json:"content"` // 消息内容，使用text/template语法引用变量
+}
+
+// GuardrailPatternConfig 自定义敏感信息检测器
+type GuardrailPatternConfig struct {
+	Name    string `yaml:"name" json:"name"`       // 检测器名称，用于占位符，如EMPLOYEE_ID_1
+	Pattern string `yaml:"pattern" json:"pattern"` // 正则表达式
+}
+
+// TransportConfig 上游连接池配置，零值使用默认值
+type TransportConfig struct {
+	MaxIdleConns          int  `yaml:"max_idle_conns"`          // 最大空闲连接数
+	MaxIdleConnsPerHost   int  `yaml:"max_idle_conns_per_host"` // 每个主机的最大空闲连接数
+	MaxConnsPerHost       int  `yaml:"max_conns_per_host"`      // 每个主机的最大连接数，0表示不限制
+	IdleConnTimeout       int  `yaml:"idle_conn_timeout"`       // 空闲连接超时(秒)
+	DialTimeout           int  `yaml:"dial_timeout"`            // 建立连接超时(秒)
+	ResponseHeaderTimeout int  `yaml:"response_header_timeout"` // 等待响应头超时(秒)，0表示不限制
+	DisableHTTP2          bool `yaml:"disable_http2"`           // 禁用HTTP/2
+}
+
+// TLSConfig 监听器TLS配置
+type TLSConfig struct {
+	Enable         bool                   `yaml:"enable"`          // 是否启用TLS
+	CertFile       string                 `yaml:"cert_file"`       // 默认证书文件
+	KeyFile        string                 `yaml:"key_file"`        // 默认私钥文件
+	Certificates   []TLSCertificateConfig `yaml:"certificates"`    // 其他证书，按SNI主机名选择
+	MinVersion     string                 `yaml:"min_version"`     // 最低TLS版本: 1.0, 1.1, 1.2, 1.3
+	CipherSuites   []string               `yaml:"cipher_suites"`   // 允许的密码套件（仅TLS 1.2及以下生效），为空使用Go默认值
+	ClientCAFile   string                 `yaml:"client_ca_file"`  // 校验客户端证书的CA文件，配置后启用mTLS
+	ClientAuth     string                 `yaml:"client_auth"`     // 客户端认证模式: none, request, require, verify_if_given, require_and_verify，校验证书的模式需要配置ClientCAFile
+	ReloadInterval int                    `yaml:"reload_interval"` // 检查证书文件变更的间隔(秒)，0表示不自动检查
+}
+
+// TLSCertificateConfig 证书与私钥文件
+type TLSCertificateConfig struct {
+	CertFile string `yaml:"cert_file"` // 证书文件
+	KeyFile  string `yaml:"key_file"`  // 私钥文件
+}
+
+// PolicyConfig 授权策略配置
+type PolicyConfig struct {
+	Enable        bool               `yaml:"enable"`         // 是否启用授权策略
+	DefaultEffect string             `yaml:"default_effect"` // 没有规则匹配时的处理: allow, deny
+	Rules         []PolicyRuleConfig `yaml:"rules"`          // 策略规则
+}
+
+// PolicyRuleConfig 授权策略规则配置
+type PolicyRuleConfig struct {
+	Name     string `yaml:"name" json:"name"`         // 规则名称
+	Effect   string `yaml:"effect" json:"effect"`     // 规则效果: allow, deny
+	Priority int    `yaml:"priority" json:"priority"` // 优先级，数值越大越先匹配
+
+	// 调用方匹配条件，任一条件满足即匹配，全部为空时匹配所有调用方
+	Subjects struct {
+		IDs    []string `yaml:"ids" json:"ids"`       // 身份ID
+		Roles  []string `yaml:"roles" json:"roles"`   // 角色
+		Groups []string `yaml:"groups" json:"groups"` // 团队/分组
+		Scopes []string `yaml:"scopes" json:"scopes"` // 授权范围
+	} `yaml:"subjects" json:"subjects"`
+
+	// 资源匹配条件
+	Resource string   `yaml:"resource" json:"resource"` // 资源类型: agent, mcp_tool, route
+	Targets  []string `yaml:"targets" json:"targets"`   // 资源名称，mcp_tool格式为"服务/工具"，支持*通配
+	Models   []string `yaml:"models" json:"models"`     // 模型名称，仅对agent资源生效
 }
 
 // GlobalConfig 全局配置实例
@@ -93,12 +458,42 @@ func InitConfig(file string) error {
 
 // ReloadConfig 重新加载配置
 func ReloadConfig() error {
+	newConfig, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	// 使用互斥锁保护配置更新
+	configMutex.Lock()
+	GlobalConfig = newConfig
+	configMutex.Unlock()
+
+	return nil
+}
+
+// LoadPolicyConfig 从配置文件读取授权策略配置，不修改当前配置
+func LoadPolicyConfig() (PolicyConfig, error) {
+	newConfig, err := loadConfig()
+	if err != nil {
+		return PolicyConfig{}, err
+	}
+	return newConfig.Policy, nil
+}
+
+// SetPolicyConfig 只替换当前配置中的授权策略配置
+func SetPolicyConfig(policy PolicyConfig) {
+	configMutex.Lock()
+	GlobalConfig.Policy = policy
+	configMutex.Unlock()
+}
+
+// loadConfig 从配置文件创建新的配置实例，避免直接修改正在使用的配置
+func loadConfig() (Config, error) {
 	// 检查是否有配置文件
 	if configFile == "" {
-		return fmt.Errorf("no config file specified")
+		return Config{}, fmt.Errorf("no config file specified")
 	}
 
-	// 创建新的配置实例，避免直接修改正在使用的配置
 	newConfig := Config{}
 
 	// 初始化默认值
@@ -106,18 +501,13 @@ func ReloadConfig() error {
 
 	// 从配置文件加载配置
 	if err := loadFromFileFor(configFile, &newConfig); err != nil {
-		return fmt.Errorf("reload config file failed: %w", err)
+		return Config{}, fmt.Errorf("reload config file failed: %w", err)
 	}
 
 	// 从命令行参数覆盖配置（保持与初始化时一致）
 	loadFromCmdLineFor(&newConfig)
 
-	// 使用互斥锁保护配置更新
-	configMutex.Lock()
-	GlobalConfig = newConfig
-	configMutex.Unlock()
-
-	return nil
+	return newConfig, nil
 }
 
 // GetConfig 获取当前配置（线程安全）
@@ -141,6 +531,11 @@ func initDefaultConfigFor(config *Config) {
 	config.Server.Debug = false
 	config.Server.ConnTimeout = DefaultConnTimeout
 	config.Server.RWTimeout = DefaultRWTimeout
+	for _, tlsConfig := range []*TLSConfig{&config.Server.TLS.HTTP, &config.Server.TLS.WS, &config.Server.TLS.Admin} {
+		tlsConfig.Enable = false
+		tlsConfig.MinVersion = DefaultTLSMinVersion
+		tlsConfig.ReloadInterval = DefaultTLSReloadInterval
+	}
 
 	// 日志配置
 	config.Log.Level = DefaultLogLevel
@@ -157,6 +552,91 @@ func initDefaultConfigFor(config *Config) {
 	config.Router.DefaultRateLimit = DefaultRateLimit
 	config.Router.CircuitBreak = true
 	config.Router.CircuitBreakThreshold = DefaultCircuitBreakThreshold
+
+	// 认证配置
+	config.Auth.Enable = false
+	config.Auth.Required = true
+	config.Auth.SkipPaths = []string{"/api/v1/health", "/api/v1/version"}
+	config.Auth.APIKey.Enable = true
+	config.Auth.APIKey.Header = DefaultAPIKeyHeader
+	config.Auth.APIKey.Store = "file"
+	config.Auth.APIKey.StoreDir = DefaultAPIKeyStoreDir
+	config.Auth.JWT.Enable = false
+	config.Auth.JWT.Algorithms = []string{"RS256", "ES256"}
+	config.Auth.JWT.RefreshInterval = DefaultJWKSRefreshInterval
+	config.Auth.JWT.Leeway = DefaultJWTLeeway
+
+	// OpenAI兼容接口配置
+	config.OpenAI.Enable = true
+
+	// AI响应缓存配置
+	config.Cache.TTL = DefaultCacheTTL
+	config.Cache.MaxEntries = DefaultCacheMaxEntries
+	config.Cache.Scope = DefaultCacheScope
+	config.Cache.Semantic.Threshold = DefaultSemanticThreshold
+
+	// 嵌入向量请求合并配置
+	config.EmbeddingBatch.Window = DefaultEmbeddingBatchWindow
+	config.EmbeddingBatch.MaxBatchSize = DefaultEmbeddingBatchSize
+
+	// 调用中间件配置
+	config.Middleware.Timing.Enable = true
+	config.Middleware.Validation.Enable = true
+
+	// 调用防护配置
+	config.Guardrail.Detectors = []string{"email", "phone", "id_card", "credit_card"}
+	config.Guardrail.Restore = true
+
+	// 对话会话配置
+	config.Session.Store = "memory"
+	config.Session.StoreDir = DefaultSessionStoreDir
+	config.Session.MaxTokens = DefaultSessionMaxTokens
+	config.Session.Strategy = "sliding_window"
+	config.Session.TTL = DefaultSessionTTL
+
+	// 提示模板配置
+	config.Prompts.Store = "memory"
+	config.Prompts.StoreDir = DefaultPromptStoreDir
+
+	// 用量统计配置
+	config.Usage.Enable = true
+	config.Usage.Sink = "file"
+	config.Usage.File = DefaultUsageFile
+	config.Usage.RetentionDays = DefaultUsageRetentionDays
+	config.Usage.Currency = "USD"
+
+	// token计数配置
+	config.Tokenizer.Enable = true
+
+	// 异步任务配置
+	config.Jobs.Enable = true
+	config.Jobs.Workers = DefaultJobWorkers
+	config.Jobs.QueueSize = DefaultJobQueueSize
+	config.Jobs.Store = "file"
+	config.Jobs.StoreDir = DefaultJobStoreDir
+	config.Jobs.TTL = DefaultJobTTL
+	config.Jobs.Timeout = DefaultJobTimeout
+	config.Jobs.CallbackTimeout = DefaultJobCallbackTimeout
+
+	// Webhook配置
+	config.Webhooks.Enable = true
+	config.Webhooks.Workers = DefaultWebhookWorkers
+	config.Webhooks.QueueSize = DefaultWebhookQueueSize
+	config.Webhooks.Timeout = DefaultWebhookTimeout
+	config.Webhooks.MaxAttempts = DefaultWebhookMaxAttempts
+	config.Webhooks.InitialBackoff = DefaultWebhookInitialBackoff
+	config.Webhooks.MaxBackoff = DefaultWebhookMaxBackoff
+	config.Webhooks.Store = "file"
+	config.Webhooks.StoreDir = DefaultWebhookStoreDir
+
+	// 工具调用编排配置
+	config.Orchestrator.Enable = true
+	config.Orchestrator.MaxSteps = DefaultOrchestratorMaxSteps
+	config.Orchestrator.ToolTimeout = DefaultToolTimeout
+
+	// 授权策略配置
+	config.Policy.Enable = false
+	config.Policy.DefaultEffect = "allow"
 }
 
 // loadFromFile 从配置文件加载配置
@@ -25,6 +25,18 @@ const (
 	DefaultConnTimeout = 30
 	// 读写超时时间(秒)
 	DefaultRWTimeout = 60
+	// 默认最低TLS版本
+	DefaultTLSMinVersion = "1.2"
+	// 默认证书文件变更检查间隔(秒)
+	DefaultTLSReloadInterval = 60
+	// 默认上游最大空闲连接数
+	DefaultUpstreamMaxIdleConns = 100
+	// 默认上游每个主机的最大空闲连接数
+	DefaultUpstreamMaxIdleConnsPerHost = 32
+	// 默认上游空闲连接超时(秒)
+	DefaultUpstreamIdleConnTimeout = 90
+	// 默认上游建立连接超时(秒)
+	DefaultUpstreamDialTimeout = 10
 	// WebSocket心跳间隔(秒)
 	DefaultWSHeartbeatInterval = 30
 
@@ -32,4 +44,78 @@ const (
 	DefaultRateLimit = 100
 	// 默认熔断阈值(错误率百分比)
 	DefaultCircuitBreakThreshold = 50
+
+	// 默认API Key请求头
+	DefaultAPIKeyHeader = "X-API-Key"
+	// 默认API Key存储目录
+	DefaultAPIKeyStoreDir = "data/apikeys"
+	// 默认JWKS缓存时间(秒)
+	DefaultJWKSRefreshInterval = 300
+	// 默认JWT时间校验容差(秒)
+	DefaultJWTLeeway = 30
+
+	// 默认AI响应缓存有效期(秒)
+	DefaultCacheTTL = 3600
+	// 默认最大缓存条目数
+	DefaultCacheMaxEntries = 1000
+	// 默认语义缓存相似度阈值
+	DefaultSemanticThreshold = 0.95
+	// 默认缓存共享范围，每个调用方只命中自己写入的缓存
+	DefaultCacheScope = "caller"
+
+	// 默认嵌入向量请求合并等待时间(毫秒)
+	DefaultEmbeddingBatchWindow = 10
+	// 默认单次上游嵌入向量调用的最大输入数
+	DefaultEmbeddingBatchSize = 256
+
+	// 默认会话文件存储目录
+	DefaultSessionStoreDir = "data/sessions"
+	// 默认会话历史token预算
+	DefaultSessionMaxTokens = 4000
+	// 默认会话空闲过期时间(秒)
+	DefaultSessionTTL = 7 * 24 * 3600
+
+	// 默认AI Agent实例池健康检查间隔(秒)
+	DefaultAgentPoolHealthCheckInterval = 30
+
+	// 默认提示模板文件存储目录
+	DefaultPromptStoreDir = "data/prompts"
+
+	// 默认用量记录文件
+	DefaultUsageFile = "data/usage/usage.jsonl"
+	// 默认用量汇总保留天数
+	DefaultUsageRetentionDays = 30
+
+	// 默认异步任务worker数
+	DefaultJobWorkers = 4
+	// 默认异步任务队列长度
+	DefaultJobQueueSize = 100
+	// 默认异步任务文件存储目录
+	DefaultJobStoreDir = "data/jobs"
+	// 默认异步任务结果保留时间(秒)
+	DefaultJobTTL = 24 * 3600
+	// 默认单个异步任务最长执行时间(秒)
+	DefaultJobTimeout = 600
+	// 默认异步任务回调超时时间(秒)
+	DefaultJobCallbackTimeout = 10
+
+	// 默认Webhook投递worker数
+	DefaultWebhookWorkers = 4
+	// 默认Webhook投递队列长度
+	DefaultWebhookQueueSize = 1000
+	// 默认Webhook投递请求超时时间(秒)
+	DefaultWebhookTimeout = 10
+	// 默认Webhook最大请求次数
+	DefaultWebhookMaxAttempts = 5
+	// 默认Webhook首次重试等待时间(秒)
+	DefaultWebhookInitialBackoff = 1
+	// 默认Webhook最长重试等待时间(秒)
+	DefaultWebhookMaxBackoff = 300
+	// 默认Webhook文件存储目录
+	DefaultWebhookStoreDir = "data/webhooks"
+
+	// 默认最大工具调用轮数
+	DefaultOrchestratorMaxSteps = 8
+	// 默认单次工具调用超时时间(秒)
+	DefaultToolTimeout = 30
 )
\ No newline at end of file
@@ -0,0 +1,113 @@
+package guardrail
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// 内置检测器名称
+const (
+	DetectorEmail      = "email"
+	DetectorPhone      = "phone"
+	DetectorIDCard     = "id_card"
+	DetectorCreditCard = "credit_card"
+	DetectorIPv4       = "ipv4"
+)
+
+// Detector 敏感信息检测器，Validate不为空时只处理通过校验的匹配
+type Detector struct {
+	Name     string
+	Pattern  *regexp.Regexp
+	Validate func(match string) bool
+}
+
+// builtinDetectors 内置检测器，按此顺序匹配，身份证号需要在银行卡号之前匹配
+var builtinDetectors = []Detector{
+	{Name: DetectorEmail, Pattern: regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)},
+	{Name: DetectorIDCard, Pattern: regexp.MustCompile(`\b[1-9]\d{5}(?:19|20)\d{2}(?:0[1-9]|1[0-2])(?:0[1-9]|[12]\d|3[01])\d{3}[\dXx]\b`)},
+	{Name: DetectorCreditCard, Pattern: regexp.MustCompile(`\b(?:\d[ \-]?){12,18}\d\b`), Validate: luhnValid},
+	{Name: DetectorPhone, Pattern: regexp.MustCompile(`(?:\+?86[ \-]?)?\b1[3-9]\d{9}\b|\+\d{1,3}[ \-]?\(?\d{2,4}\)?[ \-]?\d{3,4}[ \-]?\d{3,4}\b`)},
+	{Name: DetectorIPv4, Pattern: regexp.MustCompile(`\b(?:(?:25[0-5]|2[0-4]\d|1?\d?\d)\.){3}(?:25[0-5]|2[0-4]\d|1?\d?\d)\b`)},
+}
+
+// BuiltinDetectors 返回指定名称的内置检测器，名称为空时返回全部内置检测器
+// 返回的检测器保持内置的匹配顺序
+func BuiltinDetectors(names ...string) ([]Detector, error) {
+	if len(names) == 0 {
+		return append([]Detector(nil), builtinDetectors...), nil
+	}
+
+	wanted := make(map[string]bool, len(names))
+	for _, name := range names {
+		wanted[strings.ToLower(name)] = true
+	}
+	detectors := make([]Detector, 0, len(names))
+	for _, detector := range builtinDetectors {
+		if wanted[detector.Name] {
+			detectors = append(detectors, detector)
+			delete(wanted, detector.Name)
+		}
+	}
+	for name := range wanted {
+		return nil, fmt.Errorf("unknown detector %q", name)
+	}
+	return detectors, nil
+}
+
+// NewDetector 使用正则表达式创建自定义检测器
+func NewDetector(name, pattern string) (Detector, error) {
+	if name == "" {
+		return Detector{}, fmt.Errorf("detector name cannot be empty")
+	}
+	regex, err := regexp.Compile(pattern)
+	if err != nil {
+		return Detector{}, fmt.Errorf("invalid pattern for detector %s: %w", name, err)
+	}
+	return Detector{Name: name, Pattern: regex}, nil
+}
+
+// Redact 将文本中检测到的敏感信息替换为[REDACTED_<名称>]
+func Redact(text string, detectors []Detector) string {
+	return replace(text, detectors, func(detector Detector, _ string) string {
+		return "[REDACTED_" + strings.ToUpper(detector.Name) + "]"
+	})
+}
+
+// replace 依次使用检测器替换文本中的敏感信息
+func replace(text string, detectors []Detector, replacement func(detector Detector, match string) string) string {
+	if text == "" {
+		return text
+	}
+	for _, detector := range detectors {
+		text = detector.Pattern.ReplaceAllStringFunc(text, func(match string) string {
+			if detector.Validate != nil && !detector.Validate(match) {
+				return match
+			}
+			return replacement(detector, match)
+		})
+	}
+	return text
+}
+
+// luhnValid 使用Luhn算法校验银行卡号，减少误判
+func luhnValid(number string) bool {
+	digits := strings.NewReplacer(" ", "", "-", "").Replace(number)
+	if len(digits) < 13 || len(digits) > 19 {
+		return false
+	}
+	sum := 0
+	double := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		digit := int(digits[i] - '0')
+		if double {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+		double = !double
+	}
+	return sum%10 == 0
+}
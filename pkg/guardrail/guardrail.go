@@ -0,0 +1,324 @@
+// Package guardrail 在AI Agent调用前后检查和遮盖敏感内容
+// 请求中的敏感信息替换为占位符后再发送给模型，响应中的占位符可以还原为原文；匹配拒绝列表的请求直接拒绝
+package guardrail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"kai/kaigate/pkg/log"
+	"kai/kaigate/pkg/service/ai_agent"
+)
+
+// Name 防护中间件名称
+const Name = "guardrail"
+
+// ErrBlocked 请求匹配拒绝列表
+var ErrBlocked = errors.New("request blocked by guardrail")
+
+// Stats 防护统计
+type Stats struct {
+	Requests int64            `json:"requests"` // 检查的请求数
+	Blocked  int64            `json:"blocked"`  // 拒绝的请求数
+	Masked   map[string]int64 `json:"masked"`   // 按检测器统计的遮盖次数
+	Restored int64            `json:"restored"` // 还原了占位符的请求数
+}
+
+// denyRule 拒绝规则
+type denyRule struct {
+	name    string
+	keyword string // 小写关键词，不为空时按子串匹配
+	pattern *regexp.Regexp
+}
+
+// Guard AI Agent调用防护中间件
+// 处理聊天消息内容、文本生成提示和嵌入输入，嵌入请求只遮盖不还原
+type Guard struct {
+	detectors     []Detector
+	deny          []denyRule
+	agents        map[string]bool
+	targets       ai_agent.TargetResolver
+	restore       bool
+	maskResponses bool
+	stats         Stats
+	mutex         sync.Mutex
+	logger        log.Logger
+}
+
+// Option Guard配置选项
+type Option func(*Guard)
+
+// WithDetectors 设置检测器，按顺序匹配
+func WithDetectors(detectors ...Detector) Option {
+	return func(g *Guard) {
+		g.detectors = append(g.detectors, detectors...)
+	}
+}
+
+// WithDenyKeywords 添加拒绝关键词，不区分大小写
+func WithDenyKeywords(keywords ...string) Option {
+	return func(g *Guard) {
+		for _, keyword := range keywords {
+			if keyword = strings.TrimSpace(keyword); keyword != "" {
+				g.deny = append(g.deny, denyRule{name: keyword, keyword: strings.ToLower(keyword)})
+			}
+		}
+	}
+}
+
+// WithDenyPatterns 添加拒绝正则表达式
+func WithDenyPatterns(patterns ...*regexp.Regexp) Option {
+	return func(g *Guard) {
+		for _, pattern := range patterns {
+			g.deny = append(g.deny, denyRule{name: pattern.String(), pattern: pattern})
+		}
+	}
+}
+
+// WithAgents 限制防护的AI Agent，为空表示全部
+func WithAgents(agents []string) Option {
+	return func(g *Guard) {
+		if len(agents) == 0 {
+			g.agents = nil
+			return
+		}
+		g.agents = make(map[string]bool, len(agents))
+		for _, agent := range agents {
+			g.agents[agent] = true
+		}
+	}
+}
+
+// WithTargetResolver 设置逻辑模型路由的目标解析函数，目标受防护时经过该路由的调用同样受防护
+func WithTargetResolver(resolve ai_agent.TargetResolver) Option {
+	return func(g *Guard) {
+		g.targets = resolve
+	}
+}
+
+// WithRestore 设置是否将响应中的占位符还原为原文
+func WithRestore(restore bool) Option {
+	return func(g *Guard) {
+		g.restore = restore
+	}
+}
+
+// WithMaskResponses 设置是否遮盖模型响应中出现的敏感信息
+func WithMaskResponses(mask bool) Option {
+	return func(g *Guard) {
+		g.maskResponses = mask
+	}
+}
+
+// WithLogger 设置日志器
+func WithLogger(logger log.Logger) Option {
+	return func(g *Guard) {
+		g.logger = logger
+	}
+}
+
+// NewGuard 创建Guard实例
+func NewGuard(options ...Option) *Guard {
+	g := &Guard{
+		restore: true,
+		stats:   Stats{Masked: make(map[string]int64)},
+		logger:  log.GlobalLogger,
+	}
+	for _, option := range options {
+		option(g)
+	}
+	return g
+}
+
+// Name 实现ai_agent.AIAgentMiddleware接口的Name方法
+func (g *Guard) Name() string {
+	return Name
+}
+
+// Stats 返回防护统计
+func (g *Guard) Stats() Stats {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	stats := g.stats
+	stats.Masked = make(map[string]int64, len(g.stats.Masked))
+	for name, count := range g.stats.Masked {
+		stats.Masked[name] = count
+	}
+	return stats
+}
+
+// Process 实现ai_agent.AIAgentMiddleware接口的Process方法
+func (g *Guard) Process(ctx context.Context, req interface{}, next func(context.Context, interface{}) (interface{}, error)) (interface{}, error) {
+	info, _ := ai_agent.CallInfoFromContext(ctx)
+	if !ai_agent.InScope(g.agents, info.Agent, g.targets) {
+		return next(ctx, req)
+	}
+
+	vault := NewVault()
+	masked, err := g.inspect(req, vault)
+	if err != nil {
+		g.logger.Warn("Request blocked by guardrail",
+			zap.String("agent", info.Agent),
+			zap.String("operation", info.Operation),
+			zap.Error(err),
+		)
+		return nil, err
+	}
+	g.record(vault)
+
+	resp, err := next(ctx, masked)
+	if err != nil {
+		return nil, err
+	}
+	if _, isEmbedding := req.(*ai_agent.EmbeddingRequest); isEmbedding {
+		return resp, nil
+	}
+	return g.filterResponse(ctx, resp, vault), nil
+}
+
+// inspect 检查拒绝列表并返回遮盖后的请求副本，不修改调用方的消息列表
+func (g *Guard) inspect(req interface{}, vault *Vault) (interface{}, error) {
+	switch r := req.(type) {
+	case *ai_agent.ChatRequest:
+		masked := *r
+		masked.Messages = make([]ai_agent.Message, len(r.Messages))
+		for i, message := range r.Messages {
+			if err := g.checkDeny(message.Content); err != nil {
+				return nil, err
+			}
+			message.Content = vault.Mask(message.Content, g.detectors)
+			if len(message.Parts) > 0 {
+				parts := make([]ai_agent.ContentPart, len(message.Parts))
+				for j, part := range message.Parts {
+					if err := g.checkDeny(part.Text); err != nil {
+						return nil, err
+					}
+					part.Text = vault.Mask(part.Text, g.detectors)
+					parts[j] = part
+				}
+				message.Parts = parts
+			}
+			// 历史assistant消息中的工具调用参数同样会发送给上游
+			if len(message.ToolCalls) > 0 {
+				toolCalls := make([]ai_agent.ToolCall, len(message.ToolCalls))
+				for j, call := range message.ToolCalls {
+					if err := g.checkDeny(call.Function.Arguments); err != nil {
+						return nil, err
+					}
+					call.Function.Arguments = vault.Mask(call.Function.Arguments, g.detectors)
+					toolCalls[j] = call
+				}
+				message.ToolCalls = toolCalls
+			}
+			masked.Messages[i] = message
+		}
+		return &masked, nil
+	case *ai_agent.CompletionRequest:
+		if err := g.checkDeny(r.Prompt); err != nil {
+			return nil, err
+		}
+		masked := *r
+		masked.Prompt = vault.Mask(r.Prompt, g.detectors)
+		return &masked, nil
+	case *ai_agent.EmbeddingRequest:
+		masked := *r
+		masked.Input = make([]string, len(r.Input))
+		for i, input := range r.Input {
+			if err := g.checkDeny(input); err != nil {
+				return nil, err
+			}
+			masked.Input[i] = vault.Mask(input, g.detectors)
+		}
+		return &masked, nil
+	}
+	return req, nil
+}
+
+// checkDeny 检查文本是否匹配拒绝列表
+func (g *Guard) checkDeny(text string) error {
+	if text == "" || len(g.deny) == 0 {
+		return nil
+	}
+	lower := strings.ToLower(text)
+	for _, rule := range g.deny {
+		if (rule.pattern != nil && rule.pattern.MatchString(text)) || (rule.keyword != "" && strings.Contains(lower, rule.keyword)) {
+			g.mutex.Lock()
+			g.stats.Blocked++
+			g.stats.Requests++
+			g.mutex.Unlock()
+			return fmt.Errorf("%w: matched deny rule %q", ErrBlocked, rule.name)
+		}
+	}
+	return nil
+}
+
+// record 记录一次检查通过的请求
+func (g *Guard) record(vault *Vault) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	g.stats.Requests++
+	for name, count := range vault.Counts() {
+		g.stats.Masked[name] += int64(count)
+	}
+	if g.restore && vault.Len() > 0 {
+		g.stats.Restored++
+	}
+}
+
+// responseFilter 创建处理响应文本的过滤器
+func (g *Guard) responseFilter(vault *Vault) *streamFilter {
+	filter := &streamFilter{}
+	if g.restore && vault.Len() > 0 {
+		filter.vault = vault
+	}
+	if g.maskResponses {
+		filter.detectors = g.detectors
+	}
+	return filter
+}
+
+// argumentFilter 创建处理工具调用参数的过滤器，参数只还原不遮盖，以便工具使用原文
+func (g *Guard) argumentFilter(vault *Vault) *streamFilter {
+	filter := &streamFilter{}
+	if g.restore && vault.Len() > 0 {
+		filter.vault = vault
+	}
+	return filter
+}
+
+// filterResponse 处理响应中的文本，流式响应逐个数据块增量处理
+func (g *Guard) filterResponse(ctx context.Context, resp interface{}, vault *Vault) interface{} {
+	if !g.maskResponses && (!g.restore || vault.Len() == 0) {
+		return resp
+	}
+
+	switch r := resp.(type) {
+	case *ai_agent.ChatResponse:
+		for i := range r.Choices {
+			message := &r.Choices[i].Message
+			message.Content = g.responseFilter(vault).process(message.Content)
+			for j := range message.ToolCalls {
+				message.ToolCalls[j].Function.Arguments = g.argumentFilter(vault).process(message.ToolCalls[j].Function.Arguments)
+			}
+		}
+	case *ai_agent.CompletionResponse:
+		for i := range r.Choices {
+			r.Choices[i].Text = g.responseFilter(vault).process(r.Choices[i].Text)
+		}
+	case *ai_agent.ChatStreamResult:
+		chunks, errs := g.filterChatStream(ctx, r.Chunks, r.Errors, vault)
+		return &ai_agent.ChatStreamResult{Chunks: chunks, Errors: errs}
+	case *ai_agent.CompletionStreamResult:
+		chunks, errs := g.filterCompletionStream(ctx, r.Chunks, r.Errors, vault)
+		return &ai_agent.CompletionStreamResult{Chunks: chunks, Errors: errs}
+	}
+	return resp
+}
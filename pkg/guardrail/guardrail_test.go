@@ -0,0 +1,82 @@
+package guardrail
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"kai/kaigate/pkg/log"
+	"kai/kaigate/pkg/service/ai_agent"
+)
+
+func TestMain(m *testing.M) {
+	if err := log.InitLogger("error", "json", "", false); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+func newTestGuard(t *testing.T) *Guard {
+	t.Helper()
+	detectors, err := BuiltinDetectors("email")
+	if err != nil {
+		t.Fatalf("builtin detectors: %v", err)
+	}
+	return NewGuard(WithDetectors(detectors...), WithDenyKeywords("drop table"))
+}
+
+// toolCallRequest 构造历史消息中带有工具调用的聊天请求
+func toolCallRequest(arguments string) *ai_agent.ChatRequest {
+	return &ai_agent.ChatRequest{Messages: []ai_agent.Message{
+		{Role: "user", Content: "send the report"},
+		{Role: "assistant", ToolCalls: []ai_agent.ToolCall{{ID: "call_1", Type: "function", Function: ai_agent.FunctionCall{Name: "send_mail", Arguments: arguments}}}},
+		{Role: "tool", ToolCallID: "call_1", Content: "sent"},
+	}}
+}
+
+func TestProcessMasksToolCallArguments(t *testing.T) {
+	guard := newTestGuard(t)
+	req := toolCallRequest(`{"to":"alice@example.com"}`)
+
+	var sent *ai_agent.ChatRequest
+	resp, err := guard.Process(context.Background(), req, func(ctx context.Context, req interface{}) (interface{}, error) {
+		sent = req.(*ai_agent.ChatRequest)
+		arguments := sent.Messages[1].ToolCalls[0].Function.Arguments
+		return &ai_agent.ChatResponse{Choices: []ai_agent.ChatChoice{{Message: ai_agent.Message{Role: "assistant", Content: "mailed " + arguments}}}}, nil
+	})
+	if err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+
+	if got := sent.Messages[1].ToolCalls[0].Function.Arguments; got != `{"to":"[EMAIL_1]"}` {
+		t.Errorf("upstream arguments = %s", got)
+	}
+	// 不修改调用方的消息列表
+	if got := req.Messages[1].ToolCalls[0].Function.Arguments; got != `{"to":"alice@example.com"}` {
+		t.Errorf("caller arguments modified: %s", got)
+	}
+	// 响应中的占位符还原为原文
+	if got := resp.(*ai_agent.ChatResponse).Choices[0].Message.Content; !strings.Contains(got, "alice@example.com") {
+		t.Errorf("response not restored: %s", got)
+	}
+}
+
+func TestProcessDeniesToolCallArguments(t *testing.T) {
+	guard := newTestGuard(t)
+	called := false
+	_, err := guard.Process(context.Background(), toolCallRequest(`{"sql":"DROP TABLE users"}`), func(ctx context.Context, req interface{}) (interface{}, error) {
+		called = true
+		return &ai_agent.ChatResponse{}, nil
+	})
+	if !errors.Is(err, ErrBlocked) {
+		t.Fatalf("err = %v, want ErrBlocked", err)
+	}
+	if called {
+		t.Error("blocked request was sent upstream")
+	}
+	if stats := guard.Stats(); stats.Blocked != 1 {
+		t.Errorf("blocked = %d, want 1", stats.Blocked)
+	}
+}
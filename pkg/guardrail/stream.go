@@ -0,0 +1,208 @@
+package guardrail
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"kai/kaigate/pkg/service/ai_agent"
+)
+
+// outputHoldback 遮盖流式输出时保留的末尾字节数，跨数据块的敏感信息在补全后再处理
+const outputHoldback = 64
+
+// streamFilter 增量处理流式文本
+// 可能属于未完成的占位符或敏感信息的末尾文本暂不输出，等后续数据块到达或数据流结束时再处理
+type streamFilter struct {
+	vault     *Vault
+	detectors []Detector // 遮盖输出时使用的检测器，为空表示不遮盖
+	pending   string
+}
+
+// Write 追加一段文本，返回可以安全输出的处理后文本
+func (f *streamFilter) Write(text string) string {
+	f.pending += text
+	cut := f.safeCut()
+	out := f.pending[:cut]
+	f.pending = f.pending[cut:]
+	return f.process(out)
+}
+
+// Flush 返回剩余的处理后文本
+func (f *streamFilter) Flush() string {
+	out := f.pending
+	f.pending = ""
+	return f.process(out)
+}
+
+// process 遮盖输出中的敏感信息并还原占位符
+func (f *streamFilter) process(text string) string {
+	if len(f.detectors) > 0 {
+		text = Redact(text, f.detectors)
+	}
+	if f.vault != nil {
+		text = f.vault.Restore(text)
+	}
+	return text
+}
+
+// safeCut 返回可以安全输出的前缀长度
+func (f *streamFilter) safeCut() int {
+	cut := len(f.pending)
+
+	// 保留末尾可能未完成的敏感信息
+	if len(f.detectors) > 0 {
+		cut -= outputHoldback
+		if cut <= 0 {
+			return 0
+		}
+		for cut > 0 && !utf8.RuneStart(f.pending[cut]) {
+			cut--
+		}
+		for _, detector := range f.detectors {
+			for _, loc := range detector.Pattern.FindAllStringIndex(f.pending, -1) {
+				if loc[0] < cut && loc[1] > cut {
+					cut = loc[0]
+				}
+			}
+		}
+	}
+
+	// 保留末尾未完成的占位符
+	if f.vault != nil && f.vault.Len() > 0 {
+		if start := strings.LastIndex(f.pending[:cut], "["); start >= 0 &&
+			!strings.Contains(f.pending[start:cut], "]") && cut-start < f.vault.maxLen {
+			cut = start
+		}
+	}
+	return cut
+}
+
+// toolKey 流式工具调用参数过滤器的键
+type toolKey struct {
+	choice int
+	tool   int
+}
+
+// filterChatStream 增量处理聊天数据流，每个候选结果和工具调用使用独立的过滤器
+// 候选结果结束或数据流结束时输出保留的剩余文本
+func (g *Guard) filterChatStream(ctx context.Context, chunks <-chan *ai_agent.ChatResponse, errs <-chan error, vault *Vault) (<-chan *ai_agent.ChatResponse, <-chan error) {
+	contents := make(map[int]*streamFilter)
+	arguments := make(map[toolKey]*streamFilter)
+	var last *ai_agent.ChatResponse
+
+	// flushChoice 将候选结果保留的剩余文本追加到数据块
+	flushChoice := func(choice *ai_agent.ChatChoice) {
+		if filter, exists := contents[choice.Index]; exists {
+			choice.Message.Content += filter.Flush()
+			delete(contents, choice.Index)
+		}
+		for key, filter := range arguments {
+			if key.choice != choice.Index {
+				continue
+			}
+			if rest := filter.Flush(); rest != "" {
+				choice.Message.ToolCalls = append(choice.Message.ToolCalls, ai_agent.ToolCall{
+					Index:    key.tool,
+					Function: ai_agent.FunctionCall{Arguments: rest},
+				})
+			}
+			delete(arguments, key)
+		}
+	}
+
+	transform := func(chunk *ai_agent.ChatResponse) *ai_agent.ChatResponse {
+		if chunk == nil {
+			return chunk
+		}
+		last = chunk
+		for i := range chunk.Choices {
+			choice := &chunk.Choices[i]
+			filter, exists := contents[choice.Index]
+			if !exists {
+				filter = g.responseFilter(vault)
+				contents[choice.Index] = filter
+			}
+			choice.Message.Content = filter.Write(choice.Message.Content)
+			for j := range choice.Message.ToolCalls {
+				call := &choice.Message.ToolCalls[j]
+				key := toolKey{choice: choice.Index, tool: call.Index}
+				argFilter, exists := arguments[key]
+				if !exists {
+					argFilter = g.argumentFilter(vault)
+					arguments[key] = argFilter
+				}
+				call.Function.Arguments = argFilter.Write(call.Function.Arguments)
+			}
+			if choice.FinishReason != "" {
+				flushChoice(choice)
+			}
+		}
+		return chunk
+	}
+
+	final := func() (*ai_agent.ChatResponse, bool) {
+		if last == nil || (len(contents) == 0 && len(arguments) == 0) {
+			return nil, false
+		}
+		chunk := &ai_agent.ChatResponse{ID: last.ID, Object: last.Object, Created: last.Created, Model: last.Model}
+		indexes := make(map[int]bool)
+		for index := range contents {
+			indexes[index] = true
+		}
+		for key := range arguments {
+			indexes[key.choice] = true
+		}
+		for index := range indexes {
+			choice := ai_agent.ChatChoice{Index: index, Message: ai_agent.Message{Role: "assistant"}}
+			flushChoice(&choice)
+			if choice.Message.Content != "" || len(choice.Message.ToolCalls) > 0 {
+				chunk.Choices = append(chunk.Choices, choice)
+			}
+		}
+		sort.Slice(chunk.Choices, func(i, j int) bool { return chunk.Choices[i].Index < chunk.Choices[j].Index })
+		return chunk, len(chunk.Choices) > 0
+	}
+
+	return ai_agent.ForwardStream(ctx, chunks, errs, ai_agent.StreamHooks[*ai_agent.ChatResponse]{Transform: transform, Final: final})
+}
+
+// filterCompletionStream 增量处理文本生成数据流
+func (g *Guard) filterCompletionStream(ctx context.Context, chunks <-chan *ai_agent.CompletionResponse, errs <-chan error, vault *Vault) (<-chan *ai_agent.CompletionResponse, <-chan error) {
+	texts := make(map[int]*streamFilter)
+	var last *ai_agent.CompletionResponse
+
+	transform := func(chunk *ai_agent.CompletionResponse) *ai_agent.CompletionResponse {
+		if chunk == nil {
+			return chunk
+		}
+		last = chunk
+		for i := range chunk.Choices {
+			choice := &chunk.Choices[i]
+			filter, exists := texts[choice.Index]
+			if !exists {
+				filter = g.responseFilter(vault)
+				texts[choice.Index] = filter
+			}
+			choice.Text = filter.Write(choice.Text)
+		}
+		return chunk
+	}
+
+	final := func() (*ai_agent.CompletionResponse, bool) {
+		if last == nil {
+			return nil, false
+		}
+		chunk := &ai_agent.CompletionResponse{ID: last.ID, Object: last.Object, Created: last.Created, Model: last.Model}
+		for index, filter := range texts {
+			if rest := filter.Flush(); rest != "" {
+				chunk.Choices = append(chunk.Choices, ai_agent.CompletionChoice{Index: index, Text: rest})
+			}
+		}
+		sort.Slice(chunk.Choices, func(i, j int) bool { return chunk.Choices[i].Index < chunk.Choices[j].Index })
+		return chunk, len(chunk.Choices) > 0
+	}
+
+	return ai_agent.ForwardStream(ctx, chunks, errs, ai_agent.StreamHooks[*ai_agent.CompletionResponse]{Transform: transform, Final: final})
+}
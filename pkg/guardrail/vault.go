@@ -0,0 +1,68 @@
+package guardrail
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Vault 单次调用中被遮盖的敏感信息
+// 相同的原文使用相同的占位符，占位符格式为[<名称>_<序号>]，响应中的占位符可以还原为原文
+type Vault struct {
+	originals    map[string]string // 占位符到原文
+	placeholders map[string]string // 原文到占位符
+	counters     map[string]int
+	maxLen       int
+}
+
+// NewVault 创建Vault实例
+func NewVault() *Vault {
+	return &Vault{
+		originals:    make(map[string]string),
+		placeholders: make(map[string]string),
+		counters:     make(map[string]int),
+	}
+}
+
+// Mask 使用检测器遮盖文本中的敏感信息，返回遮盖后的文本
+func (v *Vault) Mask(text string, detectors []Detector) string {
+	return replace(text, detectors, func(detector Detector, match string) string {
+		if placeholder, exists := v.placeholders[match]; exists {
+			return placeholder
+		}
+		name := strings.ToUpper(detector.Name)
+		v.counters[name]++
+		placeholder := "[" + name + "_" + strconv.Itoa(v.counters[name]) + "]"
+		v.originals[placeholder] = match
+		v.placeholders[match] = placeholder
+		if len(placeholder) > v.maxLen {
+			v.maxLen = len(placeholder)
+		}
+		return placeholder
+	})
+}
+
+// Restore 将文本中的占位符还原为原文
+func (v *Vault) Restore(text string) string {
+	if len(v.originals) == 0 || !strings.Contains(text, "[") {
+		return text
+	}
+	pairs := make([]string, 0, len(v.originals)*2)
+	for placeholder, original := range v.originals {
+		pairs = append(pairs, placeholder, original)
+	}
+	return strings.NewReplacer(pairs...).Replace(text)
+}
+
+// Len 返回被遮盖的不同原文数量
+func (v *Vault) Len() int {
+	return len(v.originals)
+}
+
+// Counts 返回各检测器遮盖的不同原文数量
+func (v *Vault) Counts() map[string]int {
+	counts := make(map[string]int, len(v.counters))
+	for name, count := range v.counters {
+		counts[strings.ToLower(name)] = count
+	}
+	return counts
+}
@@ -0,0 +1,114 @@
+// Package job 异步执行耗时较长的AI Agent和MCP调用
+// 调用方提交任务后立即获得任务ID，通过轮询或回调获取结果；任务由固定数量的worker执行，
+// 结果在任务结束后保留一段时间，任务状态持久化到存储中，网关重启后继续执行排队中的任务
+package job
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"kai/kaigate/pkg/auth"
+)
+
+// 任务类型
+const (
+	TypeChat       = "chat"       // AI Agent聊天
+	TypeCompletion = "completion" // AI Agent文本补全
+	TypeMCP        = "mcp"        // MCP服务调用
+)
+
+// 任务状态
+const (
+	StatusQueued    = "queued"    // 等待执行
+	StatusRunning   = "running"   // 执行中
+	StatusSucceeded = "succeeded" // 执行成功
+	StatusFailed    = "failed"    // 执行失败
+	StatusCanceled  = "canceled"  // 已取消
+)
+
+// storePrefix 任务在存储中的键前缀
+const storePrefix = "job/"
+
+var (
+	// ErrNotFound 任务不存在或结果已过期
+	ErrNotFound = errors.New("job not found")
+	// ErrQueueFull 等待执行的任务已达到队列上限
+	ErrQueueFull = errors.New("job queue is full")
+	// ErrFinished 任务已结束，不能取消
+	ErrFinished = errors.New("job already finished")
+	// ErrClosed 任务管理器已关闭
+	ErrClosed = errors.New("job manager is closed")
+	// ErrInvalidJob 任务类型或参数无效
+	ErrInvalidJob = errors.New("invalid job")
+)
+
+// Job 异步任务
+type Job struct {
+	ID          string          `json:"id"`
+	Type        string          `json:"type"`
+	Target      string          `json:"target"` // AI Agent或MCP服务名称
+	Status      string          `json:"status"`
+	Owner       string          `json:"owner,omitempty"` // 提交任务的调用方，为空表示匿名任务
+	Request     json.RawMessage `json:"request"`
+	Result      json.RawMessage `json:"result,omitempty"`
+	Error       string          `json:"error,omitempty"`
+	CallbackURL string          `json:"callback_url,omitempty"`
+	// 回调失败的原因，回调成功或未设置回调时为空
+	CallbackError string     `json:"callback_error,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	StartedAt     *time.Time `json:"started_at,omitempty"`
+	FinishedAt    *time.Time `json:"finished_at,omitempty"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"` // 结果过期时间，过期后任务被删除
+}
+
+// Summary 任务摘要，不包含请求、结果、调用方和回调地址，用于向订阅方发布任务事件
+type Summary struct {
+	ID         string     `json:"id"`
+	Type       string     `json:"type"`
+	Target     string     `json:"target"`
+	Status     string     `json:"status"`
+	Error      string     `json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// Submission 提交的任务
+type Submission struct {
+	Type        string
+	Target      string
+	Request     interface{} // ai_agent.ChatRequest、ai_agent.CompletionRequest或mcp.MCPServiceRequest
+	CallbackURL string
+	// 提交任务的调用方身份，执行任务时放入上下文，供中间件记录调用方
+	Identity *auth.Identity
+}
+
+// Summary 获取任务摘要
+func (j *Job) Summary() Summary {
+	return Summary{
+		ID:         j.ID,
+		Type:       j.Type,
+		Target:     j.Target,
+		Status:     j.Status,
+		Error:      j.Error,
+		CreatedAt:  j.CreatedAt,
+		StartedAt:  j.StartedAt,
+		FinishedAt: j.FinishedAt,
+	}
+}
+
+// Finished 任务是否已结束
+func (j *Job) Finished() bool {
+	switch j.Status {
+	case StatusSucceeded, StatusFailed, StatusCanceled:
+		return true
+	}
+	return false
+}
+
+// record 任务在存储中的数据，包含执行任务需要的调用方身份
+type record struct {
+	Job
+	Identity *auth.Identity `json:"identity,omitempty"`
+}
@@ -0,0 +1,607 @@
+package job
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"kai/kaigate/pkg/auth"
+	"kai/kaigate/pkg/log"
+	"kai/kaigate/pkg/netutil"
+	"kai/kaigate/pkg/service/ai_agent"
+	"kai/kaigate/pkg/service/mcp"
+	"kai/kaigate/pkg/storage"
+)
+
+// cleanupInterval 清理过期任务的间隔
+const cleanupInterval = time.Minute
+
+// Manager 异步任务管理器
+// 提交的任务进入有界队列，由固定数量的worker依次执行；任务状态每次变化都写入存储
+type Manager struct {
+	store     storage.KV
+	agents    ai_agent.AIAgentManager
+	services  mcp.MCPServiceManager
+	notifier  Notifier
+	guard     *netutil.Guard // 校验回调地址，拒绝内部网络地址
+	listener  func(Job)
+	workers   int
+	queueSize int
+	ttl       time.Duration
+	timeout   time.Duration
+	jobs      map[string]*record
+	running   map[string]context.CancelFunc // 执行中任务的取消函数
+	queue     chan string
+	ctx       context.Context // 关闭时取消，中断执行中的任务
+	stop      context.CancelFunc
+	closed    bool
+	closeOnce sync.Once
+	closeErr  error
+	wg        sync.WaitGroup
+	mutex     sync.Mutex
+	logger    log.Logger
+}
+
+// Option Manager配置选项
+type Option func(*Manager)
+
+// WithWorkers 设置并发执行任务的worker数
+func WithWorkers(workers int) Option {
+	return func(m *Manager) {
+		m.workers = workers
+	}
+}
+
+// WithQueueSize 设置等待执行的任务队列长度，队列满时拒绝提交
+func WithQueueSize(size int) Option {
+	return func(m *Manager) {
+		m.queueSize = size
+	}
+}
+
+// WithTTL 设置任务结束后结果的保留时间，0表示不过期
+func WithTTL(ttl time.Duration) Option {
+	return func(m *Manager) {
+		m.ttl = ttl
+	}
+}
+
+// WithTimeout 设置单个任务的最长执行时间，0表示不限制
+func WithTimeout(timeout time.Duration) Option {
+	return func(m *Manager) {
+		m.timeout = timeout
+	}
+}
+
+// WithNotifier 设置任务结束后发送回调的通知器
+func WithNotifier(notifier Notifier) Option {
+	return func(m *Manager) {
+		m.notifier = notifier
+	}
+}
+
+// WithCallbackGuard 设置校验回调地址的Guard，提交时拒绝解析到内部网络的回调地址
+func WithCallbackGuard(guard *netutil.Guard) Option {
+	return func(m *Manager) {
+		m.guard = guard
+	}
+}
+
+// WithListener 设置任务结束时调用的函数，用于发布任务事件，调用时持有锁，不能阻塞
+func WithListener(listener func(Job)) Option {
+	return func(m *Manager) {
+		m.listener = listener
+	}
+}
+
+// WithLogger 设置日志器
+func WithLogger(logger log.Logger) Option {
+	return func(m *Manager) {
+		m.logger = logger
+	}
+}
+
+// NewManager 创建Manager实例，加载存储中的任务并启动worker
+// 上次退出时排队中的任务重新进入队列，执行中的任务无法确定是否已完成，标记为失败
+func NewManager(store storage.KV, agents ai_agent.AIAgentManager, services mcp.MCPServiceManager, options ...Option) (*Manager, error) {
+	if store == nil {
+		return nil, errors.New("job store cannot be nil")
+	}
+
+	m := &Manager{
+		store:     store,
+		agents:    agents,
+		services:  services,
+		workers:   1,
+		queueSize: 100,
+		jobs:      make(map[string]*record),
+		running:   make(map[string]context.CancelFunc),
+		logger:    log.GlobalLogger,
+	}
+	for _, option := range options {
+		option(m)
+	}
+	if m.workers <= 0 {
+		return nil, fmt.Errorf("invalid job worker count: %d", m.workers)
+	}
+	if m.queueSize <= 0 {
+		return nil, fmt.Errorf("invalid job queue size: %d", m.queueSize)
+	}
+	if m.guard == nil {
+		m.guard, _ = netutil.NewGuard(nil)
+	}
+	if m.notifier == nil {
+		m.notifier = NewHTTPNotifier(10*time.Second, m.guard)
+	}
+	m.queue = make(chan string, m.queueSize)
+	m.ctx, m.stop = context.WithCancel(context.Background())
+
+	if err := m.load(); err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < m.workers; i++ {
+		m.wg.Add(1)
+		go m.work()
+	}
+	m.wg.Add(1)
+	go m.cleanup()
+	return m, nil
+}
+
+// Submit 提交任务，返回排队中的任务
+func (m *Manager) Submit(sub Submission) (*Job, error) {
+	switch sub.Type {
+	case TypeChat, TypeCompletion:
+		if m.agents == nil {
+			return nil, fmt.Errorf("%w: AI agents are not available", ErrInvalidJob)
+		}
+	case TypeMCP:
+		if m.services == nil {
+			return nil, fmt.Errorf("%w: MCP services are not available", ErrInvalidJob)
+		}
+	default:
+		return nil, fmt.Errorf("%w: unsupported job type %q", ErrInvalidJob, sub.Type)
+	}
+	if sub.Target == "" {
+		return nil, fmt.Errorf("%w: target is required", ErrInvalidJob)
+	}
+	if sub.CallbackURL != "" {
+		if err := m.guard.CheckURL(context.Background(), sub.CallbackURL); err != nil {
+			return nil, fmt.Errorf("%w: invalid callback_url: %v", ErrInvalidJob, err)
+		}
+	}
+	request, err := json.Marshal(sub.Request)
+	if err != nil {
+		return nil, fmt.Errorf("%w: encode request failed: %v", ErrInvalidJob, err)
+	}
+
+	rec := &record{
+		Job: Job{
+			ID:          newID(),
+			Type:        sub.Type,
+			Target:      sub.Target,
+			Status:      StatusQueued,
+			Request:     request,
+			CallbackURL: sub.CallbackURL,
+			CreatedAt:   time.Now(),
+		},
+		Identity: sub.Identity,
+	}
+	if sub.Identity != nil && !sub.Identity.IsAnonymous() {
+		rec.Owner = sub.Identity.ID
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.closed {
+		return nil, ErrClosed
+	}
+	if len(m.queue) == cap(m.queue) {
+		return nil, ErrQueueFull
+	}
+	if err := m.save(rec); err != nil {
+		return nil, err
+	}
+	m.jobs[rec.ID] = rec
+	// 持有锁时只有Submit和load写入队列，队列未满时不会阻塞
+	m.queue <- rec.ID
+
+	m.logger.Info("Job submitted",
+		zap.String("job_id", rec.ID),
+		zap.String("type", rec.Type),
+		zap.String("target", rec.Target),
+		zap.String("owner", rec.Owner),
+	)
+	job := rec.Job
+	return &job, nil
+}
+
+// Get 获取任务，结果已过期的任务视为不存在
+func (m *Manager) Get(id string) (*Job, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	rec, err := m.lookup(id)
+	if err != nil {
+		return nil, err
+	}
+	job := rec.Job
+	return &job, nil
+}
+
+// List 列出任务，owner为空时列出全部任务，结果按创建时间倒序排列
+func (m *Manager) List(owner string) []*Job {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	now := time.Now()
+	jobs := make([]*Job, 0, len(m.jobs))
+	for _, rec := range m.jobs {
+		if expired(rec, now) || (owner != "" && rec.Owner != owner) {
+			continue
+		}
+		job := rec.Job
+		jobs = append(jobs, &job)
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.After(jobs[j].CreatedAt)
+	})
+	return jobs
+}
+
+// Cancel 取消排队中或执行中的任务
+func (m *Manager) Cancel(id string) (*Job, error) {
+	m.mutex.Lock()
+	rec, err := m.lookup(id)
+	if err != nil {
+		m.mutex.Unlock()
+		return nil, err
+	}
+	if rec.Finished() {
+		m.mutex.Unlock()
+		return nil, ErrFinished
+	}
+	// 排队中的任务出队时发现已取消会直接跳过；执行中的任务结束时发现已取消会丢弃结果
+	if cancel, ok := m.running[id]; ok {
+		cancel()
+	}
+	m.finish(rec, StatusCanceled, nil, errors.New("canceled by caller"))
+	job := rec.Job
+	m.mutex.Unlock()
+
+	m.logger.Info("Job canceled", zap.String("job_id", id))
+	return &job, nil
+}
+
+// Close 停止worker并清理资源
+// 执行中的任务被中断并标记为失败，排队中的任务保留在存储中，下次启动后继续执行
+// 可以并发调用，所有调用都等待worker退出后返回
+func (m *Manager) Close() error {
+	m.closeOnce.Do(func() {
+		m.mutex.Lock()
+		m.closed = true
+		m.mutex.Unlock()
+
+		m.stop()
+		m.wg.Wait()
+		m.closeErr = m.store.Close()
+	})
+	return m.closeErr
+}
+
+// load 加载存储中的任务
+func (m *Manager) load() error {
+	keys, err := m.store.List(storePrefix)
+	if err != nil {
+		return fmt.Errorf("list jobs failed: %w", err)
+	}
+
+	now := time.Now()
+	queued := make([]*record, 0)
+	for _, key := range keys {
+		data, err := m.store.Get(key)
+		if err != nil {
+			m.logger.Warn("Skipping unreadable job", zap.String("key", key), zap.Error(err))
+			continue
+		}
+		var rec record
+		if err := json.Unmarshal(data, &rec); err != nil {
+			m.logger.Warn("Skipping unreadable job", zap.String("key", key), zap.Error(err))
+			continue
+		}
+		if expired(&rec, now) {
+			m.store.Delete(key)
+			continue
+		}
+		m.jobs[rec.ID] = &rec
+
+		switch rec.Status {
+		case StatusQueued:
+			queued = append(queued, &rec)
+		case StatusRunning:
+			m.finish(&rec, StatusFailed, nil, errors.New("interrupted by gateway restart"))
+		}
+	}
+
+	// 按提交顺序重新排队，超出队列长度的任务标记为失败
+	sort.Slice(queued, func(i, j int) bool {
+		return queued[i].CreatedAt.Before(queued[j].CreatedAt)
+	})
+	for _, rec := range queued {
+		if len(m.queue) == cap(m.queue) {
+			m.finish(rec, StatusFailed, nil, ErrQueueFull)
+			continue
+		}
+		m.queue <- rec.ID
+	}
+
+	if len(m.jobs) > 0 {
+		m.logger.Info("Jobs restored from store", zap.Int("jobs", len(m.jobs)), zap.Int("queued", len(m.queue)))
+	}
+	return nil
+}
+
+// work worker循环，依次执行队列中的任务
+func (m *Manager) work() {
+	defer m.wg.Done()
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case id := <-m.queue:
+			m.run(id)
+		}
+	}
+}
+
+// run 执行任务并保存结果
+func (m *Manager) run(id string) {
+	m.mutex.Lock()
+	rec, ok := m.jobs[id]
+	if !ok || rec.Status != StatusQueued || m.closed {
+		// 排队期间已取消或已过期；正在关闭时保留在队列中，下次启动后执行
+		m.mutex.Unlock()
+		return
+	}
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if m.timeout > 0 {
+		ctx, cancel = context.WithTimeout(m.ctx, m.timeout)
+	} else {
+		ctx, cancel = context.WithCancel(m.ctx)
+	}
+	defer cancel()
+	m.running[id] = cancel
+
+	now := time.Now()
+	rec.Status = StatusRunning
+	rec.StartedAt = &now
+	if err := m.save(rec); err != nil {
+		m.logger.Error("Failed to save job", zap.String("job_id", id), zap.Error(err))
+	}
+	jobType, target, request := rec.Type, rec.Target, rec.Request
+	if rec.Identity != nil {
+		ctx = auth.WithIdentity(ctx, rec.Identity)
+	}
+	m.mutex.Unlock()
+
+	result, err := m.execute(ctx, jobType, target, request)
+	switch {
+	case err != nil && m.ctx.Err() != nil:
+		err = errors.New("interrupted by gateway shutdown")
+	case err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded):
+		err = fmt.Errorf("job timed out after %s", m.timeout)
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	delete(m.running, id)
+	if rec.Status != StatusRunning {
+		// 执行期间已取消
+		return
+	}
+	if err != nil {
+		m.finish(rec, StatusFailed, nil, err)
+	} else {
+		m.finish(rec, StatusSucceeded, result, nil)
+	}
+	m.logger.Info("Job finished",
+		zap.String("job_id", id),
+		zap.String("status", rec.Status),
+		zap.Duration("duration", rec.FinishedAt.Sub(*rec.StartedAt)),
+		zap.String("error", rec.Error),
+	)
+}
+
+// execute 调用任务对应的AI Agent或MCP服务
+func (m *Manager) execute(ctx context.Context, jobType, target string, request json.RawMessage) (interface{}, error) {
+	switch jobType {
+	case TypeChat:
+		var req ai_agent.ChatRequest
+		if err := json.Unmarshal(request, &req); err != nil {
+			return nil, fmt.Errorf("decode chat request failed: %w", err)
+		}
+		// 任务结果整体保存，不使用流式响应
+		req.Stream = false
+		agent, err := m.agents.GetAIAgent(target, nil)
+		if err != nil {
+			return nil, err
+		}
+		return agent.Chat(ctx, req)
+	case TypeCompletion:
+		var req ai_agent.CompletionRequest
+		if err := json.Unmarshal(request, &req); err != nil {
+			return nil, fmt.Errorf("decode completion request failed: %w", err)
+		}
+		req.Stream = false
+		agent, err := m.agents.GetAIAgent(target, nil)
+		if err != nil {
+			return nil, err
+		}
+		return agent.Completion(ctx, req)
+	case TypeMCP:
+		var req mcp.MCPServiceRequest
+		if err := json.Unmarshal(request, &req); err != nil {
+			return nil, fmt.Errorf("decode MCP request failed: %w", err)
+		}
+		service, err := m.services.GetMCPService(target, nil)
+		if err != nil {
+			return nil, err
+		}
+		return callAsync(ctx, service, req)
+	}
+	return nil, fmt.Errorf("%w: unsupported job type %q", ErrInvalidJob, jobType)
+}
+
+// callAsync 使用MCP服务的异步调用接口执行调用，等待回调或上下文取消
+func callAsync(ctx context.Context, service mcp.MCPService, req mcp.MCPServiceRequest) (*mcp.MCPServiceResponse, error) {
+	type result struct {
+		resp *mcp.MCPServiceResponse
+		err  error
+	}
+	done := make(chan result, 1)
+	if err := service.CallAsync(ctx, req, func(resp *mcp.MCPServiceResponse, err error) {
+		done <- result{resp: resp, err: err}
+	}); err != nil {
+		return nil, err
+	}
+
+	select {
+	case r := <-done:
+		return r.resp, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// finish 记录任务结果并保存，通知监听函数，设置了回调地址时发送回调，调用方需持有锁
+func (m *Manager) finish(rec *record, status string, result interface{}, err error) {
+	now := time.Now()
+	rec.Status = status
+	rec.FinishedAt = &now
+	if m.ttl > 0 {
+		expiresAt := now.Add(m.ttl)
+		rec.ExpiresAt = &expiresAt
+	}
+	if err != nil {
+		rec.Error = err.Error()
+	}
+	if result != nil {
+		data, encodeErr := json.Marshal(result)
+		if encodeErr != nil {
+			rec.Status = StatusFailed
+			rec.Error = "encode result failed: " + encodeErr.Error()
+		} else {
+			rec.Result = data
+		}
+	}
+	if err := m.save(rec); err != nil {
+		m.logger.Error("Failed to save job", zap.String("job_id", rec.ID), zap.Error(err))
+	}
+
+	if m.listener != nil {
+		m.listener(rec.Job)
+	}
+	if rec.CallbackURL != "" && m.notifier != nil {
+		m.wg.Add(1)
+		go m.notify(rec.ID, rec.CallbackURL, rec.Job)
+	}
+}
+
+// notify 发送任务结束的回调，失败原因记录在任务中
+func (m *Manager) notify(id, url string, job Job) {
+	defer m.wg.Done()
+
+	err := m.notifier.Notify(context.Background(), url, &job)
+	if err == nil {
+		return
+	}
+	m.logger.Warn("Job callback failed", zap.String("job_id", id), zap.String("url", url), zap.Error(err))
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if rec, ok := m.jobs[id]; ok {
+		rec.CallbackError = err.Error()
+		if err := m.save(rec); err != nil {
+			m.logger.Error("Failed to save job", zap.String("job_id", id), zap.Error(err))
+		}
+	}
+}
+
+// cleanup 定期删除结果已过期的任务
+func (m *Manager) cleanup() {
+	defer m.wg.Done()
+	ticker := time.NewTicker(cleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case now := <-ticker.C:
+			m.mutex.Lock()
+			for id, rec := range m.jobs {
+				if expired(rec, now) {
+					m.remove(id)
+				}
+			}
+			m.mutex.Unlock()
+		}
+	}
+}
+
+// lookup 获取任务，已过期的任务被删除，调用方需持有锁
+func (m *Manager) lookup(id string) (*record, error) {
+	rec, ok := m.jobs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if expired(rec, time.Now()) {
+		m.remove(id)
+		return nil, ErrNotFound
+	}
+	return rec, nil
+}
+
+// remove 删除任务，调用方需持有锁
+func (m *Manager) remove(id string) {
+	delete(m.jobs, id)
+	if err := m.store.Delete(storePrefix + id); err != nil {
+		m.logger.Warn("Failed to delete expired job", zap.String("job_id", id), zap.Error(err))
+	}
+}
+
+// save 保存任务
+func (m *Manager) save(rec *record) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("encode job failed: %w", err)
+	}
+	if err := m.store.Put(storePrefix+rec.ID, data); err != nil {
+		return fmt.Errorf("save job failed: %w", err)
+	}
+	return nil
+}
+
+// expired 任务结果是否已过期
+func expired(rec *record, now time.Time) bool {
+	return rec.ExpiresAt != nil && now.After(*rec.ExpiresAt)
+}
+
+// newID 生成任务ID
+func newID() string {
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Sprintf("job_%d", time.Now().UnixNano())
+	}
+	return "job_" + hex.EncodeToString(buf)
+}
@@ -0,0 +1,194 @@
+package job
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"kai/kaigate/pkg/log"
+	"kai/kaigate/pkg/service/ai_agent"
+	"kai/kaigate/pkg/storage"
+)
+
+func TestMain(m *testing.M) {
+	if err := log.InitLogger("error", "json", "", false); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// blockingAgent 开始执行时通知started，收到release或上下文取消后返回
+type blockingAgent struct {
+	ai_agent.AIAgent
+	started chan string
+	release chan struct{}
+}
+
+func newBlockingAgent() *blockingAgent {
+	return &blockingAgent{started: make(chan string, 10), release: make(chan struct{})}
+}
+
+func (a *blockingAgent) Chat(ctx context.Context, req ai_agent.ChatRequest) (*ai_agent.ChatResponse, error) {
+	a.started <- req.Messages[0].Content
+	select {
+	case <-a.release:
+		return &ai_agent.ChatResponse{Choices: []ai_agent.ChatChoice{{Message: ai_agent.Message{Role: "assistant", Content: "done"}}}}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// stubAgents 所有名称都返回同一个Agent
+type stubAgents struct {
+	ai_agent.AIAgentManager
+	agent ai_agent.AIAgent
+}
+
+func (m *stubAgents) GetAIAgent(name string, config map[string]interface{}) (ai_agent.AIAgent, error) {
+	return m.agent, nil
+}
+
+func newTestManager(t *testing.T, store storage.KV, agent ai_agent.AIAgent, options ...Option) *Manager {
+	t.Helper()
+	manager, err := NewManager(store, &stubAgents{agent: agent}, nil, options...)
+	if err != nil {
+		t.Fatalf("create manager: %v", err)
+	}
+	t.Cleanup(func() { manager.Close() })
+	return manager
+}
+
+func chatSubmission(content string) Submission {
+	return Submission{Type: TypeChat, Target: "openai", Request: ai_agent.ChatRequest{Messages: []ai_agent.Message{{Role: "user", Content: content}}}}
+}
+
+// waitStatus 等待任务进入指定状态
+func waitStatus(t *testing.T, manager *Manager, id, status string) *Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		job, err := manager.Get(id)
+		if err != nil {
+			t.Fatalf("Get %s: %v", id, err)
+		}
+		if job.Status == status {
+			return job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %s status = %s, want %s", id, job.Status, status)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestCancelRunningJob(t *testing.T) {
+	agent := newBlockingAgent()
+	var events []Job
+	manager := newTestManager(t, storage.NewMemoryKV(), agent, WithListener(func(job Job) { events = append(events, job) }))
+
+	job, err := manager.Submit(chatSubmission("long task"))
+	if err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	if job.Status != StatusQueued {
+		t.Errorf("submitted status = %s, want %s", job.Status, StatusQueued)
+	}
+
+	<-agent.started
+	running := waitStatus(t, manager, job.ID, StatusRunning)
+	if running.StartedAt == nil {
+		t.Error("running job has no start time")
+	}
+
+	canceled, err := manager.Cancel(job.ID)
+	if err != nil {
+		t.Fatalf("Cancel: %v", err)
+	}
+	if canceled.Status != StatusCanceled || canceled.FinishedAt == nil {
+		t.Errorf("canceled job = %+v", canceled)
+	}
+	if _, err := manager.Cancel(job.ID); !errors.Is(err, ErrFinished) {
+		t.Errorf("second Cancel err = %v, want ErrFinished", err)
+	}
+
+	// 执行中断后不覆盖取消状态，worker可以继续执行下一个任务
+	next, err := manager.Submit(chatSubmission("next task"))
+	if err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	<-agent.started
+	close(agent.release)
+	waitStatus(t, manager, next.ID, StatusSucceeded)
+	if final, _ := manager.Get(job.ID); final.Status != StatusCanceled || final.Result != nil {
+		t.Errorf("canceled job changed to %+v", final)
+	}
+
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+	if len(events) != 2 || events[0].Status != StatusCanceled || events[1].Status != StatusSucceeded {
+		t.Errorf("listener events = %+v", events)
+	}
+}
+
+func TestCancelQueuedJob(t *testing.T) {
+	agent := newBlockingAgent()
+	manager := newTestManager(t, storage.NewMemoryKV(), agent, WithQueueSize(1))
+
+	first, err := manager.Submit(chatSubmission("first"))
+	if err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	<-agent.started
+	queued, err := manager.Submit(chatSubmission("second"))
+	if err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	if _, err := manager.Submit(chatSubmission("third")); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Submit to full queue err = %v, want ErrQueueFull", err)
+	}
+
+	if _, err := manager.Cancel(queued.ID); err != nil {
+		t.Fatalf("Cancel: %v", err)
+	}
+	close(agent.release)
+	waitStatus(t, manager, first.ID, StatusSucceeded)
+
+	// 已取消的排队任务出队时被跳过
+	manager.Close()
+	select {
+	case content := <-agent.started:
+		t.Errorf("canceled job %q was executed", content)
+	default:
+	}
+	if job, _ := manager.Get(queued.ID); job.Status != StatusCanceled || job.StartedAt != nil {
+		t.Errorf("canceled queued job = %+v", job)
+	}
+}
+
+func TestRestoreJobs(t *testing.T) {
+	store := storage.NewMemoryKV()
+	request, _ := json.Marshal(chatSubmission("queued").Request)
+	created := time.Now()
+	for _, job := range []Job{
+		{ID: "job_running", Type: TypeChat, Target: "openai", Status: StatusRunning, Request: request, CreatedAt: created},
+		{ID: "job_queued", Type: TypeChat, Target: "openai", Status: StatusQueued, Request: request, CreatedAt: created},
+	} {
+		data, _ := json.Marshal(record{Job: job})
+		if err := store.Put(storePrefix+job.ID, data); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	agent := newBlockingAgent()
+	close(agent.release)
+	manager := newTestManager(t, store, agent)
+
+	// 上次退出时执行中的任务标记为失败，排队中的任务继续执行
+	if job := waitStatus(t, manager, "job_running", StatusFailed); job.Error == "" {
+		t.Error("interrupted job has no error")
+	}
+	waitStatus(t, manager, "job_queued", StatusSucceeded)
+}
@@ -0,0 +1,54 @@
+package job
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"kai/kaigate/pkg/netutil"
+)
+
+// Notifier 任务结束后向回调地址发送结果
+type Notifier interface {
+	Notify(ctx context.Context, url string, job *Job) error
+}
+
+// HTTPNotifier 以JSON请求体POST任务结果的通知器，回调地址返回非2xx状态码时视为失败
+type HTTPNotifier struct {
+	client *http.Client
+}
+
+// NewHTTPNotifier 创建HTTPNotifier实例，只连接guard允许的地址
+func NewHTTPNotifier(timeout time.Duration, guard *netutil.Guard) *HTTPNotifier {
+	return &HTTPNotifier{client: guard.Client(timeout)}
+}
+
+// Notify 实现Notifier接口的Notify方法
+func (n *HTTPNotifier) Notify(ctx context.Context, url string, job *Job) error {
+	payload, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("encode job failed: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("create callback request failed: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("send callback failed: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("callback returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+// Package netutil 校验网关代替调用方发起的出站请求
+// 回调地址由调用方提供，不加限制时可以让网关访问内网服务或云厂商元数据地址（SSRF），
+// Guard在提交时解析地址并在建立连接时再次校验实际连接的IP，防止DNS重绑定绕过
+package netutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenAddress 目标地址属于内部网络且不在允许列表中
+var ErrForbiddenAddress = errors.New("destination address is not allowed")
+
+// blockedNetworks 回环、私有、链路本地等地址之外需要拒绝的网段
+var blockedNetworks = mustParseCIDRs(
+	"0.0.0.0/8",     // 本网络
+	"100.64.0.0/10", // 运营商级NAT共享地址
+	"192.0.0.0/24",  // IETF协议分配
+	"198.18.0.0/15", // 基准测试
+	"240.0.0.0/4",   // 保留地址
+	"64:ff9b::/96",  // NAT64，可以映射到任意IPv4地址
+)
+
+// Guard 出站地址校验器
+// 拒绝解析到回环、私有、链路本地、组播等内部地址的目标，允许列表中的主机名或网段除外
+type Guard struct {
+	hosts    map[string]bool
+	networks []*net.IPNet
+	resolver *net.Resolver
+}
+
+// NewGuard 创建Guard实例，allowed中的每一项可以是主机名、IP地址或CIDR网段
+func NewGuard(allowed []string) (*Guard, error) {
+	g := &Guard{hosts: make(map[string]bool), resolver: net.DefaultResolver}
+	for _, item := range allowed {
+		item = strings.ToLower(strings.TrimSpace(item))
+		if item == "" {
+			continue
+		}
+		if strings.Contains(item, "/") {
+			_, network, err := net.ParseCIDR(item)
+			if err != nil {
+				return nil, fmt.Errorf("invalid allowed network %q: %w", item, err)
+			}
+			g.networks = append(g.networks, network)
+			continue
+		}
+		if ip := net.ParseIP(item); ip != nil {
+			bits := 8 * net.IPv4len
+			if ip.To4() == nil {
+				bits = 8 * net.IPv6len
+			}
+			g.networks = append(g.networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		g.hosts[item] = true
+	}
+	return g, nil
+}
+
+// CheckURL 校验地址是否为http或https的绝对地址，并解析主机名确认所有地址都允许访问
+func (g *Guard) CheckURL(ctx context.Context, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return errors.New("url must be an absolute http or https URL")
+	}
+	host := strings.ToLower(u.Hostname())
+	if g.hosts[host] {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return g.checkIP(ip)
+	}
+
+	addrs, err := g.resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return fmt.Errorf("resolve %s failed: %w", host, err)
+	}
+	for _, addr := range addrs {
+		if err := g.checkIP(addr.IP); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Client 创建只能连接允许地址的HTTP客户端
+// 建立连接时校验实际连接的IP，重定向和DNS重绑定都无法绕过；不使用环境变量中的代理
+func (g *Guard) Client(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			return g.checkIP(net.ParseIP(host))
+		},
+	}
+	direct := &net.Dialer{Timeout: dialer.Timeout, KeepAlive: dialer.KeepAlive}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+		// 允许列表中的主机名不限制解析结果
+		if host, _, err := net.SplitHostPort(address); err == nil && g.hosts[strings.ToLower(host)] {
+			return direct.DialContext(ctx, network, address)
+		}
+		return dialer.DialContext(ctx, network, address)
+	}
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+// checkIP 校验IP是否允许访问
+func (g *Guard) checkIP(ip net.IP) error {
+	if ip == nil {
+		return fmt.Errorf("%w: invalid address", ErrForbiddenAddress)
+	}
+	for _, network := range g.networks {
+		if network.Contains(ip) {
+			return nil
+		}
+	}
+	if internal(ip) {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, ip)
+	}
+	return nil
+}
+
+// internal 判断IP是否属于内部或保留地址
+func internal(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return true
+	}
+	for _, network := range blockedNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// mustParseCIDRs 解析固定的网段列表
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
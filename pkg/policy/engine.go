@@ -0,0 +1,277 @@
+package policy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"kai/kaigate/pkg/auth"
+	"kai/kaigate/pkg/config"
+	"kai/kaigate/pkg/log"
+)
+
+// 规则效果
+const (
+	EffectAllow = "allow"
+	EffectDeny  = "deny"
+)
+
+// 资源类型
+const (
+	ResourceAgent   = "agent"    // AI Agent调用
+	ResourceMCPTool = "mcp_tool" // MCP工具调用
+	ResourceRoute   = "route"    // 代理路由访问
+)
+
+// Input 策略评估输入
+type Input struct {
+	Identity *auth.Identity `json:"identity"` // 调用方身份
+	Resource string         `json:"resource"` // 资源类型
+	Target   string         `json:"target"`   // 资源名称
+	Model    string         `json:"model"`    // 模型名称，仅对agent资源有效
+}
+
+// Decision 策略评估结果
+type Decision struct {
+	Allowed bool   `json:"allowed"`        // 是否允许
+	Effect  string `json:"effect"`         // 生效的效果
+	Rule    string `json:"rule,omitempty"` // 命中的规则名称，为空表示使用默认效果
+	Reason  string `json:"reason"`         // 决策原因
+}
+
+// Engine 授权策略引擎
+// 规则按优先级从高到低匹配，第一条命中的规则决定结果；同优先级下deny规则优先
+type Engine struct {
+	enabled       bool
+	rules         []config.PolicyRuleConfig
+	defaultEffect string
+	mutex         sync.RWMutex
+	logger        log.Logger
+}
+
+// NewEngine 创建Engine实例
+func NewEngine() *Engine {
+	return &Engine{
+		defaultEffect: EffectAllow,
+		logger:        log.GlobalLogger,
+	}
+}
+
+// Load 加载策略规则，替换当前所有规则
+func (e *Engine) Load(rules []config.PolicyRuleConfig, defaultEffect string) error {
+	compiled, effect, err := compile(rules, defaultEffect)
+	if err != nil {
+		return err
+	}
+
+	e.mutex.Lock()
+	e.rules = compiled
+	e.defaultEffect = effect
+	e.mutex.Unlock()
+
+	e.logger.Info("Policies loaded",
+		zap.Int("rules", len(compiled)),
+		zap.String("default_effect", effect),
+	)
+	return nil
+}
+
+// SetEnabled 启用或禁用策略评估，禁用时所有调用都被允许
+func (e *Engine) SetEnabled(enabled bool) {
+	e.mutex.Lock()
+	e.enabled = enabled
+	e.mutex.Unlock()
+}
+
+// Enabled 是否启用策略评估
+func (e *Engine) Enabled() bool {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+	return e.enabled
+}
+
+// Rules 获取当前生效的规则（已按匹配顺序排序）
+func (e *Engine) Rules() ([]config.PolicyRuleConfig, string) {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	rules := make([]config.PolicyRuleConfig, len(e.rules))
+	copy(rules, e.rules)
+	return rules, e.defaultEffect
+}
+
+// Evaluate 评估调用是否被允许
+func (e *Engine) Evaluate(input Input) Decision {
+	e.mutex.RLock()
+	enabled := e.enabled
+	rules := e.rules
+	defaultEffect := e.defaultEffect
+	e.mutex.RUnlock()
+
+	if !enabled {
+		return Decision{Allowed: true, Effect: EffectAllow, Reason: "policy evaluation disabled"}
+	}
+	return evaluate(rules, defaultEffect, input)
+}
+
+// DryRun 使用候选规则评估调用，不影响当前生效的规则
+func DryRun(rules []config.PolicyRuleConfig, defaultEffect string, input Input) (Decision, error) {
+	compiled, effect, err := compile(rules, defaultEffect)
+	if err != nil {
+		return Decision{}, err
+	}
+	return evaluate(compiled, effect, input), nil
+}
+
+// compile 校验规则并按匹配顺序排序
+func compile(rules []config.PolicyRuleConfig, defaultEffect string) ([]config.PolicyRuleConfig, string, error) {
+	if defaultEffect == "" {
+		defaultEffect = EffectAllow
+	}
+	if defaultEffect != EffectAllow && defaultEffect != EffectDeny {
+		return nil, "", fmt.Errorf("invalid default effect: %s", defaultEffect)
+	}
+
+	compiled := make([]config.PolicyRuleConfig, len(rules))
+	for i, rule := range rules {
+		if rule.Effect != EffectAllow && rule.Effect != EffectDeny {
+			return nil, "", fmt.Errorf("policy rule %q has invalid effect: %s", rule.Name, rule.Effect)
+		}
+		switch rule.Resource {
+		case ResourceAgent, ResourceMCPTool, ResourceRoute:
+		default:
+			return nil, "", fmt.Errorf("policy rule %q has invalid resource: %s", rule.Name, rule.Resource)
+		}
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("rule-%d", i)
+		}
+		compiled[i] = rule
+	}
+
+	// 按优先级降序排序，同优先级deny优先，其余保持配置顺序
+	sort.SliceStable(compiled, func(i, j int) bool {
+		if compiled[i].Priority != compiled[j].Priority {
+			return compiled[i].Priority > compiled[j].Priority
+		}
+		return compiled[i].Effect == EffectDeny && compiled[j].Effect != EffectDeny
+	})
+	return compiled, defaultEffect, nil
+}
+
+// evaluate 按顺序匹配规则
+func evaluate(rules []config.PolicyRuleConfig, defaultEffect string, input Input) Decision {
+	for _, rule := range rules {
+		if !matchRule(rule, input) {
+			continue
+		}
+		return Decision{
+			Allowed: rule.Effect == EffectAllow,
+			Effect:  rule.Effect,
+			Rule:    rule.Name,
+			Reason:  fmt.Sprintf("matched rule %q", rule.Name),
+		}
+	}
+
+	return Decision{
+		Allowed: defaultEffect == EffectAllow,
+		Effect:  defaultEffect,
+		Reason:  "no rule matched, default effect applied",
+	}
+}
+
+// matchRule 检查规则是否匹配输入
+func matchRule(rule config.PolicyRuleConfig, input Input) bool {
+	if rule.Resource != input.Resource {
+		return false
+	}
+	if len(rule.Targets) > 0 && !matchAny(rule.Targets, input.Target) {
+		return false
+	}
+	// 规则限定模型时，未指定模型的调用实际使用的模型由Agent的默认模型或路由目标决定，无法在调用前确认：
+	// deny规则视为匹配，避免省略模型绕过限制；allow规则视为不匹配，避免省略模型获得授权
+	if len(rule.Models) > 0 {
+		if input.Model == "" {
+			if rule.Effect != EffectDeny {
+				return false
+			}
+		} else if !matchAny(rule.Models, input.Model) {
+			return false
+		}
+	}
+	return matchSubject(rule, input.Identity)
+}
+
+// matchSubject 检查调用方是否匹配规则的主体条件
+func matchSubject(rule config.PolicyRuleConfig, identity *auth.Identity) bool {
+	subjects := rule.Subjects
+	if len(subjects.IDs) == 0 && len(subjects.Roles) == 0 && len(subjects.Groups) == 0 && len(subjects.Scopes) == 0 {
+		return true
+	}
+	if identity == nil {
+		return false
+	}
+
+	if matchAny(subjects.IDs, identity.ID) {
+		return true
+	}
+	for _, role := range identity.Roles {
+		if matchAny(subjects.Roles, role) {
+			return true
+		}
+	}
+	for _, group := range identity.Groups {
+		if matchAny(subjects.Groups, group) {
+			return true
+		}
+	}
+	for _, scope := range identity.Scopes {
+		if matchAny(subjects.Scopes, scope) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchAny 检查值是否匹配任一模式，模式中的"*"匹配任意字符（包括"/"）
+func matchAny(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if wildcardMatch(pattern, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// wildcardMatch 通配符匹配
+func wildcardMatch(pattern, value string) bool {
+	if !strings.Contains(pattern, "*") {
+		return pattern == value
+	}
+
+	parts := strings.Split(pattern, "*")
+	// 首段必须是前缀
+	if !strings.HasPrefix(value, parts[0]) {
+		return false
+	}
+	value = value[len(parts[0]):]
+
+	// 中间段按顺序出现
+	for _, part := range parts[1 : len(parts)-1] {
+		index := strings.Index(value, part)
+		if index < 0 {
+			return false
+		}
+		value = value[index+len(part):]
+	}
+
+	// 末段必须是后缀
+	return strings.HasSuffix(value, parts[len(parts)-1])
+}
+
+// MCPToolTarget 构造MCP工具资源名称
+func MCPToolTarget(serviceID, tool string) string {
+	return serviceID + "/" + tool
+}
@@ -0,0 +1,160 @@
+package policy
+
+import (
+	"os"
+	"testing"
+
+	"kai/kaigate/pkg/auth"
+	"kai/kaigate/pkg/config"
+	"kai/kaigate/pkg/log"
+)
+
+func TestMain(m *testing.M) {
+	if err := log.InitLogger("error", "json", "", false); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// rule 构造测试规则
+func rule(name, effect string, priority int, resource string, targets ...string) config.PolicyRuleConfig {
+	return config.PolicyRuleConfig{Name: name, Effect: effect, Priority: priority, Resource: resource, Targets: targets}
+}
+
+func newEngine(t *testing.T, defaultEffect string, rules ...config.PolicyRuleConfig) *Engine {
+	t.Helper()
+	engine := NewEngine()
+	if err := engine.Load(rules, defaultEffect); err != nil {
+		t.Fatalf("load rules: %v", err)
+	}
+	engine.SetEnabled(true)
+	return engine
+}
+
+func TestEvaluateOrdering(t *testing.T) {
+	admin := rule("admin-allow", EffectAllow, 100, ResourceMCPTool, "*/calculate")
+	admin.Subjects.Roles = []string{"admin"}
+	engine := newEngine(t, EffectAllow,
+		rule("deny-calculate", EffectDeny, 0, ResourceMCPTool, "*/calculate"),
+		admin,
+		// 同优先级下deny优先，与配置顺序无关
+		rule("allow-search", EffectAllow, 10, ResourceMCPTool, "search/*"),
+		rule("deny-search", EffectDeny, 10, ResourceMCPTool, "search/*"),
+		// 同优先级同效果时按配置顺序
+		rule("first", EffectDeny, 5, ResourceAgent, "gpt"),
+		rule("second", EffectDeny, 5, ResourceAgent, "gpt"),
+	)
+
+	adminIdentity := &auth.Identity{ID: "u1", Roles: []string{"admin"}}
+	userIdentity := &auth.Identity{ID: "u2", Roles: []string{"user"}}
+	tests := []struct {
+		name     string
+		input    Input
+		allowed  bool
+		wantRule string
+	}{
+		{"higher priority allow wins", Input{Identity: adminIdentity, Resource: ResourceMCPTool, Target: "svc/calculate"}, true, "admin-allow"},
+		{"subject mismatch falls through", Input{Identity: userIdentity, Resource: ResourceMCPTool, Target: "svc/calculate"}, false, "deny-calculate"},
+		{"anonymous falls through", Input{Resource: ResourceMCPTool, Target: "svc/calculate"}, false, "deny-calculate"},
+		{"deny first at same priority", Input{Identity: adminIdentity, Resource: ResourceMCPTool, Target: "search/web"}, false, "deny-search"},
+		{"config order within same priority", Input{Resource: ResourceAgent, Target: "gpt"}, false, "first"},
+		{"default effect", Input{Resource: ResourceRoute, Target: "/proxy"}, true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision := engine.Evaluate(tt.input)
+			if decision.Allowed != tt.allowed || decision.Rule != tt.wantRule {
+				t.Errorf("decision = %+v, want allowed=%v rule=%q", decision, tt.allowed, tt.wantRule)
+			}
+		})
+	}
+}
+
+func TestEvaluateModelScope(t *testing.T) {
+	denyModel := rule("deny-gpt4", EffectDeny, 10, ResourceAgent, "openai")
+	denyModel.Models = []string{"gpt-4*"}
+	allowModel := rule("allow-mini", EffectAllow, 5, ResourceAgent, "openai")
+	allowModel.Models = []string{"gpt-4o-mini"}
+	engine := newEngine(t, EffectDeny, denyModel, allowModel)
+
+	tests := []struct {
+		model    string
+		allowed  bool
+		wantRule string
+	}{
+		{"gpt-4o", false, "deny-gpt4"},
+		{"gpt-3.5-turbo", false, ""},
+		// 未指定模型时deny规则匹配，不能通过省略模型绕过限制
+		{"", false, "deny-gpt4"},
+	}
+	for _, tt := range tests {
+		decision := engine.Evaluate(Input{Resource: ResourceAgent, Target: "openai", Model: tt.model})
+		if decision.Allowed != tt.allowed || decision.Rule != tt.wantRule {
+			t.Errorf("model %q: decision = %+v, want allowed=%v rule=%q", tt.model, decision, tt.allowed, tt.wantRule)
+		}
+	}
+
+	// 未指定模型时allow规则不匹配，不能通过省略模型获得授权
+	engine = newEngine(t, EffectDeny, allowModel)
+	if decision := engine.Evaluate(Input{Resource: ResourceAgent, Target: "openai"}); decision.Allowed {
+		t.Errorf("allow rule matched a request without model: %+v", decision)
+	}
+	if decision := engine.Evaluate(Input{Resource: ResourceAgent, Target: "openai", Model: "gpt-4o-mini"}); !decision.Allowed {
+		t.Errorf("allow rule did not match its model: %+v", decision)
+	}
+}
+
+func TestEvaluateDisabled(t *testing.T) {
+	engine := newEngine(t, EffectDeny)
+	if decision := engine.Evaluate(Input{Resource: ResourceAgent, Target: "openai"}); decision.Allowed {
+		t.Fatalf("default deny not applied: %+v", decision)
+	}
+	engine.SetEnabled(false)
+	if decision := engine.Evaluate(Input{Resource: ResourceAgent, Target: "openai"}); !decision.Allowed {
+		t.Errorf("disabled engine denied a request: %+v", decision)
+	}
+}
+
+func TestLoadRejectsInvalidRules(t *testing.T) {
+	engine := newEngine(t, EffectAllow, rule("keep", EffectDeny, 0, ResourceAgent, "openai"))
+
+	invalid := [][]config.PolicyRuleConfig{
+		{rule("bad-effect", "block", 0, ResourceAgent)},
+		{rule("bad-resource", EffectDeny, 0, "database")},
+	}
+	for _, rules := range invalid {
+		if err := engine.Load(rules, EffectAllow); err == nil {
+			t.Errorf("Load(%+v) succeeded", rules)
+		}
+	}
+	if err := engine.Load(nil, "maybe"); err == nil {
+		t.Error("Load accepted an invalid default effect")
+	}
+
+	// 加载失败时保留原有规则
+	rules, _ := engine.Rules()
+	if len(rules) != 1 || rules[0].Name != "keep" {
+		t.Errorf("rules after failed load = %+v", rules)
+	}
+}
+
+func TestWildcardMatch(t *testing.T) {
+	tests := []struct {
+		pattern, value string
+		want           bool
+	}{
+		{"*", "anything/at/all", true},
+		{"svc/*", "svc/tool", true},
+		{"svc/*", "other/tool", false},
+		{"*/calculate", "svc/calculate", true},
+		{"gpt-*-mini", "gpt-4o-mini", true},
+		{"gpt-*-mini", "gpt-4o", false},
+		{"exact", "exact", true},
+		{"exact", "exactly", false},
+	}
+	for _, tt := range tests {
+		if got := wildcardMatch(tt.pattern, tt.value); got != tt.want {
+			t.Errorf("wildcardMatch(%q, %q) = %v, want %v", tt.pattern, tt.value, got, tt.want)
+		}
+	}
+}
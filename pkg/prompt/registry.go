@@ -0,0 +1,318 @@
+package prompt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"kai/kaigate/pkg/config"
+	"kai/kaigate/pkg/log"
+	"kai/kaigate/pkg/service/ai_agent"
+	"kai/kaigate/pkg/storage"
+)
+
+// storePrefix 通过管理接口创建的模板在存储中的键前缀
+const storePrefix = "prompt/"
+
+var (
+	// ErrNotFound 模板或版本不存在
+	ErrNotFound = errors.New("prompt template not found")
+	// ErrExists 模板版本已存在
+	ErrExists = errors.New("prompt template version already exists")
+	// ErrReadOnly 模板来自配置文件，不能通过管理接口修改
+	ErrReadOnly = errors.New("prompt template is defined in config")
+	// ErrInvalid 模板、引用或变量无效
+	ErrInvalid = errors.New("invalid prompt template")
+)
+
+// Info 模板列表中的摘要信息
+type Info struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"` // 最新版本的说明
+	Versions    []int  `json:"versions"`
+	Latest      int    `json:"latest"`
+}
+
+// Registry 提示模板注册表
+// 配置文件中的模板随配置重载整体替换，管理接口创建的模板保存在存储中
+type Registry struct {
+	store     storage.KV
+	templates map[string]map[int]*Template
+	mutex     sync.RWMutex
+	logger    log.Logger
+}
+
+// Option Registry配置选项
+type Option func(*Registry)
+
+// WithLogger 设置日志器
+func WithLogger(logger log.Logger) Option {
+	return func(r *Registry) {
+		r.logger = logger
+	}
+}
+
+// NewRegistry 创建Registry实例并加载存储中的模板
+func NewRegistry(store storage.KV, options ...Option) (*Registry, error) {
+	if store == nil {
+		return nil, errors.New("prompt store cannot be nil")
+	}
+
+	r := &Registry{
+		store:     store,
+		templates: make(map[string]map[int]*Template),
+		logger:    log.GlobalLogger,
+	}
+	for _, option := range options {
+		option(r)
+	}
+
+	keys, err := store.List(storePrefix)
+	if err != nil {
+		return nil, fmt.Errorf("list prompt templates failed: %w", err)
+	}
+	for _, key := range keys {
+		data, err := store.Get(key)
+		if err != nil {
+			r.logger.Warn("Skipping unreadable prompt template", zap.String("key", key), zap.Error(err))
+			continue
+		}
+		var tmpl Template
+		if err := json.Unmarshal(data, &tmpl); err != nil {
+			r.logger.Warn("Skipping unreadable prompt template", zap.String("key", key), zap.Error(err))
+			continue
+		}
+		if err := tmpl.compile(); err != nil {
+			r.logger.Warn("Skipping invalid prompt template", zap.String("key", key), zap.Error(err))
+			continue
+		}
+		tmpl.Source = SourceAPI
+		r.put(&tmpl)
+	}
+	return r, nil
+}
+
+// LoadConfig 使用配置中的模板替换已加载的配置模板
+// 任一模板无效时返回错误并保留原有模板；与管理接口创建的版本冲突的模板跳过
+func (r *Registry) LoadConfig(templates []config.PromptTemplateConfig) error {
+	loaded := make([]*Template, 0, len(templates))
+	for _, cfg := range templates {
+		tmpl := &Template{
+			Name:        cfg.Name,
+			Version:     cfg.Version,
+			Description: cfg.Description,
+			Source:      SourceConfig,
+		}
+		if tmpl.Version == 0 {
+			tmpl.Version = 1
+		}
+		for _, variable := range cfg.Variables {
+			tmpl.Variables = append(tmpl.Variables, Variable{
+				Name:        variable.Name,
+				Required:    variable.Required,
+				Default:     variable.Default,
+				Description: variable.Description,
+			})
+		}
+		for _, message := range cfg.Messages {
+			tmpl.Messages = append(tmpl.Messages, Message{Role: message.Role, Content: message.Content})
+		}
+		if err := tmpl.compile(); err != nil {
+			return err
+		}
+		loaded = append(loaded, tmpl)
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for name, versions := range r.templates {
+		for version, tmpl := range versions {
+			if tmpl.Source == SourceConfig {
+				delete(versions, version)
+			}
+		}
+		if len(versions) == 0 {
+			delete(r.templates, name)
+		}
+	}
+
+	now := time.Now()
+	for _, tmpl := range loaded {
+		if existing := r.lookup(tmpl.Name, tmpl.Version); existing != nil {
+			r.logger.Error("Prompt template version already exists, skipped",
+				zap.String("template", tmpl.Ref()),
+				zap.String("source", existing.Source),
+			)
+			continue
+		}
+		tmpl.CreatedAt = now
+		r.put(tmpl)
+	}
+
+	r.logger.Info("Prompt templates loaded", zap.Int("templates", len(loaded)))
+	return nil
+}
+
+// Create 通过管理接口创建模板版本，版本为0时使用最新版本号加1
+func (r *Registry) Create(tmpl Template) (*Template, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if tmpl.Version == 0 {
+		tmpl.Version = r.latest(tmpl.Name) + 1
+	}
+	tmpl.Source = SourceAPI
+	tmpl.CreatedAt = time.Now()
+	if err := tmpl.compile(); err != nil {
+		return nil, err
+	}
+	if r.lookup(tmpl.Name, tmpl.Version) != nil {
+		return nil, ErrExists
+	}
+
+	data, err := json.Marshal(&tmpl)
+	if err != nil {
+		return nil, fmt.Errorf("encode prompt template failed: %w", err)
+	}
+	if err := r.store.Put(storeKey(tmpl.Name, tmpl.Version), data); err != nil {
+		return nil, fmt.Errorf("save prompt template failed: %w", err)
+	}
+	r.put(&tmpl)
+
+	r.logger.Info("Prompt template created", zap.String("template", tmpl.Ref()))
+	return &tmpl, nil
+}
+
+// Delete 删除通过管理接口创建的模板版本
+func (r *Registry) Delete(name string, version int) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	tmpl := r.lookup(name, version)
+	if tmpl == nil {
+		return ErrNotFound
+	}
+	if tmpl.Source == SourceConfig {
+		return ErrReadOnly
+	}
+	if err := r.store.Delete(storeKey(name, version)); err != nil {
+		return fmt.Errorf("delete prompt template failed: %w", err)
+	}
+
+	delete(r.templates[name], version)
+	if len(r.templates[name]) == 0 {
+		delete(r.templates, name)
+	}
+	r.logger.Info("Prompt template deleted", zap.String("template", tmpl.Ref()))
+	return nil
+}
+
+// Get 获取模板版本，version为0时返回最新版本
+func (r *Registry) Get(name string, version int) (*Template, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	if version == 0 {
+		version = r.latest(name)
+	}
+	tmpl := r.lookup(name, version)
+	if tmpl == nil {
+		return nil, ErrNotFound
+	}
+	return tmpl, nil
+}
+
+// Versions 返回模板的全部版本，按版本号升序排列
+func (r *Registry) Versions(name string) ([]*Template, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	versions, exists := r.templates[name]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	result := make([]*Template, 0, len(versions))
+	for _, tmpl := range versions {
+		result = append(result, tmpl)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Version < result[j].Version })
+	return result, nil
+}
+
+// List 列出全部模板，结果按名称排列
+func (r *Registry) List() []Info {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	infos := make([]Info, 0, len(r.templates))
+	for name, versions := range r.templates {
+		info := Info{Name: name, Latest: r.latest(name)}
+		for version := range versions {
+			info.Versions = append(info.Versions, version)
+		}
+		sort.Ints(info.Versions)
+		info.Description = versions[info.Latest].Description
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+// Render 解析模板引用并渲染消息，返回实际使用的模板版本
+func (r *Registry) Render(ref string, variables map[string]interface{}) (*Template, []ai_agent.Message, error) {
+	name, version, err := ParseRef(ref)
+	if err != nil {
+		return nil, nil, err
+	}
+	tmpl, err := r.Get(name, version)
+	if err != nil {
+		return nil, nil, err
+	}
+	messages, err := tmpl.Render(variables)
+	if err != nil {
+		return nil, nil, err
+	}
+	return tmpl, messages, nil
+}
+
+// Close 清理资源
+func (r *Registry) Close() error {
+	return r.store.Close()
+}
+
+// put 添加模板版本，调用方需持有写锁
+func (r *Registry) put(tmpl *Template) {
+	versions, exists := r.templates[tmpl.Name]
+	if !exists {
+		versions = make(map[int]*Template)
+		r.templates[tmpl.Name] = versions
+	}
+	versions[tmpl.Version] = tmpl
+}
+
+// lookup 查找模板版本，调用方需持有锁
+func (r *Registry) lookup(name string, version int) *Template {
+	return r.templates[name][version]
+}
+
+// latest 返回模板的最新版本号，不存在时返回0，调用方需持有锁
+func (r *Registry) latest(name string) int {
+	latest := 0
+	for version := range r.templates[name] {
+		if version > latest {
+			latest = version
+		}
+	}
+	return latest
+}
+
+// storeKey 返回模板版本在存储中的键
+func storeKey(name string, version int) string {
+	return fmt.Sprintf("%s%s/%d", storePrefix, name, version)
+}
@@ -0,0 +1,168 @@
+// Package prompt 管理带版本的提示模板
+// 聊天请求通过template字段引用模板，网关渲染出系统消息和用户消息后再调用AI Agent
+package prompt
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"kai/kaigate/pkg/service/ai_agent"
+)
+
+// 模板来源
+const (
+	SourceConfig = "config" // 配置文件，只能通过修改配置变更
+	SourceAPI    = "api"    // 管理接口
+)
+
+// namePattern 模板名称只允许字母、数字和常用分隔符
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9_.\-]{1,64}$`)
+
+// Variable 模板变量声明
+type Variable struct {
+	Name        string `json:"name"`
+	Required    bool   `json:"required,omitempty"`    // 是否必须由请求提供
+	Default     string `json:"default,omitempty"`     // 请求未提供时使用的默认值
+	Description string `json:"description,omitempty"` // 变量说明
+}
+
+// Message 模板消息，Content使用text/template语法引用变量，如{{.product}}
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// Template 提示模板的一个版本
+type Template struct {
+	Name        string     `json:"name"`
+	Version     int        `json:"version"`
+	Description string     `json:"description,omitempty"`
+	Variables   []Variable `json:"variables,omitempty"`
+	Messages    []Message  `json:"messages"`
+	Source      string     `json:"source"`
+	CreatedAt   time.Time  `json:"created_at"`
+
+	compiled []*template.Template
+}
+
+// Ref 返回模板的引用形式，如support-bot@v3
+func (t *Template) Ref() string {
+	return fmt.Sprintf("%s@v%d", t.Name, t.Version)
+}
+
+// funcs 模板中可用的函数
+var funcs = template.FuncMap{
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"trim":  strings.TrimSpace,
+	// default 值为空时返回默认值，如{{default "en" .lang}}
+	"default": func(fallback, value interface{}) interface{} {
+		if value == nil || value == "" {
+			return fallback
+		}
+		return value
+	},
+	// join 使用分隔符拼接列表
+	"join": func(sep string, value interface{}) string {
+		items, ok := value.([]interface{})
+		if !ok {
+			return fmt.Sprint(value)
+		}
+		parts := make([]string, len(items))
+		for i, item := range items {
+			parts[i] = fmt.Sprint(item)
+		}
+		return strings.Join(parts, sep)
+	},
+	// json 将值编码为JSON
+	"json": func(value interface{}) (string, error) {
+		data, err := json.Marshal(value)
+		return string(data), err
+	},
+}
+
+// compile 校验模板并解析消息内容
+func (t *Template) compile() error {
+	if !namePattern.MatchString(t.Name) {
+		return fmt.Errorf("%w: invalid template name %q", ErrInvalid, t.Name)
+	}
+	if t.Version < 1 {
+		return fmt.Errorf("%w: template version must be positive", ErrInvalid)
+	}
+	if len(t.Messages) == 0 {
+		return fmt.Errorf("%w: template %s has no messages", ErrInvalid, t.Ref())
+	}
+
+	seen := make(map[string]bool, len(t.Variables))
+	for _, variable := range t.Variables {
+		if variable.Name == "" || seen[variable.Name] {
+			return fmt.Errorf("%w: template %s has an empty or duplicate variable name", ErrInvalid, t.Ref())
+		}
+		seen[variable.Name] = true
+	}
+
+	compiled := make([]*template.Template, len(t.Messages))
+	for i, message := range t.Messages {
+		switch message.Role {
+		case "system", "developer", "user", "assistant":
+		default:
+			return fmt.Errorf("%w: template %s message %d has unsupported role %q", ErrInvalid, t.Ref(), i, message.Role)
+		}
+		tmpl, err := template.New(fmt.Sprintf("%s#%d", t.Ref(), i)).Funcs(funcs).Option("missingkey=error").Parse(message.Content)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalid, err)
+		}
+		compiled[i] = tmpl
+	}
+	t.compiled = compiled
+	return nil
+}
+
+// Render 使用变量渲染模板消息
+// 未提供的变量使用默认值，缺少必填变量或消息引用了未提供的变量时返回ErrInvalid
+func (t *Template) Render(variables map[string]interface{}) ([]ai_agent.Message, error) {
+	data := make(map[string]interface{}, len(t.Variables)+len(variables))
+	for _, variable := range t.Variables {
+		if _, exists := variables[variable.Name]; exists {
+			continue
+		}
+		if variable.Required {
+			return nil, fmt.Errorf("%w: template %s requires variable %q", ErrInvalid, t.Ref(), variable.Name)
+		}
+		data[variable.Name] = variable.Default
+	}
+	for name, value := range variables {
+		data[name] = value
+	}
+
+	messages := make([]ai_agent.Message, len(t.compiled))
+	for i, tmpl := range t.compiled {
+		var content strings.Builder
+		if err := tmpl.Execute(&content, data); err != nil {
+			return nil, fmt.Errorf("%w: render template %s failed: %v", ErrInvalid, t.Ref(), err)
+		}
+		messages[i] = ai_agent.Message{Role: t.Messages[i].Role, Content: content.String()}
+	}
+	return messages, nil
+}
+
+// ParseRef 解析模板引用，支持name、name@v3和name@3，未指定版本时返回0表示最新版本
+func ParseRef(ref string) (string, int, error) {
+	name, version, hasVersion := strings.Cut(strings.TrimSpace(ref), "@")
+	if !namePattern.MatchString(name) {
+		return "", 0, fmt.Errorf("%w: invalid template reference %q", ErrInvalid, ref)
+	}
+	if !hasVersion || version == "latest" {
+		return name, 0, nil
+	}
+	number, err := strconv.Atoi(strings.TrimPrefix(version, "v"))
+	if err != nil || number < 1 {
+		return "", 0, fmt.Errorf("%w: invalid template version %q", ErrInvalid, version)
+	}
+	return name, number, nil
+}
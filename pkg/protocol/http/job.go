@@ -0,0 +1,213 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"kai/kaigate/pkg/auth"
+	"kai/kaigate/pkg/job"
+	"kai/kaigate/pkg/policy"
+	"kai/kaigate/pkg/service/ai_agent"
+	"kai/kaigate/pkg/service/mcp"
+)
+
+// errJobForbidden 任务属于其他调用方
+var errJobForbidden = errors.New("job belongs to another caller")
+
+// registerJobRoutes 注册异步任务接口
+func registerJobRoutes(api *gin.RouterGroup, agentManager ai_agent.AIAgentManager, mcpManager mcp.MCPServiceManager, opts *routeOptions) {
+	jobs := api.Group("/jobs")
+
+	jobs.POST("", createHandleSubmitJob(agentManager, mcpManager, opts))
+
+	// 列出调用方的任务，匿名调用方无法列出任务
+	jobs.GET("", func(c *gin.Context) {
+		owner := sessionOwner(c)
+		if owner == "" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Listing jobs requires authentication"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"jobs": opts.jobs.List(owner)})
+	})
+
+	jobs.GET("/:id", func(c *gin.Context) {
+		j, ok := ownedJob(c, opts.jobs)
+		if !ok {
+			return
+		}
+		c.JSON(http.StatusOK, j)
+	})
+
+	// 取消排队中或执行中的任务
+	jobs.DELETE("/:id", func(c *gin.Context) {
+		j, ok := ownedJob(c, opts.jobs)
+		if !ok {
+			return
+		}
+		j, err := opts.jobs.Cancel(j.ID)
+		if err != nil {
+			c.JSON(jobErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, j)
+	})
+}
+
+// createHandleSubmitJob 创建提交异步任务处理函数
+// 请求体与同步接口一致，另外通过type指定任务类型，权限在提交时检查
+func createHandleSubmitJob(agentManager ai_agent.AIAgentManager, mcpManager mcp.MCPServiceManager, opts *routeOptions) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		logger := requestLogger(c)
+
+		// 解析请求体
+		var request struct {
+			Type        string                 `json:"type" binding:"required"`
+			AgentID     string                 `json:"agent_id"`
+			Messages    []ai_agent.Message     `json:"messages"`
+			Template    string                 `json:"template"`
+			Variables   map[string]interface{} `json:"variables"`
+			Prompt      string                 `json:"prompt"`
+			ServiceID   string                 `json:"service_id"`
+			Command     string                 `json:"command"`
+			Parameters  map[string]interface{} `json:"parameters"`
+			CallbackURL string                 `json:"callback_url"`
+		}
+		if err := c.ShouldBindJSON(&request); err != nil {
+			logger.Error("Invalid job request format", zap.Error(err))
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+			return
+		}
+		if request.CallbackURL != "" && !validCallbackURL(request.CallbackURL) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "callback_url must be an absolute http or https URL"})
+			return
+		}
+
+		identity, _ := auth.GetIdentity(c)
+		sub := job.Submission{Type: request.Type, CallbackURL: request.CallbackURL, Identity: identity}
+
+		switch request.Type {
+		case job.TypeChat, job.TypeCompletion:
+			if request.AgentID == "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "agent_id is required"})
+				return
+			}
+
+			// 检查调用方权限
+			model, _ := request.Parameters["model"].(string)
+			if !checkAgentAccess(c, request.AgentID) || !authorize(c, opts.policyEngine, policy.ResourceAgent, request.AgentID, model) {
+				return
+			}
+			if _, err := agentManager.GetAIAgent(request.AgentID, nil); err != nil {
+				logger.Error("Failed to get AI agent", zap.String("agent_id", request.AgentID), zap.Error(err))
+				c.JSON(http.StatusNotFound, gin.H{"error": "AI agent not found"})
+				return
+			}
+			sub.Target = request.AgentID
+
+			if request.Type == job.TypeChat {
+				if len(request.Messages) == 0 && request.Template == "" {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "messages or template is required"})
+					return
+				}
+				chatReq := ai_agent.ChatRequest{}
+				if err := applyChatParameters(&chatReq, request.Parameters); err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid parameters: " + err.Error()})
+					return
+				}
+				// 提交时渲染提示模板，模板之后的变更不影响已提交的任务
+				messages, status, err := applyTemplate(c, opts, request.Template, request.Variables, request.Messages)
+				if err != nil {
+					c.JSON(status, gin.H{"error": err.Error()})
+					return
+				}
+				chatReq.Messages = messages
+				sub.Request = chatReq
+			} else {
+				if request.Prompt == "" {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "prompt is required"})
+					return
+				}
+				completionReq := ai_agent.CompletionRequest{Prompt: request.Prompt, Model: model}
+				completionReq.Temperature, _ = request.Parameters["temperature"].(float64)
+				if maxTokens, ok := request.Parameters["max_tokens"].(float64); ok {
+					completionReq.MaxTokens = int(maxTokens)
+				}
+				sub.Request = completionReq
+			}
+		case job.TypeMCP:
+			if request.ServiceID == "" || request.Command == "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "service_id and command are required"})
+				return
+			}
+
+			// 检查调用方权限
+			if !checkMCPServiceAccess(c, request.ServiceID) ||
+				!authorize(c, opts.policyEngine, policy.ResourceMCPTool, policy.MCPToolTarget(request.ServiceID, request.Command), "") {
+				return
+			}
+			if _, err := mcpManager.GetMCPService(request.ServiceID, nil); err != nil {
+				logger.Error("Failed to get MCP service", zap.String("service_id", request.ServiceID), zap.Error(err))
+				c.JSON(http.StatusNotFound, gin.H{"error": "MCP service not found"})
+				return
+			}
+			sub.Target = request.ServiceID
+			sub.Request = mcp.MCPServiceRequest{
+				ServiceName: request.ServiceID,
+				ToolName:    request.Command,
+				Params:      request.Parameters,
+			}
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "type must be one of chat, completion, mcp"})
+			return
+		}
+
+		j, err := opts.jobs.Submit(sub)
+		if err != nil {
+			logger.Error("Failed to submit job", zap.String("type", request.Type), zap.Error(err))
+			c.JSON(jobErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.Header("Location", c.Request.URL.Path+"/"+j.ID)
+		c.JSON(http.StatusAccepted, j)
+	}
+}
+
+// ownedJob 获取调用方有权访问的任务，失败时写入错误响应
+func ownedJob(c *gin.Context, manager *job.Manager) (*job.Job, bool) {
+	j, err := manager.Get(c.Param("id"))
+	if err == nil && j.Owner != "" && j.Owner != sessionOwner(c) {
+		err = errJobForbidden
+	}
+	if err != nil {
+		c.JSON(jobErrorStatus(err), gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return j, true
+}
+
+// validCallbackURL 回调地址必须是http或https的绝对地址
+func validCallbackURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// jobErrorStatus 根据任务错误返回HTTP状态码
+func jobErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, job.ErrInvalidJob):
+		return http.StatusBadRequest
+	case errors.Is(err, errJobForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, job.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, job.ErrFinished):
+		return http.StatusConflict
+	case errors.Is(err, job.ErrQueueFull), errors.Is(err, job.ErrClosed):
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
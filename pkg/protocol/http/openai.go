@@ -0,0 +1,627 @@
+package http
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"kai/kaigate/pkg/auth"
+	"kai/kaigate/pkg/config"
+	"kai/kaigate/pkg/guardrail"
+	"kai/kaigate/pkg/log"
+	"kai/kaigate/pkg/policy"
+	"kai/kaigate/pkg/service/ai_agent"
+	"kai/kaigate/pkg/service/orchestrator"
+)
+
+// openAIModel OpenAI模型列表中的模型
+type openAIModel struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Created int64  `json:"created"`
+	OwnedBy string `json:"owned_by"`
+}
+
+// openAIChatChoice OpenAI聊天响应中的候选结果
+type openAIChatChoice struct {
+	Index        int               `json:"index"`
+	Message      *ai_agent.Message `json:"message,omitempty"`
+	Delta        *openAIDelta      `json:"delta,omitempty"`
+	FinishReason *string           `json:"finish_reason"`
+}
+
+// openAIDelta 流式响应中的增量消息
+type openAIDelta struct {
+	Role      string                `json:"role,omitempty"`
+	Content   string                `json:"content,omitempty"`
+	ToolCalls []openAIToolCallDelta `json:"tool_calls,omitempty"`
+}
+
+// openAIToolCallDelta 流式响应中的工具调用增量，index必须返回
+type openAIToolCallDelta struct {
+	Index    int                   `json:"index"`
+	ID       string                `json:"id,omitempty"`
+	Type     string                `json:"type,omitempty"`
+	Function ai_agent.FunctionCall `json:"function"`
+}
+
+// openAIChatResponse OpenAI聊天响应，流式响应时为chat.completion.chunk
+type openAIChatResponse struct {
+	ID      string             `json:"id"`
+	Object  string             `json:"object"`
+	Created int64              `json:"created"`
+	Model   string             `json:"model"`
+	Choices []openAIChatChoice `json:"choices"`
+	Usage   interface{}        `json:"usage,omitempty"`
+}
+
+// openAICompletionChoice OpenAI文本生成响应中的候选结果
+type openAICompletionChoice struct {
+	Index        int     `json:"index"`
+	Text         string  `json:"text"`
+	FinishReason *string `json:"finish_reason"`
+}
+
+// openAICompletionResponse OpenAI文本生成响应
+type openAICompletionResponse struct {
+	ID      string                   `json:"id"`
+	Object  string                   `json:"object"`
+	Created int64                    `json:"created"`
+	Model   string                   `json:"model"`
+	Choices []openAICompletionChoice `json:"choices"`
+	Usage   interface{}              `json:"usage,omitempty"`
+}
+
+// finishReasonStop 正常结束
+var finishReasonStop = "stop"
+
+// registerOpenAIRoutes 注册OpenAI兼容接口
+// 请求中的model映射到AI Agent，使现有的OpenAI SDK可以直接访问网关
+func registerOpenAIRoutes(router *gin.Engine, agentManager ai_agent.AIAgentManager, opts *routeOptions) {
+	v1 := router.Group("/v1")
+	{
+		v1.POST("/chat/completions", createHandleOpenAIChat(agentManager, opts))
+		v1.POST("/completions", createHandleOpenAICompletion(agentManager, opts))
+		v1.POST("/embeddings", createHandleOpenAIEmbedding(agentManager, opts))
+		v1.GET("/models", createHandleOpenAIModels(agentManager))
+	}
+}
+
+// resolveAgent 根据请求的model确定AI Agent和传给Agent的模型名
+// 依次匹配：model_map配置、"agent/model"格式、与Agent同名、默认Agent
+func resolveAgent(agentManager ai_agent.AIAgentManager, model string) (string, string, bool) {
+	openAIConfig := config.GetConfig().OpenAI
+	if agentID, ok := openAIConfig.ModelMap[model]; ok {
+		return agentID, model, true
+	}
+
+	available := make(map[string]bool)
+	for _, name := range agentManager.ListAvailableAgents() {
+		available[name] = true
+	}
+
+	if index := strings.Index(model, "/"); index > 0 && available[model[:index]] {
+		return model[:index], model[index+1:], true
+	}
+	if available[model] {
+		return model, "", true
+	}
+	if openAIConfig.DefaultAgent != "" {
+		return openAIConfig.DefaultAgent, model, true
+	}
+	return "", "", false
+}
+
+// openAIAgent 解析model并完成权限检查，返回AI Agent和传给Agent的模型名
+func openAIAgent(c *gin.Context, agentManager ai_agent.AIAgentManager, opts *routeOptions, model string) (ai_agent.AIAgent, string, bool) {
+	if model == "" {
+		openAIError(c, http.StatusBadRequest, "you must provide a model parameter", "invalid_request_error", "")
+		return nil, "", false
+	}
+
+	agentID, agentModel, ok := resolveAgent(agentManager, model)
+	if !ok {
+		openAIError(c, http.StatusNotFound, fmt.Sprintf("The model '%s' does not exist", model), "invalid_request_error", "model_not_found")
+		return nil, "", false
+	}
+
+	// 检查调用方权限
+	if !checkAgentAccess(c, agentID) || !authorize(c, opts.policyEngine, policy.ResourceAgent, agentID, agentModel) {
+		return nil, "", false
+	}
+
+	agent, err := agentManager.GetAIAgent(agentID, nil)
+	if err != nil {
+		requestLogger(c).Error("Failed to get AI agent", zap.String("agent_id", agentID), zap.Error(err))
+		openAIError(c, http.StatusNotFound, fmt.Sprintf("The model '%s' does not exist", model), "invalid_request_error", "model_not_found")
+		return nil, "", false
+	}
+	return wrapAgent(opts, agentID, agent), agentModel, true
+}
+
+// createHandleOpenAIChat 创建OpenAI兼容的聊天处理函数
+func createHandleOpenAIChat(agentManager ai_agent.AIAgentManager, opts *routeOptions) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		logger := requestLogger(c)
+
+		// mcp_services和max_tool_steps是网关扩展字段，用于启用MCP工具调用编排；session_id用于使用网关保存的对话历史
+		// template和variables用于引用网关保存的提示模板
+		var request struct {
+			ai_agent.ChatRequest
+			MCPServices  []string               `json:"mcp_services"`
+			MaxToolSteps int                    `json:"max_tool_steps"`
+			SessionID    string                 `json:"session_id"`
+			Template     string                 `json:"template"`
+			Variables    map[string]interface{} `json:"variables"`
+		}
+		if err := c.ShouldBindJSON(&request); err != nil {
+			openAIError(c, http.StatusBadRequest, "Invalid request format: "+err.Error(), "invalid_request_error", "")
+			return
+		}
+		if len(request.Messages) == 0 && request.Template == "" {
+			openAIError(c, http.StatusBadRequest, "messages must not be empty", "invalid_request_error", "")
+			return
+		}
+
+		model := request.Model
+		agent, agentModel, ok := openAIAgent(c, agentManager, opts, model)
+		if !ok {
+			return
+		}
+		request.Model = agentModel
+
+		messages, status, err := applyTemplate(c, opts, request.Template, request.Variables, request.Messages)
+		if err != nil {
+			openAIError(c, status, err.Error(), "invalid_request_error", "")
+			return
+		}
+		request.Messages = messages
+
+		if request.SessionID != "" {
+			if len(request.MCPServices) > 0 {
+				openAIError(c, http.StatusBadRequest, "session_id cannot be combined with mcp_services", "invalid_request_error", "")
+				return
+			}
+			agentID, _, _ := resolveAgent(agentManager, model)
+			sessionAgent, status, err := bindSession(c, opts, agent, agentID, request.SessionID)
+			if err != nil {
+				openAIError(c, status, err.Error(), "invalid_request_error", "")
+				return
+			}
+			agent = sessionAgent
+		}
+
+		if len(request.MCPServices) > 0 {
+			runOrchestration(c, logger, agent, opts, orchestrator.Request{
+				Chat:     request.ChatRequest,
+				Services: request.MCPServices,
+				MaxSteps: request.MaxToolSteps,
+			}, model)
+			return
+		}
+
+		if request.Stream {
+			streamChat(c, logger, agent, request.ChatRequest, model)
+			return
+		}
+
+		response, err := agent.Chat(c.Request.Context(), request.ChatRequest)
+		if err != nil {
+			logger.Error("AI chat failed", zap.String("agent", agent.Name()), zap.Error(err))
+			openAICallError(c, "Chat failed", err)
+			return
+		}
+
+		c.JSON(http.StatusOK, chatCompletion(response, model))
+	}
+}
+
+// chatCompletion 将聊天响应转换为OpenAI格式
+func chatCompletion(response *ai_agent.ChatResponse, model string) openAIChatResponse {
+	result := openAIChatResponse{
+		ID:      defaultString(response.ID, newResponseID("chatcmpl-")),
+		Object:  "chat.completion",
+		Created: defaultCreated(response.Created),
+		Model:   model,
+		Choices: make([]openAIChatChoice, 0, len(response.Choices)),
+		Usage:   response.Usage,
+	}
+	for _, choice := range response.Choices {
+		message := choice.Message
+		finishReason := defaultString(choice.FinishReason, finishReasonStop)
+		result.Choices = append(result.Choices, openAIChatChoice{
+			Index:        choice.Index,
+			Message:      &message,
+			FinishReason: &finishReason,
+		})
+	}
+	return result
+}
+
+// streamChat 以Server-Sent Events转发ChatStream的响应，以[DONE]结束
+func streamChat(c *gin.Context, logger log.Logger, agent ai_agent.AIAgent, request ai_agent.ChatRequest, model string) {
+	respChan, errChan := agent.ChatStream(c.Request.Context(), request)
+
+	id := newResponseID("chatcmpl-")
+	created := time.Now().Unix()
+	writeSSEHeaders(c)
+
+	// Agent未返回结束原因时补充一个结束数据块
+	finished := false
+	for response := range respChan {
+		for _, choice := range response.Choices {
+			chunk := chatChunkChoice(choice)
+			if chunk.FinishReason != nil {
+				finished = true
+			}
+			writeSSEData(c, openAIChatResponse{
+				ID:      id,
+				Object:  "chat.completion.chunk",
+				Created: created,
+				Model:   model,
+				Choices: []openAIChatChoice{chunk},
+			})
+		}
+	}
+
+	if err := <-errChan; err != nil {
+		logger.Error("AI chat stream failed", zap.String("agent", agent.Name()), zap.Error(err))
+		writeSSEError(c, err)
+	} else if !finished {
+		writeSSEData(c, openAIChatResponse{
+			ID:      id,
+			Object:  "chat.completion.chunk",
+			Created: created,
+			Model:   model,
+			Choices: []openAIChatChoice{{Index: 0, Delta: &openAIDelta{}, FinishReason: &finishReasonStop}},
+		})
+	}
+	writeSSEDone(c)
+}
+
+// chatChunkChoice 将流式数据块中的候选结果转换为OpenAI格式的增量
+func chatChunkChoice(choice ai_agent.ChatChoice) openAIChatChoice {
+	delta := &openAIDelta{Role: choice.Message.Role, Content: choice.Message.Content}
+	for _, call := range choice.Message.ToolCalls {
+		delta.ToolCalls = append(delta.ToolCalls, openAIToolCallDelta{
+			Index:    call.Index,
+			ID:       call.ID,
+			Type:     call.Type,
+			Function: call.Function,
+		})
+	}
+
+	chunk := openAIChatChoice{Index: choice.Index, Delta: delta}
+	if choice.FinishReason != "" {
+		finishReason := choice.FinishReason
+		chunk.FinishReason = &finishReason
+	}
+	return chunk
+}
+
+// createHandleOpenAICompletion 创建OpenAI兼容的文本生成处理函数
+func createHandleOpenAICompletion(agentManager ai_agent.AIAgentManager, opts *routeOptions) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		logger := requestLogger(c)
+
+		var request struct {
+			Model       string          `json:"model"`
+			Prompt      json.RawMessage `json:"prompt"`
+			Temperature float64         `json:"temperature"`
+			MaxTokens   int             `json:"max_tokens"`
+			Stream      bool            `json:"stream"`
+		}
+		if err := c.ShouldBindJSON(&request); err != nil {
+			openAIError(c, http.StatusBadRequest, "Invalid request format: "+err.Error(), "invalid_request_error", "")
+			return
+		}
+
+		// prompt可以是字符串或字符串数组，数组时只支持单个提示
+		prompts, err := parseStringOrArray(request.Prompt)
+		if err != nil || len(prompts) != 1 {
+			openAIError(c, http.StatusBadRequest, "prompt must be a string or an array with exactly one string", "invalid_request_error", "")
+			return
+		}
+
+		agent, agentModel, ok := openAIAgent(c, agentManager, opts, request.Model)
+		if !ok {
+			return
+		}
+
+		completionReq := ai_agent.CompletionRequest{
+			Model:       agentModel,
+			Prompt:      prompts[0],
+			Temperature: request.Temperature,
+			MaxTokens:   request.MaxTokens,
+			Stream:      request.Stream,
+		}
+
+		if request.Stream {
+			streamCompletion(c, logger, agent, completionReq, request.Model)
+			return
+		}
+
+		response, err := agent.Completion(c.Request.Context(), completionReq)
+		if err != nil {
+			logger.Error("AI completion failed", zap.String("agent", agent.Name()), zap.Error(err))
+			openAICallError(c, "Completion failed", err)
+			return
+		}
+
+		result := openAICompletionResponse{
+			ID:      defaultString(response.ID, newResponseID("cmpl-")),
+			Object:  "text_completion",
+			Created: defaultCreated(response.Created),
+			Model:   request.Model,
+			Choices: make([]openAICompletionChoice, 0, len(response.Choices)),
+			Usage:   response.Usage,
+		}
+		for _, choice := range response.Choices {
+			result.Choices = append(result.Choices, openAICompletionChoice{
+				Index:        choice.Index,
+				Text:         choice.Text,
+				FinishReason: &finishReasonStop,
+			})
+		}
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// streamCompletion 以Server-Sent Events转发CompletionStream的响应，以[DONE]结束
+func streamCompletion(c *gin.Context, logger log.Logger, agent ai_agent.AIAgent, request ai_agent.CompletionRequest, model string) {
+	respChan, errChan := agent.CompletionStream(c.Request.Context(), request)
+
+	id := newResponseID("cmpl-")
+	created := time.Now().Unix()
+	writeSSEHeaders(c)
+
+	for response := range respChan {
+		for _, choice := range response.Choices {
+			writeSSEData(c, openAICompletionResponse{
+				ID:      id,
+				Object:  "text_completion",
+				Created: created,
+				Model:   model,
+				Choices: []openAICompletionChoice{{Index: choice.Index, Text: choice.Text}},
+			})
+		}
+	}
+
+	if err := <-errChan; err != nil {
+		logger.Error("AI completion stream failed", zap.String("agent", agent.Name()), zap.Error(err))
+		writeSSEError(c, err)
+	} else {
+		writeSSEData(c, openAICompletionResponse{
+			ID:      id,
+			Object:  "text_completion",
+			Created: created,
+			Model:   model,
+			Choices: []openAICompletionChoice{{Index: 0, FinishReason: &finishReasonStop}},
+		})
+	}
+	writeSSEDone(c)
+}
+
+// createHandleOpenAIEmbedding 创建OpenAI兼容的嵌入向量处理函数
+func createHandleOpenAIEmbedding(agentManager ai_agent.AIAgentManager, opts *routeOptions) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		logger := requestLogger(c)
+
+		var request struct {
+			Model string          `json:"model"`
+			Input json.RawMessage `json:"input"`
+		}
+		if err := c.ShouldBindJSON(&request); err != nil {
+			openAIError(c, http.StatusBadRequest, "Invalid request format: "+err.Error(), "invalid_request_error", "")
+			return
+		}
+
+		input, err := parseStringOrArray(request.Input)
+		if err != nil || len(input) == 0 {
+			openAIError(c, http.StatusBadRequest, "input must be a string or an array of strings", "invalid_request_error", "")
+			return
+		}
+
+		agent, agentModel, ok := openAIAgent(c, agentManager, opts, request.Model)
+		if !ok {
+			return
+		}
+
+		response, err := agent.Embedding(c.Request.Context(), ai_agent.EmbeddingRequest{
+			Model: agentModel,
+			Input: input,
+		})
+		if err != nil {
+			logger.Error("AI embedding failed", zap.String("agent", agent.Name()), zap.Error(err))
+			openAICallError(c, "Embedding failed", err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"object": "list",
+			"data":   response.Data,
+			"model":  request.Model,
+			"usage":  response.Usage,
+		})
+	}
+}
+
+// createHandleOpenAIModels 创建OpenAI兼容的模型列表处理函数
+// 每个AI Agent本身作为一个模型，Agent提供的模型以"agent/model"形式列出
+func createHandleOpenAIModels(agentManager ai_agent.AIAgentManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		logger := requestLogger(c)
+		created := time.Now().Unix()
+		models := []openAIModel{}
+		seen := make(map[string]bool)
+
+		addModel := func(id, ownedBy string) {
+			if seen[id] {
+				return
+			}
+			seen[id] = true
+			models = append(models, openAIModel{ID: id, Object: "model", Created: created, OwnedBy: ownedBy})
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+		defer cancel()
+
+		for _, agentID := range agentManager.ListAvailableAgents() {
+			if !agentVisible(c, agentID) {
+				continue
+			}
+			addModel(agentID, "kaigate")
+
+			agent, err := agentManager.GetAIAgent(agentID, nil)
+			if err != nil {
+				continue
+			}
+			agentModels, err := agent.ListModels(ctx)
+			if err != nil {
+				logger.Warn("Failed to list models", zap.String("agent", agentID), zap.Error(err))
+				continue
+			}
+			for _, model := range agentModels {
+				addModel(agentID+"/"+model, agentID)
+			}
+		}
+
+		for model, agentID := range config.GetConfig().OpenAI.ModelMap {
+			if agentVisible(c, agentID) {
+				addModel(model, agentID)
+			}
+		}
+
+		c.JSON(http.StatusOK, gin.H{"object": "list", "data": models})
+	}
+}
+
+// agentVisible 检查调用方是否可以访问AI Agent，不写入响应
+func agentVisible(c *gin.Context, agentID string) bool {
+	identity, ok := auth.GetIdentity(c)
+	return !ok || identity.AllowsAgent(agentID)
+}
+
+// parseStringOrArray 解析字符串或字符串数组
+func parseStringOrArray(raw json.RawMessage) ([]string, error) {
+	if len(raw) == 0 {
+		return nil, errors.New("value is empty")
+	}
+
+	var single string
+	if err := json.Unmarshal(raw, &single); err == nil {
+		return []string{single}, nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(raw, &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// openAICallError 返回AI Agent调用失败的OpenAI格式错误，请求参数无效时返回400
+func openAICallError(c *gin.Context, message string, err error) {
+	if errors.Is(err, guardrail.ErrBlocked) {
+		openAIError(c, http.StatusBadRequest, err.Error(), "invalid_request_error", "content_filter")
+		return
+	}
+	if isInvalidRequest(err) {
+		openAIError(c, http.StatusBadRequest, err.Error(), "invalid_request_error", "")
+		return
+	}
+	openAIError(c, http.StatusBadGateway, message+": "+err.Error(), "api_error", "")
+}
+
+// openAIError 返回OpenAI格式的错误响应
+func openAIError(c *gin.Context, status int, message, errType, code string) {
+	body := gin.H{"message": message, "type": errType}
+	if code != "" {
+		body["code"] = code
+	}
+	c.AbortWithStatusJSON(status, gin.H{"error": body})
+}
+
+// writeSSEHeaders 写入Server-Sent Events响应头
+func writeSSEHeaders(c *gin.Context) {
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+}
+
+// writeSSEData 写入一个data事件并立即刷新
+func writeSSEData(c *gin.Context, data interface{}) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(c.Writer, "data: %s\n\n", payload)
+	c.Writer.Flush()
+}
+
+// writeSSEEvent 写入一个命名事件，OpenAI SDK会忽略不认识的事件
+func writeSSEEvent(c *gin.Context, event string, data interface{}) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", event, payload)
+	c.Writer.Flush()
+}
+
+// writeSSEError 写入OpenAI格式的错误事件
+func writeSSEError(c *gin.Context, err error) {
+	writeSSEData(c, gin.H{"error": gin.H{"message": err.Error(), "type": "api_error"}})
+}
+
+// writeSSEDone 写入流结束标记
+func writeSSEDone(c *gin.Context) {
+	fmt.Fprint(c.Writer, "data: [DONE]\n\n")
+	c.Writer.Flush()
+}
+
+// requestLogger 从上下文获取日志器
+func requestLogger(c *gin.Context) log.Logger {
+	if logger, exists := c.Get("logger"); exists {
+		if l, ok := logger.(log.Logger); ok {
+			return l
+		}
+	}
+	return log.GlobalLogger
+}
+
+// newResponseID 生成响应ID
+func newResponseID(prefix string) string {
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		return prefix + fmt.Sprint(time.Now().UnixNano())
+	}
+	return prefix + hex.EncodeToString(buf)
+}
+
+// defaultString 值为空时返回默认值
+func defaultString(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+// defaultCreated 创建时间为空时使用当前时间
+func defaultCreated(created int64) int64 {
+	if created == 0 {
+		return time.Now().Unix()
+	}
+	return created
+}
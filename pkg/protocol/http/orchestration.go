@@ -0,0 +1,104 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"kai/kaigate/pkg/auth"
+	"kai/kaigate/pkg/log"
+	"kai/kaigate/pkg/policy"
+	"kai/kaigate/pkg/service/ai_agent"
+	"kai/kaigate/pkg/service/orchestrator"
+)
+
+// runOrchestration 启用MCP工具执行聊天请求
+// 流式请求中文本增量以普通数据块返回，工具调用过程以tool_call和tool_result命名事件返回
+func runOrchestration(c *gin.Context, logger log.Logger, agent ai_agent.AIAgent, opts *routeOptions, req orchestrator.Request, model string) {
+	if opts.orchestrator == nil {
+		openAIError(c, http.StatusBadRequest, "MCP tool orchestration is not enabled", "invalid_request_error", "")
+		return
+	}
+	for _, service := range req.Services {
+		if !checkMCPServiceAccess(c, service) {
+			return
+		}
+	}
+
+	// 工具调用由模型决定，需要逐个检查授权策略
+	identity, _ := auth.GetIdentity(c)
+	req.Authorize = func(service, tool string) error {
+		if opts.policyEngine == nil {
+			return nil
+		}
+		decision := opts.policyEngine.Evaluate(policy.Input{
+			Identity: identity,
+			Resource: policy.ResourceMCPTool,
+			Target:   policy.MCPToolTarget(service, tool),
+		})
+		if !decision.Allowed {
+			return errors.New("forbidden by policy")
+		}
+		return nil
+	}
+
+	if !req.Chat.Stream {
+		response, err := opts.orchestrator.Run(c.Request.Context(), agent, req)
+		if err != nil {
+			logger.Error("AI tool orchestration failed", zap.String("agent", agent.Name()), zap.Error(err))
+			openAICallError(c, "Chat failed", err)
+			return
+		}
+		c.JSON(http.StatusOK, chatCompletion(response, model))
+		return
+	}
+
+	id := newResponseID("chatcmpl-")
+	created := time.Now().Unix()
+	chunk := func(choice openAIChatChoice) openAIChatResponse {
+		return openAIChatResponse{
+			ID:      id,
+			Object:  "chat.completion.chunk",
+			Created: created,
+			Model:   model,
+			Choices: []openAIChatChoice{choice},
+		}
+	}
+
+	writeSSEHeaders(c)
+	req.OnEvent = func(event orchestrator.Event) {
+		if event.Type != orchestrator.EventDelta {
+			writeSSEEvent(c, event.Type, event)
+			return
+		}
+		for _, choice := range event.Chunk.Choices {
+			writeSSEData(c, chunk(chatChunkChoice(choice)))
+		}
+	}
+
+	response, err := opts.orchestrator.Run(c.Request.Context(), agent, req)
+	if err != nil {
+		logger.Error("AI tool orchestration failed", zap.String("agent", agent.Name()), zap.Error(err))
+		writeSSEError(c, err)
+		writeSSEDone(c)
+		return
+	}
+
+	// 文本已经以增量返回，结束数据块只包含调用方自定义工具的调用和结束原因
+	for _, choice := range response.Choices {
+		final := chatChunkChoice(ai_agent.ChatChoice{
+			Index:        choice.Index,
+			Message:      ai_agent.Message{ToolCalls: choice.Message.ToolCalls},
+			FinishReason: defaultString(choice.FinishReason, finishReasonStop),
+		})
+		result := chunk(final)
+		if response.Usage.TotalTokens > 0 {
+			result.Usage = response.Usage
+		}
+		writeSSEData(c, result)
+	}
+	writeSSEDone(c)
+}
@@ -0,0 +1,50 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"kai/kaigate/pkg/prompt"
+	"kai/kaigate/pkg/service/ai_agent"
+)
+
+// TemplateHeader 返回本次请求使用的提示模板版本的响应头，如support-bot@v3
+const TemplateHeader = "X-Kaigate-Template"
+
+// applyTemplate 渲染请求引用的提示模板，渲染出的消息放在请求消息之前；ref为空时返回原消息
+// 失败时返回对应的HTTP状态码和错误
+func applyTemplate(c *gin.Context, opts *routeOptions, ref string, variables map[string]interface{}, messages []ai_agent.Message) ([]ai_agent.Message, int, error) {
+	if ref == "" {
+		return messages, 0, nil
+	}
+	if opts.prompts == nil {
+		return nil, http.StatusBadRequest, errors.New("prompt templates are not available")
+	}
+
+	tmpl, rendered, err := opts.prompts.Render(ref, variables)
+	if err != nil {
+		return nil, promptErrorStatus(err), err
+	}
+
+	requestLogger(c).Info("Rendered prompt template",
+		zap.String("template", tmpl.Name),
+		zap.Int("version", tmpl.Version),
+		zap.String("source", tmpl.Source),
+	)
+	c.Header(TemplateHeader, tmpl.Ref())
+	return append(rendered, messages...), 0, nil
+}
+
+// promptErrorStatus 根据提示模板错误返回HTTP状态码
+func promptErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, prompt.ErrInvalid):
+		return http.StatusBadRequest
+	case errors.Is(err, prompt.ErrNotFound):
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
@@ -1,6 +1,8 @@
 package http
 
 import (
+	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
@@ -11,21 +13,141 @@ import (
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 
+	"kai/kaigate/pkg/auth"
+	"kai/kaigate/pkg/cache"
 	"kai/kaigate/pkg/config"
+	"kai/kaigate/pkg/guardrail"
+	"kai/kaigate/pkg/job"
 	"kai/kaigate/pkg/log"
+	"kai/kaigate/pkg/policy"
+	"kai/kaigate/pkg/prompt"
+	gw_router "kai/kaigate/pkg/router"
 	"kai/kaigate/pkg/service/ai_agent"
 	"kai/kaigate/pkg/service/mcp"
+	"kai/kaigate/pkg/service/orchestrator"
+	"kai/kaigate/pkg/session"
 )
 
+// RouteOption HTTP路由选项
+type RouteOption func(*routeOptions)
+
+// routeOptions HTTP路由的可选组件
+type routeOptions struct {
+	authMiddleware gin.HandlerFunc
+	rateLimiter    *gw_router.RateLimitManager
+	policyEngine   *policy.Engine
+	transports     *gw_router.TransportManager
+	orchestrator   *orchestrator.Orchestrator
+	cache          *cache.Cache
+	batcher        *ai_agent.EmbeddingBatcher
+	sessions       *session.Manager
+	prompts        *prompt.Registry
+	jobs           *job.Manager
+}
+
+// newRouteOptions 应用路由选项
+func newRouteOptions(options []RouteOption) *routeOptions {
+	opts := &routeOptions{}
+	for _, option := range options {
+		option(opts)
+	}
+	return opts
+}
+
+// WithAuthMiddleware 设置认证中间件
+func WithAuthMiddleware(middleware gin.HandlerFunc) RouteOption {
+	return func(o *routeOptions) {
+		o.authMiddleware = middleware
+	}
+}
+
+// WithRateLimiter 设置按调用方限流的限流管理器
+func WithRateLimiter(manager *gw_router.RateLimitManager) RouteOption {
+	return func(o *routeOptions) {
+		o.rateLimiter = manager
+	}
+}
+
+// WithPolicyEngine 设置授权策略引擎
+func WithPolicyEngine(engine *policy.Engine) RouteOption {
+	return func(o *routeOptions) {
+		o.policyEngine = engine
+	}
+}
+
+// WithOrchestrator 设置工具调用编排器，未设置时聊天请求不能启用MCP工具
+func WithOrchestrator(orch *orchestrator.Orchestrator) RouteOption {
+	return func(o *routeOptions) {
+		o.orchestrator = orch
+	}
+}
+
+// WithCache 设置AI响应缓存
+func WithCache(c *cache.Cache) RouteOption {
+	return func(o *routeOptions) {
+		o.cache = c
+	}
+}
+
+// WithEmbeddingBatcher 设置嵌入向量请求合并器
+func WithEmbeddingBatcher(batcher *ai_agent.EmbeddingBatcher) RouteOption {
+	return func(o *routeOptions) {
+		o.batcher = batcher
+	}
+}
+
+// WithSessionManager 设置对话会话管理器，未设置时聊天请求不能使用session_id
+func WithSessionManager(manager *session.Manager) RouteOption {
+	return func(o *routeOptions) {
+		o.sessions = manager
+	}
+}
+
+// WithPromptRegistry 设置提示模板注册表，未设置时聊天请求不能使用template
+func WithPromptRegistry(registry *prompt.Registry) RouteOption {
+	return func(o *routeOptions) {
+		o.prompts = registry
+	}
+}
+
+// WithJobManager 设置异步任务管理器，未设置时不注册/api/v1/jobs接口
+func WithJobManager(manager *job.Manager) RouteOption {
+	return func(o *routeOptions) {
+		o.jobs = manager
+	}
+}
+
+// WithTransportManager 设置代理路由使用的上游连接池管理器
+func WithTransportManager(manager *gw_router.TransportManager) RouteOption {
+	return func(o *routeOptions) {
+		o.transports = manager
+	}
+}
+
 // RegisterRoutes 注册HTTP路由
-func RegisterRoutes(router *gin.Engine, logger log.Logger, agentManager ai_agent.AIAgentManager, mcpManager mcp.MCPServiceManager, onRouteRegistered func(string)) {
+func RegisterRoutes(router *gin.Engine, logger log.Logger, agentManager ai_agent.AIAgentManager, mcpManager mcp.MCPServiceManager, onRouteRegistered func(string), options ...RouteOption) {
+	// 应用路由选项
+	opts := newRouteOptions(options)
+
 	// 添加全局中间件
 	router.Use(loggerMiddleware(logger))
 	router.Use(recoveryMiddleware())
 	router.Use(corsMiddleware())
+	router.Use(servedByMiddleware())
+	if opts.cache != nil {
+		router.Use(cacheMiddleware())
+	}
+
+	// 认证和限流中间件需要在代理路由注册之前添加，才能作用于代理路由
+	if opts.authMiddleware != nil {
+		router.Use(opts.authMiddleware)
+	}
+	if opts.rateLimiter != nil {
+		router.Use(rateLimitMiddleware(opts.rateLimiter))
+	}
 
 	// 从配置中动态注册代理路由
-	registerProxyRoutesFromConfig(router, logger, onRouteRegistered)
+	registerProxyRoutesFromConfig(router, logger, onRouteRegistered, options...)
 
 	// API路由组
 	api := router.Group("/api/v1")
@@ -37,20 +159,35 @@ func RegisterRoutes(router *gin.Engine, logger log.Logger, agentManager ai_agent
 		// AI Agent接口
 		aia := api.Group("/ai-agent")
 		{
-			aia.POST("/chat", createHandleAIChat(agentManager))
-			aia.POST("/completion", createHandleAICompletion(agentManager))
-			aia.POST("/embedding", createHandleAIEmbedding(agentManager))
+			aia.POST("/chat", createHandleAIChat(agentManager, opts))
+			aia.POST("/completion", createHandleAICompletion(agentManager, opts))
+			aia.POST("/embedding", createHandleAIEmbedding(agentManager, opts))
 			aia.GET("/models", createHandleListModels(agentManager))
 		}
 
+		// 会话接口
+		if opts.sessions != nil {
+			registerSessionRoutes(api, opts.sessions)
+		}
+
+		// 异步任务接口
+		if opts.jobs != nil {
+			registerJobRoutes(api, agentManager, mcpManager, opts)
+		}
+
 		// MCP服务接口
 		mcp := api.Group("/mcp")
 		{
-			mcp.POST("/command", createHandleMCPCommand(mcpManager))
+			mcp.POST("/command", createHandleMCPCommand(mcpManager, opts))
 			mcp.GET("/services", createHandleListMCPServices(mcpManager))
 		}
 	}
 
+	// OpenAI兼容接口
+	if config.GlobalConfig.OpenAI.Enable {
+		registerOpenAIRoutes(router, agentManager, opts)
+	}
+
 	// 404处理
 	router.NoRoute(func(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
@@ -77,10 +214,17 @@ func loggerMiddleware(logger log.Logger) gin.HandlerFunc {
 		statusCode := c.Writer.Status()
 
 		// 记录访问日志
-		logger.Access(path, method, statusCode, latency, remoteAddr,
+		fields := []zap.Field{
 			zap.String("user_agent", c.Request.UserAgent()),
 			zap.Int("content_length", c.Writer.Size()),
-		)
+		}
+		if identity, ok := auth.GetIdentity(c); ok {
+			fields = append(fields, zap.String("caller", identity.ID))
+		}
+		if servedBy, ok := gw_router.ServedByFromContext(c.Request.Context()); ok {
+			fields = append(fields, zap.String("served_by", servedBy.String()))
+		}
+		logger.Access(path, method, statusCode, latency, remoteAddr, fields...)
 
 		// 记录错误日志
 		if len(c.Errors) > 0 {
@@ -129,8 +273,8 @@ func corsMiddleware() gin.HandlerFunc {
 		// 设置CORS头
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-		c.Writer.Header().Set("Access-Control-Expose-Headers", "Content-Length")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "Content-Length, "+gw_router.ServedByHeader+", "+cache.StatusHeader+", "+SessionHeader+", "+TemplateHeader)
 
 		// 处理OPTIONS请求
 		if c.Request.Method == "OPTIONS" {
@@ -142,6 +286,162 @@ func corsMiddleware() gin.HandlerFunc {
 	}
 }
 
+// servedByMiddleware 记录经过模型路由的请求实际使用的目标
+// 目标在调用AI Agent时才确定，因此在写出响应头时再添加X-Kaigate-Served-By，流式响应在首个数据块写出前即已确定
+func servedByMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, servedBy := gw_router.WithServedBy(c.Request.Context())
+		c.Request = c.Request.WithContext(ctx)
+		c.Writer = &headerWriter{ResponseWriter: c.Writer, setHeaders: func(header http.Header) {
+			if servedBy.Agent != "" {
+				header.Set(gw_router.ServedByHeader, servedBy.String())
+			}
+		}}
+		c.Next()
+	}
+}
+
+// cacheMiddleware 解析请求的缓存控制，并在响应头X-Kaigate-Cache中返回缓存结果
+func cacheMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		control := cache.ParseControl(c.GetHeader("Cache-Control"), c.GetHeader("Pragma"))
+		ctx, result := cache.WithControl(c.Request.Context(), control)
+		c.Request = c.Request.WithContext(ctx)
+		c.Writer = &headerWriter{ResponseWriter: c.Writer, setHeaders: func(header http.Header) {
+			if result.Status != "" {
+				header.Set(cache.StatusHeader, result.Status)
+			}
+		}}
+		c.Next()
+	}
+}
+
+// headerWriter 在写出响应头前调用setHeaders，用于添加处理过程中才确定的响应头
+type headerWriter struct {
+	gin.ResponseWriter
+	setHeaders func(http.Header)
+}
+
+// beforeWrite 响应头尚未写出时添加响应头
+func (w *headerWriter) beforeWrite() {
+	if !w.Written() {
+		w.setHeaders(w.Header())
+	}
+}
+
+// WriteHeaderNow 写出响应头
+func (w *headerWriter) WriteHeaderNow() {
+	w.beforeWrite()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+// Write 写出响应体
+func (w *headerWriter) Write(data []byte) (int, error) {
+	w.beforeWrite()
+	return w.ResponseWriter.Write(data)
+}
+
+// WriteString 写出字符串响应体
+func (w *headerWriter) WriteString(s string) (int, error) {
+	w.beforeWrite()
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Flush 刷新响应
+func (w *headerWriter) Flush() {
+	w.beforeWrite()
+	w.ResponseWriter.Flush()
+}
+
+// rateLimitMiddleware 按调用方身份限流的中间件
+// 已认证调用方按身份限流，匿名调用方按客户端IP限流
+func rateLimitMiddleware(manager *gw_router.RateLimitManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		identity, _ := auth.GetIdentity(c)
+		if !manager.LimiterFor(identity, c.ClientIP()).Allow() {
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "Rate limit exceeded"})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// wrapAgent 为AI Agent添加嵌入向量请求合并和缓存，缓存在外层，命中缓存的请求不会进入合并队列
+func wrapAgent(opts *routeOptions, agentID string, agent ai_agent.AIAgent) ai_agent.AIAgent {
+	if opts.batcher != nil {
+		agent = opts.batcher.Wrap(agentID, agent)
+	}
+	if opts.cache != nil {
+		agent = opts.cache.Wrap(agentID, agent)
+	}
+	return agent
+}
+
+// checkAgentAccess 检查调用方是否有权调用指定的AI Agent
+func checkAgentAccess(c *gin.Context, agentID string) bool {
+	if identity, ok := auth.GetIdentity(c); ok && !identity.AllowsAgent(agentID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access to AI agent denied"})
+		return false
+	}
+	return true
+}
+
+// checkMCPServiceAccess 检查调用方是否有权调用指定的MCP服务
+func checkMCPServiceAccess(c *gin.Context, serviceID string) bool {
+	if identity, ok := auth.GetIdentity(c); ok && !identity.AllowsMCPService(serviceID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access to MCP service denied"})
+		return false
+	}
+	return true
+}
+
+// authorize 使用授权策略评估调用，未配置策略引擎时直接放行
+func authorize(c *gin.Context, engine *policy.Engine, resource, target, model string) bool {
+	if engine == nil {
+		return true
+	}
+
+	identity, _ := auth.GetIdentity(c)
+	decision := engine.Evaluate(policy.Input{
+		Identity: identity,
+		Resource: resource,
+		Target:   target,
+		Model:    model,
+	})
+	if decision.Allowed {
+		return true
+	}
+
+	callerID := ""
+	if identity != nil {
+		callerID = identity.ID
+	}
+	log.GlobalLogger.Warn("Request denied by policy",
+		zap.String("caller", callerID),
+		zap.String("resource", resource),
+		zap.String("target", target),
+		zap.String("model", model),
+		zap.String("rule", decision.Rule),
+	)
+	c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden by policy", "rule": decision.Rule})
+	return false
+}
+
+// applyChatParameters 将parameters应用到聊天请求
+// 参数名与ChatRequest的JSON字段一致，包括model、temperature、max_tokens、stream、top_p、seed、stop、
+// response_format、tools和tool_choice
+func applyChatParameters(req *ai_agent.ChatRequest, parameters map[string]interface{}) error {
+	if len(parameters) == 0 {
+		return nil
+	}
+	payload, err := json.Marshal(parameters)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(payload, req)
+}
+
 // handleHealthCheck 处理健康检查请求
 func handleHealthCheck(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"status": "ok", "time": time.Now().Format(time.RFC3339)})
@@ -153,7 +453,7 @@ func handleVersion(c *gin.Context) {
 }
 
 // createHandleAIChat 创建AI聊天处理函数
-func createHandleAIChat(agentManager ai_agent.AIAgentManager) gin.HandlerFunc {
+func createHandleAIChat(agentManager ai_agent.AIAgentManager, opts *routeOptions) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 从上下文获取日志器
 		logger, exists := c.Get("logger")
@@ -170,8 +470,11 @@ func createHandleAIChat(agentManager ai_agent.AIAgentManager) gin.HandlerFunc {
 		// 解析请求体
 		var request struct {
 			AgentID    string                 `json:"agent_id" binding:"required"`
-			Messages   []interface{}          `json:"messages" binding:"required"`
+			Messages   []ai_agent.Message     `json:"messages"`
 			Parameters map[string]interface{} `json:"parameters"`
+			SessionID  string                 `json:"session_id"`
+			Template   string                 `json:"template"`
+			Variables  map[string]interface{} `json:"variables"`
 		}
 
 		if err := c.ShouldBindJSON(&request); err != nil {
@@ -179,6 +482,16 @@ func createHandleAIChat(agentManager ai_agent.AIAgentManager) gin.HandlerFunc {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
 			return
 		}
+		if len(request.Messages) == 0 && request.Template == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "messages or template is required"})
+			return
+		}
+
+		// 检查调用方权限
+		model, _ := request.Parameters["model"].(string)
+		if !checkAgentAccess(c, request.AgentID) || !authorize(c, opts.policyEngine, policy.ResourceAgent, request.AgentID, model) {
+			return
+		}
 
 		// 获取AI Agent
 		agent, err := agentManager.GetAIAgent(request.AgentID, nil)
@@ -187,34 +500,41 @@ func createHandleAIChat(agentManager ai_agent.AIAgentManager) gin.HandlerFunc {
 			c.JSON(http.StatusNotFound, gin.H{"error": "AI agent not found"})
 			return
 		}
+		agent = wrapAgent(opts, request.AgentID, agent)
+
+		// 使用会话保存的对话历史
+		agent, status, err := bindSession(c, opts, agent, request.AgentID, request.SessionID)
+		if err != nil {
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
 
 		// 创建上下文
 		ctx := c.Request.Context()
 
 		// 构建聊天请求
-		chatReq := ai_agent.ChatRequest{
-			Messages: []ai_agent.Message{},
-		}
-		// 这里简化处理，实际项目中需要进行类型转换
-		if len(request.Messages) > 0 {
-			for _, msg := range request.Messages {
-				msgMap, ok := msg.(map[string]interface{})
-				if ok {
-					role, _ := msgMap["role"].(string)
-					content, _ := msgMap["content"].(string)
-					chatReq.Messages = append(chatReq.Messages, ai_agent.Message{
-						Role:    role,
-						Content: content,
-					})
-				}
-			}
+		chatReq := ai_agent.ChatRequest{}
+		if err := applyChatParameters(&chatReq, request.Parameters); err != nil {
+			logLogger.Error("Invalid chat parameters", zap.Error(err))
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid parameters: " + err.Error()})
+			return
+		}
+		// 渲染提示模板
+		chatReq.Messages, status, err = applyTemplate(c, opts, request.Template, request.Variables, request.Messages)
+		if err != nil {
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		if chatReq.Stream {
+			streamChat(c, logLogger, agent, chatReq, defaultString(chatReq.Model, request.AgentID))
+			return
 		}
 
 		// 调用AI Agent进行聊天
 		response, err := agent.Chat(ctx, chatReq)
 		if err != nil {
 			logLogger.Error("AI chat failed", zap.String("agent_id", request.AgentID), zap.Error(err))
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Chat failed: " + err.Error()})
+			c.JSON(callErrorStatus(err), gin.H{"error": "Chat failed: " + err.Error()})
 			return
 		}
 
@@ -224,7 +544,7 @@ func createHandleAIChat(agentManager ai_agent.AIAgentManager) gin.HandlerFunc {
 }
 
 // createHandleAICompletion 创建AI补全处理函数
-func createHandleAICompletion(agentManager ai_agent.AIAgentManager) gin.HandlerFunc {
+func createHandleAICompletion(agentManager ai_agent.AIAgentManager, opts *routeOptions) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 从上下文获取日志器
 		logger, exists := c.Get("logger")
@@ -251,6 +571,12 @@ func createHandleAICompletion(agentManager ai_agent.AIAgentManager) gin.HandlerF
 			return
 		}
 
+		// 检查调用方权限
+		model, _ := request.Parameters["model"].(string)
+		if !checkAgentAccess(c, request.AgentID) || !authorize(c, opts.policyEngine, policy.ResourceAgent, request.AgentID, model) {
+			return
+		}
+
 		// 获取AI Agent
 		agent, err := agentManager.GetAIAgent(request.AgentID, nil)
 		if err != nil {
@@ -258,6 +584,7 @@ func createHandleAICompletion(agentManager ai_agent.AIAgentManager) gin.HandlerF
 			c.JSON(http.StatusNotFound, gin.H{"error": "AI agent not found"})
 			return
 		}
+		agent = wrapAgent(opts, request.AgentID, agent)
 
 		// 创建上下文
 		ctx := c.Request.Context()
@@ -266,12 +593,17 @@ func createHandleAICompletion(agentManager ai_agent.AIAgentManager) gin.HandlerF
 		completionReq := ai_agent.CompletionRequest{
 			Prompt: request.Prompt,
 		}
+		completionReq.Model, _ = request.Parameters["model"].(string)
+		completionReq.Temperature, _ = request.Parameters["temperature"].(float64)
+		if maxTokens, ok := request.Parameters["max_tokens"].(float64); ok {
+			completionReq.MaxTokens = int(maxTokens)
+		}
 
 		// 调用AI Agent进行补全
 		response, err := agent.Completion(ctx, completionReq)
 		if err != nil {
 			logLogger.Error("AI completion failed", zap.String("agent_id", request.AgentID), zap.Error(err))
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Completion failed: " + err.Error()})
+			c.JSON(callErrorStatus(err), gin.H{"error": "Completion failed: " + err.Error()})
 			return
 		}
 
@@ -281,7 +613,7 @@ func createHandleAICompletion(agentManager ai_agent.AIAgentManager) gin.HandlerF
 }
 
 // createHandleAIEmbedding 创建AI嵌入处理函数
-func createHandleAIEmbedding(agentManager ai_agent.AIAgentManager) gin.HandlerFunc {
+func createHandleAIEmbedding(agentManager ai_agent.AIAgentManager, opts *routeOptions) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 从上下文获取日志器
 		logger, exists := c.Get("logger")
@@ -308,6 +640,12 @@ func createHandleAIEmbedding(agentManager ai_agent.AIAgentManager) gin.HandlerFu
 			return
 		}
 
+		// 检查调用方权限
+		model, _ := request.Parameters["model"].(string)
+		if !checkAgentAccess(c, request.AgentID) || !authorize(c, opts.policyEngine, policy.ResourceAgent, request.AgentID, model) {
+			return
+		}
+
 		// 获取AI Agent
 		agent, err := agentManager.GetAIAgent(request.AgentID, nil)
 		if err != nil {
@@ -315,12 +653,14 @@ func createHandleAIEmbedding(agentManager ai_agent.AIAgentManager) gin.HandlerFu
 			c.JSON(http.StatusNotFound, gin.H{"error": "AI agent not found"})
 			return
 		}
+		agent = wrapAgent(opts, request.AgentID, agent)
 
 		// 创建上下文
 		ctx := c.Request.Context()
 
 		// 构建嵌入请求
 		embeddingReq := ai_agent.EmbeddingRequest{
+			Model: model,
 			Input: []string{},
 		}
 		// 处理输入数据
@@ -338,7 +678,7 @@ func createHandleAIEmbedding(agentManager ai_agent.AIAgentManager) gin.HandlerFu
 		response, err := agent.Embedding(ctx, embeddingReq)
 		if err != nil {
 			logLogger.Error("AI embedding failed", zap.String("agent_id", request.AgentID), zap.Error(err))
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Embedding failed: " + err.Error()})
+			c.JSON(callErrorStatus(err), gin.H{"error": "Embedding failed: " + err.Error()})
 			return
 		}
 
@@ -347,6 +687,22 @@ func createHandleAIEmbedding(agentManager ai_agent.AIAgentManager) gin.HandlerFu
 	}
 }
 
+// isInvalidRequest 判断调用错误是否由请求参数无效或被调用防护拒绝引起
+func isInvalidRequest(err error) bool {
+	return errors.Is(err, ai_agent.ErrInvalidRequest) || errors.Is(err, mcp.ErrInvalidRequest) || errors.Is(err, guardrail.ErrBlocked)
+}
+
+// callErrorStatus 根据调用错误返回HTTP状态码，请求参数无效或被拒绝时返回400，会话属于其他调用方时返回403
+func callErrorStatus(err error) int {
+	if isInvalidRequest(err) {
+		return http.StatusBadRequest
+	}
+	if errors.Is(err, session.ErrForbidden) {
+		return http.StatusForbidden
+	}
+	return http.StatusInternalServerError
+}
+
 // createHandleListModels 创建获取模型列表处理函数
 func createHandleListModels(agentManager ai_agent.AIAgentManager) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -371,7 +727,7 @@ func createHandleListModels(agentManager ai_agent.AIAgentManager) gin.HandlerFun
 }
 
 // createHandleMCPCommand 创建MCP命令处理函数
-func createHandleMCPCommand(mcpManager mcp.MCPServiceManager) gin.HandlerFunc {
+func createHandleMCPCommand(mcpManager mcp.MCPServiceManager, opts *routeOptions) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 从上下文获取日志器
 		logger, exists := c.Get("logger")
@@ -398,6 +754,12 @@ func createHandleMCPCommand(mcpManager mcp.MCPServiceManager) gin.HandlerFunc {
 			return
 		}
 
+		// 检查调用方权限
+		if !checkMCPServiceAccess(c, request.ServiceID) ||
+			!authorize(c, opts.policyEngine, policy.ResourceMCPTool, policy.MCPToolTarget(request.ServiceID, request.Command), "") {
+			return
+		}
+
 		// 获取MCP服务
 		service, err := mcpManager.GetMCPService(request.ServiceID, nil)
 		if err != nil {
@@ -419,7 +781,7 @@ func createHandleMCPCommand(mcpManager mcp.MCPServiceManager) gin.HandlerFunc {
 		response, err := service.Call(ctx, req)
 		if err != nil {
 			logLogger.Error("MCP command execution failed", zap.String("service_id", request.ServiceID), zap.String("command", request.Command), zap.Error(err))
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Command execution failed: " + err.Error()})
+			c.JSON(callErrorStatus(err), gin.H{"error": "Command execution failed: " + err.Error()})
 			return
 		}
 
@@ -452,10 +814,11 @@ func createHandleListMCPServices(mcpManager mcp.MCPServiceManager) gin.HandlerFu
 }
 
 // createReverseProxyHandler 创建反向代理处理函数
-func createReverseProxyHandler(logger log.Logger, targetURL string) gin.HandlerFunc {
+func createReverseProxyHandler(logger log.Logger, route config.ProxyRouteConfig, opts *routeOptions) gin.HandlerFunc {
 	if logger == nil {
 		logger = log.GlobalLogger
 	}
+	targetURL := route.TargetURL
 
 	// 解析目标URL
 	target, err := url.Parse(targetURL)
@@ -469,6 +832,18 @@ func createReverseProxyHandler(logger log.Logger, targetURL string) gin.HandlerF
 	// 创建反向代理
 	proxy := httputil.NewSingleHostReverseProxy(target)
 
+	// 使用按目标共享的连接池，支持上游TLS/mTLS配置
+	if opts.transports != nil {
+		transport, err := opts.transports.RoundTripper(target, route.TLS, route.Transport)
+		if err != nil {
+			logger.Error("Failed to create upstream transport", zap.String("target_url", targetURL), zap.Error(err))
+			return func(c *gin.Context) {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Proxy configuration error"})
+			}
+		}
+		proxy.Transport = transport
+	}
+
 	// 自定义Director函数，保留原始请求路径
 	originalDirector := proxy.Director
 	proxy.Director = func(req *http.Request) {
@@ -485,13 +860,24 @@ func createReverseProxyHandler(logger log.Logger, targetURL string) gin.HandlerF
 	}
 
 	return func(c *gin.Context) {
+		// 检查调用方是否有权访问该代理路由
+		if identity, ok := auth.GetIdentity(c); ok && !identity.AllowsProxyRoute(c.FullPath()) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access to proxy route denied"})
+			return
+		}
+		if !authorize(c, opts.policyEngine, policy.ResourceRoute, c.FullPath(), "") {
+			return
+		}
+
 		// 执行代理请求
 		proxy.ServeHTTP(c.Writer, c.Request)
 	}
 }
 
 // RegisterProxyRoutesFromConfig 从配置中注册代理路由（公开函数）
-func RegisterProxyRoutesFromConfig(router *gin.Engine, logger log.Logger, onRouteRegistered func(string)) {
+func RegisterProxyRoutesFromConfig(router *gin.Engine, logger log.Logger, onRouteRegistered func(string), options ...RouteOption) {
+	opts := newRouteOptions(options)
+
 	// 从全局配置中获取代理路由配置
 	proxyRoutes := config.GlobalConfig.ProxyRoutes
 
@@ -530,7 +916,7 @@ func RegisterProxyRoutesFromConfig(router *gin.Engine, logger log.Logger, onRout
 			}()
 
 			// 注册代理路由
-			router.Any(route.Path, createReverseProxyHandler(logger, route.TargetURL))
+			router.Any(route.Path, createReverseProxyHandler(logger, route, opts))
 			logger.Info("Registered proxy route", zap.String("path", route.Path), zap.String("target_url", route.TargetURL))
 
 			// 如果提供了回调函数，则调用它记录已注册的路由
@@ -542,7 +928,7 @@ func RegisterProxyRoutesFromConfig(router *gin.Engine, logger log.Logger, onRout
 }
 
 // registerProxyRoutesFromConfig 从配置中注册代理路由（内部调用公开函数）
-func registerProxyRoutesFromConfig(router *gin.Engine, logger log.Logger, onRouteRegistered func(string)) {
+func registerProxyRoutesFromConfig(router *gin.Engine, logger log.Logger, onRouteRegistered func(string), options ...RouteOption) {
 	// 直接调用公开的函数
-	RegisterProxyRoutesFromConfig(router, logger, onRouteRegistered)
+	RegisterProxyRoutesFromConfig(router, logger, onRouteRegistered, options...)
 }
@@ -0,0 +1,113 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"kai/kaigate/pkg/auth"
+	"kai/kaigate/pkg/service/ai_agent"
+	"kai/kaigate/pkg/session"
+)
+
+// SessionHeader 返回本次请求使用的会话ID的响应头
+const SessionHeader = "X-Kaigate-Session"
+
+// sessionOwner 返回调用方作为会话所有者的标识，匿名调用方返回空字符串
+func sessionOwner(c *gin.Context) string {
+	if identity, ok := auth.GetIdentity(c); ok && !identity.IsAnonymous() {
+		return identity.ID
+	}
+	return ""
+}
+
+// bindSession 为请求绑定会话，sessionID为空时返回原Agent
+// 失败时返回对应的HTTP状态码和错误
+func bindSession(c *gin.Context, opts *routeOptions, agent ai_agent.AIAgent, agentID, sessionID string) (ai_agent.AIAgent, int, error) {
+	if sessionID == "" {
+		return agent, 0, nil
+	}
+	if opts.sessions == nil {
+		return nil, http.StatusBadRequest, errors.New("sessions are not enabled")
+	}
+
+	owner := sessionOwner(c)
+	if err := opts.sessions.Open(sessionID, owner); err != nil {
+		return nil, sessionErrorStatus(err), err
+	}
+	c.Header(SessionHeader, sessionID)
+	return opts.sessions.Bind(agent, agentID, sessionID, owner), 0, nil
+}
+
+// registerSessionRoutes 注册调用方管理自己会话的接口
+func registerSessionRoutes(api *gin.RouterGroup, manager *session.Manager) {
+	sessions := api.Group("/sessions")
+
+	// 列出调用方的会话，匿名调用方无法列出会话
+	sessions.GET("", func(c *gin.Context) {
+		owner := sessionOwner(c)
+		if owner == "" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Listing sessions requires authentication"})
+			return
+		}
+		infos, err := manager.List(owner)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"sessions": infos})
+	})
+
+	sessions.GET("/:id", func(c *gin.Context) {
+		sess, ok := ownedSession(c, manager)
+		if !ok {
+			return
+		}
+		c.JSON(http.StatusOK, sess)
+	})
+
+	sessions.DELETE("/:id", func(c *gin.Context) {
+		sess, ok := ownedSession(c, manager)
+		if !ok {
+			return
+		}
+		if err := manager.Delete(sess.ID); err != nil {
+			c.JSON(sessionErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Session deleted"})
+	})
+}
+
+// ownedSession 获取调用方有权访问的会话，失败时写入错误响应
+// 匿名调用方不能访问任何会话
+func ownedSession(c *gin.Context, manager *session.Manager) (*session.Session, bool) {
+	owner := sessionOwner(c)
+	if owner == "" {
+		c.JSON(sessionErrorStatus(session.ErrAnonymous), gin.H{"error": session.ErrAnonymous.Error()})
+		return nil, false
+	}
+	sess, err := manager.Get(c.Param("id"))
+	if err == nil && sess.Owner != owner {
+		err = session.ErrForbidden
+	}
+	if err != nil {
+		c.JSON(sessionErrorStatus(err), gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return sess, true
+}
+
+// sessionErrorStatus 根据会话错误返回HTTP状态码
+func sessionErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, session.ErrInvalidID):
+		return http.StatusBadRequest
+	case errors.Is(err, session.ErrForbidden), errors.Is(err, session.ErrAnonymous):
+		return http.StatusForbidden
+	case errors.Is(err, session.ErrNotFound):
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
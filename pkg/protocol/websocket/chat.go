@@ -0,0 +1,108 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+
+	"go.uber.org/zap"
+
+	"kai/kaigate/pkg/log"
+	"kai/kaigate/pkg/service/ai_agent"
+)
+
+// chatRequestMessage WebSocket聊天请求，除type和id外的字段与ChatRequest一致
+type chatRequestMessage struct {
+	Type string `json:"type"`
+	ID   string `json:"id"` // 请求ID，响应中原样返回以便客户端关联
+	ai_agent.ChatRequest
+}
+
+// chatEvent WebSocket聊天响应
+// type为chat.response（非流式结果）、chat.chunk（流式数据块）、chat.done（流式结束）或chat.error
+type chatEvent struct {
+	Type     string                 `json:"type"`
+	ID       string                 `json:"id,omitempty"`
+	Response *ai_agent.ChatResponse `json:"response,omitempty"`
+	Error    string                 `json:"error,omitempty"`
+}
+
+// newChatHandler 创建AI Agent连接的chat消息处理器
+// 每个请求在独立的goroutine中执行，连接关闭时通过ctx取消；allowModel用于按请求的模型重新检查授权策略
+func newChatHandler(ctx context.Context, logger log.Logger, agent ai_agent.AIAgent, allowModel func(model string) bool) MessageHandler {
+	return func(conn *Connection, message []byte) error {
+		var request chatRequestMessage
+		if err := json.Unmarshal(message, &request); err != nil {
+			sendChatEvent(conn, chatEvent{Type: "chat.error", Error: "Invalid chat request: " + err.Error()})
+			return err
+		}
+		if len(request.Messages) == 0 {
+			sendChatEvent(conn, chatEvent{Type: "chat.error", ID: request.ID, Error: "messages must not be empty"})
+			return nil
+		}
+		if !allowModel(request.Model) {
+			sendChatEvent(conn, chatEvent{Type: "chat.error", ID: request.ID, Error: "Forbidden by policy"})
+			return nil
+		}
+
+		go func() {
+			if request.Stream {
+				streamChat(ctx, logger, conn, agent, request)
+				return
+			}
+
+			response, err := agent.Chat(ctx, request.ChatRequest)
+			if err != nil {
+				logger.Error("AI chat failed", zap.String("conn_id", conn.ID), zap.String("agent", agent.Name()), zap.Error(err))
+				sendChatEvent(conn, chatEvent{Type: "chat.error", ID: request.ID, Error: err.Error()})
+				return
+			}
+			sendChatEvent(conn, chatEvent{Type: "chat.response", ID: request.ID, Response: response})
+		}()
+		return nil
+	}
+}
+
+// streamChat 逐个转发ChatStream的数据块，以chat.done或chat.error结束
+func streamChat(ctx context.Context, logger log.Logger, conn *Connection, agent ai_agent.AIAgent, request chatRequestMessage) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	respChan, errChan := agent.ChatStream(ctx, request.ChatRequest)
+	for response := range respChan {
+		if !sendChatEvent(conn, chatEvent{Type: "chat.chunk", ID: request.ID, Response: response}) {
+			// 连接已关闭，取消上游请求并等待其结束
+			cancel()
+			for range respChan {
+			}
+			return
+		}
+	}
+
+	if err := <-errChan; err != nil {
+		logger.Error("AI chat stream failed", zap.String("conn_id", conn.ID), zap.String("agent", agent.Name()), zap.Error(err))
+		sendChatEvent(conn, chatEvent{Type: "chat.error", ID: request.ID, Error: err.Error()})
+		return
+	}
+	sendChatEvent(conn, chatEvent{Type: "chat.done", ID: request.ID})
+}
+
+// sendChatEvent 发送聊天响应，发送通道已满时等待而不是丢弃，连接关闭时返回false
+func sendChatEvent(conn *Connection, event chatEvent) bool {
+	data, err := json.Marshal(event)
+	if err != nil {
+		connManager.logger.Error("Failed to encode chat event", zap.String("conn_id", conn.ID), zap.Error(err))
+		return false
+	}
+
+	select {
+	case <-conn.CloseChan:
+		return false
+	default:
+	}
+	select {
+	case conn.SendChan <- data:
+		return true
+	case <-conn.CloseChan:
+		return false
+	}
+}
@@ -1,6 +1,7 @@
 package websocket
 
 import (
+	"context"
 	"encoding/json"
 	"math/rand"
 	"net/http"
@@ -11,7 +12,10 @@ import (
 	"github.com/gorilla/websocket"
 	"go.uber.org/zap"
 
+	"kai/kaigate/pkg/auth"
 	"kai/kaigate/pkg/log"
+	"kai/kaigate/pkg/policy"
+	gw_router "kai/kaigate/pkg/router"
 	"kai/kaigate/pkg/service/ai_agent"
 	"kai/kaigate/pkg/service/mcp"
 )
@@ -32,6 +36,11 @@ type Connection struct {
 	SendChan  chan []byte
 	RecvChan  chan []byte
 	CloseChan chan struct{}
+
+	// 连接级消息处理器，优先于全局处理器
+	handlers map[string]MessageHandler
+	// 连接级消息的限流检查，为nil表示不限流
+	allow func() bool
 }
 
 // ConnectionManager WebSocket连接管理器
@@ -63,18 +72,64 @@ func NewConnectionManager() *ConnectionManager {
 	return manager
 }
 
+// RouteOption WebSocket路由选项
+type RouteOption func(*routeOptions)
+
+// routeOptions WebSocket路由的可选组件
+type routeOptions struct {
+	authMiddleware gin.HandlerFunc
+	policyEngine   *policy.Engine
+	rateLimiter    *gw_router.RateLimitManager
+}
+
+// WithAuthMiddleware 设置认证中间件
+func WithAuthMiddleware(middleware gin.HandlerFunc) RouteOption {
+	return func(o *routeOptions) {
+		o.authMiddleware = middleware
+	}
+}
+
+// WithPolicyEngine 设置授权策略引擎
+func WithPolicyEngine(engine *policy.Engine) RouteOption {
+	return func(o *routeOptions) {
+		o.policyEngine = engine
+	}
+}
+
+// WithRateLimiter 设置按调用方限流的管理器，与HTTP接口共享同一调用方的限额
+func WithRateLimiter(manager *gw_router.RateLimitManager) RouteOption {
+	return func(o *routeOptions) {
+		o.rateLimiter = manager
+	}
+}
+
 // RegisterRoutes 注册WebSocket路由
-func RegisterRoutes(router *gin.Engine, logger log.Logger, agentManager ai_agent.AIAgentManager, mcpManager mcp.MCPServiceManager) {
+func RegisterRoutes(router *gin.Engine, logger log.Logger, agentManager ai_agent.AIAgentManager, mcpManager mcp.MCPServiceManager, options ...RouteOption) {
+	// 应用路由选项
+	opts := &routeOptions{}
+	for _, option := range options {
+		option(opts)
+	}
+
 	// 更新全局连接管理器的logger
 	connManager.logger = logger
 
+	// 认证需要在连接升级之前完成
+	if opts.authMiddleware != nil {
+		router.Use(opts.authMiddleware)
+	}
+	// 限流在认证之后，建立连接也计入调用方的限额
+	if opts.rateLimiter != nil {
+		router.Use(rateLimitMiddleware(opts.rateLimiter))
+	}
+
 	// 启动心跳检测
 	go connManager.startHeartbeat()
 
 	// WebSocket连接端点
 	router.GET("/ws/connect", createHandleWSConnect(logger))
-	router.GET("/ws/ai-agent", createHandleAIAgentWS(logger, agentManager))
-	router.GET("/ws/mcp", createHandleMCPWS(logger, mcpManager))
+	router.GET("/ws/ai-agent", createHandleAIAgentWS(logger, agentManager, opts))
+	router.GET("/ws/mcp", createHandleMCPWS(logger, mcpManager, opts))
 
 	// 注册消息处理器
 	connManager.RegisterHandler("ping", handlePing)
@@ -123,11 +178,47 @@ func createHandleWSConnect(logger log.Logger) gin.HandlerFunc {
 }
 
 // createHandleAIAgentWS 创建AI Agent WebSocket处理函数
-func createHandleAIAgentWS(logger log.Logger, agentManager ai_agent.AIAgentManager) gin.HandlerFunc {
+func createHandleAIAgentWS(logger log.Logger, agentManager ai_agent.AIAgentManager, opts *routeOptions) gin.HandlerFunc {
 	if logger == nil {
 		logger = log.GlobalLogger
 	}
 	return func(c *gin.Context) {
+		// 获取Agent ID
+		agentID := c.Query("agent_id")
+		if agentID == "" {
+			agentID = "default"
+		}
+
+		// 检查调用方权限
+		if identity, ok := auth.GetIdentity(c); ok && !identity.AllowsAgent(agentID) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access to AI agent denied"})
+			return
+		}
+		if !authorize(c, opts.policyEngine, policy.ResourceAgent, agentID, c.Query("model")) {
+			return
+		}
+
+		agent, err := agentManager.GetAIAgent(agentID, nil)
+		if err != nil {
+			logger.Error("Failed to get AI agent", zap.String("agent_id", agentID), zap.Error(err))
+			c.JSON(http.StatusNotFound, gin.H{"error": "AI agent not found"})
+			return
+		}
+
+		// chat消息中的模型可能与连接参数不同，需要逐个请求检查策略
+		identity, _ := auth.GetIdentity(c)
+		allowModel := func(model string) bool {
+			if opts.policyEngine == nil {
+				return true
+			}
+			return opts.policyEngine.Evaluate(policy.Input{
+				Identity: identity,
+				Resource: policy.ResourceAgent,
+				Target:   agentID,
+				Model:    model,
+			}).Allowed
+		}
+
 		conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 		if err != nil {
 			logger.Error("WebSocket upgrade failed", zap.Error(err))
@@ -135,12 +226,6 @@ func createHandleAIAgentWS(logger log.Logger, agentManager ai_agent.AIAgentManag
 			return
 		}
 
-		// 获取Agent ID
-		agentID := c.Query("agent_id")
-		if agentID == "" {
-			agentID = "default"
-		}
-
 		// 创建连接ID
 		connID := generateConnID()
 
@@ -153,6 +238,19 @@ func createHandleAIAgentWS(logger log.Logger, agentManager ai_agent.AIAgentManag
 			CloseChan: make(chan struct{}),
 		}
 
+		// 连接关闭时取消进行中的请求
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		connection.handlers = map[string]MessageHandler{
+			"chat": newChatHandler(ctx, logger, agent, allowModel),
+		}
+		if opts.rateLimiter != nil {
+			clientIP := c.ClientIP()
+			connection.allow = func() bool {
+				return opts.rateLimiter.LimiterFor(identity, clientIP).Allow()
+			}
+		}
+
 		// 添加连接到管理器
 		connManager.AddConnection(connection)
 		logger.Info("AI Agent WebSocket connection established", zap.String("conn_id", connID), zap.String("agent_id", agentID))
@@ -169,6 +267,38 @@ func createHandleAIAgentWS(logger log.Logger, agentManager ai_agent.AIAgentManag
 	}
 }
 
+// rateLimitMiddleware 按调用方限流的中间件，已认证调用方按身份限流，匿名调用方按客户端IP限流
+func rateLimitMiddleware(manager *gw_router.RateLimitManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		identity, _ := auth.GetIdentity(c)
+		if !manager.LimiterFor(identity, c.ClientIP()).Allow() {
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "Rate limit exceeded"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// authorize 使用授权策略评估连接请求，未配置策略引擎时直接放行
+func authorize(c *gin.Context, engine *policy.Engine, resource, target, model string) bool {
+	if engine == nil {
+		return true
+	}
+
+	identity, _ := auth.GetIdentity(c)
+	decision := engine.Evaluate(policy.Input{
+		Identity: identity,
+		Resource: resource,
+		Target:   target,
+		Model:    model,
+	})
+	if !decision.Allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden by policy", "rule": decision.Rule})
+		return false
+	}
+	return true
+}
+
 // readMessages 从WebSocket读取消息
 func (c *Connection) readMessages(logger log.Logger) {
 	for {
@@ -204,9 +334,23 @@ func (c *Connection) readMessages(logger log.Logger) {
 				continue
 			}
 
-			// 调用对应的处理器
-			c.RecvChan <- message
-			if handler, exists := connManager.handlers[msgType]; exists {
+			// 调用对应的处理器，RecvChan没有消费者时不阻塞读取
+			select {
+			case c.RecvChan <- message:
+			default:
+			}
+			handler, exists := c.handlers[msgType]
+			// 连接级消息会调用上游服务，每条消息都按调用方限流，被拒绝时返回<type>.error
+			if exists && c.allow != nil && !c.allow() {
+				if reply, err := json.Marshal(map[string]string{"type": msgType + ".error", "error": "Rate limit exceeded"}); err == nil {
+					c.Send(reply)
+				}
+				continue
+			}
+			if !exists {
+				handler, exists = connManager.handlers[msgType]
+			}
+			if exists {
 				if err := handler(c, message); err != nil {
 					logger.Error("Failed to handle WebSocket message", zap.String("conn_id", c.ID), zap.String("msg_type", msgType), zap.Error(err))
 				}
@@ -383,11 +527,23 @@ func handleEcho(conn *Connection, message []byte) error {
 }
 
 // createHandleMCPWS 创建MCP WebSocket处理函数
-func createHandleMCPWS(logger log.Logger, mcpManager mcp.MCPServiceManager) gin.HandlerFunc {
+func createHandleMCPWS(logger log.Logger, mcpManager mcp.MCPServiceManager, opts *routeOptions) gin.HandlerFunc {
 	if logger == nil {
 		logger = log.GlobalLogger
 	}
 	return func(c *gin.Context) {
+		// 获取Service ID
+		serviceID := c.Query("service_id")
+		if serviceID == "" {
+			serviceID = "default"
+		}
+
+		// 检查调用方权限
+		if identity, ok := auth.GetIdentity(c); ok && !identity.AllowsMCPService(serviceID) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access to MCP service denied"})
+			return
+		}
+
 		conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 		if err != nil {
 			logger.Error("WebSocket upgrade failed", zap.Error(err))
@@ -395,12 +551,6 @@ func createHandleMCPWS(logger log.Logger, mcpManager mcp.MCPServiceManager) gin.
 			return
 		}
 
-		// 获取Service ID
-		serviceID := c.Query("service_id")
-		if serviceID == "" {
-			serviceID = "default"
-		}
-
 		// 创建连接ID
 		connID := generateConnID()
 
@@ -0,0 +1,353 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"kai/kaigate/pkg/config"
+	"kai/kaigate/pkg/log"
+	"kai/kaigate/pkg/service/ai_agent"
+)
+
+// ServedByHeader 记录实际处理请求的目标的响应头，格式为"agent/model"
+const ServedByHeader = "X-Kaigate-Served-By"
+
+// ModelRouter 逻辑模型路由器
+// 每个逻辑模型对应按顺序排列的多个(AI Agent, 模型)目标，按权重选择首个目标，
+// 失败、超时或被限流时依次尝试其余目标；连续失败的目标由熔断器暂时跳过。
+// 调用中间件在逻辑模型外层执行一次，目标通过ResolveAIAgent获取，不再重复执行中间件
+type ModelRouter struct {
+	agentManager ai_agent.AIAgentManager
+	routes       map[string]config.ModelRouteConfig
+	breaker      *CircuitBreaker
+	mutex        sync.RWMutex
+	logger       log.Logger
+}
+
+// ServedBy 实际处理请求的目标
+type ServedBy struct {
+	Route    string `json:"route"`
+	Agent    string `json:"agent"`
+	Model    string `json:"model"`
+	Attempts int    `json:"attempts"` // 尝试的目标数
+}
+
+// servedByKey ServedBy在上下文中的键
+type servedByKey struct{}
+
+// NewModelRouter 创建逻辑模型路由器
+func NewModelRouter(agentManager ai_agent.AIAgentManager) *ModelRouter {
+	return &ModelRouter{
+		agentManager: agentManager,
+		routes:       make(map[string]config.ModelRouteConfig),
+		breaker:      NewCircuitBreaker(),
+		logger:       log.GlobalLogger,
+	}
+}
+
+// Load 校验并替换全部路由，校验失败时保留原有路由
+func (r *ModelRouter) Load(routes []config.ModelRouteConfig) error {
+	loaded := make(map[string]config.ModelRouteConfig, len(routes))
+	for _, route := range routes {
+		if route.Name == "" {
+			return errors.New("model route name cannot be empty")
+		}
+		if _, exists := loaded[route.Name]; exists {
+			return fmt.Errorf("duplicate model route: %s", route.Name)
+		}
+		if len(route.Targets) == 0 {
+			return fmt.Errorf("model route %s has no targets", route.Name)
+		}
+		for _, target := range route.Targets {
+			if target.Agent == "" {
+				return fmt.Errorf("model route %s has a target without agent", route.Name)
+			}
+			if target.Agent == route.Name {
+				return fmt.Errorf("model route %s cannot target itself", route.Name)
+			}
+			if target.Weight < 0 {
+				return fmt.Errorf("model route %s has a negative weight", route.Name)
+			}
+		}
+		loaded[route.Name] = route
+	}
+
+	r.mutex.Lock()
+	r.routes = loaded
+	r.mutex.Unlock()
+	return nil
+}
+
+// Route 获取逻辑模型的路由配置
+func (r *ModelRouter) Route(name string) (config.ModelRouteConfig, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	route, ok := r.routes[name]
+	return route, ok
+}
+
+// TargetAgents 返回逻辑模型路由的目标AI Agent名称，路由不存在时返回nil
+func (r *ModelRouter) TargetAgents(name string) []string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	route, ok := r.routes[name]
+	if !ok {
+		return nil
+	}
+	agents := make([]string, 0, len(route.Targets))
+	for _, target := range route.Targets {
+		agents = append(agents, target.Agent)
+	}
+	return agents
+}
+
+// Routes 返回全部路由，按名称排序
+func (r *ModelRouter) Routes() []config.ModelRouteConfig {
+	r.mutex.RLock()
+	routes := make([]config.ModelRouteConfig, 0, len(r.routes))
+	for _, route := range r.routes {
+		routes = append(routes, route)
+	}
+	r.mutex.RUnlock()
+
+	sort.Slice(routes, func(i, j int) bool { return routes[i].Name < routes[j].Name })
+	return routes
+}
+
+// TargetStates 返回各目标的熔断状态，键为"agent/model"
+func (r *ModelRouter) TargetStates() map[string]interface{} {
+	return r.breaker.GetState()
+}
+
+// WithServedBy 在上下文中放入ServedBy，路由器处理请求后写入实际使用的目标
+func WithServedBy(ctx context.Context) (context.Context, *ServedBy) {
+	servedBy := &ServedBy{}
+	return context.WithValue(ctx, servedByKey{}, servedBy), servedBy
+}
+
+// EnsureServedBy 获取上下文中的ServedBy，不存在时放入新的ServedBy
+func EnsureServedBy(ctx context.Context) (context.Context, *ServedBy) {
+	if servedBy, ok := ctx.Value(servedByKey{}).(*ServedBy); ok {
+		return ctx, servedBy
+	}
+	return WithServedBy(ctx)
+}
+
+// ServedByFromContext 获取上下文中的ServedBy，请求未经过模型路由时Agent为空
+func ServedByFromContext(ctx context.Context) (*ServedBy, bool) {
+	servedBy, ok := ctx.Value(servedByKey{}).(*ServedBy)
+	return servedBy, ok && servedBy.Agent != ""
+}
+
+// String 返回"agent/model"格式的目标
+func (s *ServedBy) String() string {
+	if s.Model == "" {
+		return s.Agent
+	}
+	return s.Agent + "/" + s.Model
+}
+
+// targetKey 熔断器中目标的键
+func targetKey(target config.ModelTargetConfig) string {
+	if target.Model == "" {
+		return target.Agent
+	}
+	return target.Agent + "/" + target.Model
+}
+
+// plan 确定目标的尝试顺序：按权重随机选择首个目标，其余目标保持配置顺序
+func plan(route config.ModelRouteConfig) []config.ModelTargetConfig {
+	total := 0
+	for _, target := range route.Targets {
+		total += target.Weight
+	}
+	if total == 0 {
+		return route.Targets
+	}
+
+	pick := rand.Intn(total)
+	first := 0
+	for i, target := range route.Targets {
+		if pick < target.Weight {
+			first = i
+			break
+		}
+		pick -= target.Weight
+	}
+
+	targets := make([]config.ModelTargetConfig, 0, len(route.Targets))
+	targets = append(targets, route.Targets[first])
+	targets = append(targets, route.Targets[:first]...)
+	return append(targets, route.Targets[first+1:]...)
+}
+
+// markServed 记录实际处理请求的目标
+func (r *ModelRouter) markServed(ctx context.Context, route string, target config.ModelTargetConfig, attempts int) {
+	if servedBy, ok := ctx.Value(servedByKey{}).(*ServedBy); ok {
+		*servedBy = ServedBy{Route: route, Agent: target.Agent, Model: target.Model, Attempts: attempts}
+	}
+	r.logger.Info("Model route served request",
+		zap.String("route", route),
+		zap.String("agent", target.Agent),
+		zap.String("model", target.Model),
+		zap.Int("attempts", attempts),
+	)
+}
+
+// recordFailure 记录目标失败，调用方取消时不计入熔断
+func (r *ModelRouter) recordFailure(ctx context.Context, route string, target config.ModelTargetConfig, err error) {
+	if ctx.Err() != nil {
+		return
+	}
+	r.breaker.RecordFailure(targetKey(target))
+	r.logger.Warn("Model route target failed, trying next target",
+		zap.String("route", route),
+		zap.String("agent", target.Agent),
+		zap.String("model", target.Model),
+		zap.Error(err),
+	)
+}
+
+// invoke 依次尝试各目标直到成功
+func invoke[T any](ctx context.Context, r *ModelRouter, name string, call func(context.Context, ai_agent.AIAgent, string) (T, error)) (T, error) {
+	var zero T
+	route, ok := r.Route(name)
+	if !ok {
+		return zero, fmt.Errorf("model route not found: %s", name)
+	}
+
+	attempts := 0
+	lastErr := errors.New("all targets are unavailable")
+	for _, target := range plan(route) {
+		if !r.breaker.AllowRequest(targetKey(target)) {
+			continue
+		}
+		agent, err := r.agentManager.ResolveAIAgent(target.Agent)
+		if err != nil {
+			lastErr = err
+			r.recordFailure(ctx, name, target, err)
+			continue
+		}
+
+		attempts++
+		result, err := func() (T, error) {
+			attemptCtx := ctx
+			if route.Timeout > 0 {
+				var cancel context.CancelFunc
+				attemptCtx, cancel = context.WithTimeout(ctx, time.Duration(route.Timeout)*time.Second)
+				defer cancel()
+			}
+			return call(attemptCtx, agent, target.Model)
+		}()
+		if err == nil {
+			r.breaker.RecordSuccess(targetKey(target))
+			r.markServed(ctx, name, target, attempts)
+			return result, nil
+		}
+
+		// 调用方取消时不再尝试其他目标
+		if ctx.Err() != nil {
+			return zero, ctx.Err()
+		}
+		lastErr = err
+		r.recordFailure(ctx, name, target, err)
+	}
+	return zero, fmt.Errorf("all targets of model route %s failed: %w", name, lastErr)
+}
+
+// invokeStream 依次尝试各目标直到收到首个数据块，之后不再切换目标
+func invokeStream[T any](ctx context.Context, r *ModelRouter, name string, open func(context.Context, ai_agent.AIAgent, string) (<-chan T, <-chan error)) (<-chan T, <-chan error) {
+	respChan := make(chan T)
+	errChan := make(chan error, 1)
+
+	go func() {
+		defer close(respChan)
+		defer close(errChan)
+
+		route, ok := r.Route(name)
+		if !ok {
+			errChan <- fmt.Errorf("model route not found: %s", name)
+			return
+		}
+
+		attempts := 0
+		lastErr := errors.New("all targets are unavailable")
+		for _, target := range plan(route) {
+			if !r.breaker.AllowRequest(targetKey(target)) {
+				continue
+			}
+			agent, err := r.agentManager.ResolveAIAgent(target.Agent)
+			if err != nil {
+				lastErr = err
+				r.recordFailure(ctx, name, target, err)
+				continue
+			}
+
+			// 超时只作用于首个数据块
+			attempts++
+			attemptCtx, cancel := context.WithCancel(ctx)
+			var timer *time.Timer
+			if route.Timeout > 0 {
+				timer = time.AfterFunc(time.Duration(route.Timeout)*time.Second, cancel)
+			}
+			chunks, errs := open(attemptCtx, agent, target.Model)
+			first, received := <-chunks
+			timedOut := timer != nil && !timer.Stop()
+
+			if !received {
+				err := <-errs
+				cancel()
+				if err == nil {
+					r.breaker.RecordSuccess(targetKey(target))
+					r.markServed(ctx, name, target, attempts)
+					return
+				}
+				if ctx.Err() != nil {
+					errChan <- ctx.Err()
+					return
+				}
+				if timedOut {
+					err = fmt.Errorf("no response within %ds: %w", route.Timeout, err)
+				}
+				lastErr = err
+				r.recordFailure(ctx, name, target, err)
+				continue
+			}
+
+			r.breaker.RecordSuccess(targetKey(target))
+			r.markServed(ctx, name, target, attempts)
+			forward(ctx, respChan, first, chunks)
+			err = <-errs
+			cancel()
+			if err != nil {
+				errChan <- err
+			}
+			return
+		}
+		errChan <- fmt.Errorf("all targets of model route %s failed: %w", name, lastErr)
+	}()
+
+	return respChan, errChan
+}
+
+// forward 转发流式数据块，调用方取消后继续读取直到上游关闭
+func forward[T any](ctx context.Context, out chan<- T, first T, chunks <-chan T) {
+	value, ok := first, true
+	for ok {
+		select {
+		case out <- value:
+		case <-ctx.Done():
+			for range chunks {
+			}
+			return
+		}
+		value, ok = <-chunks
+	}
+}
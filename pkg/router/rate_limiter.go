@@ -6,17 +6,26 @@ import (
 
 	"go.uber.org/zap"
 
+	"kai/kaigate/pkg/auth"
 	"kai/kaigate/pkg/log"
 )
 
+const (
+	// defaultLimiterIdleTimeout 限流控制器空闲超过该时间后被删除，再次请求时重新创建
+	defaultLimiterIdleTimeout = 10 * time.Minute
+	// limiterSweepInterval 检查空闲限流控制器的最小间隔
+	limiterSweepInterval = time.Minute
+)
+
 // RateLimiter 限流控制器
 type RateLimiter struct {
 	rate       int           // 每秒允许的请求数
 	burst      int           // 最大突发请求数
 	mutex      sync.Mutex    // 互斥锁
 	tokens     float64       // 当前可用令牌数
-	lastRefill time.Time     // 上次填充令牌的时间
+	lastRefill time.Time     // 上次填充令牌的时间，即最近一次请求的时间
 	enabled    bool          // 是否启用
+	pinned     bool          // 手动配置过的限流控制器不会因空闲被删除
 }
 
 // NewRateLimiter 创建限流控制器
@@ -114,12 +123,29 @@ func (rl *RateLimiter) GetState() map[string]interface{} {
 	}
 }
 
+// idle 限流控制器是否空闲超过指定时间，手动配置过的限流控制器不视为空闲
+func (rl *RateLimiter) idle(now time.Time, timeout time.Duration) bool {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+	return !rl.pinned && now.Sub(rl.lastRefill) > timeout
+}
+
+// pin 标记限流控制器为手动配置
+func (rl *RateLimiter) pin() {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+	rl.pinned = true
+}
+
 // RateLimitManager 限流管理器
+// 限流控制器按调用方身份或客户端IP创建，空闲超过idleTimeout后在创建新控制器时顺带删除，避免随调用方数量无限增长
 type RateLimitManager struct {
 	rateLimiters map[string]*RateLimiter
 	mutex        sync.RWMutex
 	defaultRate  int
 	defaultBurst int
+	idleTimeout  time.Duration
+	lastSweep    time.Time
 }
 
 // NewRateLimitManager 创建限流管理器
@@ -128,6 +154,54 @@ func NewRateLimitManager(defaultRate, defaultBurst int) *RateLimitManager {
 		rateLimiters: make(map[string]*RateLimiter),
 		defaultRate:  defaultRate,
 		defaultBurst: defaultBurst,
+		idleTimeout:  defaultLimiterIdleTimeout,
+		lastSweep:    time.Now(),
+	}
+}
+
+// SetIdleTimeout 设置空闲限流控制器的删除时间
+// 空闲时间不短于令牌填满所需的时间时，删除后重新创建的控制器与原控制器状态相同
+func (rlm *RateLimitManager) SetIdleTimeout(timeout time.Duration) {
+	rlm.mutex.Lock()
+	defer rlm.mutex.Unlock()
+	if timeout > 0 {
+		rlm.idleTimeout = timeout
+	}
+}
+
+// LimiterFor 获取调用方的限流控制器
+// 已认证调用方按身份限流，匿名调用方按客户端IP限流；身份配置了速率时使用该速率
+func (rlm *RateLimitManager) LimiterFor(identity *auth.Identity, clientIP string) *RateLimiter {
+	key := "ip:" + clientIP
+	if identity != nil && !identity.IsAnonymous() {
+		key = identity.ID
+	}
+	if identity != nil && identity.RateLimit > 0 {
+		return rlm.GetRateLimiterWithRate(key, identity.RateLimit, identity.RateLimit)
+	}
+	return rlm.GetRateLimiter(key)
+}
+
+// sweep 删除空闲的限流控制器，距上次检查不足limiterSweepInterval时跳过，调用方需持有写锁
+func (rlm *RateLimitManager) sweep() {
+	now := time.Now()
+	if now.Sub(rlm.lastSweep) < limiterSweepInterval {
+		return
+	}
+	rlm.lastSweep = now
+
+	removed := 0
+	for key, rl := range rlm.rateLimiters {
+		if rl.idle(now, rlm.idleTimeout) {
+			delete(rlm.rateLimiters, key)
+			removed++
+		}
+	}
+	if removed > 0 {
+		log.GlobalLogger.Debug("Idle rate limiters removed",
+			zap.Int("removed", removed),
+			zap.Int("remaining", len(rlm.rateLimiters)),
+		)
 	}
 }
 
@@ -153,6 +227,7 @@ func (rlm *RateLimitManager) GetRateLimiter(key string) *RateLimiter {
 	}
 
 	// 创建新的限流控制器
+	rlm.sweep()
 	rl = NewRateLimiter(rlm.defaultRate, rlm.defaultBurst)
 	rlm.rateLimiters[key] = rl
 
@@ -165,6 +240,35 @@ func (rlm *RateLimitManager) GetRateLimiter(key string) *RateLimiter {
 	return rl
 }
 
+// GetRateLimiterWithRate 获取或创建指定速率的限流控制器
+// 已存在的限流控制器速率与指定值不一致时会被更新
+func (rlm *RateLimitManager) GetRateLimiterWithRate(key string, rate, burst int) *RateLimiter {
+	rlm.mutex.Lock()
+	defer rlm.mutex.Unlock()
+
+	rl, ok := rlm.rateLimiters[key]
+	if !ok {
+		rlm.sweep()
+		rl = NewRateLimiter(rate, burst)
+		rlm.rateLimiters[key] = rl
+		log.GlobalLogger.Info("Rate limiter created",
+			zap.String("key", key),
+			zap.Int("rate", rate),
+			zap.Int("burst", burst),
+		)
+		return rl
+	}
+
+	rl.mutex.Lock()
+	changed := rl.rate != rate || rl.burst != burst
+	rl.mutex.Unlock()
+	if changed {
+		rl.SetRate(rate)
+		rl.SetBurst(burst)
+	}
+	return rl
+}
+
 // RemoveRateLimiter 移除限流控制器
 func (rlm *RateLimitManager) RemoveRateLimiter(key string) {
 	rlm.mutex.Lock()
@@ -179,6 +283,7 @@ func (rlm *RateLimitManager) UpdateRateLimiter(key string, rate, burst int) {
 	// 获取限流控制器
 	rl := rlm.GetRateLimiter(key)
 	// 更新配置
+	rl.pin()
 	rl.SetRate(rate)
 	rl.SetBurst(burst)
 
@@ -194,6 +299,7 @@ func (rlm *RateLimitManager) EnableRateLimiter(key string) {
 	// 获取限流控制器
 	rl := rlm.GetRateLimiter(key)
 	// 启用限流
+	rl.pin()
 	rl.Enable()
 
 	log.GlobalLogger.Info("Rate limiter enabled", zap.String("key", key))
@@ -204,6 +310,7 @@ func (rlm *RateLimitManager) DisableRateLimiter(key string) {
 	// 获取限流控制器
 	rl := rlm.GetRateLimiter(key)
 	// 禁用限流
+	rl.pin()
 	rl.Disable()
 
 	log.GlobalLogger.Info("Rate limiter disabled", zap.String("key", key))
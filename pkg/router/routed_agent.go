@@ -0,0 +1,145 @@
+package router
+
+import (
+	"context"
+	"errors"
+
+	"kai/kaigate/pkg/service/ai_agent"
+)
+
+// RoutedAgent 逻辑模型对应的AI Agent
+// 以路由名注册到AI Agent管理器，请求按路由配置转发给各目标，目标配置了模型时替换请求中的模型名
+type RoutedAgent struct {
+	*ai_agent.BaseAIAgent
+	router *ModelRouter
+}
+
+// ModelRouteFactory RoutedAgent的工厂实现
+type ModelRouteFactory struct {
+	name   string
+	router *ModelRouter
+}
+
+// NewModelRouteFactory 创建ModelRouteFactory实例
+func NewModelRouteFactory(name string, router *ModelRouter) *ModelRouteFactory {
+	return &ModelRouteFactory{name: name, router: router}
+}
+
+// Create 实现AIAgentFactory接口的Create方法
+func (f *ModelRouteFactory) Create() (ai_agent.AIAgent, error) {
+	return &RoutedAgent{
+		BaseAIAgent: ai_agent.NewBaseAIAgent(f.name, "1.0.0"),
+		router:      f.router,
+	}, nil
+}
+
+// Name 实现AIAgentFactory接口的Name方法
+func (f *ModelRouteFactory) Name() string {
+	return f.name
+}
+
+// targetModel 返回目标使用的模型名，目标未配置模型时沿用请求中的模型名
+func targetModel(model, requested string) string {
+	if model == "" {
+		return requested
+	}
+	return model
+}
+
+// Chat 实现AIAgent接口的Chat方法
+func (a *RoutedAgent) Chat(ctx context.Context, req ai_agent.ChatRequest) (*ai_agent.ChatResponse, error) {
+	return invoke(ctx, a.router, a.Name(), func(ctx context.Context, agent ai_agent.AIAgent, model string) (*ai_agent.ChatResponse, error) {
+		routed := req
+		routed.Model = targetModel(model, req.Model)
+		return agent.Chat(ctx, routed)
+	})
+}
+
+// ChatStream 实现AIAgent接口的ChatStream方法
+func (a *RoutedAgent) ChatStream(ctx context.Context, req ai_agent.ChatRequest) (<-chan *ai_agent.ChatResponse, <-chan error) {
+	return invokeStream(ctx, a.router, a.Name(), func(ctx context.Context, agent ai_agent.AIAgent, model string) (<-chan *ai_agent.ChatResponse, <-chan error) {
+		routed := req
+		routed.Model = targetModel(model, req.Model)
+		return agent.ChatStream(ctx, routed)
+	})
+}
+
+// Completion 实现AIAgent接口的Completion方法
+func (a *RoutedAgent) Completion(ctx context.Context, req ai_agent.CompletionRequest) (*ai_agent.CompletionResponse, error) {
+	return invoke(ctx, a.router, a.Name(), func(ctx context.Context, agent ai_agent.AIAgent, model string) (*ai_agent.CompletionResponse, error) {
+		routed := req
+		routed.Model = targetModel(model, req.Model)
+		return agent.Completion(ctx, routed)
+	})
+}
+
+// CompletionStream 实现AIAgent接口的CompletionStream方法
+func (a *RoutedAgent) CompletionStream(ctx context.Context, req ai_agent.CompletionRequest) (<-chan *ai_agent.CompletionResponse, <-chan error) {
+	return invokeStream(ctx, a.router, a.Name(), func(ctx context.Context, agent ai_agent.AIAgent, model string) (<-chan *ai_agent.CompletionResponse, <-chan error) {
+		routed := req
+		routed.Model = targetModel(model, req.Model)
+		return agent.CompletionStream(ctx, routed)
+	})
+}
+
+// Embedding 实现AIAgent接口的Embedding方法
+func (a *RoutedAgent) Embedding(ctx context.Context, req ai_agent.EmbeddingRequest) (*ai_agent.EmbeddingResponse, error) {
+	return invoke(ctx, a.router, a.Name(), func(ctx context.Context, agent ai_agent.AIAgent, model string) (*ai_agent.EmbeddingResponse, error) {
+		routed := req
+		routed.Model = targetModel(model, req.Model)
+		return agent.Embedding(ctx, routed)
+	})
+}
+
+// BatchEmbedding 实现AIAgent接口的BatchEmbedding方法，整批请求由同一个目标处理
+func (a *RoutedAgent) BatchEmbedding(ctx context.Context, reqs []ai_agent.EmbeddingRequest) ([]*ai_agent.EmbeddingResponse, error) {
+	return invoke(ctx, a.router, a.Name(), func(ctx context.Context, agent ai_agent.AIAgent, model string) ([]*ai_agent.EmbeddingResponse, error) {
+		routed := make([]ai_agent.EmbeddingRequest, len(reqs))
+		for i, req := range reqs {
+			routed[i] = req
+			routed[i].Model = targetModel(model, req.Model)
+		}
+		return agent.BatchEmbedding(ctx, routed)
+	})
+}
+
+// ListModels 实现AIAgent接口的ListModels方法
+// 逻辑模型本身即是模型名，不再展开各目标的模型
+func (a *RoutedAgent) ListModels(ctx context.Context) ([]string, error) {
+	return []string{}, nil
+}
+
+// GetModel 实现AIAgent接口的GetModel方法，返回路由配置
+func (a *RoutedAgent) GetModel(ctx context.Context, modelName string) (map[string]interface{}, error) {
+	route, ok := a.router.Route(a.Name())
+	if !ok {
+		return nil, errors.New("model route not found: " + a.Name())
+	}
+	return map[string]interface{}{
+		"id":      route.Name,
+		"object":  "model",
+		"timeout": route.Timeout,
+		"targets": route.Targets,
+	}, nil
+}
+
+// HealthCheck 实现AIAgent接口的HealthCheck方法，任一目标健康即视为健康
+func (a *RoutedAgent) HealthCheck() error {
+	route, ok := a.router.Route(a.Name())
+	if !ok {
+		return errors.New("model route not found: " + a.Name())
+	}
+
+	var lastErr error
+	for _, target := range route.Targets {
+		agent, err := a.router.agentManager.ResolveAIAgent(target.Agent)
+		if err == nil {
+			err = agent.HealthCheck()
+		}
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+	}
+	return lastErr
+}
@@ -0,0 +1,230 @@
+package router
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"kai/kaigate/pkg/config"
+	"kai/kaigate/pkg/log"
+	"kai/kaigate/pkg/tlsutil"
+)
+
+// transportCheckInterval 检查上游证书文件是否变更的最小间隔
+const transportCheckInterval = 5 * time.Second
+
+// TransportManager 上游连接池管理器
+// 相同目标和相同配置的代理路由共享同一个Transport，复用连接；
+// 证书、私钥或CA文件变更后重新创建Transport，配置重载时清空缓存，被替换的Transport关闭空闲连接
+type TransportManager struct {
+	entries map[string]*transportEntry
+	mutex   sync.RWMutex
+	logger  log.Logger
+}
+
+// transportEntry 缓存的Transport及创建时证书文件的修改时间
+type transportEntry struct {
+	transport *http.Transport
+	files     map[string]time.Time
+	checkedAt time.Time
+}
+
+// upstreamTransport 每次请求时从管理器获取当前Transport，证书轮换或配置重载后新请求使用新的Transport
+type upstreamTransport struct {
+	manager         *TransportManager
+	target          *url.URL
+	tlsConfig       config.UpstreamTLSConfig
+	transportConfig config.TransportConfig
+}
+
+// NewTransportManager 创建上游连接池管理器
+func NewTransportManager() *TransportManager {
+	return &TransportManager{
+		entries: make(map[string]*transportEntry),
+		logger:  log.GlobalLogger,
+	}
+}
+
+// RoundTripper 返回访问目标的RoundTripper，创建时校验配置，之后每次请求使用管理器中当前的Transport
+func (m *TransportManager) RoundTripper(target *url.URL, tlsConfig config.UpstreamTLSConfig, transportConfig config.TransportConfig) (http.RoundTripper, error) {
+	if _, err := m.GetTransport(target, tlsConfig, transportConfig); err != nil {
+		return nil, err
+	}
+	return &upstreamTransport{manager: m, target: target, tlsConfig: tlsConfig, transportConfig: transportConfig}, nil
+}
+
+// RoundTrip 实现http.RoundTripper接口
+func (t *upstreamTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport, err := t.manager.GetTransport(t.target, t.tlsConfig, t.transportConfig)
+	if err != nil {
+		return nil, err
+	}
+	return transport.RoundTrip(req)
+}
+
+// GetTransport 获取访问目标的Transport，不存在或证书文件已变更时创建
+func (m *TransportManager) GetTransport(target *url.URL, tlsConfig config.UpstreamTLSConfig, transportConfig config.TransportConfig) (*http.Transport, error) {
+	// 按目标地址和配置区分连接池，配置不同的路由不会共享连接
+	key := fmt.Sprintf("%s://%s|%+v|%+v", target.Scheme, target.Host, tlsConfig, transportConfig)
+
+	m.mutex.RLock()
+	entry, exists := m.entries[key]
+	if exists && time.Since(entry.checkedAt) < transportCheckInterval {
+		m.mutex.RUnlock()
+		return entry.transport, nil
+	}
+	m.mutex.RUnlock()
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	// 双重检查，避免并发创建
+	entry, exists = m.entries[key]
+	if exists {
+		if time.Since(entry.checkedAt) < transportCheckInterval {
+			return entry.transport, nil
+		}
+		entry.checkedAt = time.Now()
+		files := tlsFileModTimes(tlsConfig)
+		if sameModTimes(entry.files, files) {
+			return entry.transport, nil
+		}
+
+		transport, err := newTransport(target, tlsConfig, transportConfig)
+		if err != nil {
+			// 新证书无效时继续使用原有Transport
+			m.logger.Error("Failed to reload upstream transport, keeping previous certificates",
+				zap.String("target", target.Scheme+"://"+target.Host),
+				zap.Error(err),
+			)
+			return entry.transport, nil
+		}
+		entry.transport.CloseIdleConnections()
+		entry.transport = transport
+		entry.files = files
+		m.logger.Info("Reloaded upstream transport after certificate change", zap.String("target", target.Scheme+"://"+target.Host))
+		return transport, nil
+	}
+
+	files := tlsFileModTimes(tlsConfig)
+	transport, err := newTransport(target, tlsConfig, transportConfig)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig.InsecureSkipVerify {
+		m.logger.Warn("Upstream TLS verification disabled", zap.String("target", target.Host))
+	}
+
+	m.entries[key] = &transportEntry{transport: transport, files: files, checkedAt: time.Now()}
+	m.logger.Info("Created upstream transport", zap.String("target", target.Scheme+"://"+target.Host))
+	return transport, nil
+}
+
+// Reload 清空缓存的Transport并关闭其空闲连接，之后的请求按当前配置和证书重新创建
+// 进行中的请求继续使用原有连接直到完成
+func (m *TransportManager) Reload() {
+	m.mutex.Lock()
+	entries := m.entries
+	m.entries = make(map[string]*transportEntry)
+	m.mutex.Unlock()
+
+	for _, entry := range entries {
+		entry.transport.CloseIdleConnections()
+	}
+	m.logger.Info("Upstream transports reset", zap.Int("closed", len(entries)))
+}
+
+// CloseIdleConnections 关闭所有空闲连接
+func (m *TransportManager) CloseIdleConnections() {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	for _, entry := range m.entries {
+		entry.transport.CloseIdleConnections()
+	}
+}
+
+// tlsFileModTimes 获取上游TLS配置中证书相关文件的修改时间
+func tlsFileModTimes(tlsConfig config.UpstreamTLSConfig) map[string]time.Time {
+	modTimes := make(map[string]time.Time)
+	for _, file := range []string{tlsConfig.CAFile, tlsConfig.CertFile, tlsConfig.KeyFile} {
+		if file == "" {
+			continue
+		}
+		if info, err := os.Stat(file); err == nil {
+			modTimes[file] = info.ModTime()
+		}
+	}
+	return modTimes
+}
+
+// sameModTimes 比较两组文件修改时间是否一致
+func sameModTimes(a, b map[string]time.Time) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for file, modTime := range a {
+		if !modTime.Equal(b[file]) {
+			return false
+		}
+	}
+	return true
+}
+
+// newTransport 根据配置创建Transport
+func newTransport(target *url.URL, tlsConfig config.UpstreamTLSConfig, transportConfig config.TransportConfig) (*http.Transport, error) {
+	maxIdleConns := transportConfig.MaxIdleConns
+	if maxIdleConns <= 0 {
+		maxIdleConns = config.DefaultUpstreamMaxIdleConns
+	}
+	maxIdleConnsPerHost := transportConfig.MaxIdleConnsPerHost
+	if maxIdleConnsPerHost <= 0 {
+		maxIdleConnsPerHost = config.DefaultUpstreamMaxIdleConnsPerHost
+	}
+	idleConnTimeout := transportConfig.IdleConnTimeout
+	if idleConnTimeout <= 0 {
+		idleConnTimeout = config.DefaultUpstreamIdleConnTimeout
+	}
+	dialTimeout := transportConfig.DialTimeout
+	if dialTimeout <= 0 {
+		dialTimeout = config.DefaultUpstreamDialTimeout
+	}
+
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   time.Duration(dialTimeout) * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		MaxIdleConns:          maxIdleConns,
+		MaxIdleConnsPerHost:   maxIdleConnsPerHost,
+		MaxConnsPerHost:       transportConfig.MaxConnsPerHost,
+		IdleConnTimeout:       time.Duration(idleConnTimeout) * time.Second,
+		ResponseHeaderTimeout: time.Duration(transportConfig.ResponseHeaderTimeout) * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+		// 自定义TLSClientConfig后需要显式开启HTTP/2
+		ForceAttemptHTTP2: !transportConfig.DisableHTTP2,
+	}
+
+	if target.Scheme == "https" {
+		clientConfig, err := tlsutil.ClientConfig(tlsConfig)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = clientConfig
+	}
+	if transportConfig.DisableHTTP2 {
+		// 非nil的空映射会禁用HTTP/2
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+
+	return transport, nil
+}
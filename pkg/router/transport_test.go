@@ -0,0 +1,156 @@
+package router
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"kai/kaigate/pkg/config"
+	"kai/kaigate/pkg/log"
+)
+
+func TestMain(m *testing.M) {
+	if err := log.InitLogger("error", "json", "", false); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// writeCAFile 将证书写入CA文件，修改时间设为modTime
+func writeCAFile(t *testing.T, file string, der []byte, modTime time.Time) {
+	t.Helper()
+	content := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	if err := os.WriteFile(file, content, 0o600); err != nil {
+		t.Fatalf("write ca file: %v", err)
+	}
+	if err := os.Chtimes(file, modTime, modTime); err != nil {
+		t.Fatalf("set ca file time: %v", err)
+	}
+}
+
+// newCertificate 生成与上游证书无关的自签名证书
+func newCertificate(t *testing.T) []byte {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "other-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+// expireChecks 让所有缓存的Transport在下次获取时检查证书文件
+func expireChecks(m *TransportManager) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for _, entry := range m.entries {
+		entry.checkedAt = time.Time{}
+	}
+}
+
+func get(t *testing.T, rt http.RoundTripper, rawURL string) error {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodGet, rawURL, nil)
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func TestTransportReloadsRotatedCA(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	upstream := httptest.NewTLSServer(handler)
+	defer upstream.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	base := time.Now().Add(-time.Hour)
+	writeCAFile(t, caFile, upstream.Certificate().Raw, base)
+
+	manager := NewTransportManager()
+	target, _ := url.Parse(upstream.URL)
+	tlsConfig := config.UpstreamTLSConfig{CAFile: caFile}
+	rt, err := manager.RoundTripper(target, tlsConfig, config.TransportConfig{})
+	if err != nil {
+		t.Fatalf("RoundTripper: %v", err)
+	}
+	first, _ := manager.GetTransport(target, tlsConfig, config.TransportConfig{})
+	if err := get(t, rt, upstream.URL); err != nil {
+		t.Fatalf("request with initial CA: %v", err)
+	}
+
+	// 文件未变更时继续使用同一个Transport
+	expireChecks(manager)
+	if transport, _ := manager.GetTransport(target, tlsConfig, config.TransportConfig{}); transport != first {
+		t.Fatal("transport replaced without certificate change")
+	}
+
+	// 新文件无效时保留原有Transport
+	if err := os.WriteFile(caFile, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(caFile, base.Add(time.Minute), base.Add(time.Minute))
+	expireChecks(manager)
+	if err := get(t, rt, upstream.URL); err != nil {
+		t.Fatalf("request after invalid CA update: %v", err)
+	}
+
+	// CA轮换为其他证书后，新请求使用新的CA，不再信任原有上游
+	writeCAFile(t, caFile, newCertificate(t), base.Add(2*time.Minute))
+	expireChecks(manager)
+	if err := get(t, rt, upstream.URL); err == nil {
+		t.Fatal("request succeeded with a rotated CA that does not trust the upstream")
+	}
+	if transport, _ := manager.GetTransport(target, tlsConfig, config.TransportConfig{}); transport == first {
+		t.Fatal("transport was not replaced after CA rotation")
+	}
+}
+
+func TestTransportManagerReload(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer upstream.Close()
+
+	manager := NewTransportManager()
+	target, _ := url.Parse(upstream.URL)
+	rt, err := manager.RoundTripper(target, config.UpstreamTLSConfig{}, config.TransportConfig{})
+	if err != nil {
+		t.Fatalf("RoundTripper: %v", err)
+	}
+	first, _ := manager.GetTransport(target, config.UpstreamTLSConfig{}, config.TransportConfig{})
+
+	manager.Reload()
+	if len(manager.entries) != 0 {
+		t.Fatalf("%d transports left after reload", len(manager.entries))
+	}
+	// 已注册的代理继续可用，并使用重新创建的Transport
+	if err := get(t, rt, upstream.URL); err != nil {
+		t.Fatalf("request after reload: %v", err)
+	}
+	if transport, _ := manager.GetTransport(target, config.UpstreamTLSConfig{}, config.TransportConfig{}); transport == first {
+		t.Error("transport was not recreated after reload")
+	}
+}
@@ -0,0 +1,615 @@
+package ai_agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Anthropic Messages API的默认配置
+const (
+	DefaultAnthropicBaseURL = "https://api.anthropic.com"
+	DefaultAnthropicVersion = "2023-06-01"
+	defaultAnthropicTokens  = 1024
+)
+
+// AnthropicAgent 访问Anthropic Messages API的AI Agent
+// 将统一的ChatRequest/ChatResponse转换为Messages API的请求、响应和流式事件
+// 支持的配置项：
+//   - base_url: 接口地址，默认https://api.anthropic.com
+//   - api_key: API密钥，支持"${ENV}"格式从环境变量读取
+//   - version: anthropic-version请求头，默认2023-06-01
+//   - max_tokens: 请求未指定max_tokens时使用的默认值（该接口要求必填）
+//   - timeout: 非流式请求超时时间(秒)
+//   - default_model、model_map、models、headers: 同OpenAIAgent
+type AnthropicAgent struct {
+	*BaseAIAgent
+	client    *httpClient
+	maxTokens int
+	models    []string
+}
+
+// AnthropicAgentFactory AnthropicAgent的工厂实现
+type AnthropicAgentFactory struct {
+	name   string
+	config map[string]interface{}
+}
+
+// anthropicMessage Messages API的消息
+type anthropicMessage struct {
+	Role    string           `json:"role"`
+	Content []anthropicBlock `json:"content"`
+}
+
+// anthropicBlock Messages API的内容块，包括text、image、tool_use和tool_result
+type anthropicBlock struct {
+	Type      string                `json:"type"`
+	Text      string                `json:"text,omitempty"`
+	Source    *anthropicImageSource `json:"source,omitempty"`
+	ID        string                `json:"id,omitempty"`
+	Name      string                `json:"name,omitempty"`
+	Input     json.RawMessage       `json:"input,omitempty"`
+	ToolUseID string                `json:"tool_use_id,omitempty"`
+	Content   string                `json:"content,omitempty"`
+}
+
+// anthropicImageSource 图片内容块的来源，base64内联或URL
+type anthropicImageSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+// anthropicTool Messages API的工具定义
+type anthropicTool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	InputSchema map[string]interface{} `json:"input_schema"`
+}
+
+// anthropicUsage Messages API的token用量
+type anthropicUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// anthropicResponse Messages API的响应
+type anthropicResponse struct {
+	ID         string           `json:"id"`
+	Type       string           `json:"type"`
+	Role       string           `json:"role"`
+	Model      string           `json:"model"`
+	Content    []anthropicBlock `json:"content"`
+	StopReason string           `json:"stop_reason"`
+	Usage      anthropicUsage   `json:"usage"`
+}
+
+// anthropicStreamEvent Messages API的流式事件
+type anthropicStreamEvent struct {
+	Type         string             `json:"type"`
+	Message      *anthropicResponse `json:"message"`
+	Index        int                `json:"index"`
+	ContentBlock *anthropicBlock    `json:"content_block"`
+	Delta        struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		PartialJSON string `json:"partial_json"`
+		StopReason  string `json:"stop_reason"`
+	} `json:"delta"`
+	Usage *anthropicUsage `json:"usage"`
+	Error *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// NewAnthropicAgent 创建AnthropicAgent实例
+// config作为默认配置，Init时传入的配置项会覆盖同名配置
+func NewAnthropicAgent(name string, config map[string]interface{}) *AnthropicAgent {
+	agent := &AnthropicAgent{
+		BaseAIAgent: NewBaseAIAgent(name, "1.0.0"),
+	}
+	for key, value := range config {
+		agent.config[key] = value
+	}
+	return agent
+}
+
+// NewAnthropicAgentFactory 创建AnthropicAgentFactory实例
+func NewAnthropicAgentFactory(name string, config map[string]interface{}) *AnthropicAgentFactory {
+	return &AnthropicAgentFactory{name: name, config: config}
+}
+
+// Init 初始化AnthropicAgent
+func (a *AnthropicAgent) Init(config map[string]interface{}) error {
+	merged := make(map[string]interface{}, len(a.config)+len(config))
+	for key, value := range a.config {
+		merged[key] = value
+	}
+	for key, value := range config {
+		merged[key] = value
+	}
+	if err := a.BaseAIAgent.Init(merged); err != nil {
+		return err
+	}
+
+	baseURL := a.ConfigString("base_url", DefaultAnthropicBaseURL)
+	if _, err := url.Parse(baseURL); err != nil {
+		return errors.New("invalid base_url: " + baseURL)
+	}
+
+	headers := a.ConfigStringMap("headers")
+	headers["anthropic-version"] = a.ConfigString("version", DefaultAnthropicVersion)
+	if apiKey := a.ConfigString("api_key", ""); apiKey != "" {
+		headers["x-api-key"] = apiKey
+	}
+
+	timeout := time.Duration(a.ConfigInt("timeout", defaultAgentTimeout)) * time.Second
+	a.client = newHTTPClient(baseURL, headers, timeout)
+	a.maxTokens = a.ConfigInt("max_tokens", defaultAnthropicTokens)
+	a.models = a.ConfigStrings("models")
+
+	a.GetLogger().Info("Anthropic agent initialized",
+		zap.String("name", a.Name()),
+		zap.String("base_url", baseURL),
+	)
+	return nil
+}
+
+// messagesBody 构造Messages API请求体
+// system消息合并为顶层system参数，tool消息转换为用户消息中的tool_result块，
+// 相邻的同角色消息合并为一条，以满足接口对角色交替的要求
+func (a *AnthropicAgent) messagesBody(req ChatRequest, stream bool) (map[string]interface{}, error) {
+	systemPrompts := []string{}
+	messages := make([]anthropicMessage, 0, len(req.Messages))
+	for _, message := range req.Messages {
+		if message.Role == "system" {
+			systemPrompts = append(systemPrompts, message.Content)
+			continue
+		}
+
+		role, blocks, err := anthropicBlocks(message)
+		if err != nil {
+			return nil, err
+		}
+		if len(blocks) == 0 {
+			continue
+		}
+		if last := len(messages) - 1; last >= 0 && messages[last].Role == role {
+			messages[last].Content = append(messages[last].Content, blocks...)
+			continue
+		}
+		messages = append(messages, anthropicMessage{Role: role, Content: blocks})
+	}
+
+	maxTokens := req.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = a.maxTokens
+	}
+
+	body := map[string]interface{}{
+		"model":      a.MapModel(req.Model),
+		"messages":   messages,
+		"max_tokens": maxTokens,
+	}
+	if len(systemPrompts) > 0 {
+		body["system"] = strings.Join(systemPrompts, "\n\n")
+	}
+	if req.Temperature > 0 {
+		body["temperature"] = req.Temperature
+	}
+	if req.TopP > 0 {
+		body["top_p"] = req.TopP
+	}
+	if len(req.Stop) > 0 {
+		body["stop_sequences"] = req.Stop
+	}
+	if len(req.Tools) > 0 {
+		tools := make([]anthropicTool, 0, len(req.Tools))
+		for _, tool := range req.Tools {
+			schema := tool.Function.Parameters
+			if schema == nil {
+				schema = map[string]interface{}{"type": "object"}
+			}
+			tools = append(tools, anthropicTool{
+				Name:        tool.Function.Name,
+				Description: tool.Function.Description,
+				InputSchema: schema,
+			})
+		}
+		body["tools"] = tools
+	}
+	if choice := anthropicToolChoice(req.ToolChoice); choice != nil {
+		body["tool_choice"] = choice
+	}
+	if stream {
+		body["stream"] = true
+	}
+	return body, nil
+}
+
+// Chat 实现聊天功能
+func (a *AnthropicAgent) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	body, err := a.messagesBody(req, false)
+	if err != nil {
+		return nil, err
+	}
+	var resp anthropicResponse
+	if err := a.client.postJSON(ctx, "/v1/messages", body, &resp); err != nil {
+		return nil, err
+	}
+
+	message := Message{Role: "assistant"}
+	text := strings.Builder{}
+	for _, block := range resp.Content {
+		switch block.Type {
+		case "text":
+			text.WriteString(block.Text)
+		case "tool_use":
+			message.ToolCalls = append(message.ToolCalls, ToolCall{
+				Index:    len(message.ToolCalls),
+				ID:       block.ID,
+				Type:     "function",
+				Function: FunctionCall{Name: block.Name, Arguments: string(block.Input)},
+			})
+		}
+	}
+	message.Content = text.String()
+
+	return &ChatResponse{
+		ID:      resp.ID,
+		Object:  "chat.completion",
+		Created: time.Now().Unix(),
+		Model:   resp.Model,
+		Choices: []ChatChoice{{
+			Index:        0,
+			Message:      message,
+			FinishReason: anthropicFinishReason(resp.StopReason),
+		}},
+		Usage: anthropicToUsage(resp.Usage),
+	}, nil
+}
+
+// ChatStream 实现流式聊天功能，将Messages API的事件流转换为统一的数据块
+func (a *AnthropicAgent) ChatStream(ctx context.Context, req ChatRequest) (<-chan *ChatResponse, <-chan error) {
+	respChan := make(chan *ChatResponse)
+	errChan := make(chan error, 1)
+
+	go func() {
+		defer close(respChan)
+		defer close(errChan)
+
+		body, err := a.messagesBody(req, true)
+		if err != nil {
+			errChan <- err
+			return
+		}
+		resp, err := a.client.postStream(ctx, "/v1/messages", body)
+		if err != nil {
+			errChan <- err
+			return
+		}
+		defer resp.Body.Close()
+
+		id, model, inputTokens := "", req.Model, 0
+		// 内容块序号到工具调用序号的映射
+		toolIndexes := map[int]int{}
+		created := time.Now().Unix()
+		chunk := func(message Message, finishReason string) *ChatResponse {
+			return &ChatResponse{
+				ID:      id,
+				Object:  "chat.completion.chunk",
+				Created: created,
+				Model:   model,
+				Choices: []ChatChoice{{Index: 0, Message: message, FinishReason: finishReason}},
+			}
+		}
+
+		err = readSSE(resp.Body, func(eventType, data string) error {
+			var event anthropicStreamEvent
+			if err := json.Unmarshal([]byte(data), &event); err != nil {
+				return errors.New("decode stream event failed: " + err.Error())
+			}
+
+			var response *ChatResponse
+			switch event.Type {
+			case "message_start":
+				if event.Message != nil {
+					id, model = event.Message.ID, event.Message.Model
+					inputTokens = event.Message.Usage.InputTokens
+				}
+				response = chunk(Message{Role: "assistant"}, "")
+			case "content_block_start":
+				if event.ContentBlock == nil || event.ContentBlock.Type != "tool_use" {
+					return nil
+				}
+				index := len(toolIndexes)
+				toolIndexes[event.Index] = index
+				response = chunk(Message{ToolCalls: []ToolCall{{
+					Index:    index,
+					ID:       event.ContentBlock.ID,
+					Type:     "function",
+					Function: FunctionCall{Name: event.ContentBlock.Name},
+				}}}, "")
+			case "content_block_delta":
+				switch event.Delta.Type {
+				case "text_delta":
+					response = chunk(Message{Content: event.Delta.Text}, "")
+				case "input_json_delta":
+					response = chunk(Message{ToolCalls: []ToolCall{{
+						Index:    toolIndexes[event.Index],
+						Function: FunctionCall{Arguments: event.Delta.PartialJSON},
+					}}}, "")
+				default:
+					return nil
+				}
+			case "message_delta":
+				response = chunk(Message{}, anthropicFinishReason(event.Delta.StopReason))
+				if event.Usage != nil {
+					// 输入token数只在message_start中返回
+					usage := *event.Usage
+					if usage.InputTokens == 0 {
+						usage.InputTokens = inputTokens
+					}
+					response.Usage = anthropicToUsage(usage)
+				}
+			case "message_stop":
+				return io.EOF
+			case "error":
+				if event.Error != nil {
+					return errors.New(event.Error.Type + ": " + event.Error.Message)
+				}
+				return errors.New("upstream stream error")
+			default:
+				// ping、content_block_stop等事件无需转发
+				return nil
+			}
+
+			if !sendStream(ctx, respChan, response) {
+				return ctx.Err()
+			}
+			return nil
+		})
+		if err == nil && ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		if err != nil {
+			errChan <- err
+		}
+	}()
+
+	return respChan, errChan
+}
+
+// Completion 实现文本生成功能，提示文本作为单条用户消息发送
+func (a *AnthropicAgent) Completion(ctx context.Context, req CompletionRequest) (*CompletionResponse, error) {
+	resp, err := a.Chat(ctx, completionToChat(req))
+	if err != nil {
+		return nil, err
+	}
+	return chatToCompletion(resp), nil
+}
+
+// CompletionStream 实现流式文本生成功能
+func (a *AnthropicAgent) CompletionStream(ctx context.Context, req CompletionRequest) (<-chan *CompletionResponse, <-chan error) {
+	chatChan, chatErrChan := a.ChatStream(ctx, completionToChat(req))
+	respChan := make(chan *CompletionResponse)
+	errChan := make(chan error, 1)
+
+	go func() {
+		defer close(respChan)
+		defer close(errChan)
+
+		for chunk := range chatChan {
+			if !sendStream(ctx, respChan, chatToCompletion(chunk)) {
+				// 继续读取直到上游关闭，避免阻塞上游goroutine
+				for range chatChan {
+				}
+				break
+			}
+		}
+		if err := <-chatErrChan; err != nil {
+			errChan <- err
+		}
+	}()
+
+	return respChan, errChan
+}
+
+// Embedding Messages API不提供嵌入向量
+func (a *AnthropicAgent) Embedding(ctx context.Context, req EmbeddingRequest) (*EmbeddingResponse, error) {
+	return nil, errors.New("Embedding not supported by Anthropic Messages API")
+}
+
+// BatchEmbedding Messages API不提供嵌入向量
+func (a *AnthropicAgent) BatchEmbedding(ctx context.Context, req []EmbeddingRequest) ([]*EmbeddingResponse, error) {
+	return nil, errors.New("BatchEmbedding not supported by Anthropic Messages API")
+}
+
+// ListModels 实现模型列表查询功能
+func (a *AnthropicAgent) ListModels(ctx context.Context) ([]string, error) {
+	if len(a.models) > 0 {
+		return a.models, nil
+	}
+
+	var resp struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := a.client.getJSON(ctx, "/v1/models", &resp); err != nil {
+		return nil, err
+	}
+
+	models := make([]string, 0, len(resp.Data))
+	for _, model := range resp.Data {
+		models = append(models, model.ID)
+	}
+	return models, nil
+}
+
+// GetModel 实现模型详情查询功能
+func (a *AnthropicAgent) GetModel(ctx context.Context, modelName string) (map[string]interface{}, error) {
+	var model map[string]interface{}
+	if err := a.client.getJSON(ctx, "/v1/models/"+url.PathEscape(a.MapModel(modelName)), &model); err != nil {
+		return nil, err
+	}
+	return model, nil
+}
+
+// HealthCheck 检查上游服务是否可用
+func (a *AnthropicAgent) HealthCheck() error {
+	if a.client == nil {
+		return errors.New("agent not initialized")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return a.client.getJSON(ctx, "/v1/models", nil)
+}
+
+// Create 实现AIAgentFactory接口的Create方法
+func (f *AnthropicAgentFactory) Create() (AIAgent, error) {
+	return NewAnthropicAgent(f.name, f.config), nil
+}
+
+// Name 实现AIAgentFactory接口的Name方法
+func (f *AnthropicAgentFactory) Name() string {
+	return f.name
+}
+
+// anthropicFinishReason 将stop_reason转换为统一的结束原因
+func anthropicFinishReason(stopReason string) string {
+	switch stopReason {
+	case "":
+		return ""
+	case "end_turn", "stop_sequence":
+		return "stop"
+	case "max_tokens":
+		return "length"
+	case "tool_use":
+		return "tool_calls"
+	case "refusal":
+		return "content_filter"
+	default:
+		return stopReason
+	}
+}
+
+// anthropicBlocks 将消息转换为Messages API的角色和内容块
+func anthropicBlocks(message Message) (string, []anthropicBlock, error) {
+	if message.Role == "tool" {
+		return "user", []anthropicBlock{{
+			Type:      "tool_result",
+			ToolUseID: message.ToolCallID,
+			Content:   message.Content,
+		}}, nil
+	}
+
+	role := "user"
+	if message.Role == "assistant" {
+		role = "assistant"
+	}
+
+	blocks := []anthropicBlock{}
+	for _, part := range message.ContentParts() {
+		switch part.Type {
+		case "text":
+			// 接口不接受空文本块
+			if part.Text != "" {
+				blocks = append(blocks, anthropicBlock{Type: "text", Text: part.Text})
+			}
+		case "image_url":
+			if part.ImageURL == nil {
+				continue
+			}
+			source := &anthropicImageSource{Type: "url", URL: part.ImageURL.URL}
+			if mediaType, data, ok := ParseDataURL(part.ImageURL.URL); ok {
+				source = &anthropicImageSource{Type: "base64", MediaType: mediaType, Data: data}
+			}
+			blocks = append(blocks, anthropicBlock{Type: "image", Source: source})
+		default:
+			return "", nil, errors.New("unsupported content part type: " + part.Type)
+		}
+	}
+
+	for _, call := range message.ToolCalls {
+		input := json.RawMessage(call.Function.Arguments)
+		if len(input) == 0 {
+			input = json.RawMessage("{}")
+		}
+		if !json.Valid(input) {
+			return "", nil, errors.New("invalid arguments for tool call " + call.ID)
+		}
+		blocks = append(blocks, anthropicBlock{Type: "tool_use", ID: call.ID, Name: call.Function.Name, Input: input})
+	}
+	return role, blocks, nil
+}
+
+// anthropicToolChoice 将tool_choice转换为Messages API格式
+// 支持"auto"、"none"、"required"和{"type":"function","function":{"name":...}}
+func anthropicToolChoice(choice interface{}) map[string]interface{} {
+	switch value := choice.(type) {
+	case string:
+		switch value {
+		case "auto", "none":
+			return map[string]interface{}{"type": value}
+		case "required":
+			return map[string]interface{}{"type": "any"}
+		}
+	case map[string]interface{}:
+		if function, ok := value["function"].(map[string]interface{}); ok {
+			if name, ok := function["name"].(string); ok && name != "" {
+				return map[string]interface{}{"type": "tool", "name": name}
+			}
+		}
+	}
+	return nil
+}
+
+// anthropicToUsage 将Messages API的用量转换为统一格式
+func anthropicToUsage(usage anthropicUsage) Usage {
+	return Usage{
+		PromptTokens:     usage.InputTokens,
+		CompletionTokens: usage.OutputTokens,
+		TotalTokens:      usage.InputTokens + usage.OutputTokens,
+	}
+}
+
+// completionToChat 将文本生成请求转换为单条用户消息的聊天请求
+func completionToChat(req CompletionRequest) ChatRequest {
+	return ChatRequest{
+		Model:       req.Model,
+		Messages:    []Message{{Role: "user", Content: req.Prompt}},
+		Temperature: req.Temperature,
+		MaxTokens:   req.MaxTokens,
+		Stream:      req.Stream,
+	}
+}
+
+// chatToCompletion 将聊天响应转换为文本生成响应
+func chatToCompletion(resp *ChatResponse) *CompletionResponse {
+	object := "text_completion"
+	if strings.HasSuffix(resp.Object, ".chunk") {
+		object = "text_completion.chunk"
+	}
+
+	completion := &CompletionResponse{
+		ID:      resp.ID,
+		Object:  object,
+		Created: resp.Created,
+		Model:   resp.Model,
+		Choices: make([]CompletionChoice, 0, len(resp.Choices)),
+		Usage:   resp.Usage,
+	}
+	for _, choice := range resp.Choices {
+		completion.Choices = append(completion.Choices, CompletionChoice{Index: choice.Index, Text: choice.Message.Content})
+	}
+	return completion
+}
@@ -3,6 +3,9 @@ package ai_agent
 import (
 	"context"
 	"errors"
+	"fmt"
+	"os"
+	"strconv"
 
 	"go.uber.org/zap"
 	"kai/kaigate/pkg/log"
@@ -79,4 +82,103 @@ func (b *BaseAIAgent) Embedding(ctx context.Context, req EmbeddingRequest) (*Emb
 // GetLogger 获取日志记录器
 func (b *BaseAIAgent) GetLogger() log.Logger {
 	return b.logger
-}
\ No newline at end of file
+}
+
+// GetConfig 获取初始化时传入的配置
+func (b *BaseAIAgent) GetConfig() map[string]interface{} {
+	return b.config
+}
+
+// ConfigString 获取字符串类型的配置项，不存在时返回默认值
+// 值为"${ENV}"格式时从环境变量读取，避免在配置文件中保存密钥
+func (b *BaseAIAgent) ConfigString(key, defaultValue string) string {
+	value, ok := b.config[key]
+	if !ok || value == nil {
+		return defaultValue
+	}
+	str := fmt.Sprint(value)
+	if len(str) > 3 && str[0] == '$' && str[1] == '{' && str[len(str)-1] == '}' {
+		str = os.Getenv(str[2 : len(str)-1])
+	}
+	if str == "" {
+		return defaultValue
+	}
+	return str
+}
+
+// ConfigInt 获取整数类型的配置项，不存在或格式错误时返回默认值
+func (b *BaseAIAgent) ConfigInt(key string, defaultValue int) int {
+	switch value := b.config[key].(type) {
+	case int:
+		return value
+	case int64:
+		return int(value)
+	case float64:
+		return int(value)
+	case string:
+		if parsed, err := strconv.Atoi(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+// ConfigBool 获取布尔类型的配置项，不存在或格式错误时返回默认值
+func (b *BaseAIAgent) ConfigBool(key string, defaultValue bool) bool {
+	switch value := b.config[key].(type) {
+	case bool:
+		return value
+	case string:
+		if parsed, err := strconv.ParseBool(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+// ConfigStrings 获取字符串列表类型的配置项
+func (b *BaseAIAgent) ConfigStrings(key string) []string {
+	switch value := b.config[key].(type) {
+	case []string:
+		return value
+	case []interface{}:
+		result := make([]string, 0, len(value))
+		for _, item := range value {
+			result = append(result, fmt.Sprint(item))
+		}
+		return result
+	case string:
+		if value != "" {
+			return []string{value}
+		}
+	}
+	return nil
+}
+
+// ConfigStringMap 获取字符串映射类型的配置项
+func (b *BaseAIAgent) ConfigStringMap(key string) map[string]string {
+	result := make(map[string]string)
+	switch value := b.config[key].(type) {
+	case map[string]string:
+		for k, v := range value {
+			result[k] = v
+		}
+	case map[string]interface{}:
+		for k, v := range value {
+			result[k] = fmt.Sprint(v)
+		}
+	}
+	return result
+}
+
+// MapModel 将网关模型名映射为上游模型名
+// 未指定模型时使用default_model配置，model_map中存在映射时使用映射后的名称
+func (b *BaseAIAgent) MapModel(model string) string {
+	if model == "" {
+		model = b.ConfigString("default_model", "")
+	}
+	if mapped, ok := b.ConfigStringMap("model_map")[model]; ok {
+		return mapped
+	}
+	return model
+}
@@ -0,0 +1,418 @@
+package ai_agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"kai/kaigate/pkg/log"
+)
+
+const (
+	defaultBatchWindow  = 10 * time.Millisecond
+	defaultMaxBatchSize = 256
+)
+
+// errEmptyEmbeddingInput 嵌入向量请求没有输入
+var errEmptyEmbeddingInput = errors.New("embedding input cannot be empty")
+
+// EmbeddingBatcher 嵌入向量请求合并器
+// 在时间窗口内合并同一AI Agent、同一模型的并发Embedding调用，去除重复输入后按最大批量调用上游，再把结果拆分给各调用方
+type EmbeddingBatcher struct {
+	window       time.Duration
+	maxBatchSize int
+	agents       map[string]bool // 启用合并的AI Agent，为空表示全部
+	targets      TargetResolver  // 展开逻辑模型路由的目标
+	queues       map[string]*embeddingQueue
+	stats        map[string]*BatcherStats
+	mutex        sync.Mutex
+	logger       log.Logger
+}
+
+// BatcherOption 合并器选项
+type BatcherOption func(*EmbeddingBatcher)
+
+// BatcherStats 合并统计
+type BatcherStats struct {
+	Requests     int64 `json:"requests"`      // 合并前的调用次数
+	Batches      int64 `json:"batches"`       // 上游调用次数
+	Inputs       int64 `json:"inputs"`        // 合并前的输入数
+	UniqueInputs int64 `json:"unique_inputs"` // 去重后发送给上游的输入数
+}
+
+// embeddingQueue 等待合并的调用，按AI Agent和模型区分
+type embeddingQueue struct {
+	name   string
+	agent  AIAgent
+	model  string
+	calls  []*embeddingCall
+	unique map[string]bool
+	timer  *time.Timer
+}
+
+// embeddingCall 一次等待结果的Embedding调用
+type embeddingCall struct {
+	ctx    context.Context
+	inputs []string
+	result chan embeddingResult
+}
+
+// embeddingResult Embedding调用的结果
+type embeddingResult struct {
+	response *EmbeddingResponse
+	err      error
+}
+
+// batchedAgent 通过合并器调用Embedding的AI Agent
+type batchedAgent struct {
+	AIAgent
+	batcher *EmbeddingBatcher
+	name    string
+}
+
+// NewEmbeddingBatcher 创建嵌入向量请求合并器
+func NewEmbeddingBatcher(options ...BatcherOption) *EmbeddingBatcher {
+	b := &EmbeddingBatcher{
+		window:       defaultBatchWindow,
+		maxBatchSize: defaultMaxBatchSize,
+		agents:       make(map[string]bool),
+		queues:       make(map[string]*embeddingQueue),
+		stats:        make(map[string]*BatcherStats),
+		logger:       log.GlobalLogger,
+	}
+	for _, option := range options {
+		option(b)
+	}
+	if b.window <= 0 {
+		b.window = defaultBatchWindow
+	}
+	if b.maxBatchSize <= 0 {
+		b.maxBatchSize = defaultMaxBatchSize
+	}
+	return b
+}
+
+// WithBatchWindow 设置合并等待时间
+func WithBatchWindow(window time.Duration) BatcherOption {
+	return func(b *EmbeddingBatcher) {
+		b.window = window
+	}
+}
+
+// WithMaxBatchSize 设置单次上游调用的最大输入数
+func WithMaxBatchSize(size int) BatcherOption {
+	return func(b *EmbeddingBatcher) {
+		b.maxBatchSize = size
+	}
+}
+
+// WithBatchAgents 设置启用合并的AI Agent，为空表示全部
+func WithBatchAgents(agents []string) BatcherOption {
+	return func(b *EmbeddingBatcher) {
+		b.agents = make(map[string]bool, len(agents))
+		for _, agent := range agents {
+			b.agents[agent] = true
+		}
+	}
+}
+
+// WithBatchTargetResolver 设置逻辑模型路由的目标解析函数，目标启用合并时经过该路由的调用同样合并
+func WithBatchTargetResolver(resolve TargetResolver) BatcherOption {
+	return func(b *EmbeddingBatcher) {
+		b.targets = resolve
+	}
+}
+
+// WithBatcherLogger 设置日志记录器
+func WithBatcherLogger(logger log.Logger) BatcherOption {
+	return func(b *EmbeddingBatcher) {
+		b.logger = logger
+	}
+}
+
+// Wrap 为启用合并的AI Agent返回通过合并器调用Embedding的Agent，未启用时返回原Agent
+// 合并器放在中间件链内侧：每个调用方的调用分别经过中间件，身份、调用信息和拆分后的用量都按调用方记录，
+// 合并后的上游调用不再经过中间件
+func (b *EmbeddingBatcher) Wrap(name string, agent AIAgent) AIAgent {
+	if !InScope(b.agents, name, b.targets) {
+		return agent
+	}
+	if chained, ok := agent.(*middlewareAgent); ok {
+		return WithMiddlewares(chained.name, &batchedAgent{AIAgent: chained.AIAgent, batcher: b, name: name}, chained.middlewares)
+	}
+	return &batchedAgent{AIAgent: agent, batcher: b, name: name}
+}
+
+// Stats 返回各AI Agent的合并统计
+func (b *EmbeddingBatcher) Stats() map[string]BatcherStats {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	stats := make(map[string]BatcherStats, len(b.stats))
+	for name, s := range b.stats {
+		stats[name] = *s
+	}
+	return stats
+}
+
+// Embedding 实现AIAgent接口的Embedding方法
+func (a *batchedAgent) Embedding(ctx context.Context, req EmbeddingRequest) (*EmbeddingResponse, error) {
+	if len(req.Input) == 0 {
+		return a.AIAgent.Embedding(ctx, req)
+	}
+	return a.batcher.submit(ctx, a.name, a.AIAgent, req)
+}
+
+// submit 加入等待队列并等待结果
+func (b *EmbeddingBatcher) submit(ctx context.Context, name string, agent AIAgent, req EmbeddingRequest) (*EmbeddingResponse, error) {
+	call := &embeddingCall{ctx: ctx, inputs: req.Input, result: make(chan embeddingResult, 1)}
+	key := name + "\x00" + req.Model
+
+	b.mutex.Lock()
+	queue, ok := b.queues[key]
+	if !ok {
+		queue = &embeddingQueue{name: name, agent: agent, model: req.Model, unique: make(map[string]bool)}
+		queue.timer = time.AfterFunc(b.window, func() { b.flushKey(key, queue) })
+		b.queues[key] = queue
+	}
+	queue.calls = append(queue.calls, call)
+	for _, input := range req.Input {
+		queue.unique[input] = true
+	}
+
+	// 达到最大批量时立即发送
+	full := len(queue.unique) >= b.maxBatchSize
+	if full {
+		queue.timer.Stop()
+		delete(b.queues, key)
+	}
+	b.mutex.Unlock()
+
+	if full {
+		go b.flush(queue)
+	}
+
+	select {
+	case result := <-call.result:
+		return result.response, result.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// flushKey 时间窗口结束时发送队列，队列已因达到最大批量被发送时忽略
+func (b *EmbeddingBatcher) flushKey(key string, queue *embeddingQueue) {
+	b.mutex.Lock()
+	if b.queues[key] != queue {
+		b.mutex.Unlock()
+		return
+	}
+	delete(b.queues, key)
+	b.mutex.Unlock()
+
+	b.flush(queue)
+}
+
+// flush 去除重复输入后分批调用上游，并把结果拆分给各调用方
+func (b *EmbeddingBatcher) flush(queue *embeddingQueue) {
+	// 跳过已取消的调用
+	calls := make([]*embeddingCall, 0, len(queue.calls))
+	for _, call := range queue.calls {
+		if call.ctx.Err() == nil {
+			calls = append(calls, call)
+		}
+	}
+	if len(calls) == 0 {
+		return
+	}
+
+	unique := make([]string, 0, len(queue.unique))
+	positions := make(map[string]int, len(queue.unique))
+	total := 0
+	for _, call := range calls {
+		total += len(call.inputs)
+		for _, input := range call.inputs {
+			if _, ok := positions[input]; !ok {
+				positions[input] = len(unique)
+				unique = append(unique, input)
+			}
+		}
+	}
+
+	// 所有调用方都取消后取消上游调用
+	ctx, cancel := mergeContexts(calls)
+	defer cancel()
+
+	vectors := make([][]float64, len(unique))
+	usage := EmbeddingUsage{}
+	var head *EmbeddingResponse
+	batches := 0
+	var err error
+	for start := 0; start < len(unique) && err == nil; start += b.maxBatchSize {
+		end := start + b.maxBatchSize
+		if end > len(unique) {
+			end = len(unique)
+		}
+		var resp *EmbeddingResponse
+		resp, err = queue.agent.Embedding(ctx, EmbeddingRequest{Model: queue.model, Input: unique[start:end]})
+		batches++
+		if err != nil {
+			break
+		}
+		if head == nil {
+			head = resp
+		}
+		usage.PromptTokens += resp.Usage.PromptTokens
+		usage.TotalTokens += resp.Usage.TotalTokens
+		err = placeEmbeddings(vectors[start:end], resp.Data)
+	}
+
+	b.mutex.Lock()
+	stats, ok := b.stats[queue.name]
+	if !ok {
+		stats = &BatcherStats{}
+		b.stats[queue.name] = stats
+	}
+	stats.Requests += int64(len(calls))
+	stats.Batches += int64(batches)
+	stats.Inputs += int64(total)
+	stats.UniqueInputs += int64(len(unique))
+	b.mutex.Unlock()
+
+	if err != nil {
+		b.logger.Error("Batched embedding request failed",
+			zap.String("agent", queue.name),
+			zap.String("model", queue.model),
+			zap.Int("callers", len(calls)),
+			zap.Error(err),
+		)
+		for _, call := range calls {
+			call.result <- embeddingResult{err: err}
+		}
+		return
+	}
+
+	// 按各调用方的输入数分摊token用量，余数计入第一个调用方
+	responses := make([]*EmbeddingResponse, len(calls))
+	assigned := EmbeddingUsage{}
+	for i, call := range calls {
+		resp := &EmbeddingResponse{
+			ID:      head.ID,
+			Object:  head.Object,
+			Created: head.Created,
+			Model:   head.Model,
+			Data:    make([]EmbeddingData, len(call.inputs)),
+			Usage: EmbeddingUsage{
+				PromptTokens: usage.PromptTokens * len(call.inputs) / total,
+				TotalTokens:  usage.TotalTokens * len(call.inputs) / total,
+			},
+		}
+		for j, input := range call.inputs {
+			resp.Data[j] = EmbeddingData{Index: j, Embedding: vectors[positions[input]], Object: "embedding"}
+		}
+		assigned.PromptTokens += resp.Usage.PromptTokens
+		assigned.TotalTokens += resp.Usage.TotalTokens
+		responses[i] = resp
+	}
+	responses[0].Usage.PromptTokens += usage.PromptTokens - assigned.PromptTokens
+	responses[0].Usage.TotalTokens += usage.TotalTokens - assigned.TotalTokens
+
+	for i, call := range calls {
+		call.result <- embeddingResult{response: responses[i]}
+	}
+}
+
+// placeEmbeddings 按响应中的index把嵌入向量放到对应位置
+func placeEmbeddings(vectors [][]float64, data []EmbeddingData) error {
+	if len(data) != len(vectors) {
+		return fmt.Errorf("embedding response has %d items, expected %d", len(data), len(vectors))
+	}
+	for i, item := range data {
+		index := item.Index
+		// 部分上游不返回index，按顺序放置
+		if index < 0 || index >= len(vectors) || vectors[index] != nil {
+			index = i
+		}
+		vectors[index] = item.Embedding
+	}
+	return nil
+}
+
+// mergeContexts 返回在所有调用方都取消后才取消的上下文
+// 合并调用不属于任何一个调用方，上下文不携带调用方的身份，用量由各调用方的中间件按拆分结果记录
+func mergeContexts(calls []*embeddingCall) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		for _, call := range calls {
+			select {
+			case <-call.ctx.Done():
+			case <-ctx.Done():
+				return
+			}
+		}
+		cancel()
+	}()
+	return ctx, cancel
+}
+
+// batchByModel 将同一模型的多个请求合并为一次Embedding调用，再按请求拆分结果
+// 用于BatchEmbedding的实现，返回结果与请求一一对应
+func batchByModel(ctx context.Context, reqs []EmbeddingRequest, embed func(context.Context, EmbeddingRequest) (*EmbeddingResponse, error)) ([]*EmbeddingResponse, error) {
+	groups := make(map[string][]int)
+	models := []string{}
+	for i, req := range reqs {
+		if _, ok := groups[req.Model]; !ok {
+			models = append(models, req.Model)
+		}
+		groups[req.Model] = append(groups[req.Model], i)
+	}
+	sort.Strings(models)
+
+	responses := make([]*EmbeddingResponse, len(reqs))
+	for _, model := range models {
+		merged := EmbeddingRequest{Model: model}
+		for _, i := range groups[model] {
+			merged.Input = append(merged.Input, reqs[i].Input...)
+		}
+		if len(merged.Input) == 0 {
+			for _, i := range groups[model] {
+				responses[i] = &EmbeddingResponse{Object: "list", Model: model, Data: []EmbeddingData{}}
+			}
+			continue
+		}
+
+		resp, err := embed(ctx, merged)
+		if err != nil {
+			return nil, err
+		}
+		vectors := make([][]float64, len(merged.Input))
+		if err := placeEmbeddings(vectors, resp.Data); err != nil {
+			return nil, err
+		}
+
+		offset := 0
+		for _, i := range groups[model] {
+			count := len(reqs[i].Input)
+			item := &EmbeddingResponse{
+				ID:      resp.ID,
+				Object:  resp.Object,
+				Created: resp.Created,
+				Model:   resp.Model,
+				Data:    make([]EmbeddingData, count),
+			}
+			for j := 0; j < count; j++ {
+				item.Data[j] = EmbeddingData{Index: j, Embedding: vectors[offset+j], Object: "embedding"}
+			}
+			item.Usage.PromptTokens = resp.Usage.PromptTokens * count / len(merged.Input)
+			item.Usage.TotalTokens = resp.Usage.TotalTokens * count / len(merged.Input)
+			responses[i] = item
+			offset += count
+		}
+	}
+	return responses, nil
+}
@@ -2,7 +2,9 @@ package ai_agent
 
 import (
 	"context"
-	"errors"
+	"hash/fnv"
+	"math"
+	"strings"
 	"time"
 
 	"go.uber.org/zap"
@@ -19,8 +21,13 @@ type ExampleAIAgentFactory struct {}
 
 // NewExampleAIAgent 创建ExampleAIAgent实例
 func NewExampleAIAgent() *ExampleAIAgent {
+	return newExampleAIAgent("example-ai-agent")
+}
+
+// newExampleAIAgent 创建指定名称的ExampleAIAgent实例
+func newExampleAIAgent(name string) *ExampleAIAgent {
 	return &ExampleAIAgent{
-		BaseAIAgent: NewBaseAIAgent("example-ai-agent", "1.0.0"),
+		BaseAIAgent: NewBaseAIAgent(name, "1.0.0"),
 	}
 }
 
@@ -53,21 +60,14 @@ func (e *ExampleAIAgent) Chat(ctx context.Context, req ChatRequest) (*ChatRespon
 		Object:  "chat.completion",
 		Created: time.Now().Unix(),
 		Model:   req.Model,
-		Choices: []struct {
-			Index   int    `json:"index"`
-			Message Message `json:"message"`
-		}{{
+		Choices: []ChatChoice{{
 			Index: 0,
 			Message: Message{
 				Role:    "assistant",
 				Content: "This is a response from ExampleAIAgent.",
 			},
 		}},
-		Usage: struct {
-			PromptTokens     int `json:"prompt_tokens"`
-			CompletionTokens int `json:"completion_tokens"`
-			TotalTokens      int `json:"total_tokens"`
-		}{},
+		Usage: Usage{},
 	}
 
 	return resp, nil
@@ -98,10 +98,7 @@ func (e *ExampleAIAgent) ChatStream(ctx context.Context, req ChatRequest) (<-cha
 				Object:  "chat.completion.chunk",
 				Created: time.Now().Unix(),
 				Model:   req.Model,
-				Choices: []struct {
-					Index   int    `json:"index"`
-					Message Message `json:"message"`
-				}{{
+				Choices: []ChatChoice{{
 					Index: 0,
 					Message: Message{
 						Role:    "assistant",
@@ -137,18 +134,11 @@ func (e *ExampleAIAgent) Completion(ctx context.Context, req CompletionRequest)
 		Object:  "text.completion",
 		Created: time.Now().Unix(),
 		Model:   req.Model,
-		Choices: []struct {
-			Index int    `json:"index"`
-			Text  string `json:"text"`
-		}{{
+		Choices: []CompletionChoice{{
 			Index: 0,
 			Text:  "This is a completion response from ExampleAIAgent.",
 		}},
-		Usage: struct {
-			PromptTokens     int `json:"prompt_tokens"`
-			CompletionTokens int `json:"completion_tokens"`
-			TotalTokens      int `json:"total_tokens"`
-		}{},
+		Usage: Usage{},
 	}
 
 	return resp, nil
@@ -179,10 +169,7 @@ func (e *ExampleAIAgent) CompletionStream(ctx context.Context, req CompletionReq
 				Object:  "text.completion.chunk",
 				Created: time.Now().Unix(),
 				Model:   req.Model,
-				Choices: []struct {
-					Index int    `json:"index"`
-					Text  string `json:"text"`
-				}{{
+				Choices: []CompletionChoice{{
 					Index: 0,
 					Text:  "Chunk from ExampleAIAgent.",
 				}},
@@ -200,13 +187,56 @@ func (e *ExampleAIAgent) CompletionStream(ctx context.Context, req CompletionReq
 }
 
 // Embedding 实现嵌入向量生成功能
+// 根据文本哈希生成固定维度的单位向量，相同文本得到相同向量
 func (e *ExampleAIAgent) Embedding(ctx context.Context, req EmbeddingRequest) (*EmbeddingResponse, error) {
-	return nil, errors.New("Embedding not implemented")
+	if len(req.Input) == 0 {
+		return nil, errEmptyEmbeddingInput
+	}
+
+	// 记录嵌入请求
+	e.GetLogger().Info("Processing embedding request",
+		zap.String("model", req.Model),
+		zap.Int("inputs", len(req.Input)),
+	)
+
+	resp := &EmbeddingResponse{
+		ID:      "embedding-" + time.Now().Format("20060102-150405.000"),
+		Object:  "list",
+		Created: time.Now().Unix(),
+		Model:   req.Model,
+		Data:    make([]EmbeddingData, 0, len(req.Input)),
+	}
+	for i, input := range req.Input {
+		resp.Data = append(resp.Data, EmbeddingData{Index: i, Embedding: exampleEmbedding(input), Object: "embedding"})
+		resp.Usage.PromptTokens += len(strings.Fields(input))
+	}
+	resp.Usage.TotalTokens = resp.Usage.PromptTokens
+	return resp, nil
 }
 
-// BatchEmbedding 实现批量嵌入向量生成功能
+// BatchEmbedding 实现批量嵌入向量生成功能，同一模型的请求合并为一次调用
 func (e *ExampleAIAgent) BatchEmbedding(ctx context.Context, req []EmbeddingRequest) ([]*EmbeddingResponse, error) {
-	return nil, errors.New("BatchEmbedding not implemented")
+	return batchByModel(ctx, req, e.Embedding)
+}
+
+// exampleEmbedding 根据文本哈希生成8维单位向量
+func exampleEmbedding(text string) []float64 {
+	vector := make([]float64, 8)
+	norm := 0.0
+	for i := range vector {
+		hash := fnv.New64a()
+		hash.Write([]byte{byte(i)})
+		hash.Write([]byte(text))
+		vector[i] = float64(hash.Sum64()%2001)/1000 - 1
+		norm += vector[i] * vector[i]
+	}
+	norm = math.Sqrt(norm)
+	for i := range vector {
+		if norm > 0 {
+			vector[i] /= norm
+		}
+	}
+	return vector
 }
 
 // ListModels 实现模型列表查询功能
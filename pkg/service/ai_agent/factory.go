@@ -0,0 +1,59 @@
+package ai_agent
+
+// 内置的AI Agent类型，作为按类型注册的工厂名称
+const (
+	TypeOpenAI    = "openai"
+	TypeAnthropic = "anthropic"
+	TypeOllama    = "ollama"
+	TypeExample   = "example"
+)
+
+// typedFactory 按类型创建命名AI Agent实例的工厂
+// 实例配置中的max_concurrency大于0时限制实例的并发调用数
+type typedFactory struct {
+	agentType string
+}
+
+// BuiltinFactories 返回内置类型的AI Agent工厂
+func BuiltinFactories() []AIAgentFactory {
+	return []AIAgentFactory{
+		&typedFactory{agentType: TypeOpenAI},
+		&typedFactory{agentType: TypeAnthropic},
+		&typedFactory{agentType: TypeOllama},
+		&typedFactory{agentType: TypeExample},
+	}
+}
+
+// Create 实现AIAgentFactory接口的Create方法，创建与类型同名的实例
+func (f *typedFactory) Create() (AIAgent, error) {
+	return f.CreateInstance(f.agentType, nil)
+}
+
+// CreateInstance 实现AIAgentInstanceFactory接口的CreateInstance方法
+func (f *typedFactory) CreateInstance(name string, config map[string]interface{}) (AIAgent, error) {
+	var agent AIAgent
+	switch f.agentType {
+	case TypeOpenAI:
+		agent = NewOpenAIAgent(name, nil)
+	case TypeAnthropic:
+		agent = NewAnthropicAgent(name, nil)
+	case TypeOllama:
+		agent = NewOllamaAgent(name, nil)
+	default:
+		agent = newExampleAIAgent(name)
+	}
+
+	limit := 0
+	switch value := config["max_concurrency"].(type) {
+	case int:
+		limit = value
+	case float64:
+		limit = int(value)
+	}
+	return WithConcurrencyLimit(agent, limit), nil
+}
+
+// Name 实现AIAgentFactory接口的Name方法
+func (f *typedFactory) Name() string {
+	return f.agentType
+}
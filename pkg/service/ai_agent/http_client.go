@@ -0,0 +1,213 @@
+package ai_agent
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxStreamLineSize 流式响应中单行的最大长度
+const maxStreamLineSize = 4 << 20
+
+// APIError 上游模型服务返回的错误
+type APIError struct {
+	StatusCode int    // HTTP状态码
+	Message    string // 错误信息
+}
+
+// Error 实现error接口
+func (e *APIError) Error() string {
+	return fmt.Sprintf("upstream returned status %d: %s", e.StatusCode, e.Message)
+}
+
+// httpClient 访问模型服务HTTP接口的客户端
+type httpClient struct {
+	baseURL string
+	headers map[string]string
+	client  *http.Client
+}
+
+// newHTTPClient 创建httpClient实例
+// timeout只作用于非流式请求，流式请求由ctx控制
+func newHTTPClient(baseURL string, headers map[string]string, timeout time.Duration) *httpClient {
+	return &httpClient{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		headers: headers,
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+// getJSON 发送GET请求并解析JSON响应
+func (h *httpClient) getJSON(ctx context.Context, path string, out interface{}) error {
+	resp, err := h.do(ctx, http.MethodGet, path, nil, h.client)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return decodeJSON(resp.Body, out)
+}
+
+// postJSON 发送JSON请求并解析JSON响应
+func (h *httpClient) postJSON(ctx context.Context, path string, body, out interface{}) error {
+	resp, err := h.do(ctx, http.MethodPost, path, body, h.client)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return decodeJSON(resp.Body, out)
+}
+
+// postStream 发送JSON请求并返回流式响应，调用方负责关闭响应体
+func (h *httpClient) postStream(ctx context.Context, path string, body interface{}) (*http.Response, error) {
+	// 流式响应持续时间不可预估，不使用客户端超时
+	streamClient := &http.Client{Transport: h.client.Transport}
+	return h.do(ctx, http.MethodPost, path, body, streamClient)
+}
+
+// do 发送请求，非2xx响应转换为APIError
+func (h *httpClient) do(ctx context.Context, method, path string, body interface{}, client *http.Client) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("encode request failed: %w", err)
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, h.baseURL+path, reader)
+	if err != nil {
+		return nil, fmt.Errorf("create request failed: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for key, value := range h.headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request %s failed: %w", path, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		return nil, &APIError{StatusCode: resp.StatusCode, Message: errorMessage(resp.Body)}
+	}
+	return resp, nil
+}
+
+// decodeJSON 解析JSON响应
+func decodeJSON(body io.Reader, out interface{}) error {
+	if out == nil {
+		_, err := io.Copy(io.Discard, body)
+		return err
+	}
+	if err := json.NewDecoder(body).Decode(out); err != nil {
+		return fmt.Errorf("decode response failed: %w", err)
+	}
+	return nil
+}
+
+// errorMessage 从错误响应中提取错误信息
+// 兼容{"error":{"message":...}}、{"error":"..."}和纯文本格式
+func errorMessage(body io.Reader) string {
+	content, _ := io.ReadAll(io.LimitReader(body, 64<<10))
+
+	var payload struct {
+		Error   json.RawMessage `json:"error"`
+		Message string          `json:"message"`
+	}
+	if err := json.Unmarshal(content, &payload); err == nil {
+		var nested struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(payload.Error, &nested) == nil && nested.Message != "" {
+			return nested.Message
+		}
+		var message string
+		if json.Unmarshal(payload.Error, &message) == nil && message != "" {
+			return message
+		}
+		if payload.Message != "" {
+			return payload.Message
+		}
+	}
+	return strings.TrimSpace(string(content))
+}
+
+// readSSE 逐个读取Server-Sent Events事件，直到流结束或handler返回错误
+// handler返回io.EOF表示正常结束
+func readSSE(body io.Reader, handler func(event, data string) error) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64<<10), maxStreamLineSize)
+
+	event := ""
+	data := []string{}
+	dispatch := func() error {
+		if len(data) == 0 {
+			event = ""
+			return nil
+		}
+		err := handler(event, strings.Join(data, "\n"))
+		event = ""
+		data = data[:0]
+		return err
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if err := dispatch(); err != nil {
+				return ignoreEOF(err)
+			}
+		case strings.HasPrefix(line, ":"):
+			// 注释行，通常用于保活
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read event stream failed: %w", err)
+	}
+	// 处理末尾没有空行的事件
+	return ignoreEOF(dispatch())
+}
+
+// readNDJSON 逐行读取以换行分隔的JSON流，直到流结束或handler返回错误
+// handler返回io.EOF表示正常结束
+func readNDJSON(body io.Reader, handler func(line []byte) error) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64<<10), maxStreamLineSize)
+
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if err := handler(line); err != nil {
+			return ignoreEOF(err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read stream failed: %w", err)
+	}
+	return nil
+}
+
+// ignoreEOF 将io.EOF视为正常结束
+func ignoreEOF(err error) error {
+	if err == io.EOF {
+		return nil
+	}
+	return err
+}
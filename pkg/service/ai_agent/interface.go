@@ -2,14 +2,9 @@ package ai_agent
 
 import (
 	"context"
+	"time"
 )
 
-// Message AI消息结构
-type Message struct {
-	Role    string `json:"role"`    // 角色：user, assistant, system
-	Content string `json:"content"` // 消息内容
-}
-
 // ChatRequest 聊天请求
 type ChatRequest struct {
 	Model       string    `json:"model"`       // 模型名称
@@ -17,6 +12,16 @@ type ChatRequest struct {
 	Temperature float64   `json:"temperature"` // 温度参数
 	MaxTokens   int       `json:"max_tokens"`  // 最大token数
 
+	// 采样控制
+	TopP           float64         `json:"top_p,omitempty"`           // 核采样概率
+	Seed           *int64          `json:"seed,omitempty"`            // 随机种子
+	Stop           StopSequences   `json:"stop,omitempty"`            // 停止序列
+	ResponseFormat *ResponseFormat `json:"response_format,omitempty"` // 输出格式
+
+	// 工具调用
+	Tools      []Tool      `json:"tools,omitempty"`       // 可调用的工具
+	ToolChoice interface{} `json:"tool_choice,omitempty"` // 工具选择：auto, none, required或指定函数
+
 	// 流式响应控制
 	Stream bool `json:"stream"` // 是否使用流式响应
 }
@@ -27,15 +32,22 @@ type ChatResponse struct {
 	Object  string  `json:"object"`  // 对象类型
 	Created int64   `json:"created"` // 创建时间戳
 	Model   string  `json:"model"`   // 模型名称
-	Choices []struct {
-		Index   int    `json:"index"`
-		Message Message `json:"message"`
-	} `json:"choices"`
-	Usage struct {
-		PromptTokens     int `json:"prompt_tokens"`
-		CompletionTokens int `json:"completion_tokens"`
-		TotalTokens      int `json:"total_tokens"`
-	} `json:"usage"`
+	Choices []ChatChoice `json:"choices"` // 候选结果
+	Usage   Usage        `json:"usage"`   // token用量
+}
+
+// ChatChoice 聊天响应中的候选结果
+type ChatChoice struct {
+	Index        int     `json:"index"`
+	Message      Message `json:"message"`
+	FinishReason string  `json:"finish_reason,omitempty"` // 结束原因：stop, length, tool_calls, content_filter
+}
+
+// Usage token用量
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
 }
 
 // CompletionRequest 文本生成请求
@@ -55,15 +67,14 @@ type CompletionResponse struct {
 	Object  string  `json:"object"`  // 对象类型
 	Created int64   `json:"created"` // 创建时间戳
 	Model   string  `json:"model"`   // 模型名称
-	Choices []struct {
-		Index   int    `json:"index"`
-		Text    string `json:"text"`
-	} `json:"choices"`
-	Usage struct {
-		PromptTokens     int `json:"prompt_tokens"`
-		CompletionTokens int `json:"completion_tokens"`
-		TotalTokens      int `json:"total_tokens"`
-	} `json:"usage"`
+	Choices []CompletionChoice `json:"choices"` // 候选结果
+	Usage   Usage              `json:"usage"`   // token用量
+}
+
+// CompletionChoice 文本生成响应中的候选结果
+type CompletionChoice struct {
+	Index int    `json:"index"`
+	Text  string `json:"text"`
 }
 
 // EmbeddingRequest 嵌入向量请求
@@ -78,15 +89,21 @@ type EmbeddingResponse struct {
 	Object  string  `json:"object"`  // 对象类型
 	Created int64   `json:"created"` // 创建时间戳
 	Model   string  `json:"model"`   // 模型名称
-	Data    []struct {
-		Index     int       `json:"index"`
-		Embedding []float64 `json:"embedding"`
-		Object    string    `json:"object"`
-	} `json:"data"`
-	Usage struct {
-		PromptTokens int `json:"prompt_tokens"`
-		TotalTokens  int `json:"total_tokens"`
-	} `json:"usage"`
+	Data    []EmbeddingData `json:"data"`  // 嵌入向量列表
+	Usage   EmbeddingUsage  `json:"usage"` // token用量
+}
+
+// EmbeddingData 单个输入的嵌入向量
+type EmbeddingData struct {
+	Index     int       `json:"index"`
+	Embedding []float64 `json:"embedding"`
+	Object    string    `json:"object"`
+}
+
+// EmbeddingUsage 嵌入向量的token用量
+type EmbeddingUsage struct {
+	PromptTokens int `json:"prompt_tokens"`
+	TotalTokens  int `json:"total_tokens"`
 }
 
 // AIAgent 接口定义
@@ -148,6 +165,23 @@ type AIAgentFactory interface {
 	Name() string
 }
 
+// AIAgentInstanceFactory 可以按名称创建多个实例的AI Agent工厂
+// 工厂名称表示实例类型，实例只能通过AIAgentManager.CreateInstance创建
+type AIAgentInstanceFactory interface {
+	AIAgentFactory
+	
+	// 创建指定名称的AIAgent实例，config为实例配置，创建后由管理器调用Init
+	CreateInstance(name string, config map[string]interface{}) (AIAgent, error)
+}
+
+// AIAgentInfo AI Agent实例信息
+type AIAgentInfo struct {
+	Name    string `json:"name"`            // 实例名称
+	Factory string `json:"factory"`         // 创建实例的工厂
+	Status  string `json:"status"`          // 状态: healthy, unhealthy, idle(尚未创建)
+	Error   string `json:"error,omitempty"` // 健康检查失败的原因
+}
+
 // AIAgentManager AI Agent管理器
 // 用于管理多个AIAgent实例
 type AIAgentManager interface {
@@ -157,12 +191,36 @@ type AIAgentManager interface {
 	// 创建并获取AI Agent实例
 	GetAIAgent(name string, config map[string]interface{}) (AIAgent, error)
 	
+	// 获取AI Agent实例或实例池，不包含中间件，供已在外层执行中间件的逻辑模型使用
+	ResolveAIAgent(name string) (AIAgent, error)
+	
 	// 释放AI Agent实例
 	ReleaseAIAgent(name string) error
 	
+	// 使用指定工厂和配置创建或替换命名实例
+	CreateInstance(name, factory string, config map[string]interface{}) error
+	
+	// 删除命名实例
+	RemoveInstance(name string) error
+	
+	// 创建或替换实例池，interval为成员健康检查间隔
+	CreatePool(name string, members []string, interval time.Duration) error
+	
+	// 删除实例池
+	RemovePool(name string) error
+	
+	// 列出实例池及其成员状态
+	ListPools() []AgentPoolStatus
+	
 	// 列出所有可用的AI Agent名称
 	ListAvailableAgents() []string
 	
+	// 列出AI Agent实例及其工厂和健康状态
+	ListAgentInstances() []AIAgentInfo
+	
+	// 注册在每次调用外执行的中间件
+	Use(middlewares ...AIAgentMiddleware)
+	
 	// 清理所有资源
 	Close() error
 }
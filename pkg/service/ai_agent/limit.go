@@ -0,0 +1,89 @@
+package ai_agent
+
+import (
+	"context"
+)
+
+// limitedAgent 限制并发调用数的AI Agent
+// 达到上限时调用等待空闲名额，直到上下文取消；流式调用在数据流结束后释放名额
+type limitedAgent struct {
+	AIAgent
+	slots chan struct{}
+}
+
+// WithConcurrencyLimit 返回最多同时执行limit个调用的AI Agent，limit不大于0时返回原Agent
+func WithConcurrencyLimit(agent AIAgent, limit int) AIAgent {
+	if limit <= 0 {
+		return agent
+	}
+	return &limitedAgent{AIAgent: agent, slots: make(chan struct{}, limit)}
+}
+
+// acquire 获取调用名额
+func (a *limitedAgent) acquire(ctx context.Context) error {
+	select {
+	case a.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// release 释放调用名额
+func (a *limitedAgent) release() {
+	<-a.slots
+}
+
+// Chat 实现AIAgent接口的Chat方法
+func (a *limitedAgent) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	if err := a.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer a.release()
+	return a.AIAgent.Chat(ctx, req)
+}
+
+// ChatStream 实现AIAgent接口的ChatStream方法
+func (a *limitedAgent) ChatStream(ctx context.Context, req ChatRequest) (<-chan *ChatResponse, <-chan error) {
+	if err := a.acquire(ctx); err != nil {
+		return failedStream[*ChatResponse](err)
+	}
+	chunks, errs := a.AIAgent.ChatStream(ctx, req)
+	return ForwardStream(ctx, chunks, errs, StreamHooks[*ChatResponse]{Done: func(int, error) { a.release() }})
+}
+
+// Completion 实现AIAgent接口的Completion方法
+func (a *limitedAgent) Completion(ctx context.Context, req CompletionRequest) (*CompletionResponse, error) {
+	if err := a.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer a.release()
+	return a.AIAgent.Completion(ctx, req)
+}
+
+// CompletionStream 实现AIAgent接口的CompletionStream方法
+func (a *limitedAgent) CompletionStream(ctx context.Context, req CompletionRequest) (<-chan *CompletionResponse, <-chan error) {
+	if err := a.acquire(ctx); err != nil {
+		return failedStream[*CompletionResponse](err)
+	}
+	chunks, errs := a.AIAgent.CompletionStream(ctx, req)
+	return ForwardStream(ctx, chunks, errs, StreamHooks[*CompletionResponse]{Done: func(int, error) { a.release() }})
+}
+
+// Embedding 实现AIAgent接口的Embedding方法
+func (a *limitedAgent) Embedding(ctx context.Context, req EmbeddingRequest) (*EmbeddingResponse, error) {
+	if err := a.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer a.release()
+	return a.AIAgent.Embedding(ctx, req)
+}
+
+// BatchEmbedding 实现AIAgent接口的BatchEmbedding方法
+func (a *limitedAgent) BatchEmbedding(ctx context.Context, req []EmbeddingRequest) ([]*EmbeddingResponse, error) {
+	if err := a.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer a.release()
+	return a.AIAgent.BatchEmbedding(ctx, req)
+}
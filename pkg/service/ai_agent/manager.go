@@ -2,29 +2,52 @@ package ai_agent
 
 import (
 	"errors"
+	"fmt"
+	"sort"
 	"sync"
+	"time"
 
 	"go.uber.org/zap"
 	"kai/kaigate/pkg/log"
 )
 
+// AI Agent实例状态
+const (
+	AgentHealthy   = "healthy"
+	AgentUnhealthy = "unhealthy"
+	AgentIdle      = "idle" // 实例尚未创建或已释放
+)
+
 // DefaultAIAgentManager 默认的AI Agent管理器实现
 // 用于管理多个AI Agent实例
+// 普通工厂按工厂名称创建单个实例；AIAgentInstanceFactory按类型注册，可以通过CreateInstance创建多个命名实例；
+// 实例池以一个名称转发给多个实例
 type DefaultAIAgentManager struct {
 	factories    map[string]AIAgentFactory
 	instances    map[string]AIAgent
 	configs      map[string]map[string]interface{}
+	definitions  map[string]instanceDefinition
+	pools        map[string]*AgentPool
+	middlewares  []AIAgentMiddleware
 	mutex        sync.RWMutex
 	logger       log.Logger
 }
 
+// instanceDefinition 命名实例的工厂和配置，实例释放后按定义重新创建
+type instanceDefinition struct {
+	factory string
+	config  map[string]interface{}
+}
+
 // NewDefaultAIAgentManager 创建DefaultAIAgentManager实例
 func NewDefaultAIAgentManager() *DefaultAIAgentManager {
 	return &DefaultAIAgentManager{
-		factories: make(map[string]AIAgentFactory),
-		instances: make(map[string]AIAgent),
-		configs:   make(map[string]map[string]interface{}),
-		logger:    log.GlobalLogger,
+		factories:   make(map[string]AIAgentFactory),
+		instances:   make(map[string]AIAgent),
+		configs:     make(map[string]map[string]interface{}),
+		definitions: make(map[string]instanceDefinition),
+		pools:       make(map[string]*AgentPool),
+		logger:      log.GlobalLogger,
 	}
 }
 
@@ -49,8 +72,34 @@ func (m *DefaultAIAgentManager) RegisterFactory(factory AIAgentFactory) error {
 	return nil
 }
 
-// GetAIAgent 创建并获取AI Agent实例
+// GetAIAgent 创建并获取AI Agent实例，名称为实例池时返回实例池
 func (m *DefaultAIAgentManager) GetAIAgent(name string, config map[string]interface{}) (AIAgent, error) {
+	agent, err := m.resolve(name, config)
+	if err != nil {
+		return nil, err
+	}
+	return m.withMiddlewares(name, agent), nil
+}
+
+// ResolveAIAgent 获取AI Agent实例或实例池，返回的Agent不包含中间件
+// 逻辑模型的调用已在外层经过中间件，转发给目标时使用该方法避免中间件重复执行
+func (m *DefaultAIAgentManager) ResolveAIAgent(name string) (AIAgent, error) {
+	return m.resolve(name, nil)
+}
+
+// resolve 获取实例池或实例，实例不存在时创建，返回的Agent不包含中间件
+func (m *DefaultAIAgentManager) resolve(name string, config map[string]interface{}) (AIAgent, error) {
+	m.mutex.RLock()
+	pool, isPool := m.pools[name]
+	m.mutex.RUnlock()
+	if isPool {
+		return pool, nil
+	}
+	return m.getInstance(name, config)
+}
+
+// getInstance 获取实例，实例不存在时创建，返回的实例不包含中间件
+func (m *DefaultAIAgentManager) getInstance(name string, config map[string]interface{}) (AIAgent, error) {
 	// 先检查是否已有实例
 	m.mutex.RLock()
 	agent, exists := m.instances[name]
@@ -70,11 +119,25 @@ func (m *DefaultAIAgentManager) GetAIAgent(name string, config map[string]interf
 		return agent, nil
 	}
 
-	// 获取工厂
+	// 命名实例释放后按原定义重新创建
+	if definition, defined := m.definitions[name]; defined {
+		instance, err := m.createInstance(name, definition.factory, definition.config)
+		if err != nil {
+			return nil, err
+		}
+		m.instances[name] = instance
+		m.configs[name] = definition.config
+		return instance, nil
+	}
+
+	// 获取工厂，按类型注册的工厂只能创建命名实例
 	factory, exists := m.factories[name]
 	if !exists {
 		return nil, errors.New("AI Agent factory not found: " + name)
 	}
+	if _, isInstanceFactory := factory.(AIAgentInstanceFactory); isInstanceFactory {
+		return nil, errors.New("AI Agent not found: " + name)
+	}
 
 	// 创建实例
 	instance, err := factory.Create()
@@ -109,6 +172,205 @@ func (m *DefaultAIAgentManager) GetAIAgent(name string, config map[string]interf
 	return instance, nil
 }
 
+// CreateInstance 使用指定工厂和配置创建命名实例，同名实例已存在时在新实例创建成功后关闭并替换
+func (m *DefaultAIAgentManager) CreateInstance(name, factory string, config map[string]interface{}) error {
+	if name == "" {
+		return errors.New("instance name cannot be empty")
+	}
+	if config == nil {
+		config = make(map[string]interface{})
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, isPool := m.pools[name]; isPool {
+		return fmt.Errorf("AI Agent pool already exists: %s", name)
+	}
+	instance, err := m.createInstance(name, factory, config)
+	if err != nil {
+		return err
+	}
+	if previous, exists := m.instances[name]; exists {
+		if err := previous.Close(); err != nil {
+			m.logger.Error("Failed to close AI Agent instance",
+				zap.String("name", name),
+				zap.Error(err),
+			)
+		}
+	}
+
+	m.instances[name] = instance
+	m.configs[name] = config
+	m.definitions[name] = instanceDefinition{factory: factory, config: config}
+
+	m.logger.Info("Created AI Agent instance",
+		zap.String("name", name),
+		zap.String("factory", factory),
+	)
+	return nil
+}
+
+// RemoveInstance 关闭并删除命名实例，实例不存在时视为成功
+func (m *DefaultAIAgentManager) RemoveInstance(name string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, defined := m.definitions[name]; !defined {
+		return nil
+	}
+	if instance, exists := m.instances[name]; exists {
+		if err := instance.Close(); err != nil {
+			m.logger.Error("Failed to close AI Agent instance",
+				zap.String("name", name),
+				zap.Error(err),
+			)
+		}
+	}
+
+	delete(m.instances, name)
+	delete(m.configs, name)
+	delete(m.definitions, name)
+
+	m.logger.Info("Removed AI Agent instance",
+		zap.String("name", name),
+	)
+	return nil
+}
+
+// CreatePool 创建或替换实例池，members为已有实例或普通工厂的名称，interval为健康检查间隔
+func (m *DefaultAIAgentManager) CreatePool(name string, members []string, interval time.Duration) error {
+	if name == "" {
+		return errors.New("pool name cannot be empty")
+	}
+	if len(members) == 0 {
+		return fmt.Errorf("AI Agent pool %s has no members", name)
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, defined := m.definitions[name]; defined {
+		return fmt.Errorf("AI Agent pool conflicts with an existing instance: %s", name)
+	}
+	if _, exists := m.factories[name]; exists {
+		return fmt.Errorf("AI Agent pool conflicts with an existing factory: %s", name)
+	}
+	seen := make(map[string]bool, len(members))
+	for _, member := range members {
+		if member == name {
+			return fmt.Errorf("AI Agent pool %s cannot contain itself", name)
+		}
+		if _, isPool := m.pools[member]; isPool {
+			return fmt.Errorf("AI Agent pool %s cannot contain another pool: %s", name, member)
+		}
+		if seen[member] {
+			return fmt.Errorf("AI Agent pool %s has duplicate member: %s", name, member)
+		}
+		seen[member] = true
+	}
+
+	if previous, exists := m.pools[name]; exists {
+		previous.Close()
+	}
+	m.pools[name] = newAgentPool(name, members, interval, func(member string) (AIAgent, error) {
+		return m.getInstance(member, nil)
+	}, m.logger)
+
+	m.logger.Info("Created AI Agent pool",
+		zap.String("name", name),
+		zap.Strings("members", members),
+	)
+	return nil
+}
+
+// RemovePool 停止并删除实例池，成员实例保留，实例池不存在时视为成功
+func (m *DefaultAIAgentManager) RemovePool(name string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	pool, exists := m.pools[name]
+	if !exists {
+		return nil
+	}
+	pool.Close()
+	delete(m.pools, name)
+
+	m.logger.Info("Removed AI Agent pool",
+		zap.String("name", name),
+	)
+	return nil
+}
+
+// ListPools 列出实例池及其成员状态，结果按名称排列
+func (m *DefaultAIAgentManager) ListPools() []AgentPoolStatus {
+	m.mutex.RLock()
+	pools := make([]*AgentPool, 0, len(m.pools))
+	for _, pool := range m.pools {
+		pools = append(pools, pool)
+	}
+	m.mutex.RUnlock()
+
+	statuses := make([]AgentPoolStatus, 0, len(pools))
+	for _, pool := range pools {
+		statuses = append(statuses, pool.Status())
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// createInstance 使用按类型注册的工厂创建并初始化命名实例，调用方需持有写锁
+func (m *DefaultAIAgentManager) createInstance(name, factoryName string, config map[string]interface{}) (AIAgent, error) {
+	factory, exists := m.factories[factoryName]
+	if !exists {
+		return nil, errors.New("AI Agent factory not found: " + factoryName)
+	}
+	instanceFactory, ok := factory.(AIAgentInstanceFactory)
+	if !ok {
+		return nil, fmt.Errorf("AI Agent factory %s does not support named instances", factoryName)
+	}
+
+	instance, err := instanceFactory.CreateInstance(name, config)
+	if err != nil {
+		m.logger.Error("Failed to create AI Agent instance",
+			zap.String("name", name),
+			zap.String("factory", factoryName),
+			zap.Error(err),
+		)
+		return nil, err
+	}
+	if err := instance.Init(config); err != nil {
+		m.logger.Error("Failed to initialize AI Agent instance",
+			zap.String("name", name),
+			zap.String("factory", factoryName),
+			zap.Error(err),
+		)
+		return nil, err
+	}
+	return instance, nil
+}
+
+// Use 注册中间件，按注册顺序在每次Chat、ChatStream、Completion和Embedding调用外执行
+func (m *DefaultAIAgentManager) Use(middlewares ...AIAgentMiddleware) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for _, middleware := range middlewares {
+		m.middlewares = append(m.middlewares, middleware)
+		m.logger.Info("Registered AI Agent middleware",
+			zap.String("name", middleware.Name()),
+		)
+	}
+}
+
+// withMiddlewares 为实例添加当前注册的中间件
+func (m *DefaultAIAgentManager) withMiddlewares(name string, agent AIAgent) AIAgent {
+	m.mutex.RLock()
+	middlewares := m.middlewares
+	m.mutex.RUnlock()
+	return WithMiddlewares(name, agent, middlewares)
+}
+
 // ReleaseAIAgent 释放AI Agent实例
 func (m *DefaultAIAgentManager) ReleaseAIAgent(name string) error {
 	m.mutex.Lock()
@@ -138,23 +400,85 @@ func (m *DefaultAIAgentManager) ReleaseAIAgent(name string) error {
 }
 
 // ListAvailableAgents 列出所有可用的AI Agent名称
+// 包括命名实例、实例池和普通工厂，按类型注册的工厂不能直接调用，不在列表中
 func (m *DefaultAIAgentManager) ListAvailableAgents() []string {
 	m.mutex.RLock()
 	defer m.mutex.RUnlock()
 
-	agents := make([]string, 0, len(m.factories))
-	for name := range m.factories {
+	agents := make([]string, 0, len(m.factories)+len(m.definitions)+len(m.pools))
+	for name := range m.definitions {
+		agents = append(agents, name)
+	}
+	for name := range m.pools {
 		agents = append(agents, name)
 	}
+	for name, factory := range m.factories {
+		if _, isInstanceFactory := factory.(AIAgentInstanceFactory); isInstanceFactory {
+			continue
+		}
+		if _, defined := m.definitions[name]; !defined {
+			agents = append(agents, name)
+		}
+	}
 
 	return agents
 }
 
+// ListAgentInstances 列出AI Agent实例及其工厂和健康状态，结果按名称排列
+// 已创建的实例并发执行健康检查，尚未创建的实例状态为idle
+func (m *DefaultAIAgentManager) ListAgentInstances() []AIAgentInfo {
+	names := m.ListAvailableAgents()
+	sort.Strings(names)
+
+	m.mutex.RLock()
+	infos := make([]AIAgentInfo, len(names))
+	instances := make([]AIAgent, len(names))
+	for i, name := range names {
+		infos[i] = AIAgentInfo{Name: name, Factory: name, Status: AgentIdle}
+		if definition, defined := m.definitions[name]; defined {
+			infos[i].Factory = definition.factory
+		}
+		if pool, isPool := m.pools[name]; isPool {
+			infos[i].Factory = FactoryPool
+			instances[i] = pool
+			continue
+		}
+		instances[i] = m.instances[name]
+	}
+	m.mutex.RUnlock()
+
+	// 健康检查可能访问上游服务，在锁外执行
+	var wg sync.WaitGroup
+	for i, instance := range instances {
+		if instance == nil {
+			continue
+		}
+		wg.Add(1)
+		go func(info *AIAgentInfo, instance AIAgent) {
+			defer wg.Done()
+			if err := instance.HealthCheck(); err != nil {
+				info.Status = AgentUnhealthy
+				info.Error = err.Error()
+				return
+			}
+			info.Status = AgentHealthy
+		}(&infos[i], instance)
+	}
+	wg.Wait()
+	return infos
+}
+
 // Close 清理所有资源
 func (m *DefaultAIAgentManager) Close() error {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 
+	// 停止实例池的健康检查
+	for _, pool := range m.pools {
+		pool.Close()
+	}
+	m.pools = make(map[string]*AgentPool)
+
 	// 关闭所有实例
 	for name, instance := range m.instances {
 		if err := instance.Close(); err != nil {
@@ -0,0 +1,210 @@
+package ai_agent
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Message AI消息结构
+// content可以是字符串或内容片段数组，解析为片段数组时Parts保存全部片段，
+// Content为其中文本片段的拼接，只支持纯文本的Agent可以直接使用Content
+type Message struct {
+	Role       string        `json:"role"`                   // 角色：user, assistant, system, tool
+	Content    string        `json:"content"`                // 消息内容
+	Parts      []ContentPart `json:"-"`                      // 多模态内容片段，非空时序列化为content数组
+	Name       string        `json:"name,omitempty"`         // 参与者名称
+	ToolCalls  []ToolCall    `json:"tool_calls,omitempty"`   // assistant消息中的工具调用
+	ToolCallID string        `json:"tool_call_id,omitempty"` // tool消息对应的工具调用ID
+}
+
+// ContentPart 消息内容片段
+type ContentPart struct {
+	Type     string    `json:"type"`                // 片段类型：text, image_url
+	Text     string    `json:"text,omitempty"`      // 文本内容
+	ImageURL *ImageURL `json:"image_url,omitempty"` // 图片地址
+}
+
+// ImageURL 图片地址，URL可以是http(s)地址或"data:image/png;base64,..."格式的内联图片
+type ImageURL struct {
+	URL    string `json:"url"`
+	Detail string `json:"detail,omitempty"` // 图片精度：auto, low, high
+}
+
+// ToolCall 模型发起的工具调用
+// 流式响应中同一调用的参数分多个数据块返回，以Index关联
+type ToolCall struct {
+	Index    int          `json:"index,omitempty"`
+	ID       string       `json:"id,omitempty"`
+	Type     string       `json:"type,omitempty"` // 调用类型，目前只有function
+	Function FunctionCall `json:"function"`
+}
+
+// FunctionCall 函数调用的名称和JSON格式参数
+type FunctionCall struct {
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments"`
+}
+
+// Tool 可供模型调用的工具
+type Tool struct {
+	Type     string             `json:"type"` // 工具类型，目前只有function
+	Function FunctionDefinition `json:"function"`
+}
+
+// FunctionDefinition 函数定义，Parameters为JSON Schema
+type FunctionDefinition struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Parameters  map[string]interface{} `json:"parameters,omitempty"`
+}
+
+// ResponseFormat 输出格式
+type ResponseFormat struct {
+	Type       string                 `json:"type"`                  // 格式类型：text, json_object, json_schema
+	JSONSchema map[string]interface{} `json:"json_schema,omitempty"` // json_schema类型的定义，包含name和schema
+}
+
+// StopSequences 停止序列，JSON中可以是字符串或字符串数组
+type StopSequences []string
+
+// UnmarshalJSON 解析字符串或字符串数组
+func (s *StopSequences) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '"' {
+		var value string
+		if err := json.Unmarshal(data, &value); err != nil {
+			return err
+		}
+		*s = StopSequences{value}
+		return nil
+	}
+
+	var values []string
+	if err := json.Unmarshal(data, &values); err != nil {
+		return fmt.Errorf("stop must be a string or an array of strings: %w", err)
+	}
+	*s = values
+	return nil
+}
+
+// MarshalJSON 有内容片段时content序列化为数组，只有工具调用时为null
+func (m Message) MarshalJSON() ([]byte, error) {
+	type message Message
+	out := struct {
+		message
+		Content interface{} `json:"content"`
+	}{message: message(m), Content: m.Content}
+
+	if len(m.Parts) > 0 {
+		out.Content = m.Parts
+	} else if m.Content == "" && len(m.ToolCalls) > 0 {
+		out.Content = nil
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON 解析字符串、内容片段数组或null格式的content
+func (m *Message) UnmarshalJSON(data []byte) error {
+	type message Message
+	aux := struct {
+		*message
+		Content json.RawMessage `json:"content"`
+	}{message: (*message)(m)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	m.Content, m.Parts = "", nil
+	content := bytes.TrimSpace(aux.Content)
+	switch {
+	case len(content) == 0 || string(content) == "null":
+		return nil
+	case content[0] == '"':
+		return json.Unmarshal(content, &m.Content)
+	}
+
+	if err := json.Unmarshal(content, &m.Parts); err != nil {
+		return fmt.Errorf("content must be a string or an array of content parts: %w", err)
+	}
+	texts := make([]string, 0, len(m.Parts))
+	for _, part := range m.Parts {
+		if part.Type == "text" {
+			texts = append(texts, part.Text)
+		}
+	}
+	m.Content = strings.Join(texts, "\n")
+	return nil
+}
+
+// MergeToolCalls 按Index合并流式响应中的工具调用增量
+func MergeToolCalls(calls, deltas []ToolCall) []ToolCall {
+	for _, delta := range deltas {
+		found := false
+		for i := range calls {
+			if calls[i].Index != delta.Index {
+				continue
+			}
+			if delta.ID != "" {
+				calls[i].ID = delta.ID
+			}
+			if delta.Type != "" {
+				calls[i].Type = delta.Type
+			}
+			if delta.Function.Name != "" {
+				calls[i].Function.Name = delta.Function.Name
+			}
+			calls[i].Function.Arguments += delta.Function.Arguments
+			found = true
+			break
+		}
+		if !found {
+			calls = append(calls, delta)
+		}
+	}
+	return calls
+}
+
+// ContentParts 返回消息的内容片段，纯文本消息返回单个文本片段
+func (m Message) ContentParts() []ContentPart {
+	if len(m.Parts) > 0 {
+		return m.Parts
+	}
+	if m.Content == "" {
+		return nil
+	}
+	return []ContentPart{{Type: "text", Text: m.Content}}
+}
+
+// Text 返回消息的文本内容，多模态消息拼接所有文本片段
+func (m Message) Text() string {
+	if len(m.Parts) == 0 {
+		return m.Content
+	}
+	texts := make([]string, 0, len(m.Parts))
+	for _, part := range m.Parts {
+		if part.Text != "" {
+			texts = append(texts, part.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// ParseDataURL 解析"data:<media type>;base64,<data>"格式的内联图片
+// 返回媒体类型和base64数据，不是该格式时ok为false
+func ParseDataURL(url string) (mediaType, data string, ok bool) {
+	rest, found := strings.CutPrefix(url, "data:")
+	if !found {
+		return "", "", false
+	}
+	header, data, found := strings.Cut(rest, ",")
+	if !found {
+		return "", "", false
+	}
+	mediaType, found = strings.CutSuffix(header, ";base64")
+	if !found {
+		return "", "", false
+	}
+	return mediaType, data, true
+}
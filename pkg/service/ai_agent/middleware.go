@@ -0,0 +1,304 @@
+package ai_agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// 中间件处理的调用类型
+const (
+	OperationChat             = "chat"
+	OperationChatStream       = "chat_stream"
+	OperationCompletion       = "completion"
+	OperationCompletionStream = "completion_stream"
+	OperationEmbedding        = "embedding"
+)
+
+// ErrInvalidRequest 请求参数无效，中间件校验失败时返回包装该错误的错误
+var ErrInvalidRequest = errors.New("invalid request")
+
+// CallInfo 中间件可以从上下文获取的调用信息
+type CallInfo struct {
+	Agent     string // AI Agent名称
+	Operation string // 调用类型
+}
+
+// TargetResolver 返回以name转发调用的目标AI Agent（如逻辑模型路由的各目标），name不转发调用时返回nil
+type TargetResolver func(name string) []string
+
+// InScope 判断AI Agent是否在按名称限定的范围内，agents为空表示全部
+// 逻辑模型路由以路由名执行中间件，路由的任一目标在范围内时整个路由都视为在范围内
+func InScope(agents map[string]bool, name string, resolve TargetResolver) bool {
+	if len(agents) == 0 || agents[name] {
+		return true
+	}
+	if resolve == nil {
+		return false
+	}
+	for _, target := range resolve(name) {
+		if agents[target] {
+			return true
+		}
+	}
+	return false
+}
+
+// ChatStreamResult ChatStream经过中间件时的响应，中间件可以替换通道以处理流式数据块
+type ChatStreamResult struct {
+	Chunks <-chan *ChatResponse
+	Errors <-chan error
+}
+
+// CompletionStreamResult CompletionStream经过中间件时的响应
+type CompletionStreamResult struct {
+	Chunks <-chan *CompletionResponse
+	Errors <-chan error
+}
+
+// MiddlewareHandler 中间件链中的下一个处理函数
+type MiddlewareHandler func(ctx context.Context, req interface{}) (interface{}, error)
+
+// middlewareFunc 函数形式的中间件
+type middlewareFunc struct {
+	name    string
+	process func(ctx context.Context, req interface{}, next func(context.Context, interface{}) (interface{}, error)) (interface{}, error)
+}
+
+// callInfoKey CallInfo在上下文中的键
+type callInfoKey struct{}
+
+// middlewareAgent 执行中间件链的AI Agent
+// 请求依次为*ChatRequest、*CompletionRequest或*EmbeddingRequest，响应为对应的*ChatResponse、*CompletionResponse、
+// *EmbeddingResponse，流式调用的响应为*ChatStreamResult或*CompletionStreamResult
+type middlewareAgent struct {
+	AIAgent
+	name        string
+	middlewares []AIAgentMiddleware
+}
+
+// NewMiddleware 使用函数创建中间件
+func NewMiddleware(name string, process func(ctx context.Context, req interface{}, next func(context.Context, interface{}) (interface{}, error)) (interface{}, error)) AIAgentMiddleware {
+	return &middlewareFunc{name: name, process: process}
+}
+
+// Process 实现AIAgentMiddleware接口的Process方法
+func (m *middlewareFunc) Process(ctx context.Context, req interface{}, next func(context.Context, interface{}) (interface{}, error)) (interface{}, error) {
+	return m.process(ctx, req, next)
+}
+
+// Name 实现AIAgentMiddleware接口的Name方法
+func (m *middlewareFunc) Name() string {
+	return m.name
+}
+
+// CallInfoFromContext 获取中间件链中的调用信息
+func CallInfoFromContext(ctx context.Context) (CallInfo, bool) {
+	info, ok := ctx.Value(callInfoKey{}).(CallInfo)
+	return info, ok
+}
+
+// WithMiddlewares 返回执行中间件链的AI Agent，中间件为空时返回原Agent
+// 中间件按顺序执行，第一个中间件在最外层
+func WithMiddlewares(name string, agent AIAgent, middlewares []AIAgentMiddleware) AIAgent {
+	if len(middlewares) == 0 {
+		return agent
+	}
+	return &middlewareAgent{AIAgent: agent, name: name, middlewares: middlewares}
+}
+
+// run 依次执行中间件，最后调用final
+func (a *middlewareAgent) run(ctx context.Context, operation string, req interface{}, final MiddlewareHandler) (interface{}, error) {
+	handler := final
+	for i := len(a.middlewares) - 1; i >= 0; i-- {
+		middleware, next := a.middlewares[i], handler
+		handler = func(ctx context.Context, req interface{}) (interface{}, error) {
+			return middleware.Process(ctx, req, next)
+		}
+	}
+	return handler(context.WithValue(ctx, callInfoKey{}, CallInfo{Agent: a.name, Operation: operation}), req)
+}
+
+// Chat 实现AIAgent接口的Chat方法
+func (a *middlewareAgent) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	resp, err := a.run(ctx, OperationChat, &req, func(ctx context.Context, req interface{}) (interface{}, error) {
+		chatReq, ok := req.(*ChatRequest)
+		if !ok {
+			return nil, unexpectedType("request", req)
+		}
+		return a.AIAgent.Chat(ctx, *chatReq)
+	})
+	if err != nil {
+		return nil, err
+	}
+	result, ok := resp.(*ChatResponse)
+	if !ok {
+		return nil, unexpectedType("response", resp)
+	}
+	return result, nil
+}
+
+// ChatStream 实现AIAgent接口的ChatStream方法
+func (a *middlewareAgent) ChatStream(ctx context.Context, req ChatRequest) (<-chan *ChatResponse, <-chan error) {
+	resp, err := a.run(ctx, OperationChatStream, &req, func(ctx context.Context, req interface{}) (interface{}, error) {
+		chatReq, ok := req.(*ChatRequest)
+		if !ok {
+			return nil, unexpectedType("request", req)
+		}
+		chunks, errs := a.AIAgent.ChatStream(ctx, *chatReq)
+		return &ChatStreamResult{Chunks: chunks, Errors: errs}, nil
+	})
+	if err == nil {
+		if result, ok := resp.(*ChatStreamResult); ok {
+			return result.Chunks, result.Errors
+		}
+		err = unexpectedType("response", resp)
+	}
+	return failedStream[*ChatResponse](err)
+}
+
+// Completion 实现AIAgent接口的Completion方法
+func (a *middlewareAgent) Completion(ctx context.Context, req CompletionRequest) (*CompletionResponse, error) {
+	resp, err := a.run(ctx, OperationCompletion, &req, func(ctx context.Context, req interface{}) (interface{}, error) {
+		completionReq, ok := req.(*CompletionRequest)
+		if !ok {
+			return nil, unexpectedType("request", req)
+		}
+		return a.AIAgent.Completion(ctx, *completionReq)
+	})
+	if err != nil {
+		return nil, err
+	}
+	result, ok := resp.(*CompletionResponse)
+	if !ok {
+		return nil, unexpectedType("response", resp)
+	}
+	return result, nil
+}
+
+// CompletionStream 实现AIAgent接口的CompletionStream方法
+func (a *middlewareAgent) CompletionStream(ctx context.Context, req CompletionRequest) (<-chan *CompletionResponse, <-chan error) {
+	resp, err := a.run(ctx, OperationCompletionStream, &req, func(ctx context.Context, req interface{}) (interface{}, error) {
+		completionReq, ok := req.(*CompletionRequest)
+		if !ok {
+			return nil, unexpectedType("request", req)
+		}
+		chunks, errs := a.AIAgent.CompletionStream(ctx, *completionReq)
+		return &CompletionStreamResult{Chunks: chunks, Errors: errs}, nil
+	})
+	if err == nil {
+		if result, ok := resp.(*CompletionStreamResult); ok {
+			return result.Chunks, result.Errors
+		}
+		err = unexpectedType("response", resp)
+	}
+	return failedStream[*CompletionResponse](err)
+}
+
+// Embedding 实现AIAgent接口的Embedding方法
+func (a *middlewareAgent) Embedding(ctx context.Context, req EmbeddingRequest) (*EmbeddingResponse, error) {
+	resp, err := a.run(ctx, OperationEmbedding, &req, func(ctx context.Context, req interface{}) (interface{}, error) {
+		embeddingReq, ok := req.(*EmbeddingRequest)
+		if !ok {
+			return nil, unexpectedType("request", req)
+		}
+		return a.AIAgent.Embedding(ctx, *embeddingReq)
+	})
+	if err != nil {
+		return nil, err
+	}
+	result, ok := resp.(*EmbeddingResponse)
+	if !ok {
+		return nil, unexpectedType("response", resp)
+	}
+	return result, nil
+}
+
+// failedStream 返回只包含一个错误的流
+func failedStream[T any](err error) (<-chan T, <-chan error) {
+	respChan := make(chan T)
+	errChan := make(chan error, 1)
+	close(respChan)
+	errChan <- err
+	close(errChan)
+	return respChan, errChan
+}
+
+// StreamHooks ForwardStream转发时的处理函数，均可以为空
+type StreamHooks[T any] struct {
+	// Transform 转换每个数据块
+	Transform func(T) T
+	// Final 数据块通道关闭且没有出错时调用，返回的数据块作为最后一个数据块发送
+	Final func() (T, bool)
+	// Done 转发结束后、关闭输出通道前调用，chunks为收到的数据块数；err为第一个错误，调用方不再读取时为上下文的错误
+	Done func(chunks int, err error)
+}
+
+// ForwardStream 转发流式数据块和错误，供需要处理流式响应的中间件和包装器使用
+// 调用方只读取一个错误，因此只转发第一个非nil错误；调用方不再读取时随上下文取消退出，避免转发协程阻塞
+func ForwardStream[T any](ctx context.Context, chunks <-chan T, errs <-chan error, hooks StreamHooks[T]) (<-chan T, <-chan error) {
+	outChunks := make(chan T)
+	outErrs := make(chan error, 1)
+
+	go func() {
+		defer close(outErrs)
+		defer close(outChunks)
+
+		count := 0
+		var streamErr error
+		if hooks.Done != nil {
+			defer func() { hooks.Done(count, streamErr) }()
+		}
+
+		send := func(chunk T) bool {
+			select {
+			case outChunks <- chunk:
+				return true
+			case <-ctx.Done():
+				if streamErr == nil {
+					streamErr = ctx.Err()
+				}
+				return false
+			}
+		}
+
+		for chunks != nil || errs != nil {
+			select {
+			case chunk, ok := <-chunks:
+				if !ok {
+					chunks = nil
+					if hooks.Final != nil && streamErr == nil {
+						if rest, exists := hooks.Final(); exists && !send(rest) {
+							return
+						}
+					}
+					continue
+				}
+				count++
+				if hooks.Transform != nil {
+					chunk = hooks.Transform(chunk)
+				}
+				if !send(chunk) {
+					return
+				}
+			case err, ok := <-errs:
+				if !ok {
+					errs = nil
+					continue
+				}
+				if err == nil || streamErr != nil {
+					continue
+				}
+				streamErr = err
+				outErrs <- err
+			}
+		}
+	}()
+
+	return outChunks, outErrs
+}
+
+// unexpectedType 中间件返回了类型不匹配的请求或响应
+func unexpectedType(kind string, value interface{}) error {
+	return fmt.Errorf("middleware returned unexpected %s type %T", kind, value)
+}
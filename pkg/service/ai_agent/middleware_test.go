@@ -0,0 +1,122 @@
+package ai_agent
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestInScope(t *testing.T) {
+	routes := map[string][]string{"smart": {"openai-prod", "ollama"}}
+	resolve := func(name string) []string { return routes[name] }
+	agents := map[string]bool{"openai-prod": true}
+
+	tests := []struct {
+		name    string
+		agents  map[string]bool
+		resolve TargetResolver
+		want    bool
+	}{
+		{"openai-prod", agents, resolve, true},
+		{"ollama", agents, resolve, false},
+		// 经过逻辑模型路由到范围内的目标
+		{"smart", agents, resolve, true},
+		{"smart", agents, nil, false},
+		{"anything", nil, resolve, true},
+	}
+	for _, tt := range tests {
+		if got := InScope(tt.agents, tt.name, tt.resolve); got != tt.want {
+			t.Errorf("InScope(%v, %q) = %v, want %v", tt.agents, tt.name, got, tt.want)
+		}
+	}
+}
+
+// testStream 依次发送数据块和错误后关闭通道，通道不带缓冲，保证转发时按发送顺序收到
+func testStream(chunks []int, errs ...error) (<-chan int, <-chan error) {
+	chunkChan := make(chan int)
+	errChan := make(chan error)
+	go func() {
+		for _, chunk := range chunks {
+			chunkChan <- chunk
+		}
+		for _, err := range errs {
+			errChan <- err
+		}
+		close(chunkChan)
+		close(errChan)
+	}()
+	return chunkChan, errChan
+}
+
+func TestForwardStream(t *testing.T) {
+	first := errors.New("first")
+	tests := []struct {
+		name       string
+		errs       []error
+		wantChunks []int
+		wantErr    error
+	}{
+		{"success", nil, []int{10, 20, 30}, nil},
+		// 只转发第一个错误，出错时不发送最后的数据块
+		{"first error only", []error{nil, first, errors.New("second")}, []int{10, 20}, first},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var doneCount int
+			var doneErr error
+			doneCalled := false
+			chunks, errs := testStream([]int{1, 2}, tt.errs...)
+			out, outErrs := ForwardStream(context.Background(), chunks, errs, StreamHooks[int]{
+				Transform: func(chunk int) int { return chunk * 10 },
+				Final:     func() (int, bool) { return 30, true },
+				Done:      func(chunks int, err error) { doneCount, doneErr, doneCalled = chunks, err, true },
+			})
+
+			var got []int
+			for chunk := range out {
+				got = append(got, chunk)
+			}
+			// 输出通道关闭前已经调用Done
+			if !doneCalled {
+				t.Fatal("Done not called before the chunk channel was closed")
+			}
+			if err := <-outErrs; err != tt.wantErr {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+			if err, ok := <-outErrs; ok {
+				t.Errorf("unexpected second error %v", err)
+			}
+			if len(got) != len(tt.wantChunks) {
+				t.Fatalf("chunks = %v, want %v", got, tt.wantChunks)
+			}
+			for i := range got {
+				if got[i] != tt.wantChunks[i] {
+					t.Fatalf("chunks = %v, want %v", got, tt.wantChunks)
+				}
+			}
+			if doneCount != 2 || doneErr != tt.wantErr {
+				t.Errorf("Done(%d, %v), want Done(2, %v)", doneCount, doneErr, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestForwardStreamCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	chunks := make(chan int)
+	errs := make(chan error)
+	done := make(chan error, 1)
+	out, outErrs := ForwardStream(ctx, chunks, errs, StreamHooks[int]{Done: func(_ int, err error) { done <- err }})
+
+	// 调用方不再读取，转发协程随上下文取消退出
+	chunks <- 1
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Done err = %v, want context.Canceled", err)
+	}
+	for range out {
+	}
+	if err := <-outErrs; err != nil {
+		t.Errorf("err = %v, want nil", err)
+	}
+}
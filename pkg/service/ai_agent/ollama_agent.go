@@ -0,0 +1,485 @@
+package ai_agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Ollama兼容服务的默认配置
+const (
+	DefaultOllamaBaseURL = "http://localhost:11434"
+	defaultOllamaTimeout = 300
+)
+
+// OllamaAgent 访问Ollama兼容本地模型服务的AI Agent
+// 流式响应为以换行分隔的JSON（NDJSON）
+// 支持的配置项：
+//   - base_url: 接口地址，默认http://localhost:11434
+//   - timeout: 非流式请求超时时间(秒)，本地模型加载较慢，默认300
+//   - keep_alive: 模型在内存中保留的时间，如"5m"
+//   - default_model、model_map、headers: 同OpenAIAgent
+type OllamaAgent struct {
+	*BaseAIAgent
+	client    *httpClient
+	keepAlive string
+}
+
+// OllamaAgentFactory OllamaAgent的工厂实现
+type OllamaAgentFactory struct {
+	name   string
+	config map[string]interface{}
+}
+
+// ollamaResponse chat和generate接口的响应，流式响应的每一行也是该结构
+type ollamaResponse struct {
+	Model           string        `json:"model"`
+	CreatedAt       string        `json:"created_at"`
+	Message         ollamaMessage `json:"message"`
+	Response        string        `json:"response"`
+	Done            bool          `json:"done"`
+	DoneReason      string        `json:"done_reason"`
+	PromptEvalCount int           `json:"prompt_eval_count"`
+	EvalCount       int           `json:"eval_count"`
+	Error           string        `json:"error"`
+}
+
+// ollamaMessage chat接口的消息，图片为base64数据列表
+type ollamaMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	Images    []string         `json:"images,omitempty"`
+	ToolCalls []ollamaToolCall `json:"tool_calls,omitempty"`
+}
+
+// ollamaToolCall chat接口的工具调用，参数为JSON对象而不是字符串
+type ollamaToolCall struct {
+	Function struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	} `json:"function"`
+}
+
+// NewOllamaAgent 创建OllamaAgent实例
+// config作为默认配置，Init时传入的配置项会覆盖同名配置
+func NewOllamaAgent(name string, config map[string]interface{}) *OllamaAgent {
+	agent := &OllamaAgent{
+		BaseAIAgent: NewBaseAIAgent(name, "1.0.0"),
+	}
+	for key, value := range config {
+		agent.config[key] = value
+	}
+	return agent
+}
+
+// NewOllamaAgentFactory 创建OllamaAgentFactory实例
+func NewOllamaAgentFactory(name string, config map[string]interface{}) *OllamaAgentFactory {
+	return &OllamaAgentFactory{name: name, config: config}
+}
+
+// Init 初始化OllamaAgent
+func (a *OllamaAgent) Init(config map[string]interface{}) error {
+	merged := make(map[string]interface{}, len(a.config)+len(config))
+	for key, value := range a.config {
+		merged[key] = value
+	}
+	for key, value := range config {
+		merged[key] = value
+	}
+	if err := a.BaseAIAgent.Init(merged); err != nil {
+		return err
+	}
+
+	baseURL := a.ConfigString("base_url", DefaultOllamaBaseURL)
+	if _, err := url.Parse(baseURL); err != nil {
+		return errors.New("invalid base_url: " + baseURL)
+	}
+
+	timeout := time.Duration(a.ConfigInt("timeout", defaultOllamaTimeout)) * time.Second
+	a.client = newHTTPClient(baseURL, a.ConfigStringMap("headers"), timeout)
+	a.keepAlive = a.ConfigString("keep_alive", "")
+
+	a.GetLogger().Info("Ollama agent initialized",
+		zap.String("name", a.Name()),
+		zap.String("base_url", baseURL),
+	)
+	return nil
+}
+
+// requestBody 构造请求体，stream需要显式指定，Ollama默认使用流式响应
+func (a *OllamaAgent) requestBody(model string, options map[string]interface{}, stream bool) map[string]interface{} {
+	body := map[string]interface{}{
+		"model":  a.MapModel(model),
+		"stream": stream,
+	}
+	if len(options) > 0 {
+		body["options"] = options
+	}
+	if a.keepAlive != "" {
+		body["keep_alive"] = a.keepAlive
+	}
+	return body
+}
+
+// chatBody 构造chat接口请求体
+func (a *OllamaAgent) chatBody(req ChatRequest, stream bool) (map[string]interface{}, error) {
+	messages := make([]ollamaMessage, 0, len(req.Messages))
+	for _, message := range req.Messages {
+		converted, err := toOllamaMessage(message)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, converted)
+	}
+
+	options := ollamaOptions(req.Temperature, req.MaxTokens)
+	if req.TopP > 0 {
+		options["top_p"] = req.TopP
+	}
+	if req.Seed != nil {
+		options["seed"] = *req.Seed
+	}
+	if len(req.Stop) > 0 {
+		options["stop"] = req.Stop
+	}
+
+	body := a.requestBody(req.Model, options, stream)
+	body["messages"] = messages
+	if len(req.Tools) > 0 {
+		body["tools"] = req.Tools
+	}
+	if req.ResponseFormat != nil {
+		switch req.ResponseFormat.Type {
+		case "json_object":
+			body["format"] = "json"
+		case "json_schema":
+			if schema, ok := req.ResponseFormat.JSONSchema["schema"]; ok {
+				body["format"] = schema
+			}
+		}
+	}
+	return body, nil
+}
+
+// generateBody 构造generate接口请求体
+func (a *OllamaAgent) generateBody(req CompletionRequest, stream bool) map[string]interface{} {
+	body := a.requestBody(req.Model, ollamaOptions(req.Temperature, req.MaxTokens), stream)
+	body["prompt"] = req.Prompt
+	return body
+}
+
+// Chat 实现聊天功能
+func (a *OllamaAgent) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	body, err := a.chatBody(req, false)
+	if err != nil {
+		return nil, err
+	}
+	var resp ollamaResponse
+	if err := a.client.postJSON(ctx, "/api/chat", body, &resp); err != nil {
+		return nil, err
+	}
+	return ollamaToChat(&resp, "chat.completion"), nil
+}
+
+// ChatStream 实现流式聊天功能，逐行解析NDJSON响应
+func (a *OllamaAgent) ChatStream(ctx context.Context, req ChatRequest) (<-chan *ChatResponse, <-chan error) {
+	respChan := make(chan *ChatResponse)
+	errChan := make(chan error, 1)
+
+	go func() {
+		defer close(respChan)
+		defer close(errChan)
+
+		body, err := a.chatBody(req, true)
+		if err != nil {
+			errChan <- err
+			return
+		}
+		// 工具调用在结束前的数据块中返回，结束原因需要据此修正
+		toolCalls := false
+		err = a.stream(ctx, "/api/chat", body, func(resp *ollamaResponse) bool {
+			chunk := ollamaToChat(resp, "chat.completion.chunk")
+			toolCalls = toolCalls || len(resp.Message.ToolCalls) > 0
+			if toolCalls && chunk.Choices[0].FinishReason == "stop" {
+				chunk.Choices[0].FinishReason = "tool_calls"
+			}
+			return sendStream(ctx, respChan, chunk)
+		})
+		if err != nil {
+			errChan <- err
+		}
+	}()
+
+	return respChan, errChan
+}
+
+// Completion 实现文本生成功能
+func (a *OllamaAgent) Completion(ctx context.Context, req CompletionRequest) (*CompletionResponse, error) {
+	var resp ollamaResponse
+	if err := a.client.postJSON(ctx, "/api/generate", a.generateBody(req, false), &resp); err != nil {
+		return nil, err
+	}
+	return ollamaToCompletion(&resp, "text_completion"), nil
+}
+
+// CompletionStream 实现流式文本生成功能，逐行解析NDJSON响应
+func (a *OllamaAgent) CompletionStream(ctx context.Context, req CompletionRequest) (<-chan *CompletionResponse, <-chan error) {
+	respChan := make(chan *CompletionResponse)
+	errChan := make(chan error, 1)
+
+	go func() {
+		defer close(respChan)
+		defer close(errChan)
+
+		err := a.stream(ctx, "/api/generate", a.generateBody(req, true), func(resp *ollamaResponse) bool {
+			return sendStream(ctx, respChan, ollamaToCompletion(resp, "text_completion.chunk"))
+		})
+		if err != nil {
+			errChan <- err
+		}
+	}()
+
+	return respChan, errChan
+}
+
+// stream 发送流式请求，逐行解析直到done为true
+// handler返回false表示调用方已取消
+func (a *OllamaAgent) stream(ctx context.Context, path string, body interface{}, handler func(*ollamaResponse) bool) error {
+	resp, err := a.client.postStream(ctx, path, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	err = readNDJSON(resp.Body, func(line []byte) error {
+		var chunk ollamaResponse
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			return errors.New("decode stream chunk failed: " + err.Error())
+		}
+		if chunk.Error != "" {
+			return errors.New(chunk.Error)
+		}
+		if !handler(&chunk) {
+			return ctx.Err()
+		}
+		if chunk.Done {
+			return io.EOF
+		}
+		return nil
+	})
+	if err == nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// Embedding 实现嵌入向量生成功能
+func (a *OllamaAgent) Embedding(ctx context.Context, req EmbeddingRequest) (*EmbeddingResponse, error) {
+	body := map[string]interface{}{
+		"model": a.MapModel(req.Model),
+		"input": req.Input,
+	}
+	if a.keepAlive != "" {
+		body["keep_alive"] = a.keepAlive
+	}
+
+	var resp struct {
+		Model           string      `json:"model"`
+		Embeddings      [][]float64 `json:"embeddings"`
+		PromptEvalCount int         `json:"prompt_eval_count"`
+	}
+	if err := a.client.postJSON(ctx, "/api/embed", body, &resp); err != nil {
+		return nil, err
+	}
+
+	result := &EmbeddingResponse{
+		Object:  "list",
+		Created: time.Now().Unix(),
+		Model:   resp.Model,
+		Data:    make([]EmbeddingData, 0, len(resp.Embeddings)),
+		Usage: EmbeddingUsage{
+			PromptTokens: resp.PromptEvalCount,
+			TotalTokens:  resp.PromptEvalCount,
+		},
+	}
+	for i, embedding := range resp.Embeddings {
+		result.Data = append(result.Data, EmbeddingData{Index: i, Embedding: embedding, Object: "embedding"})
+	}
+	return result, nil
+}
+
+// BatchEmbedding 实现批量嵌入向量生成功能，同一模型的请求合并为一次上游调用
+func (a *OllamaAgent) BatchEmbedding(ctx context.Context, req []EmbeddingRequest) ([]*EmbeddingResponse, error) {
+	return batchByModel(ctx, req, a.Embedding)
+}
+
+// ListModels 通过tags接口查询本地模型列表
+func (a *OllamaAgent) ListModels(ctx context.Context) ([]string, error) {
+	var resp struct {
+		Models []struct {
+			Name  string `json:"name"`
+			Model string `json:"model"`
+		} `json:"models"`
+	}
+	if err := a.client.getJSON(ctx, "/api/tags", &resp); err != nil {
+		return nil, err
+	}
+
+	models := make([]string, 0, len(resp.Models))
+	for _, model := range resp.Models {
+		name := model.Name
+		if name == "" {
+			name = model.Model
+		}
+		models = append(models, name)
+	}
+	return models, nil
+}
+
+// GetModel 通过show接口查询模型详情
+func (a *OllamaAgent) GetModel(ctx context.Context, modelName string) (map[string]interface{}, error) {
+	var model map[string]interface{}
+	if err := a.client.postJSON(ctx, "/api/show", map[string]interface{}{"model": a.MapModel(modelName)}, &model); err != nil {
+		return nil, err
+	}
+	model["name"] = modelName
+	return model, nil
+}
+
+// HealthCheck 检查本地模型服务是否可用
+func (a *OllamaAgent) HealthCheck() error {
+	if a.client == nil {
+		return errors.New("agent not initialized")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return a.client.getJSON(ctx, "/api/version", nil)
+}
+
+// Create 实现AIAgentFactory接口的Create方法
+func (f *OllamaAgentFactory) Create() (AIAgent, error) {
+	return NewOllamaAgent(f.name, f.config), nil
+}
+
+// Name 实现AIAgentFactory接口的Name方法
+func (f *OllamaAgentFactory) Name() string {
+	return f.name
+}
+
+// ollamaOptions 构造模型参数
+func ollamaOptions(temperature float64, maxTokens int) map[string]interface{} {
+	options := map[string]interface{}{}
+	if temperature > 0 {
+		options["temperature"] = temperature
+	}
+	if maxTokens > 0 {
+		options["num_predict"] = maxTokens
+	}
+	return options
+}
+
+// toOllamaMessage 将消息转换为chat接口格式，图片只支持base64内联数据
+func toOllamaMessage(message Message) (ollamaMessage, error) {
+	converted := ollamaMessage{Role: message.Role, Content: message.Content}
+	for _, part := range message.Parts {
+		if part.Type != "image_url" || part.ImageURL == nil {
+			continue
+		}
+		_, data, ok := ParseDataURL(part.ImageURL.URL)
+		if !ok {
+			return ollamaMessage{}, errors.New("only base64 data URL images are supported")
+		}
+		converted.Images = append(converted.Images, data)
+	}
+
+	for _, call := range message.ToolCalls {
+		var toolCall ollamaToolCall
+		toolCall.Function.Name = call.Function.Name
+		toolCall.Function.Arguments = json.RawMessage(call.Function.Arguments)
+		if len(toolCall.Function.Arguments) == 0 {
+			toolCall.Function.Arguments = json.RawMessage("{}")
+		}
+		if !json.Valid(toolCall.Function.Arguments) {
+			return ollamaMessage{}, errors.New("invalid arguments for tool call " + call.ID)
+		}
+		converted.ToolCalls = append(converted.ToolCalls, toolCall)
+	}
+	return converted, nil
+}
+
+// fromOllamaMessage 将chat接口的消息转换为统一格式，工具调用没有ID时按序号生成
+func fromOllamaMessage(message ollamaMessage) Message {
+	converted := Message{Role: message.Role, Content: message.Content}
+	for i, call := range message.ToolCalls {
+		converted.ToolCalls = append(converted.ToolCalls, ToolCall{
+			Index:    i,
+			ID:       fmt.Sprintf("call_%d", i),
+			Type:     "function",
+			Function: FunctionCall{Name: call.Function.Name, Arguments: string(call.Function.Arguments)},
+		})
+	}
+	return converted
+}
+
+// ollamaFinishReason 转换结束原因，未结束时为空
+func ollamaFinishReason(resp *ollamaResponse) string {
+	if !resp.Done {
+		return ""
+	}
+	if resp.DoneReason == "length" {
+		return "length"
+	}
+	if len(resp.Message.ToolCalls) > 0 {
+		return "tool_calls"
+	}
+	return "stop"
+}
+
+// ollamaCreated 解析响应的创建时间
+func ollamaCreated(createdAt string) int64 {
+	if created, err := time.Parse(time.RFC3339Nano, createdAt); err == nil {
+		return created.Unix()
+	}
+	return time.Now().Unix()
+}
+
+// ollamaUsage 转换token用量，只在最后一个数据块中返回
+func ollamaUsage(resp *ollamaResponse) Usage {
+	return Usage{
+		PromptTokens:     resp.PromptEvalCount,
+		CompletionTokens: resp.EvalCount,
+		TotalTokens:      resp.PromptEvalCount + resp.EvalCount,
+	}
+}
+
+// ollamaToChat 将chat接口响应转换为统一的聊天响应
+func ollamaToChat(resp *ollamaResponse, object string) *ChatResponse {
+	return &ChatResponse{
+		Object:  object,
+		Created: ollamaCreated(resp.CreatedAt),
+		Model:   resp.Model,
+		Choices: []ChatChoice{{
+			Index:        0,
+			Message:      fromOllamaMessage(resp.Message),
+			FinishReason: ollamaFinishReason(resp),
+		}},
+		Usage: ollamaUsage(resp),
+	}
+}
+
+// ollamaToCompletion 将generate接口响应转换为统一的文本生成响应
+func ollamaToCompletion(resp *ollamaResponse, object string) *CompletionResponse {
+	return &CompletionResponse{
+		Object:  object,
+		Created: ollamaCreated(resp.CreatedAt),
+		Model:   resp.Model,
+		Choices: []CompletionChoice{{Index: 0, Text: resp.Response}},
+		Usage:   ollamaUsage(resp),
+	}
+}
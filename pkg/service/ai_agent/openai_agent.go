@@ -0,0 +1,369 @@
+package ai_agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/url"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// OpenAI兼容服务的默认配置
+const (
+	DefaultOpenAIBaseURL = "https://api.openai.com/v1"
+	defaultAgentTimeout  = 60
+)
+
+// OpenAIAgent 访问OpenAI兼容HTTP接口的AI Agent
+// 支持的配置项：
+//   - base_url: 接口地址，默认https://api.openai.com/v1
+//   - api_key: API密钥，支持"${ENV}"格式从环境变量读取
+//   - organization: OpenAI组织ID
+//   - timeout: 非流式请求超时时间(秒)
+//   - default_model: 请求未指定模型时使用的模型
+//   - model_map: 网关模型名到上游模型名的映射
+//   - models: 静态模型列表，配置后ListModels不再请求上游
+//   - headers: 附加请求头
+type OpenAIAgent struct {
+	*BaseAIAgent
+	client *httpClient
+	models []string
+}
+
+// OpenAIAgentFactory OpenAIAgent的工厂实现
+type OpenAIAgentFactory struct {
+	name   string
+	config map[string]interface{}
+}
+
+// openAIStreamChoice 流式响应中的候选结果
+type openAIStreamChoice struct {
+	Index        int     `json:"index"`
+	Delta        Message `json:"delta"`
+	Text         string  `json:"text"`
+	FinishReason string  `json:"finish_reason"`
+}
+
+// openAIStreamChunk 流式响应中的数据块
+type openAIStreamChunk struct {
+	ID      string               `json:"id"`
+	Object  string               `json:"object"`
+	Created int64                `json:"created"`
+	Model   string               `json:"model"`
+	Choices []openAIStreamChoice `json:"choices"`
+	Usage   *Usage               `json:"usage"` // 只在请求stream_options.include_usage时的最后一个数据块中返回
+}
+
+// NewOpenAIAgent 创建OpenAIAgent实例
+// config作为默认配置，Init时传入的配置项会覆盖同名配置
+func NewOpenAIAgent(name string, config map[string]interface{}) *OpenAIAgent {
+	agent := &OpenAIAgent{
+		BaseAIAgent: NewBaseAIAgent(name, "1.0.0"),
+	}
+	for key, value := range config {
+		agent.config[key] = value
+	}
+	return agent
+}
+
+// NewOpenAIAgentFactory 创建OpenAIAgentFactory实例
+func NewOpenAIAgentFactory(name string, config map[string]interface{}) *OpenAIAgentFactory {
+	return &OpenAIAgentFactory{name: name, config: config}
+}
+
+// Init 初始化OpenAIAgent
+func (a *OpenAIAgent) Init(config map[string]interface{}) error {
+	merged := make(map[string]interface{}, len(a.config)+len(config))
+	for key, value := range a.config {
+		merged[key] = value
+	}
+	for key, value := range config {
+		merged[key] = value
+	}
+	if err := a.BaseAIAgent.Init(merged); err != nil {
+		return err
+	}
+
+	baseURL := a.ConfigString("base_url", DefaultOpenAIBaseURL)
+	if _, err := url.Parse(baseURL); err != nil {
+		return errors.New("invalid base_url: " + baseURL)
+	}
+
+	headers := a.ConfigStringMap("headers")
+	if apiKey := a.ConfigString("api_key", ""); apiKey != "" {
+		headers["Authorization"] = "Bearer " + apiKey
+	}
+	if organization := a.ConfigString("organization", ""); organization != "" {
+		headers["OpenAI-Organization"] = organization
+	}
+
+	timeout := time.Duration(a.ConfigInt("timeout", defaultAgentTimeout)) * time.Second
+	a.client = newHTTPClient(baseURL, headers, timeout)
+	a.models = a.ConfigStrings("models")
+
+	a.GetLogger().Info("OpenAI agent initialized",
+		zap.String("name", a.Name()),
+		zap.String("base_url", baseURL),
+	)
+	return nil
+}
+
+// chatBody 构造聊天请求体
+func (a *OpenAIAgent) chatBody(req ChatRequest, stream bool) map[string]interface{} {
+	body := map[string]interface{}{
+		"model":    a.MapModel(req.Model),
+		"messages": req.Messages,
+	}
+	if req.Temperature > 0 {
+		body["temperature"] = req.Temperature
+	}
+	if req.MaxTokens > 0 {
+		body["max_tokens"] = req.MaxTokens
+	}
+	if req.TopP > 0 {
+		body["top_p"] = req.TopP
+	}
+	if req.Seed != nil {
+		body["seed"] = *req.Seed
+	}
+	if len(req.Stop) > 0 {
+		body["stop"] = req.Stop
+	}
+	if req.ResponseFormat != nil {
+		body["response_format"] = req.ResponseFormat
+	}
+	if len(req.Tools) > 0 {
+		body["tools"] = req.Tools
+	}
+	if req.ToolChoice != nil {
+		body["tool_choice"] = req.ToolChoice
+	}
+	if stream {
+		body["stream"] = true
+	}
+	return body
+}
+
+// completionBody 构造文本生成请求体
+func (a *OpenAIAgent) completionBody(req CompletionRequest, stream bool) map[string]interface{} {
+	body := map[string]interface{}{
+		"model":  a.MapModel(req.Model),
+		"prompt": req.Prompt,
+	}
+	if req.Temperature > 0 {
+		body["temperature"] = req.Temperature
+	}
+	if req.MaxTokens > 0 {
+		body["max_tokens"] = req.MaxTokens
+	}
+	if stream {
+		body["stream"] = true
+	}
+	return body
+}
+
+// Chat 实现聊天功能
+func (a *OpenAIAgent) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	var resp ChatResponse
+	if err := a.client.postJSON(ctx, "/chat/completions", a.chatBody(req, false), &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ChatStream 实现流式聊天功能，解析上游SSE响应
+func (a *OpenAIAgent) ChatStream(ctx context.Context, req ChatRequest) (<-chan *ChatResponse, <-chan error) {
+	respChan := make(chan *ChatResponse)
+	errChan := make(chan error, 1)
+
+	go func() {
+		defer close(respChan)
+		defer close(errChan)
+
+		err := a.stream(ctx, "/chat/completions", a.chatBody(req, true), func(chunk *openAIStreamChunk) bool {
+			response := &ChatResponse{
+				ID:      chunk.ID,
+				Object:  chunk.Object,
+				Created: chunk.Created,
+				Model:   chunk.Model,
+				Choices: make([]ChatChoice, 0, len(chunk.Choices)),
+			}
+			for _, choice := range chunk.Choices {
+				response.Choices = append(response.Choices, ChatChoice{
+					Index:        choice.Index,
+					Message:      choice.Delta,
+					FinishReason: choice.FinishReason,
+				})
+			}
+			if chunk.Usage != nil {
+				response.Usage = *chunk.Usage
+			}
+			return sendStream(ctx, respChan, response)
+		})
+		if err != nil {
+			errChan <- err
+		}
+	}()
+
+	return respChan, errChan
+}
+
+// Completion 实现文本生成功能
+func (a *OpenAIAgent) Completion(ctx context.Context, req CompletionRequest) (*CompletionResponse, error) {
+	var resp CompletionResponse
+	if err := a.client.postJSON(ctx, "/completions", a.completionBody(req, false), &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// CompletionStream 实现流式文本生成功能，解析上游SSE响应
+func (a *OpenAIAgent) CompletionStream(ctx context.Context, req CompletionRequest) (<-chan *CompletionResponse, <-chan error) {
+	respChan := make(chan *CompletionResponse)
+	errChan := make(chan error, 1)
+
+	go func() {
+		defer close(respChan)
+		defer close(errChan)
+
+		err := a.stream(ctx, "/completions", a.completionBody(req, true), func(chunk *openAIStreamChunk) bool {
+			response := &CompletionResponse{
+				ID:      chunk.ID,
+				Object:  chunk.Object,
+				Created: chunk.Created,
+				Model:   chunk.Model,
+				Choices: make([]CompletionChoice, 0, len(chunk.Choices)),
+			}
+			for _, choice := range chunk.Choices {
+				response.Choices = append(response.Choices, CompletionChoice{Index: choice.Index, Text: choice.Text})
+			}
+			return sendStream(ctx, respChan, response)
+		})
+		if err != nil {
+			errChan <- err
+		}
+	}()
+
+	return respChan, errChan
+}
+
+// stream 发送流式请求，逐个解析data事件直到[DONE]
+// handler返回false表示调用方已取消
+func (a *OpenAIAgent) stream(ctx context.Context, path string, body interface{}, handler func(*openAIStreamChunk) bool) error {
+	resp, err := a.client.postStream(ctx, path, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	err = readSSE(resp.Body, func(event, data string) error {
+		if data == "[DONE]" {
+			return io.EOF
+		}
+
+		// 部分服务在流中返回错误事件
+		var chunk struct {
+			openAIStreamChunk
+			Error *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return errors.New("decode stream chunk failed: " + err.Error())
+		}
+		if chunk.Error != nil {
+			return errors.New(chunk.Error.Message)
+		}
+		if !handler(&chunk.openAIStreamChunk) {
+			return ctx.Err()
+		}
+		return nil
+	})
+	if err == nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// Embedding 实现嵌入向量生成功能
+func (a *OpenAIAgent) Embedding(ctx context.Context, req EmbeddingRequest) (*EmbeddingResponse, error) {
+	body := map[string]interface{}{
+		"model": a.MapModel(req.Model),
+		"input": req.Input,
+	}
+
+	var resp EmbeddingResponse
+	if err := a.client.postJSON(ctx, "/embeddings", body, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// BatchEmbedding 实现批量嵌入向量生成功能，同一模型的请求合并为一次上游调用
+func (a *OpenAIAgent) BatchEmbedding(ctx context.Context, req []EmbeddingRequest) ([]*EmbeddingResponse, error) {
+	return batchByModel(ctx, req, a.Embedding)
+}
+
+// ListModels 实现模型列表查询功能
+func (a *OpenAIAgent) ListModels(ctx context.Context) ([]string, error) {
+	if len(a.models) > 0 {
+		return a.models, nil
+	}
+
+	var resp struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := a.client.getJSON(ctx, "/models", &resp); err != nil {
+		return nil, err
+	}
+
+	models := make([]string, 0, len(resp.Data))
+	for _, model := range resp.Data {
+		models = append(models, model.ID)
+	}
+	return models, nil
+}
+
+// GetModel 实现模型详情查询功能
+func (a *OpenAIAgent) GetModel(ctx context.Context, modelName string) (map[string]interface{}, error) {
+	var model map[string]interface{}
+	if err := a.client.getJSON(ctx, "/models/"+url.PathEscape(a.MapModel(modelName)), &model); err != nil {
+		return nil, err
+	}
+	return model, nil
+}
+
+// HealthCheck 检查上游服务是否可用
+func (a *OpenAIAgent) HealthCheck() error {
+	if a.client == nil {
+		return errors.New("agent not initialized")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return a.client.getJSON(ctx, "/models", nil)
+}
+
+// Create 实现AIAgentFactory接口的Create方法
+func (f *OpenAIAgentFactory) Create() (AIAgent, error) {
+	return NewOpenAIAgent(f.name, f.config), nil
+}
+
+// Name 实现AIAgentFactory接口的Name方法
+func (f *OpenAIAgentFactory) Name() string {
+	return f.name
+}
+
+// sendStream 向流式响应通道发送数据，调用方取消时返回false
+func sendStream[T any](ctx context.Context, ch chan<- T, value T) bool {
+	select {
+	case ch <- value:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
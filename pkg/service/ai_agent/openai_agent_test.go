@@ -0,0 +1,352 @@
+package ai_agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"kai/kaigate/pkg/log"
+)
+
+func TestMain(m *testing.M) {
+	if err := log.InitLogger("error", "json", "", false); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// newTestOpenAIAgent 创建访问测试服务的OpenAIAgent
+func newTestOpenAIAgent(t *testing.T, handler http.HandlerFunc) *OpenAIAgent {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	agent := NewOpenAIAgent("openai", nil)
+	err := agent.Init(map[string]interface{}{
+		"base_url":  server.URL,
+		"api_key":   "sk-test",
+		"timeout":   5,
+		"model_map": map[string]interface{}{"gpt": "gpt-4o-mini"},
+	})
+	if err != nil {
+		t.Fatalf("init agent: %v", err)
+	}
+	return agent
+}
+
+// decodeBody 解析请求体
+func decodeBody(t *testing.T, r *http.Request) map[string]interface{} {
+	t.Helper()
+	var body map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		t.Errorf("decode request body: %v", err)
+	}
+	return body
+}
+
+// writeEvents 逐个写入SSE事件
+func writeEvents(w http.ResponseWriter, events ...string) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	for _, event := range events {
+		fmt.Fprintf(w, "data: %s\n\n", event)
+		w.(http.Flusher).Flush()
+	}
+}
+
+// collectStream 读取流式响应直到通道关闭
+func collectStream(t *testing.T, respChan <-chan *ChatResponse, errChan <-chan error) ([]*ChatResponse, error) {
+	t.Helper()
+	var chunks []*ChatResponse
+	timeout := time.After(5 * time.Second)
+	for respChan != nil {
+		select {
+		case chunk, ok := <-respChan:
+			if !ok {
+				respChan = nil
+				continue
+			}
+			chunks = append(chunks, chunk)
+		case <-timeout:
+			t.Fatal("stream did not finish")
+		}
+	}
+	return chunks, <-errChan
+}
+
+func TestOpenAIChat(t *testing.T) {
+	agent := newTestOpenAIAgent(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/chat/completions" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer sk-test" {
+			t.Errorf("Authorization = %q", got)
+		}
+		body := decodeBody(t, r)
+		if body["model"] != "gpt-4o-mini" {
+			t.Errorf("model = %v, want mapped model", body["model"])
+		}
+		if body["max_tokens"] != float64(16) {
+			t.Errorf("max_tokens = %v", body["max_tokens"])
+		}
+		if _, ok := body["stream"]; ok {
+			t.Error("non-stream request must not set stream")
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":      "chatcmpl-1",
+			"object":  "chat.completion",
+			"model":   "gpt-4o-mini",
+			"choices": []map[string]interface{}{{"index": 0, "message": map[string]string{"role": "assistant", "content": "pong"}, "finish_reason": "stop"}},
+			"usage":   map[string]int{"prompt_tokens": 3, "completion_tokens": 1, "total_tokens": 4},
+		})
+	})
+
+	resp, err := agent.Chat(context.Background(), ChatRequest{
+		Model:     "gpt",
+		Messages:  []Message{{Role: "user", Content: "ping"}},
+		MaxTokens: 16,
+	})
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if resp.ID != "chatcmpl-1" || len(resp.Choices) != 1 || resp.Choices[0].Message.Content != "pong" {
+		t.Errorf("unexpected response %+v", resp)
+	}
+	if resp.Usage.TotalTokens != 4 {
+		t.Errorf("usage = %+v", resp.Usage)
+	}
+}
+
+func TestOpenAIChatStream(t *testing.T) {
+	agent := newTestOpenAIAgent(t, func(w http.ResponseWriter, r *http.Request) {
+		if body := decodeBody(t, r); body["stream"] != true {
+			t.Errorf("stream = %v", body["stream"])
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		// 保活注释行应被忽略
+		fmt.Fprint(w, ": keep-alive\n\n")
+		writeEvents(w,
+			`{"id":"c1","model":"gpt-4o-mini","choices":[{"index":0,"delta":{"role":"assistant","content":"Hel"}}]}`,
+			`{"id":"c1","model":"gpt-4o-mini","choices":[{"index":0,"delta":{"content":"lo"},"finish_reason":"stop"}]}`,
+			`{"id":"c1","model":"gpt-4o-mini","choices":[],"usage":{"prompt_tokens":2,"completion_tokens":2,"total_tokens":4}}`,
+			`[DONE]`,
+			// [DONE]之后的数据不再处理
+			`{"id":"c2","choices":[{"index":0,"delta":{"content":"ignored"}}]}`,
+		)
+	})
+
+	respChan, errChan := agent.ChatStream(context.Background(), ChatRequest{Model: "gpt", Messages: []Message{{Role: "user", Content: "hi"}}})
+	chunks, err := collectStream(t, respChan, errChan)
+	if err != nil {
+		t.Fatalf("stream error: %v", err)
+	}
+	if len(chunks) != 3 {
+		t.Fatalf("got %d chunks, want 3", len(chunks))
+	}
+	content := ""
+	for _, chunk := range chunks {
+		for _, choice := range chunk.Choices {
+			content += choice.Message.Content
+		}
+	}
+	if content != "Hello" {
+		t.Errorf("content = %q", content)
+	}
+	if chunks[1].Choices[0].FinishReason != "stop" {
+		t.Errorf("finish_reason = %q", chunks[1].Choices[0].FinishReason)
+	}
+	if chunks[2].Usage.TotalTokens != 4 {
+		t.Errorf("usage = %+v", chunks[2].Usage)
+	}
+}
+
+func TestOpenAIChatStreamErrorEvent(t *testing.T) {
+	agent := newTestOpenAIAgent(t, func(w http.ResponseWriter, r *http.Request) {
+		writeEvents(w,
+			`{"id":"c1","choices":[{"index":0,"delta":{"content":"partial"}}]}`,
+			`{"error":{"message":"model overloaded"}}`,
+			`{"id":"c1","choices":[{"index":0,"delta":{"content":"ignored"}}]}`,
+		)
+	})
+
+	respChan, errChan := agent.ChatStream(context.Background(), ChatRequest{Model: "gpt"})
+	chunks, err := collectStream(t, respChan, errChan)
+	if err == nil || err.Error() != "model overloaded" {
+		t.Fatalf("err = %v, want in-stream error", err)
+	}
+	if len(chunks) != 1 {
+		t.Errorf("got %d chunks, want 1 before the error", len(chunks))
+	}
+}
+
+func TestOpenAIChatStreamMalformedChunk(t *testing.T) {
+	agent := newTestOpenAIAgent(t, func(w http.ResponseWriter, r *http.Request) {
+		writeEvents(w, `{"id":`)
+	})
+
+	respChan, errChan := agent.ChatStream(context.Background(), ChatRequest{Model: "gpt"})
+	if _, err := collectStream(t, respChan, errChan); err == nil {
+		t.Fatal("expected decode error")
+	}
+}
+
+func TestOpenAIChatStreamCancel(t *testing.T) {
+	closed := make(chan struct{})
+	agent := newTestOpenAIAgent(t, func(w http.ResponseWriter, r *http.Request) {
+		writeEvents(w, `{"id":"c1","choices":[{"index":0,"delta":{"content":"first"}}]}`)
+		// 不结束流，直到客户端断开
+		<-r.Context().Done()
+		close(closed)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	respChan, errChan := agent.ChatStream(ctx, ChatRequest{Model: "gpt"})
+
+	select {
+	case chunk := <-respChan:
+		if chunk == nil || chunk.Choices[0].Message.Content != "first" {
+			t.Fatalf("unexpected first chunk %+v", chunk)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("first chunk not received")
+	}
+	cancel()
+
+	_, err := collectStream(t, respChan, errChan)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Error("upstream connection was not closed after cancellation")
+	}
+}
+
+// embeddingHandler 返回按输入长度生成向量的嵌入接口，data按index倒序返回
+func embeddingHandler(t *testing.T, calls *[]EmbeddingRequest) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/embeddings" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		var req EmbeddingRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode request body: %v", err)
+		}
+		*calls = append(*calls, req)
+
+		data := make([]EmbeddingData, 0, len(req.Input))
+		for i := len(req.Input) - 1; i >= 0; i-- {
+			data = append(data, EmbeddingData{Index: i, Embedding: []float64{float64(len(req.Input[i]))}, Object: "embedding"})
+		}
+		json.NewEncoder(w).Encode(EmbeddingResponse{
+			Object: "list",
+			Model:  req.Model,
+			Data:   data,
+			Usage:  EmbeddingUsage{PromptTokens: len(req.Input) * 2, TotalTokens: len(req.Input) * 2},
+		})
+	}
+}
+
+func TestOpenAIEmbedding(t *testing.T) {
+	var calls []EmbeddingRequest
+	agent := newTestOpenAIAgent(t, embeddingHandler(t, &calls))
+
+	resp, err := agent.Embedding(context.Background(), EmbeddingRequest{Model: "gpt", Input: []string{"a", "bbb"}})
+	if err != nil {
+		t.Fatalf("Embedding: %v", err)
+	}
+	if len(calls) != 1 || calls[0].Model != "gpt-4o-mini" {
+		t.Fatalf("unexpected upstream calls %+v", calls)
+	}
+	if len(resp.Data) != 2 || resp.Usage.TotalTokens != 4 {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+}
+
+func TestOpenAIBatchEmbedding(t *testing.T) {
+	var calls []EmbeddingRequest
+	agent := newTestOpenAIAgent(t, embeddingHandler(t, &calls))
+
+	reqs := []EmbeddingRequest{
+		{Model: "text-embedding-3-small", Input: []string{"a", "bb"}},
+		{Model: "text-embedding-3-large", Input: []string{"ccc"}},
+		{Model: "text-embedding-3-small", Input: []string{"dddd"}},
+		{Model: "text-embedding-3-small", Input: []string{}},
+	}
+	resps, err := agent.BatchEmbedding(context.Background(), reqs)
+	if err != nil {
+		t.Fatalf("BatchEmbedding: %v", err)
+	}
+	// 同一模型的请求合并为一次上游调用
+	if len(calls) != 2 {
+		t.Fatalf("got %d upstream calls, want 2", len(calls))
+	}
+	if len(resps) != len(reqs) {
+		t.Fatalf("got %d responses, want %d", len(resps), len(reqs))
+	}
+	for i, req := range reqs {
+		resp := resps[i]
+		if len(resp.Data) != len(req.Input) {
+			t.Fatalf("response %d has %d items, want %d", i, len(resp.Data), len(req.Input))
+		}
+		for j, input := range req.Input {
+			if resp.Data[j].Index != j || resp.Data[j].Embedding[0] != float64(len(input)) {
+				t.Errorf("response %d item %d = %+v, want embedding of %q", i, j, resp.Data[j], input)
+			}
+		}
+	}
+	if resps[0].Usage.TotalTokens != 4 || resps[2].Usage.TotalTokens != 2 {
+		t.Errorf("usage not split by input count: %+v, %+v", resps[0].Usage, resps[2].Usage)
+	}
+}
+
+func TestOpenAIAPIError(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		message string
+	}{
+		{"nested error", http.StatusUnauthorized, `{"error":{"message":"invalid api key","type":"auth"}}`, "invalid api key"},
+		{"string error", http.StatusTooManyRequests, `{"error":"rate limited"}`, "rate limited"},
+		{"message field", http.StatusBadRequest, `{"message":"bad model"}`, "bad model"},
+		{"plain text", http.StatusBadGateway, "upstream unavailable\n", "upstream unavailable"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agent := newTestOpenAIAgent(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.body)
+			})
+
+			check := func(op string, err error) {
+				t.Helper()
+				var apiErr *APIError
+				if !errors.As(err, &apiErr) {
+					t.Fatalf("%s: err = %v, want APIError", op, err)
+				}
+				if apiErr.StatusCode != tt.status || apiErr.Message != tt.message {
+					t.Errorf("%s: got %d %q, want %d %q", op, apiErr.StatusCode, apiErr.Message, tt.status, tt.message)
+				}
+			}
+
+			_, err := agent.Chat(context.Background(), ChatRequest{Model: "gpt"})
+			check("Chat", err)
+			_, err = agent.Embedding(context.Background(), EmbeddingRequest{Model: "gpt", Input: []string{"a"}})
+			check("Embedding", err)
+			respChan, errChan := agent.ChatStream(context.Background(), ChatRequest{Model: "gpt"})
+			chunks, err := collectStream(t, respChan, errChan)
+			if len(chunks) != 0 {
+				t.Errorf("ChatStream: got %d chunks on error response", len(chunks))
+			}
+			check("ChatStream", err)
+		})
+	}
+}
@@ -0,0 +1,303 @@
+package ai_agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"kai/kaigate/pkg/log"
+)
+
+// FactoryPool 实例池在实例列表中显示的工厂名称
+const FactoryPool = "pool"
+
+// ErrNoHealthyMember 实例池中没有可用的健康实例
+var ErrNoHealthyMember = errors.New("no healthy member")
+
+// AgentPool AI Agent实例池
+// 以一个名称对外提供多个实例（如不同的API密钥或区域），每次调用转发给进行中请求数最少的健康实例；
+// 后台按间隔调用成员的HealthCheck，检查失败的实例移出轮换，恢复后重新加入
+type AgentPool struct {
+	*BaseAIAgent
+	members  []*poolMember
+	resolve  func(name string) (AIAgent, error)
+	interval time.Duration
+	next     uint64
+	done     chan struct{}
+	closed   sync.Once
+	logger   log.Logger
+}
+
+// poolMember 实例池成员及其状态
+type poolMember struct {
+	name        string
+	inFlight    int64
+	mutex       sync.RWMutex
+	healthy     bool
+	lastError   string
+	lastChecked time.Time
+}
+
+// AgentPoolStatus 实例池状态
+type AgentPoolStatus struct {
+	Name                string             `json:"name"`
+	HealthCheckInterval int                `json:"health_check_interval"` // 健康检查间隔(秒)
+	Healthy             int                `json:"healthy"`               // 健康实例数
+	Members             []PoolMemberStatus `json:"members"`
+}
+
+// PoolMemberStatus 实例池成员状态
+type PoolMemberStatus struct {
+	Name        string    `json:"name"`
+	Status      string    `json:"status"`    // 状态: healthy, unhealthy
+	InFlight    int64     `json:"in_flight"` // 进行中的请求数
+	Error       string    `json:"error,omitempty"`
+	LastChecked time.Time `json:"last_checked,omitempty"`
+}
+
+// newAgentPool 创建实例池并启动健康检查，resolve按名称获取成员实例
+func newAgentPool(name string, members []string, interval time.Duration, resolve func(string) (AIAgent, error), logger log.Logger) *AgentPool {
+	pool := &AgentPool{
+		BaseAIAgent: NewBaseAIAgent(name, "1.0.0"),
+		resolve:     resolve,
+		interval:    interval,
+		done:        make(chan struct{}),
+		logger:      logger,
+	}
+	// 首次检查完成前成员视为健康
+	for _, member := range members {
+		pool.members = append(pool.members, &poolMember{name: member, healthy: true})
+	}
+
+	go pool.run()
+	return pool
+}
+
+// run 按间隔执行健康检查，直到实例池关闭
+func (p *AgentPool) run() {
+	p.checkMembers()
+	if p.interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			p.checkMembers()
+		case <-p.done:
+			return
+		}
+	}
+}
+
+// checkMembers 并发检查全部成员的健康状态
+func (p *AgentPool) checkMembers() {
+	var wg sync.WaitGroup
+	for _, member := range p.members {
+		wg.Add(1)
+		go func(member *poolMember) {
+			defer wg.Done()
+			p.checkMember(member)
+		}(member)
+	}
+	wg.Wait()
+}
+
+// checkMember 检查单个成员，状态变化时记录日志
+func (p *AgentPool) checkMember(member *poolMember) {
+	agent, err := p.resolve(member.name)
+	if err == nil {
+		err = agent.HealthCheck()
+	}
+
+	member.mutex.Lock()
+	wasHealthy := member.healthy
+	member.healthy = err == nil
+	member.lastChecked = time.Now()
+	member.lastError = ""
+	if err != nil {
+		member.lastError = err.Error()
+	}
+	member.mutex.Unlock()
+
+	switch {
+	case wasHealthy && err != nil:
+		p.logger.Warn("Agent pool member unhealthy, removed from rotation",
+			zap.String("pool", p.Name()),
+			zap.String("member", member.name),
+			zap.Error(err),
+		)
+	case !wasHealthy && err == nil:
+		p.logger.Info("Agent pool member recovered, added back to rotation",
+			zap.String("pool", p.Name()),
+			zap.String("member", member.name),
+		)
+	}
+}
+
+// isHealthy 成员是否在轮换中
+func (m *poolMember) isHealthy() bool {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return m.healthy
+}
+
+// acquire 选择进行中请求数最少的健康成员并计入一个请求，请求数相同时轮流选择
+// 返回的release在调用结束后执行
+func (p *AgentPool) acquire() (AIAgent, func(), error) {
+	offset := int(atomic.AddUint64(&p.next, 1) % uint64(len(p.members)))
+
+	var selected *poolMember
+	var agent AIAgent
+	lastErr := ErrNoHealthyMember
+	for i := range p.members {
+		member := p.members[(offset+i)%len(p.members)]
+		if !member.isHealthy() {
+			continue
+		}
+		if selected != nil && atomic.LoadInt64(&member.inFlight) >= atomic.LoadInt64(&selected.inFlight) {
+			continue
+		}
+		candidate, err := p.resolve(member.name)
+		if err != nil {
+			lastErr = fmt.Errorf("%w: %s: %v", ErrNoHealthyMember, member.name, err)
+			continue
+		}
+		selected, agent = member, candidate
+	}
+	if selected == nil {
+		return nil, nil, fmt.Errorf("agent pool %s: %w", p.Name(), lastErr)
+	}
+
+	atomic.AddInt64(&selected.inFlight, 1)
+	return agent, func() { atomic.AddInt64(&selected.inFlight, -1) }, nil
+}
+
+// Chat 实现AIAgent接口的Chat方法
+func (p *AgentPool) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	agent, release, err := p.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return agent.Chat(ctx, req)
+}
+
+// ChatStream 实现AIAgent接口的ChatStream方法
+func (p *AgentPool) ChatStream(ctx context.Context, req ChatRequest) (<-chan *ChatResponse, <-chan error) {
+	agent, release, err := p.acquire()
+	if err != nil {
+		return failedStream[*ChatResponse](err)
+	}
+	chunks, errs := agent.ChatStream(ctx, req)
+	return ForwardStream(ctx, chunks, errs, StreamHooks[*ChatResponse]{Done: func(int, error) { release() }})
+}
+
+// Completion 实现AIAgent接口的Completion方法
+func (p *AgentPool) Completion(ctx context.Context, req CompletionRequest) (*CompletionResponse, error) {
+	agent, release, err := p.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return agent.Completion(ctx, req)
+}
+
+// CompletionStream 实现AIAgent接口的CompletionStream方法
+func (p *AgentPool) CompletionStream(ctx context.Context, req CompletionRequest) (<-chan *CompletionResponse, <-chan error) {
+	agent, release, err := p.acquire()
+	if err != nil {
+		return failedStream[*CompletionResponse](err)
+	}
+	chunks, errs := agent.CompletionStream(ctx, req)
+	return ForwardStream(ctx, chunks, errs, StreamHooks[*CompletionResponse]{Done: func(int, error) { release() }})
+}
+
+// Embedding 实现AIAgent接口的Embedding方法
+func (p *AgentPool) Embedding(ctx context.Context, req EmbeddingRequest) (*EmbeddingResponse, error) {
+	agent, release, err := p.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return agent.Embedding(ctx, req)
+}
+
+// BatchEmbedding 实现AIAgent接口的BatchEmbedding方法
+func (p *AgentPool) BatchEmbedding(ctx context.Context, req []EmbeddingRequest) ([]*EmbeddingResponse, error) {
+	agent, release, err := p.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return agent.BatchEmbedding(ctx, req)
+}
+
+// ListModels 实现AIAgent接口的ListModels方法，使用一个健康成员的模型列表
+func (p *AgentPool) ListModels(ctx context.Context) ([]string, error) {
+	agent, release, err := p.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return agent.ListModels(ctx)
+}
+
+// GetModel 实现AIAgent接口的GetModel方法
+func (p *AgentPool) GetModel(ctx context.Context, modelName string) (map[string]interface{}, error) {
+	agent, release, err := p.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return agent.GetModel(ctx, modelName)
+}
+
+// HealthCheck 实现AIAgent接口的HealthCheck方法，至少一个成员健康时返回nil
+// 使用最近一次后台检查的结果，不访问上游服务
+func (p *AgentPool) HealthCheck() error {
+	for _, member := range p.members {
+		if member.isHealthy() {
+			return nil
+		}
+	}
+	return fmt.Errorf("agent pool %s: %w", p.Name(), ErrNoHealthyMember)
+}
+
+// Close 实现AIAgent接口的Close方法，停止健康检查，成员实例由管理器关闭
+func (p *AgentPool) Close() error {
+	p.closed.Do(func() { close(p.done) })
+	return nil
+}
+
+// Status 返回实例池及各成员的状态
+func (p *AgentPool) Status() AgentPoolStatus {
+	status := AgentPoolStatus{
+		Name:                p.Name(),
+		HealthCheckInterval: int(p.interval / time.Second),
+		Members:             make([]PoolMemberStatus, 0, len(p.members)),
+	}
+	for _, member := range p.members {
+		member.mutex.RLock()
+		memberStatus := PoolMemberStatus{
+			Name:        member.name,
+			Status:      AgentUnhealthy,
+			InFlight:    atomic.LoadInt64(&member.inFlight),
+			Error:       member.lastError,
+			LastChecked: member.lastChecked,
+		}
+		if member.healthy {
+			memberStatus.Status = AgentHealthy
+			status.Healthy++
+		}
+		member.mutex.RUnlock()
+		status.Members = append(status.Members, memberStatus)
+	}
+	return status
+}
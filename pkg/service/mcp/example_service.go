@@ -19,8 +19,13 @@ type ExampleMCPServiceFactory struct {}
 
 // NewExampleMCPService 创建ExampleMCPService实例
 func NewExampleMCPService() *ExampleMCPService {
+	return newExampleMCPService("example-mcp-service")
+}
+
+// newExampleMCPService 创建指定名称的ExampleMCPService实例
+func newExampleMCPService(name string) *ExampleMCPService {
 	return &ExampleMCPService{
-		BaseMCPService: NewBaseMCPService("example-mcp-service", "1.0.0"),
+		BaseMCPService: NewBaseMCPService(name, "1.0.0"),
 	}
 }
 
@@ -116,6 +121,40 @@ func (e *ExampleMCPService) ListServices(ctx context.Context) ([]string, error)
 	return []string{"echo", "get_time", "calculate"}, nil
 }
 
+// GetService 实现获取工具详情功能，返回工具描述和JSON Schema格式的参数定义
+func (e *ExampleMCPService) GetService(ctx context.Context, serviceName string) (map[string]interface{}, error) {
+	switch serviceName {
+	case "echo":
+		return map[string]interface{}{
+			"name":        "echo",
+			"description": "Echo the given parameters back",
+			"parameters":  map[string]interface{}{"type": "object"},
+		}, nil
+	case "get_time":
+		return map[string]interface{}{
+			"name":        "get_time",
+			"description": "Get the current server time in RFC3339 format",
+			"parameters":  map[string]interface{}{"type": "object", "properties": map[string]interface{}{}},
+		}, nil
+	case "calculate":
+		return map[string]interface{}{
+			"name":        "calculate",
+			"description": "Perform a basic arithmetic operation on two numbers",
+			"parameters": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"a":         map[string]interface{}{"type": "number"},
+					"b":         map[string]interface{}{"type": "number"},
+					"operation": map[string]interface{}{"type": "string", "enum": []string{"add", "subtract", "multiply", "divide"}},
+				},
+				"required": []string{"a", "b", "operation"},
+			},
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown tool: %s", serviceName)
+	}
+}
+
 // Create 实现MCPServiceFactory接口的Create方法
 func (f *ExampleMCPServiceFactory) Create() (MCPService, error) {
 	return NewExampleMCPService(), nil
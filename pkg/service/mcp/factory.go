@@ -0,0 +1,64 @@
+package mcp
+
+import (
+	"errors"
+	"fmt"
+)
+
+// 可以通过配置创建的MCP服务类型
+const (
+	TypeExample = "example"
+)
+
+// typedFactory 按类型和配置创建MCP服务实例的工厂
+type typedFactory struct {
+	name        string
+	serviceType string
+	config      map[string]interface{}
+}
+
+// configuredService 使用实例默认配置的MCP服务，Init时传入的配置项覆盖同名默认配置
+type configuredService struct {
+	MCPService
+	defaults map[string]interface{}
+}
+
+// NewFactory 创建指定类型的MCP服务工厂，name为实例名称，config为实例的默认配置
+func NewFactory(serviceType, name string, config map[string]interface{}) (MCPServiceFactory, error) {
+	switch serviceType {
+	case TypeExample:
+	default:
+		return nil, fmt.Errorf("unsupported MCP service type: %s", serviceType)
+	}
+	if name == "" {
+		return nil, errors.New("MCP service name cannot be empty")
+	}
+	return &typedFactory{name: name, serviceType: serviceType, config: config}, nil
+}
+
+// Create 实现MCPServiceFactory接口的Create方法
+func (f *typedFactory) Create() (MCPService, error) {
+	var service MCPService
+	switch f.serviceType {
+	case TypeExample:
+		service = newExampleMCPService(f.name)
+	}
+	return &configuredService{MCPService: service, defaults: f.config}, nil
+}
+
+// Name 实现MCPServiceFactory接口的Name方法
+func (f *typedFactory) Name() string {
+	return f.name
+}
+
+// Init 合并默认配置后初始化MCP服务
+func (s *configuredService) Init(config map[string]interface{}) error {
+	merged := make(map[string]interface{}, len(s.defaults)+len(config))
+	for key, value := range s.defaults {
+		merged[key] = value
+	}
+	for key, value := range config {
+		merged[key] = value
+	}
+	return s.MCPService.Init(merged)
+}
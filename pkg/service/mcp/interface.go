@@ -72,6 +72,9 @@ type MCPServiceManager interface {
 	// 注册MCP服务工厂
 	RegisterFactory(factory MCPServiceFactory) error
 	
+	// 注册或替换MCP服务工厂，并释放旧工厂创建的实例
+	ReplaceFactory(factory MCPServiceFactory) error
+	
 	// 创建并获取MCP服务实例
 	GetMCPService(name string, config map[string]interface{}) (MCPService, error)
 	
@@ -81,6 +84,9 @@ type MCPServiceManager interface {
 	// 列出所有可用的MCP服务名称
 	ListAvailableServices() []string
 	
+	// 注册在每次调用外执行的中间件
+	Use(middlewares ...MCPServiceMiddleware)
+	
 	// 清理所有资源
 	Close() error
 }
@@ -14,6 +14,7 @@ type DefaultMCPServiceManager struct {
 	factories  map[string]MCPServiceFactory
 	instances  map[string]MCPService
 	configs    map[string]map[string]interface{}
+	middlewares []MCPServiceMiddleware
 	mutex      sync.RWMutex
 	logger     log.Logger
 }
@@ -56,6 +57,38 @@ func (m *DefaultMCPServiceManager) RegisterFactory(factory MCPServiceFactory) er
 	return nil
 }
 
+// ReplaceFactory 注册或替换MCP服务工厂，已有实例关闭后在下次获取时使用新工厂创建
+func (m *DefaultMCPServiceManager) ReplaceFactory(factory MCPServiceFactory) error {
+	if factory == nil {
+		return errors.New("factory cannot be nil")
+	}
+
+	name := factory.Name()
+	if name == "" {
+		return errors.New("factory name cannot be empty")
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if service, exists := m.instances[name]; exists {
+		if err := service.Close(); err != nil {
+			m.logger.Warn("Failed to close service",
+				zap.String("service_name", name),
+				zap.Error(err),
+			)
+		}
+		delete(m.instances, name)
+		delete(m.configs, name)
+	}
+
+	m.factories[name] = factory
+	m.logger.Info("MCP service factory replaced",
+		zap.String("factory_name", name),
+	)
+	return nil
+}
+
 // GetMCPService 获取MCP服务实例
 func (m *DefaultMCPServiceManager) GetMCPService(name string, config map[string]interface{}) (MCPService, error) {
 	if name == "" {
@@ -68,7 +101,7 @@ func (m *DefaultMCPServiceManager) GetMCPService(name string, config map[string]
 	m.mutex.RUnlock()
 
 	if exists {
-		return service, nil
+		return m.withMiddlewares(name, service), nil
 	}
 
 	// 如果不存在，创建新实例
@@ -78,7 +111,7 @@ func (m *DefaultMCPServiceManager) GetMCPService(name string, config map[string]
 	// 双重检查锁定模式
 	service, exists = m.instances[name]
 	if exists {
-		return service, nil
+		return WithMiddlewares(name, service, m.middlewares), nil
 	}
 
 	// 获取工厂
@@ -120,7 +153,28 @@ func (m *DefaultMCPServiceManager) GetMCPService(name string, config map[string]
 	m.logger.Info("MCP service instance created",
 		zap.String("service_name", name),
 	)
-	return service, nil
+	return WithMiddlewares(name, service, m.middlewares), nil
+}
+
+// Use 注册中间件，按注册顺序在每次Call调用外执行
+func (m *DefaultMCPServiceManager) Use(middlewares ...MCPServiceMiddleware) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for _, middleware := range middlewares {
+		m.middlewares = append(m.middlewares, middleware)
+		m.logger.Info("Registered MCP service middleware",
+			zap.String("name", middleware.Name()),
+		)
+	}
+}
+
+// withMiddlewares 为实例添加当前注册的中间件
+func (m *DefaultMCPServiceManager) withMiddlewares(name string, service MCPService) MCPService {
+	m.mutex.RLock()
+	middlewares := m.middlewares
+	m.mutex.RUnlock()
+	return WithMiddlewares(name, service, middlewares)
 }
 
 // ReleaseMCPService 释放MCP服务实例
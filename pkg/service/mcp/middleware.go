@@ -0,0 +1,81 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// OperationCall 中间件处理的MCP调用类型
+const OperationCall = "mcp_call"
+
+// ErrInvalidRequest 请求参数无效，中间件校验失败时返回包装该错误的错误
+var ErrInvalidRequest = errors.New("invalid request")
+
+// CallInfo 中间件可以从上下文获取的调用信息
+type CallInfo struct {
+	Service   string // MCP服务名称
+	Operation string // 调用类型
+}
+
+// callInfoKey CallInfo在上下文中的键
+type callInfoKey struct{}
+
+// middlewareService 执行中间件链的MCP服务
+// 请求为*MCPServiceRequest，响应为*MCPServiceResponse；CallAsync同样经过中间件链，BatchCall直接调用原服务
+type middlewareService struct {
+	MCPService
+	name        string
+	middlewares []MCPServiceMiddleware
+}
+
+// CallInfoFromContext 获取中间件链中的调用信息
+func CallInfoFromContext(ctx context.Context) (CallInfo, bool) {
+	info, ok := ctx.Value(callInfoKey{}).(CallInfo)
+	return info, ok
+}
+
+// WithMiddlewares 返回执行中间件链的MCP服务，中间件为空时返回原服务
+// 中间件按顺序执行，第一个中间件在最外层
+func WithMiddlewares(name string, service MCPService, middlewares []MCPServiceMiddleware) MCPService {
+	if len(middlewares) == 0 {
+		return service
+	}
+	return &middlewareService{MCPService: service, name: name, middlewares: middlewares}
+}
+
+// Call 实现MCPService接口的Call方法
+func (s *middlewareService) Call(ctx context.Context, req MCPServiceRequest) (*MCPServiceResponse, error) {
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		callReq, ok := req.(*MCPServiceRequest)
+		if !ok {
+			return nil, fmt.Errorf("middleware returned unexpected request type %T", req)
+		}
+		return s.MCPService.Call(ctx, *callReq)
+	}
+	for i := len(s.middlewares) - 1; i >= 0; i-- {
+		middleware, next := s.middlewares[i], handler
+		handler = func(ctx context.Context, req interface{}) (interface{}, error) {
+			return middleware.Process(ctx, req, next)
+		}
+	}
+
+	ctx = context.WithValue(ctx, callInfoKey{}, CallInfo{Service: s.name, Operation: OperationCall})
+	resp, err := handler(ctx, &req)
+	if err != nil {
+		return nil, err
+	}
+	result, ok := resp.(*MCPServiceResponse)
+	if !ok {
+		return nil, fmt.Errorf("middleware returned unexpected response type %T", resp)
+	}
+	return result, nil
+}
+
+// CallAsync 实现MCPService接口的CallAsync方法，在后台经过中间件链调用原服务
+func (s *middlewareService) CallAsync(ctx context.Context, req MCPServiceRequest, callback func(*MCPServiceResponse, error)) error {
+	go func() {
+		callback(s.Call(ctx, req))
+	}()
+	return nil
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"kai/kaigate/pkg/log"
+)
+
+// Logging 记录每次调用的日志中间件
+// 只记录调用目标、模型、耗时和错误等元数据，不记录消息内容
+type Logging struct {
+	logger log.Logger
+}
+
+// NewLogging 创建日志中间件
+func NewLogging(logger log.Logger) *Logging {
+	if logger == nil {
+		logger = log.GlobalLogger
+	}
+	return &Logging{logger: logger}
+}
+
+// Name 实现中间件接口的Name方法
+func (m *Logging) Name() string {
+	return NameLogging
+}
+
+// Process 实现中间件接口的Process方法
+func (m *Logging) Process(ctx context.Context, req interface{}, next Next) (interface{}, error) {
+	target, operation := callTarget(ctx)
+	model := requestModel(req)
+	start := time.Now()
+
+	resp, err := next(ctx, req)
+	return observe(ctx, resp, err, func(chunks int, err error) {
+		fields := []zap.Field{
+			zap.String("target", target),
+			zap.String("operation", operation),
+			zap.Duration("duration", time.Since(start)),
+		}
+		if model != "" {
+			fields = append(fields, zap.String("model", model))
+		}
+		if chunks > 0 {
+			fields = append(fields, zap.Int("chunks", chunks))
+		}
+		if err != nil {
+			m.logger.Warn("Service call failed", append(fields, zap.Error(err))...)
+			return
+		}
+		m.logger.Info("Service call completed", fields...)
+	}), err
+}
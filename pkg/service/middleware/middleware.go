@@ -0,0 +1,70 @@
+// Package middleware 提供AI Agent和MCP服务共用的内置中间件
+// 中间件同时实现ai_agent.AIAgentMiddleware和mcp.MCPServiceMiddleware接口，可以分别注册到两个管理器
+package middleware
+
+import (
+	"context"
+
+	"kai/kaigate/pkg/service/ai_agent"
+	"kai/kaigate/pkg/service/mcp"
+)
+
+// 内置中间件名称
+const (
+	NameLogging    = "logging"
+	NameTiming     = "timing"
+	NamePII        = "pii_redaction"
+	NameValidation = "validation"
+)
+
+// Middleware 内置中间件，同时满足ai_agent.AIAgentMiddleware和mcp.MCPServiceMiddleware接口
+type Middleware interface {
+	Process(ctx context.Context, req interface{}, next Next) (interface{}, error)
+	Name() string
+}
+
+// Next 中间件链中的下一个处理函数
+type Next = func(context.Context, interface{}) (interface{}, error)
+
+// callTarget 获取当前调用的Agent或MCP服务名称及调用类型
+func callTarget(ctx context.Context) (target, operation string) {
+	if info, ok := ai_agent.CallInfoFromContext(ctx); ok {
+		return info.Agent, info.Operation
+	}
+	if info, ok := mcp.CallInfoFromContext(ctx); ok {
+		return info.Service, info.Operation
+	}
+	return "", ""
+}
+
+// requestModel 获取请求中的模型名称
+func requestModel(req interface{}) string {
+	switch r := req.(type) {
+	case *ai_agent.ChatRequest:
+		return r.Model
+	case *ai_agent.CompletionRequest:
+		return r.Model
+	case *ai_agent.EmbeddingRequest:
+		return r.Model
+	}
+	return ""
+}
+
+// observe 在调用结束时执行done
+// 流式响应在数据流结束后才算调用结束，因此替换响应中的通道，转发所有数据块后再执行done
+func observe(ctx context.Context, resp interface{}, err error, done func(chunks int, err error)) interface{} {
+	if err != nil {
+		done(0, err)
+		return resp
+	}
+	switch r := resp.(type) {
+	case *ai_agent.ChatStreamResult:
+		chunks, errs := ai_agent.ForwardStream(ctx, r.Chunks, r.Errors, ai_agent.StreamHooks[*ai_agent.ChatResponse]{Done: done})
+		return &ai_agent.ChatStreamResult{Chunks: chunks, Errors: errs}
+	case *ai_agent.CompletionStreamResult:
+		chunks, errs := ai_agent.ForwardStream(ctx, r.Chunks, r.Errors, ai_agent.StreamHooks[*ai_agent.CompletionResponse]{Done: done})
+		return &ai_agent.CompletionStreamResult{Chunks: chunks, Errors: errs}
+	}
+	done(0, nil)
+	return resp
+}
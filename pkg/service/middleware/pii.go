@@ -0,0 +1,150 @@
+package middleware
+
+import (
+	"context"
+
+	"kai/kaigate/pkg/guardrail"
+	"kai/kaigate/pkg/service/ai_agent"
+	"kai/kaigate/pkg/service/mcp"
+)
+
+// piiDetectors 替换个人敏感信息使用的内置检测器
+var piiDetectors, _ = guardrail.BuiltinDetectors()
+
+// PIIRedaction 替换个人敏感信息的中间件
+// 请求中的聊天消息、提示文本、嵌入输入和MCP字符串参数中的敏感信息替换为[REDACTED_<类型>]
+type PIIRedaction struct {
+	redactResponses bool
+}
+
+// NewPIIRedaction 创建敏感信息替换中间件，redactResponses为true时同时替换响应中的敏感信息
+func NewPIIRedaction(redactResponses bool) *PIIRedaction {
+	return &PIIRedaction{redactResponses: redactResponses}
+}
+
+// RedactPII 使用全部内置检测器替换文本中的个人敏感信息
+func RedactPII(text string) string {
+	return guardrail.Redact(text, piiDetectors)
+}
+
+// Name 实现中间件接口的Name方法
+func (m *PIIRedaction) Name() string {
+	return NamePII
+}
+
+// Process 实现中间件接口的Process方法
+func (m *PIIRedaction) Process(ctx context.Context, req interface{}, next Next) (interface{}, error) {
+	resp, err := next(ctx, redactRequest(req))
+	if err != nil || !m.redactResponses {
+		return resp, err
+	}
+	return redactResponse(ctx, resp), nil
+}
+
+// redactRequest 返回替换敏感信息后的请求副本，不修改调用方的消息列表和参数
+func redactRequest(req interface{}) interface{} {
+	switch r := req.(type) {
+	case *ai_agent.ChatRequest:
+		redacted := *r
+		redacted.Messages = make([]ai_agent.Message, len(r.Messages))
+		for i, message := range r.Messages {
+			redacted.Messages[i] = redactMessage(message)
+		}
+		return &redacted
+	case *ai_agent.CompletionRequest:
+		redacted := *r
+		redacted.Prompt = RedactPII(r.Prompt)
+		return &redacted
+	case *ai_agent.EmbeddingRequest:
+		redacted := *r
+		redacted.Input = make([]string, len(r.Input))
+		for i, input := range r.Input {
+			redacted.Input[i] = RedactPII(input)
+		}
+		return &redacted
+	case *mcp.MCPServiceRequest:
+		redacted := *r
+		if r.Params != nil {
+			redacted.Params = redactValue(r.Params).(map[string]interface{})
+		}
+		return &redacted
+	}
+	return req
+}
+
+// redactMessage 替换消息文本和多模态文本片段中的敏感信息
+func redactMessage(message ai_agent.Message) ai_agent.Message {
+	message.Content = RedactPII(message.Content)
+	if len(message.Parts) > 0 {
+		parts := make([]ai_agent.ContentPart, len(message.Parts))
+		for i, part := range message.Parts {
+			part.Text = RedactPII(part.Text)
+			parts[i] = part
+		}
+		message.Parts = parts
+	}
+	return message
+}
+
+// redactValue 递归替换MCP参数中字符串的敏感信息
+func redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return RedactPII(v)
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			result[key] = redactValue(item)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = redactValue(item)
+		}
+		return result
+	}
+	return value
+}
+
+// redactResponse 替换响应中的敏感信息，流式响应逐个数据块替换
+// 跨数据块的敏感信息无法识别，需要增量处理时使用guardrail
+func redactResponse(ctx context.Context, resp interface{}) interface{} {
+	switch r := resp.(type) {
+	case *ai_agent.ChatResponse:
+		redactChatResponse(r)
+	case *ai_agent.CompletionResponse:
+		redactCompletionResponse(r)
+	case *mcp.MCPServiceResponse:
+		r.Data = redactValue(r.Data)
+	case *ai_agent.ChatStreamResult:
+		chunks, errs := ai_agent.ForwardStream(ctx, r.Chunks, r.Errors, ai_agent.StreamHooks[*ai_agent.ChatResponse]{Transform: redactChatResponse})
+		return &ai_agent.ChatStreamResult{Chunks: chunks, Errors: errs}
+	case *ai_agent.CompletionStreamResult:
+		chunks, errs := ai_agent.ForwardStream(ctx, r.Chunks, r.Errors, ai_agent.StreamHooks[*ai_agent.CompletionResponse]{Transform: redactCompletionResponse})
+		return &ai_agent.CompletionStreamResult{Chunks: chunks, Errors: errs}
+	}
+	return resp
+}
+
+// redactChatResponse 替换聊天响应中的敏感信息
+func redactChatResponse(resp *ai_agent.ChatResponse) *ai_agent.ChatResponse {
+	if resp == nil {
+		return resp
+	}
+	for i := range resp.Choices {
+		resp.Choices[i].Message = redactMessage(resp.Choices[i].Message)
+	}
+	return resp
+}
+
+// redactCompletionResponse 替换文本生成响应中的敏感信息
+func redactCompletionResponse(resp *ai_agent.CompletionResponse) *ai_agent.CompletionResponse {
+	if resp == nil {
+		return resp
+	}
+	for i := range resp.Choices {
+		resp.Choices[i].Text = RedactPII(resp.Choices[i].Text)
+	}
+	return resp
+}
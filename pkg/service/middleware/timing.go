@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// TimingStats 单个调用目标和调用类型的耗时统计
+type TimingStats struct {
+	Target    string  `json:"target"`
+	Operation string  `json:"operation"`
+	Calls     int64   `json:"calls"`
+	Errors    int64   `json:"errors"`
+	TotalMs   float64 `json:"total_ms"`
+	AvgMs     float64 `json:"avg_ms"`
+	MaxMs     float64 `json:"max_ms"`
+}
+
+// timingKey 耗时统计的键
+type timingKey struct {
+	target    string
+	operation string
+}
+
+// Timing 统计调用耗时的中间件，流式调用统计到数据流结束
+type Timing struct {
+	stats map[timingKey]*TimingStats
+	mutex sync.Mutex
+}
+
+// NewTiming 创建耗时统计中间件
+func NewTiming() *Timing {
+	return &Timing{stats: make(map[timingKey]*TimingStats)}
+}
+
+// Name 实现中间件接口的Name方法
+func (m *Timing) Name() string {
+	return NameTiming
+}
+
+// Process 实现中间件接口的Process方法
+func (m *Timing) Process(ctx context.Context, req interface{}, next Next) (interface{}, error) {
+	target, operation := callTarget(ctx)
+	start := time.Now()
+
+	resp, err := next(ctx, req)
+	return observe(ctx, resp, err, func(_ int, err error) {
+		m.record(timingKey{target: target, operation: operation}, time.Since(start), err != nil)
+	}), err
+}
+
+// record 记录一次调用的耗时
+func (m *Timing) record(key timingKey, duration time.Duration, failed bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	stats, exists := m.stats[key]
+	if !exists {
+		stats = &TimingStats{Target: key.target, Operation: key.operation}
+		m.stats[key] = stats
+	}
+	ms := float64(duration) / float64(time.Millisecond)
+	stats.Calls++
+	stats.TotalMs += ms
+	stats.AvgMs = stats.TotalMs / float64(stats.Calls)
+	if ms > stats.MaxMs {
+		stats.MaxMs = ms
+	}
+	if failed {
+		stats.Errors++
+	}
+}
+
+// Snapshot 返回按调用目标和调用类型排序的耗时统计
+func (m *Timing) Snapshot() []TimingStats {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	result := make([]TimingStats, 0, len(m.stats))
+	for _, stats := range m.stats {
+		result = append(result, *stats)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Target != result[j].Target {
+			return result[i].Target < result[j].Target
+		}
+		return result[i].Operation < result[j].Operation
+	})
+	return result
+}
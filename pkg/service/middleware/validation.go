@@ -0,0 +1,157 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"kai/kaigate/pkg/service/ai_agent"
+	"kai/kaigate/pkg/service/mcp"
+)
+
+// validRoles 聊天消息允许的角色
+var validRoles = map[string]bool{
+	"system":    true,
+	"developer": true,
+	"user":      true,
+	"assistant": true,
+	"tool":      true,
+}
+
+// Validation 校验请求参数的中间件，校验失败时不调用Agent或MCP服务
+// AI请求返回包装ai_agent.ErrInvalidRequest的错误，MCP请求返回包装mcp.ErrInvalidRequest的错误
+type Validation struct {
+	maxMessages   int
+	maxInputChars int
+}
+
+// ValidationOption 校验中间件配置选项
+type ValidationOption func(*Validation)
+
+// WithMaxMessages 设置单次聊天请求的最大消息数，0表示不限制
+func WithMaxMessages(max int) ValidationOption {
+	return func(v *Validation) {
+		v.maxMessages = max
+	}
+}
+
+// WithMaxInputChars 设置单次请求输入文本的最大字符数，0表示不限制
+func WithMaxInputChars(max int) ValidationOption {
+	return func(v *Validation) {
+		v.maxInputChars = max
+	}
+}
+
+// NewValidation 创建请求校验中间件
+func NewValidation(options ...ValidationOption) *Validation {
+	v := &Validation{}
+	for _, option := range options {
+		option(v)
+	}
+	return v
+}
+
+// Name 实现中间件接口的Name方法
+func (m *Validation) Name() string {
+	return NameValidation
+}
+
+// Process 实现中间件接口的Process方法
+func (m *Validation) Process(ctx context.Context, req interface{}, next Next) (interface{}, error) {
+	if err := m.validate(req); err != nil {
+		return nil, err
+	}
+	return next(ctx, req)
+}
+
+// validate 按请求类型校验参数
+func (m *Validation) validate(req interface{}) error {
+	switch r := req.(type) {
+	case *ai_agent.ChatRequest:
+		return m.validateChat(r)
+	case *ai_agent.CompletionRequest:
+		if strings.TrimSpace(r.Prompt) == "" {
+			return invalid("prompt must not be empty")
+		}
+		if err := validateSampling(r.Temperature, 0, r.MaxTokens); err != nil {
+			return err
+		}
+		return m.checkInputChars(len([]rune(r.Prompt)))
+	case *ai_agent.EmbeddingRequest:
+		if len(r.Input) == 0 {
+			return invalid("input must not be empty")
+		}
+		chars := 0
+		for i, input := range r.Input {
+			if input == "" {
+				return invalid(fmt.Sprintf("input[%d] must not be empty", i))
+			}
+			chars += len([]rune(input))
+		}
+		return m.checkInputChars(chars)
+	case *mcp.MCPServiceRequest:
+		if strings.TrimSpace(r.ToolName) == "" {
+			return fmt.Errorf("%w: tool name must not be empty", mcp.ErrInvalidRequest)
+		}
+	}
+	return nil
+}
+
+// validateChat 校验聊天请求的消息和采样参数
+func (m *Validation) validateChat(req *ai_agent.ChatRequest) error {
+	if len(req.Messages) == 0 {
+		return invalid("messages must not be empty")
+	}
+	if m.maxMessages > 0 && len(req.Messages) > m.maxMessages {
+		return invalid(fmt.Sprintf("too many messages: %d exceeds the limit of %d", len(req.Messages), m.maxMessages))
+	}
+
+	chars := 0
+	for i, message := range req.Messages {
+		if !validRoles[message.Role] {
+			return invalid(fmt.Sprintf("messages[%d] has invalid role %q", i, message.Role))
+		}
+		if message.Role == "tool" && message.ToolCallID == "" {
+			return invalid(fmt.Sprintf("messages[%d] with role tool must have tool_call_id", i))
+		}
+		if message.Content == "" && len(message.Parts) == 0 && len(message.ToolCalls) == 0 {
+			return invalid(fmt.Sprintf("messages[%d] must have content", i))
+		}
+		chars += len([]rune(message.Content))
+		for _, part := range message.Parts {
+			chars += len([]rune(part.Text))
+		}
+	}
+
+	if err := validateSampling(req.Temperature, req.TopP, req.MaxTokens); err != nil {
+		return err
+	}
+	return m.checkInputChars(chars)
+}
+
+// checkInputChars 检查输入文本是否超过最大字符数
+func (m *Validation) checkInputChars(chars int) error {
+	if m.maxInputChars > 0 && chars > m.maxInputChars {
+		return invalid(fmt.Sprintf("input too long: %d characters exceeds the limit of %d", chars, m.maxInputChars))
+	}
+	return nil
+}
+
+// validateSampling 校验采样参数的取值范围
+func validateSampling(temperature, topP float64, maxTokens int) error {
+	if temperature < 0 || temperature > 2 {
+		return invalid("temperature must be between 0 and 2")
+	}
+	if topP < 0 || topP > 1 {
+		return invalid("top_p must be between 0 and 1")
+	}
+	if maxTokens < 0 {
+		return invalid("max_tokens must not be negative")
+	}
+	return nil
+}
+
+// invalid 返回包装ai_agent.ErrInvalidRequest的错误
+func invalid(message string) error {
+	return fmt.Errorf("%w: %s", ai_agent.ErrInvalidRequest, message)
+}
@@ -0,0 +1,276 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"kai/kaigate/pkg/config"
+	"kai/kaigate/pkg/log"
+	"kai/kaigate/pkg/service/ai_agent"
+	"kai/kaigate/pkg/service/mcp"
+)
+
+// 编排事件类型
+const (
+	EventDelta      = "delta"       // 模型输出的文本增量
+	EventToolCall   = "tool_call"   // 开始执行工具调用
+	EventToolResult = "tool_result" // 工具调用完成
+)
+
+// ErrStepLimit 达到最大轮数时模型仍在请求工具调用
+var ErrStepLimit = errors.New("tool call step limit exceeded")
+
+// Orchestrator 工具调用编排器
+// 将MCP服务的工具提供给模型，执行模型返回的工具调用并把结果回传给模型，直到得到最终回答或达到最大轮数
+type Orchestrator struct {
+	mcpManager  mcp.MCPServiceManager
+	maxSteps    int
+	toolTimeout time.Duration
+	logger      log.Logger
+}
+
+// Option 编排器选项
+type Option func(*Orchestrator)
+
+// Event 编排过程中的事件
+type Event struct {
+	Type       string                 `json:"type"`
+	Step       int                    `json:"step"`
+	ToolCallID string                 `json:"tool_call_id,omitempty"`
+	Service    string                 `json:"service,omitempty"`
+	Tool       string                 `json:"tool,omitempty"`
+	Arguments  string                 `json:"arguments,omitempty"`
+	Result     string                 `json:"result,omitempty"`
+	Error      string                 `json:"error,omitempty"`
+	Chunk      *ai_agent.ChatResponse `json:"-"` // delta事件的数据块，只包含文本增量
+}
+
+// Request 编排请求
+type Request struct {
+	Chat     ai_agent.ChatRequest // 原始聊天请求，Stream为true时以流式方式调用模型
+	Services []string             // 启用工具的MCP服务
+	MaxSteps int                  // 最大轮数，不超过编排器的配置，0表示使用配置值
+
+	// Authorize 在执行工具调用前检查权限，返回的错误作为工具结果回传给模型
+	Authorize func(service, tool string) error
+	// OnEvent 接收编排事件，可以为空
+	OnEvent func(Event)
+}
+
+// NewOrchestrator 创建编排器，默认值来自配置
+func NewOrchestrator(mcpManager mcp.MCPServiceManager, options ...Option) *Orchestrator {
+	cfg := config.GetConfig().Orchestrator
+	o := &Orchestrator{
+		mcpManager:  mcpManager,
+		maxSteps:    cfg.MaxSteps,
+		toolTimeout: time.Duration(cfg.ToolTimeout) * time.Second,
+		logger:      log.GlobalLogger,
+	}
+	for _, option := range options {
+		option(o)
+	}
+	if o.maxSteps <= 0 {
+		o.maxSteps = config.DefaultOrchestratorMaxSteps
+	}
+	if o.toolTimeout <= 0 {
+		o.toolTimeout = config.DefaultToolTimeout * time.Second
+	}
+	return o
+}
+
+// WithMaxSteps 设置最大工具调用轮数
+func WithMaxSteps(steps int) Option {
+	return func(o *Orchestrator) {
+		o.maxSteps = steps
+	}
+}
+
+// WithToolTimeout 设置单次工具调用超时时间
+func WithToolTimeout(timeout time.Duration) Option {
+	return func(o *Orchestrator) {
+		o.toolTimeout = timeout
+	}
+}
+
+// WithLogger 设置日志记录器
+func WithLogger(logger log.Logger) Option {
+	return func(o *Orchestrator) {
+		o.logger = logger
+	}
+}
+
+// Run 执行工具调用循环，返回最终的聊天响应，usage为各轮之和
+// 模型请求了非MCP工具（调用方自己定义的工具）时直接返回该响应，由调用方处理
+func (o *Orchestrator) Run(ctx context.Context, agent ai_agent.AIAgent, req Request) (*ai_agent.ChatResponse, error) {
+	emit := req.OnEvent
+	if emit == nil {
+		emit = func(Event) {}
+	}
+
+	tools, bindings, err := o.Tools(ctx, req.Services)
+	if err != nil {
+		return nil, err
+	}
+
+	chat := req.Chat
+	chat.Tools = append(append([]ai_agent.Tool{}, req.Chat.Tools...), tools...)
+	messages := append([]ai_agent.Message{}, req.Chat.Messages...)
+
+	maxSteps := o.maxSteps
+	if req.MaxSteps > 0 && req.MaxSteps < maxSteps {
+		maxSteps = req.MaxSteps
+	}
+
+	usage := ai_agent.Usage{}
+	for step := 1; step <= maxSteps; step++ {
+		chat.Messages = messages
+		resp, err := o.complete(ctx, agent, chat, step, emit)
+		if err != nil {
+			return nil, err
+		}
+		usage.PromptTokens += resp.Usage.PromptTokens
+		usage.CompletionTokens += resp.Usage.CompletionTokens
+		usage.TotalTokens += resp.Usage.TotalTokens
+
+		if len(resp.Choices) == 0 || !allBound(resp.Choices[0].Message.ToolCalls, bindings) {
+			resp.Usage = usage
+			return resp, nil
+		}
+
+		// 记录模型的工具调用消息，依次执行并追加tool消息
+		message := resp.Choices[0].Message
+		message.Role = "assistant"
+		for i := range message.ToolCalls {
+			if message.ToolCalls[i].ID == "" {
+				message.ToolCalls[i].ID = fmt.Sprintf("call_%d_%d", step, i)
+			}
+		}
+		messages = append(messages, message)
+
+		for _, call := range message.ToolCalls {
+			binding := bindings[call.Function.Name]
+			emit(Event{
+				Type:       EventToolCall,
+				Step:       step,
+				ToolCallID: call.ID,
+				Service:    binding.service,
+				Tool:       binding.tool,
+				Arguments:  call.Function.Arguments,
+			})
+
+			result, err := o.callTool(ctx, binding, call.Function.Arguments, req.Authorize)
+			event := Event{
+				Type:       EventToolResult,
+				Step:       step,
+				ToolCallID: call.ID,
+				Service:    binding.service,
+				Tool:       binding.tool,
+				Result:     result,
+			}
+			if err != nil {
+				event.Error = err.Error()
+			}
+			emit(event)
+
+			messages = append(messages, ai_agent.Message{Role: "tool", ToolCallID: call.ID, Content: result})
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %d steps", ErrStepLimit, maxSteps)
+}
+
+// complete 调用一轮模型，流式请求时转发文本增量并拼接为完整响应
+func (o *Orchestrator) complete(ctx context.Context, agent ai_agent.AIAgent, req ai_agent.ChatRequest, step int, emit func(Event)) (*ai_agent.ChatResponse, error) {
+	if !req.Stream {
+		return agent.Chat(ctx, req)
+	}
+
+	respChan, errChan := agent.ChatStream(ctx, req)
+	result := &ai_agent.ChatResponse{Object: "chat.completion"}
+	choice := ai_agent.ChatChoice{Message: ai_agent.Message{Role: "assistant"}}
+	content := strings.Builder{}
+
+	for chunk := range respChan {
+		if result.ID == "" {
+			result.ID, result.Created, result.Model = chunk.ID, chunk.Created, chunk.Model
+		}
+		if chunk.Usage.TotalTokens > 0 {
+			result.Usage = chunk.Usage
+		}
+		for _, delta := range chunk.Choices {
+			if delta.FinishReason != "" {
+				choice.FinishReason = delta.FinishReason
+			}
+			choice.Message.ToolCalls = ai_agent.MergeToolCalls(choice.Message.ToolCalls, delta.Message.ToolCalls)
+			if delta.Message.Content == "" && delta.Message.Role == "" {
+				continue
+			}
+
+			content.WriteString(delta.Message.Content)
+			emit(Event{
+				Type: EventDelta,
+				Step: step,
+				Chunk: &ai_agent.ChatResponse{
+					ID:      chunk.ID,
+					Object:  chunk.Object,
+					Created: chunk.Created,
+					Model:   chunk.Model,
+					Choices: []ai_agent.ChatChoice{{
+						Index:   delta.Index,
+						Message: ai_agent.Message{Role: delta.Message.Role, Content: delta.Message.Content},
+					}},
+				},
+			})
+		}
+	}
+	if err := <-errChan; err != nil {
+		return nil, err
+	}
+
+	choice.Message.Content = content.String()
+	result.Choices = []ai_agent.ChatChoice{choice}
+	return result, nil
+}
+
+// callTool 执行一次工具调用，返回回传给模型的内容
+// 执行失败时内容为{"error":...}，同时返回错误用于事件
+func (o *Orchestrator) callTool(ctx context.Context, binding toolBinding, arguments string, authorize func(service, tool string) error) (string, error) {
+	if authorize != nil {
+		if err := authorize(binding.service, binding.tool); err != nil {
+			return errorResult(err), err
+		}
+	}
+
+	params, err := parseArguments(arguments)
+	if err != nil {
+		return errorResult(err), err
+	}
+
+	service, err := o.mcpManager.GetMCPService(binding.service, nil)
+	if err != nil {
+		return errorResult(err), err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, o.toolTimeout)
+	defer cancel()
+
+	o.logger.Info("Executing MCP tool for model",
+		zap.String("service", binding.service),
+		zap.String("tool", binding.tool),
+	)
+	resp, err := service.Call(ctx, mcp.MCPServiceRequest{
+		ServiceName: binding.service,
+		ToolName:    binding.tool,
+		Params:      params,
+	})
+	if err != nil {
+		o.logger.Error("MCP tool execution failed", zap.String("service", binding.service), zap.String("tool", binding.tool), zap.Error(err))
+		return errorResult(err), err
+	}
+	return toolResult(resp)
+}
@@ -0,0 +1,114 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"kai/kaigate/pkg/service/ai_agent"
+	"kai/kaigate/pkg/service/mcp"
+)
+
+// ToolSeparator 模型可见的工具名中服务名和工具名的分隔符
+const ToolSeparator = "__"
+
+// toolBinding 模型可见的工具对应的MCP服务和工具
+type toolBinding struct {
+	service string
+	tool    string
+}
+
+// ToolName 返回MCP工具对模型可见的名称，格式为"服务名__工具名"
+func ToolName(service, tool string) string {
+	return service + ToolSeparator + tool
+}
+
+// Tools 查询MCP服务提供的工具，转换为模型的工具定义
+// 工具列表来自MCPService.ListServices，描述和参数来自GetService返回的description和parameters（或input_schema），
+// 查询不到详情时使用通用描述和空对象参数
+func (o *Orchestrator) Tools(ctx context.Context, services []string) ([]ai_agent.Tool, map[string]toolBinding, error) {
+	tools := []ai_agent.Tool{}
+	bindings := make(map[string]toolBinding)
+
+	for _, serviceName := range services {
+		service, err := o.mcpManager.GetMCPService(serviceName, nil)
+		if err != nil {
+			return nil, nil, fmt.Errorf("get MCP service %s failed: %w", serviceName, err)
+		}
+		names, err := service.ListServices(ctx)
+		if err != nil {
+			return nil, nil, fmt.Errorf("list tools of MCP service %s failed: %w", serviceName, err)
+		}
+
+		for _, name := range names {
+			definition := ai_agent.FunctionDefinition{
+				Name:        ToolName(serviceName, name),
+				Description: fmt.Sprintf("Tool %s of MCP service %s", name, serviceName),
+				Parameters:  map[string]interface{}{"type": "object"},
+			}
+			if detail, err := service.GetService(ctx, name); err == nil {
+				if description, ok := detail["description"].(string); ok && description != "" {
+					definition.Description = description
+				}
+				if parameters, ok := detail["parameters"].(map[string]interface{}); ok {
+					definition.Parameters = parameters
+				} else if parameters, ok := detail["input_schema"].(map[string]interface{}); ok {
+					definition.Parameters = parameters
+				}
+			}
+
+			tools = append(tools, ai_agent.Tool{Type: "function", Function: definition})
+			bindings[definition.Name] = toolBinding{service: serviceName, tool: name}
+		}
+	}
+	return tools, bindings, nil
+}
+
+// allBound 判断工具调用是否全部对应MCP工具，没有工具调用时返回false
+func allBound(calls []ai_agent.ToolCall, bindings map[string]toolBinding) bool {
+	if len(calls) == 0 {
+		return false
+	}
+	for _, call := range calls {
+		if _, ok := bindings[call.Function.Name]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// parseArguments 解析模型生成的JSON参数
+func parseArguments(arguments string) (map[string]interface{}, error) {
+	if arguments == "" {
+		return map[string]interface{}{}, nil
+	}
+	params := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(arguments), &params); err != nil {
+		return nil, fmt.Errorf("invalid tool arguments: %w", err)
+	}
+	return params, nil
+}
+
+// toolResult 将MCP服务响应转换为回传给模型的内容
+func toolResult(resp *mcp.MCPServiceResponse) (string, error) {
+	if resp == nil {
+		return "null", nil
+	}
+	if !resp.Success {
+		content, _ := json.Marshal(map[string]interface{}{"error": resp.Error})
+		message, _ := resp.Error["message"].(string)
+		return string(content), fmt.Errorf("tool returned error: %s", message)
+	}
+
+	content, err := json.Marshal(resp.Data)
+	if err != nil {
+		return errorResult(err), err
+	}
+	return string(content), nil
+}
+
+// errorResult 将错误转换为回传给模型的内容
+func errorResult(err error) string {
+	content, _ := json.Marshal(map[string]string{"error": err.Error()})
+	return string(content)
+}
@@ -0,0 +1,117 @@
+package session
+
+import (
+	"context"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"kai/kaigate/pkg/service/ai_agent"
+)
+
+// sessionAgent 使用会话历史的AI Agent
+// Chat和ChatStream在调用前补全历史消息，调用成功后将本轮消息和回复追加到会话；其他方法直接调用原Agent
+type sessionAgent struct {
+	ai_agent.AIAgent
+	manager *Manager
+	agentID string
+	id      string
+	owner   string
+}
+
+// Bind 返回使用指定会话的AI Agent，调用前应先通过Open检查调用方能否使用会话
+// 同一会话的调用依次执行，流式调用在数据流结束后才允许下一次调用
+func (m *Manager) Bind(agent ai_agent.AIAgent, agentID, id, owner string) ai_agent.AIAgent {
+	return &sessionAgent{AIAgent: agent, manager: m, agentID: agentID, id: id, owner: owner}
+}
+
+// Chat 实现AIAgent接口的Chat方法
+func (a *sessionAgent) Chat(ctx context.Context, req ai_agent.ChatRequest) (*ai_agent.ChatResponse, error) {
+	unlock := a.manager.lock(a.id)
+	defer unlock()
+
+	sess, full, err := a.begin(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := a.AIAgent.Chat(ctx, full)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Choices) > 0 {
+		a.commit(sess, req, resp.Choices[0].Message)
+	}
+	return resp, nil
+}
+
+// ChatStream 实现AIAgent接口的ChatStream方法，数据流正常结束后将拼接的回复和工具调用追加到会话
+func (a *sessionAgent) ChatStream(ctx context.Context, req ai_agent.ChatRequest) (<-chan *ai_agent.ChatResponse, <-chan error) {
+	unlock := a.manager.lock(a.id)
+
+	sess, full, err := a.begin(ctx, req)
+	if err != nil {
+		unlock()
+		respChan := make(chan *ai_agent.ChatResponse)
+		errChan := make(chan error, 1)
+		close(respChan)
+		errChan <- err
+		close(errChan)
+		return respChan, errChan
+	}
+
+	var content strings.Builder
+	var toolCalls []ai_agent.ToolCall
+	chunks, errs := a.AIAgent.ChatStream(ctx, full)
+	return ai_agent.ForwardStream(ctx, chunks, errs, ai_agent.StreamHooks[*ai_agent.ChatResponse]{
+		Transform: func(chunk *ai_agent.ChatResponse) *ai_agent.ChatResponse {
+			if chunk != nil && len(chunk.Choices) > 0 {
+				content.WriteString(chunk.Choices[0].Message.Content)
+				toolCalls = ai_agent.MergeToolCalls(toolCalls, chunk.Choices[0].Message.ToolCalls)
+			}
+			return chunk
+		},
+		Done: func(_ int, err error) {
+			defer unlock()
+			if err != nil || (content.Len() == 0 && len(toolCalls) == 0) {
+				return
+			}
+			// Index只用于合并增量，请求消息中的工具调用不携带Index
+			for i := range toolCalls {
+				toolCalls[i].Index = 0
+			}
+			a.commit(sess, req, ai_agent.Message{Role: "assistant", Content: content.String(), ToolCalls: toolCalls})
+		},
+	})
+}
+
+// begin 加载会话并补全历史消息，压缩过的会话立即保存
+// 调用方在持有会话锁时检查归属，防止Open之后会话被其他调用方创建
+func (a *sessionAgent) begin(ctx context.Context, req ai_agent.ChatRequest) (*Session, ai_agent.ChatRequest, error) {
+	sess, err := a.manager.load(a.id, a.owner)
+	if err != nil {
+		return nil, req, err
+	}
+	if sess.Owner != a.owner {
+		return nil, req, ErrForbidden
+	}
+	sess.Agent = a.agentID
+	full, compacted := a.manager.prepare(ctx, sess, req)
+	if compacted {
+		if err := a.manager.save(sess); err != nil {
+			a.manager.logger.Warn("Failed to save compacted session", zap.String("session_id", a.id), zap.Error(err))
+		}
+	}
+	return sess, full, nil
+}
+
+// commit 将本轮的非系统消息和回复追加到会话并保存
+func (a *sessionAgent) commit(sess *Session, req ai_agent.ChatRequest, reply ai_agent.Message) {
+	_, input := splitSystem(req.Messages)
+	if reply.Role == "" {
+		reply.Role = "assistant"
+	}
+	sess.Messages = append(append(sess.Messages, input...), reply)
+	if err := a.manager.save(sess); err != nil {
+		a.manager.logger.Error("Failed to save session", zap.String("session_id", a.id), zap.Error(err))
+	}
+}
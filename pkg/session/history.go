@@ -0,0 +1,142 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"kai/kaigate/pkg/service/ai_agent"
+	"kai/kaigate/pkg/tokenizer"
+)
+
+// summaryPrompt 摘要较早消息时使用的系统提示
+const summaryPrompt = "Summarize the conversation below in a few sentences. Keep names, numbers, decisions and open questions. " +
+	"If a previous summary is given, merge it into the new summary. Reply with the summary only."
+
+// Summarizer 将较早的历史消息与已有摘要合并为新的摘要
+// agentID为会话当前使用的AI Agent
+type Summarizer func(ctx context.Context, agentID, summary string, messages []ai_agent.Message) (string, error)
+
+// NewAgentSummarizer 创建使用AI Agent生成摘要的Summarizer，agentID为空时使用会话当前的AI Agent
+func NewAgentSummarizer(manager ai_agent.AIAgentManager, agentID, model string) Summarizer {
+	return func(ctx context.Context, sessionAgent, summary string, messages []ai_agent.Message) (string, error) {
+		if agentID != "" {
+			sessionAgent = agentID
+		}
+		agent, err := manager.GetAIAgent(sessionAgent, nil)
+		if err != nil {
+			return "", err
+		}
+
+		var transcript strings.Builder
+		if summary != "" {
+			transcript.WriteString("Previous summary:\n" + summary + "\n\n")
+		}
+		transcript.WriteString("Conversation:\n")
+		for _, message := range messages {
+			if text := message.Text(); text != "" {
+				transcript.WriteString(message.Role + ": " + text + "\n")
+			}
+		}
+
+		resp, err := agent.Chat(ctx, ai_agent.ChatRequest{
+			Model: model,
+			Messages: []ai_agent.Message{
+				{Role: "system", Content: summaryPrompt},
+				{Role: "user", Content: transcript.String()},
+			},
+		})
+		if err != nil {
+			return "", err
+		}
+		if len(resp.Choices) == 0 || strings.TrimSpace(resp.Choices[0].Message.Content) == "" {
+			return "", errors.New("summary response is empty")
+		}
+		return strings.TrimSpace(resp.Choices[0].Message.Content), nil
+	}
+}
+
+// prepare 将历史消息补全到本轮请求中，历史消息超出token预算时先按策略压缩
+// 请求开头的系统消息保持在最前，其后依次为摘要、历史消息和本轮的其他消息；返回会话是否被压缩
+func (m *Manager) prepare(ctx context.Context, sess *Session, req ai_agent.ChatRequest) (ai_agent.ChatRequest, bool) {
+	system, input := splitSystem(req.Messages)
+
+	compacted := false
+	if m.maxTokens > 0 {
+		counter := m.tokenizers.For(req.Model)
+		budget := m.maxTokens - tokenizer.CountMessages(counter, system) - tokenizer.CountMessages(counter, input)
+		if sess.tokens(counter) > budget {
+			m.compact(ctx, sess, budget, counter)
+			compacted = true
+		}
+	}
+
+	messages := make([]ai_agent.Message, 0, len(system)+len(sess.Messages)+len(input)+1)
+	messages = append(messages, system...)
+	if sess.Summary != "" {
+		messages = append(messages, sess.summaryMessage())
+	}
+	messages = append(messages, sess.Messages...)
+	messages = append(messages, input...)
+	req.Messages = messages
+	return req, compacted
+}
+
+// compact 压缩历史消息使其不超过budget
+// summarize策略保留不超过一半预算的最近消息，其余消息与已有摘要合并；摘要失败或仍超出预算时按滑动窗口丢弃最早的消息
+func (m *Manager) compact(ctx context.Context, sess *Session, budget int, counter tokenizer.Tokenizer) {
+	if m.strategy == StrategySummarize && len(sess.Messages) > 0 {
+		keep := recentMessages(sess.Messages, budget/2, counter)
+		older := sess.Messages[:len(sess.Messages)-len(keep)]
+		if len(older) > 0 {
+			summary, err := m.summarizer(ctx, sess.Agent, sess.Summary, older)
+			if err != nil {
+				m.logger.Warn("Failed to summarize session, falling back to sliding window",
+					zap.String("session_id", sess.ID),
+					zap.Error(err),
+				)
+			} else {
+				sess.Summary = summary
+				sess.Messages = keep
+			}
+		}
+	}
+
+	if sess.tokens(counter) > budget {
+		sess.Messages = recentMessages(sess.Messages, budget, counter)
+	}
+	if sess.tokens(counter) > budget {
+		sess.Summary = ""
+		sess.Messages = recentMessages(sess.Messages, budget, counter)
+	}
+}
+
+// recentMessages 返回token数不超过budget的最近消息
+// 不以tool消息开头，避免工具结果与发起调用的assistant消息分离
+func recentMessages(messages []ai_agent.Message, budget int, counter tokenizer.Tokenizer) []ai_agent.Message {
+	start := len(messages)
+	tokens := 0
+	for start > 0 {
+		cost := tokenizer.CountMessages(counter, messages[start-1:start])
+		if tokens+cost > budget {
+			break
+		}
+		tokens += cost
+		start--
+	}
+	for start < len(messages) && messages[start].Role == "tool" {
+		start++
+	}
+	return messages[start:]
+}
+
+// splitSystem 拆分请求开头的系统消息和其余消息
+func splitSystem(messages []ai_agent.Message) (system, rest []ai_agent.Message) {
+	i := 0
+	for i < len(messages) && (messages[i].Role == "system" || messages[i].Role == "developer") {
+		i++
+	}
+	return messages[:i], messages[i:]
+}
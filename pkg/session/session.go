@@ -0,0 +1,325 @@
+// Package session 在网关侧保存多轮对话的历史消息
+// 客户端只需传入session_id和本轮消息，历史消息在调用AI Agent前自动补全，并按token预算截断或摘要
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"kai/kaigate/pkg/log"
+	"kai/kaigate/pkg/service/ai_agent"
+	"kai/kaigate/pkg/storage"
+	"kai/kaigate/pkg/tokenizer"
+)
+
+// 历史消息超出token预算时的处理策略
+const (
+	StrategySlidingWindow = "sliding_window" // 丢弃最早的消息
+	StrategySummarize     = "summarize"      // 将较早的消息摘要为一条系统消息
+)
+
+// storePrefix 会话在存储中的键前缀
+const storePrefix = "session/"
+
+var (
+	// ErrNotFound 会话不存在或已过期
+	ErrNotFound = errors.New("session not found")
+	// ErrForbidden 会话属于其他调用方
+	ErrForbidden = errors.New("session belongs to another caller")
+	// ErrAnonymous 匿名调用方不能使用会话，会话ID由客户端指定，无法防止被其他匿名调用方猜测
+	ErrAnonymous = errors.New("sessions require authentication")
+	// ErrInvalidID 会话ID格式无效
+	ErrInvalidID = errors.New("invalid session id")
+)
+
+// idPattern 会话ID只允许字母、数字和常用分隔符
+var idPattern = regexp.MustCompile(`^[A-Za-z0-9_.:\-]{1,128}$`)
+
+// Session 会话及其历史消息
+type Session struct {
+	ID        string             `json:"id"`
+	Owner     string             `json:"owner,omitempty"`   // 创建会话的调用方，只有该调用方可以使用会话
+	Agent     string             `json:"agent"`             // 最近一次使用的AI Agent
+	Summary   string             `json:"summary,omitempty"` // 较早消息的摘要
+	Messages  []ai_agent.Message `json:"messages"`          // 历史消息，不包含请求中的系统消息
+	CreatedAt time.Time          `json:"created_at"`
+	UpdatedAt time.Time          `json:"updated_at"`
+}
+
+// Info 会话列表中的摘要信息
+type Info struct {
+	ID         string    `json:"id"`
+	Owner      string    `json:"owner,omitempty"`
+	Agent      string    `json:"agent"`
+	Messages   int       `json:"messages"`
+	Tokens     int       `json:"tokens"` // 估算的历史消息token数
+	Summarized bool      `json:"summarized"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// Manager 会话管理器
+type Manager struct {
+	store      storage.KV
+	maxTokens  int
+	strategy   string
+	ttl        time.Duration
+	summarizer Summarizer
+	tokenizers *tokenizer.Registry
+	locks      map[string]*sessionLock
+	mutex      sync.Mutex
+	logger     log.Logger
+}
+
+// sessionLock 单个会话的锁，同一会话的多轮调用依次执行
+type sessionLock struct {
+	mutex sync.Mutex
+	refs  int
+}
+
+// Option Manager配置选项
+type Option func(*Manager)
+
+// WithMaxTokens 设置历史消息和本轮消息的token预算，0表示不限制
+func WithMaxTokens(maxTokens int) Option {
+	return func(m *Manager) {
+		m.maxTokens = maxTokens
+	}
+}
+
+// WithStrategy 设置超出token预算时的处理策略
+func WithStrategy(strategy string) Option {
+	return func(m *Manager) {
+		m.strategy = strategy
+	}
+}
+
+// WithTTL 设置会话的空闲过期时间，0表示不过期
+func WithTTL(ttl time.Duration) Option {
+	return func(m *Manager) {
+		m.ttl = ttl
+	}
+}
+
+// WithSummarizer 设置summarize策略使用的摘要函数
+func WithSummarizer(summarizer Summarizer) Option {
+	return func(m *Manager) {
+		m.summarizer = summarizer
+	}
+}
+
+// WithTokenizers 设置按模型计算token数的分词器，默认使用启发式估算
+func WithTokenizers(registry *tokenizer.Registry) Option {
+	return func(m *Manager) {
+		m.tokenizers = registry
+	}
+}
+
+// WithLogger 设置日志器
+func WithLogger(logger log.Logger) Option {
+	return func(m *Manager) {
+		m.logger = logger
+	}
+}
+
+// NewManager 创建Manager实例
+func NewManager(store storage.KV, options ...Option) (*Manager, error) {
+	if store == nil {
+		return nil, errors.New("session store cannot be nil")
+	}
+
+	m := &Manager{
+		store:      store,
+		strategy:   StrategySlidingWindow,
+		tokenizers: tokenizer.NewRegistry(),
+		locks:      make(map[string]*sessionLock),
+		logger:     log.GlobalLogger,
+	}
+	for _, option := range options {
+		option(m)
+	}
+
+	switch m.strategy {
+	case StrategySlidingWindow:
+	case StrategySummarize:
+		if m.summarizer == nil {
+			return nil, errors.New("summarize strategy requires a summarizer")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported session strategy: %s", m.strategy)
+	}
+	return m, nil
+}
+
+// Open 检查调用方能否使用会话，会话不存在时允许创建
+// owner为空（匿名调用方）时返回ErrAnonymous
+func (m *Manager) Open(id, owner string) error {
+	if owner == "" {
+		return ErrAnonymous
+	}
+	if !idPattern.MatchString(id) {
+		return ErrInvalidID
+	}
+	sess, err := m.Get(id)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if sess.Owner != owner {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// Get 获取会话，已过期的会话视为不存在
+func (m *Manager) Get(id string) (*Session, error) {
+	data, err := m.store.Get(storePrefix + id)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load session failed: %w", err)
+	}
+
+	var sess Session
+	if err := json.Unmarshal(data, &sess); err != nil {
+		return nil, fmt.Errorf("decode session failed: %w", err)
+	}
+	if m.expired(&sess) {
+		m.store.Delete(storePrefix + id)
+		return nil, ErrNotFound
+	}
+	return &sess, nil
+}
+
+// List 列出会话，owner为空时列出全部会话，结果按更新时间倒序排列
+func (m *Manager) List(owner string) ([]Info, error) {
+	keys, err := m.store.List(storePrefix)
+	if err != nil {
+		return nil, fmt.Errorf("list sessions failed: %w", err)
+	}
+
+	infos := make([]Info, 0, len(keys))
+	for _, key := range keys {
+		sess, err := m.Get(strings.TrimPrefix(key, storePrefix))
+		if err != nil {
+			if !errors.Is(err, ErrNotFound) {
+				m.logger.Warn("Skipping unreadable session", zap.String("key", key), zap.Error(err))
+			}
+			continue
+		}
+		if owner != "" && sess.Owner != owner {
+			continue
+		}
+		infos = append(infos, sess.info(m.tokenizers.For("")))
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].UpdatedAt.After(infos[j].UpdatedAt)
+	})
+	return infos, nil
+}
+
+// Delete 删除会话
+func (m *Manager) Delete(id string) error {
+	if _, err := m.Get(id); err != nil {
+		return err
+	}
+	if err := m.store.Delete(storePrefix + id); err != nil {
+		return fmt.Errorf("delete session failed: %w", err)
+	}
+	m.logger.Info("Session deleted", zap.String("session_id", id))
+	return nil
+}
+
+// Close 清理资源
+func (m *Manager) Close() error {
+	return m.store.Close()
+}
+
+// load 获取会话，不存在时创建新会话
+func (m *Manager) load(id, owner string) (*Session, error) {
+	sess, err := m.Get(id)
+	if errors.Is(err, ErrNotFound) {
+		now := time.Now()
+		return &Session{ID: id, Owner: owner, CreatedAt: now, UpdatedAt: now}, nil
+	}
+	return sess, err
+}
+
+// save 保存会话
+func (m *Manager) save(sess *Session) error {
+	sess.UpdatedAt = time.Now()
+	data, err := json.Marshal(sess)
+	if err != nil {
+		return fmt.Errorf("encode session failed: %w", err)
+	}
+	if err := m.store.Put(storePrefix+sess.ID, data); err != nil {
+		return fmt.Errorf("save session failed: %w", err)
+	}
+	return nil
+}
+
+// lock 锁定会话，返回解锁函数
+func (m *Manager) lock(id string) func() {
+	m.mutex.Lock()
+	l, exists := m.locks[id]
+	if !exists {
+		l = &sessionLock{}
+		m.locks[id] = l
+	}
+	l.refs++
+	m.mutex.Unlock()
+
+	l.mutex.Lock()
+	return func() {
+		l.mutex.Unlock()
+		m.mutex.Lock()
+		if l.refs--; l.refs == 0 {
+			delete(m.locks, id)
+		}
+		m.mutex.Unlock()
+	}
+}
+
+// expired 检查会话是否已过期
+func (m *Manager) expired(sess *Session) bool {
+	return m.ttl > 0 && time.Since(sess.UpdatedAt) > m.ttl
+}
+
+// info 返回会话的摘要信息
+func (s *Session) info(counter tokenizer.Tokenizer) Info {
+	return Info{
+		ID:         s.ID,
+		Owner:      s.Owner,
+		Agent:      s.Agent,
+		Messages:   len(s.Messages),
+		Tokens:     s.tokens(counter),
+		Summarized: s.Summary != "",
+		CreatedAt:  s.CreatedAt,
+		UpdatedAt:  s.UpdatedAt,
+	}
+}
+
+// tokens 计算摘要和历史消息的token数
+func (s *Session) tokens(counter tokenizer.Tokenizer) int {
+	tokens := tokenizer.CountMessages(counter, s.Messages)
+	if s.Summary != "" {
+		tokens += tokenizer.CountMessages(counter, []ai_agent.Message{s.summaryMessage()})
+	}
+	return tokens
+}
+
+// summaryMessage 将摘要转换为系统消息
+func (s *Session) summaryMessage() ai_agent.Message {
+	return ai_agent.Message{Role: "system", Content: "Summary of the earlier conversation:\n" + s.Summary}
+}
@@ -0,0 +1,187 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"kai/kaigate/pkg/log"
+	"kai/kaigate/pkg/service/ai_agent"
+	"kai/kaigate/pkg/storage"
+)
+
+func TestMain(m *testing.M) {
+	if err := log.InitLogger("error", "json", "", false); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// stubAgent 记录收到的请求，按预设返回回复或流式数据块
+type stubAgent struct {
+	ai_agent.AIAgent
+	requests []ai_agent.ChatRequest
+	reply    string
+	chunks   []*ai_agent.ChatResponse
+}
+
+func (a *stubAgent) Chat(ctx context.Context, req ai_agent.ChatRequest) (*ai_agent.ChatResponse, error) {
+	a.requests = append(a.requests, req)
+	return &ai_agent.ChatResponse{Choices: []ai_agent.ChatChoice{{Message: ai_agent.Message{Role: "assistant", Content: a.reply}}}}, nil
+}
+
+func (a *stubAgent) ChatStream(ctx context.Context, req ai_agent.ChatRequest) (<-chan *ai_agent.ChatResponse, <-chan error) {
+	a.requests = append(a.requests, req)
+	respChan := make(chan *ai_agent.ChatResponse, len(a.chunks))
+	errChan := make(chan error)
+	for _, chunk := range a.chunks {
+		respChan <- chunk
+	}
+	close(respChan)
+	close(errChan)
+	return respChan, errChan
+}
+
+func newTestManager(t *testing.T, options ...Option) *Manager {
+	t.Helper()
+	manager, err := NewManager(storage.NewMemoryKV(), options...)
+	if err != nil {
+		t.Fatalf("create manager: %v", err)
+	}
+	return manager
+}
+
+func userMessage(content string) []ai_agent.Message {
+	return []ai_agent.Message{{Role: "user", Content: content}}
+}
+
+func TestOpenOwnership(t *testing.T) {
+	manager := newTestManager(t)
+	agent := &stubAgent{reply: "hi"}
+
+	if err := manager.Open("s1", ""); !errors.Is(err, ErrAnonymous) {
+		t.Fatalf("anonymous Open err = %v, want ErrAnonymous", err)
+	}
+	if err := manager.Open("bad id!", "u1"); !errors.Is(err, ErrInvalidID) {
+		t.Fatalf("Open err = %v, want ErrInvalidID", err)
+	}
+	if err := manager.Open("s1", "u1"); err != nil {
+		t.Fatalf("Open new session: %v", err)
+	}
+	if _, err := manager.Bind(agent, "a", "s1", "u1").Chat(context.Background(), ai_agent.ChatRequest{Messages: userMessage("hello")}); err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+
+	if err := manager.Open("s1", "u2"); !errors.Is(err, ErrForbidden) {
+		t.Errorf("Open by other caller err = %v, want ErrForbidden", err)
+	}
+	// 跳过Open直接绑定时，会话锁内的检查同样拒绝其他调用方
+	_, err := manager.Bind(agent, "a", "s1", "u2").Chat(context.Background(), ai_agent.ChatRequest{Messages: userMessage("steal")})
+	if !errors.Is(err, ErrForbidden) {
+		t.Errorf("Chat by other caller err = %v, want ErrForbidden", err)
+	}
+	if len(agent.requests) != 1 {
+		t.Errorf("agent called %d times, want 1", len(agent.requests))
+	}
+}
+
+func TestChatAppendsHistory(t *testing.T) {
+	manager := newTestManager(t)
+	agent := &stubAgent{reply: "first reply"}
+	bound := manager.Bind(agent, "a", "s1", "u1")
+
+	system := ai_agent.Message{Role: "system", Content: "be brief"}
+	for _, content := range []string{"one", "two"} {
+		req := ai_agent.ChatRequest{Messages: append([]ai_agent.Message{system}, userMessage(content)...)}
+		if _, err := bound.Chat(context.Background(), req); err != nil {
+			t.Fatalf("Chat: %v", err)
+		}
+	}
+
+	// 第二轮请求：系统消息、上一轮的问答、本轮消息
+	got := agent.requests[1].Messages
+	want := []string{"system:be brief", "user:one", "assistant:first reply", "user:two"}
+	if len(got) != len(want) {
+		t.Fatalf("got %d messages, want %d", len(got), len(want))
+	}
+	for i, message := range got {
+		if message.Role+":"+message.Content != want[i] {
+			t.Errorf("message %d = %s:%s, want %s", i, message.Role, message.Content, want[i])
+		}
+	}
+
+	sess, err := manager.Get("s1")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if len(sess.Messages) != 4 || sess.Owner != "u1" || sess.Agent != "a" {
+		t.Errorf("unexpected session %+v", sess)
+	}
+}
+
+func TestChatStreamStoresToolCalls(t *testing.T) {
+	manager := newTestManager(t)
+	delta := func(calls ...ai_agent.ToolCall) *ai_agent.ChatResponse {
+		return &ai_agent.ChatResponse{Choices: []ai_agent.ChatChoice{{Message: ai_agent.Message{ToolCalls: calls}}}}
+	}
+	agent := &stubAgent{chunks: []*ai_agent.ChatResponse{
+		delta(ai_agent.ToolCall{Index: 0, ID: "call_1", Type: "function", Function: ai_agent.FunctionCall{Name: "lookup", Arguments: `{"q":`}}),
+		delta(ai_agent.ToolCall{Index: 1, ID: "call_2", Type: "function", Function: ai_agent.FunctionCall{Name: "calc"}}),
+		delta(ai_agent.ToolCall{Index: 0, Function: ai_agent.FunctionCall{Arguments: `"x"}`}}),
+		delta(ai_agent.ToolCall{Index: 1, Function: ai_agent.FunctionCall{Arguments: `{}`}}),
+	}}
+
+	respChan, errChan := manager.Bind(agent, "a", "s1", "u1").ChatStream(context.Background(), ai_agent.ChatRequest{Messages: userMessage("find x")})
+	for range respChan {
+	}
+	if err := <-errChan; err != nil {
+		t.Fatalf("stream error: %v", err)
+	}
+
+	sess, err := manager.Get("s1")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if len(sess.Messages) != 2 {
+		t.Fatalf("got %d messages, want 2", len(sess.Messages))
+	}
+	reply := sess.Messages[1]
+	if reply.Role != "assistant" || len(reply.ToolCalls) != 2 {
+		t.Fatalf("unexpected reply %+v", reply)
+	}
+	if call := reply.ToolCalls[0]; call.ID != "call_1" || call.Function.Name != "lookup" || call.Function.Arguments != `{"q":"x"}` || call.Index != 0 {
+		t.Errorf("tool call 0 = %+v", call)
+	}
+	if call := reply.ToolCalls[1]; call.ID != "call_2" || call.Function.Arguments != `{}` || call.Index != 0 {
+		t.Errorf("tool call 1 = %+v", call)
+	}
+}
+
+func TestSlidingWindowDropsOldestMessages(t *testing.T) {
+	manager := newTestManager(t, WithMaxTokens(60))
+	agent := &stubAgent{reply: strings.Repeat("reply ", 5)}
+	bound := manager.Bind(agent, "a", "s1", "u1")
+
+	for i := 0; i < 6; i++ {
+		if _, err := bound.Chat(context.Background(), ai_agent.ChatRequest{Messages: userMessage(strings.Repeat("question ", 5))}); err != nil {
+			t.Fatalf("Chat: %v", err)
+		}
+	}
+
+	last := agent.requests[len(agent.requests)-1].Messages
+	if len(last) >= 11 {
+		t.Fatalf("history was not truncated: %d messages", len(last))
+	}
+	if last[len(last)-1].Role != "user" {
+		t.Errorf("current message must be kept, got %+v", last[len(last)-1])
+	}
+	sess, err := manager.Get("s1")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if len(sess.Messages) >= 12 {
+		t.Errorf("stored history was not truncated: %d messages", len(sess.Messages))
+	}
+}
@@ -0,0 +1,217 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrNotFound 键不存在
+var ErrNotFound = errors.New("key not found")
+
+// KV 键值存储接口
+// 用于持久化API Key、会话、任务等网关状态数据
+type KV interface {
+	// 获取键对应的值，不存在时返回ErrNotFound
+	Get(key string) ([]byte, error)
+
+	// 写入键值
+	Put(key string, value []byte) error
+
+	// 删除键，不存在时视为成功
+	Delete(key string) error
+
+	// 列出指定前缀下的所有键
+	List(prefix string) ([]string, error)
+
+	// 清理资源
+	Close() error
+}
+
+// MemoryKV 基于内存的键值存储
+type MemoryKV struct {
+	data  map[string][]byte
+	mutex sync.RWMutex
+}
+
+// NewMemoryKV 创建MemoryKV实例
+func NewMemoryKV() *MemoryKV {
+	return &MemoryKV{
+		data: make(map[string][]byte),
+	}
+}
+
+// Get 获取键对应的值
+func (m *MemoryKV) Get(key string) ([]byte, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	value, exists := m.data[key]
+	if !exists {
+		return nil, ErrNotFound
+	}
+
+	// 返回副本，避免调用方修改内部数据
+	result := make([]byte, len(value))
+	copy(result, value)
+	return result, nil
+}
+
+// Put 写入键值
+func (m *MemoryKV) Put(key string, value []byte) error {
+	stored := make([]byte, len(value))
+	copy(stored, value)
+
+	m.mutex.Lock()
+	m.data[key] = stored
+	m.mutex.Unlock()
+	return nil
+}
+
+// Delete 删除键
+func (m *MemoryKV) Delete(key string) error {
+	m.mutex.Lock()
+	delete(m.data, key)
+	m.mutex.Unlock()
+	return nil
+}
+
+// List 列出指定前缀下的所有键
+func (m *MemoryKV) List(prefix string) ([]string, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	keys := make([]string, 0)
+	for key := range m.data {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Close 清理资源
+func (m *MemoryKV) Close() error {
+	return nil
+}
+
+// FileKV 基于本地目录的键值存储
+// 每个键对应目录下的一个文件，文件名为转义后的键
+type FileKV struct {
+	dir   string
+	mutex sync.RWMutex
+}
+
+// NewFileKV 创建FileKV实例
+func NewFileKV(dir string) (*FileKV, error) {
+	if dir == "" {
+		return nil, errors.New("storage directory cannot be empty")
+	}
+
+	// 确保目录存在
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("create storage directory failed: %w", err)
+	}
+
+	return &FileKV{dir: dir}, nil
+}
+
+// Get 获取键对应的值
+func (f *FileKV) Get(key string) ([]byte, error) {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+
+	value, err := os.ReadFile(f.path(key))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("read key failed: %w", err)
+	}
+	return value, nil
+}
+
+// Put 写入键值
+// 先写临时文件再重命名，保证写入的原子性
+func (f *FileKV) Put(key string, value []byte) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	target := f.path(key)
+	tmp := target + ".tmp"
+	if err := os.WriteFile(tmp, value, 0600); err != nil {
+		return fmt.Errorf("write key failed: %w", err)
+	}
+	if err := os.Rename(tmp, target); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("commit key failed: %w", err)
+	}
+	return nil
+}
+
+// Delete 删除键
+func (f *FileKV) Delete(key string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if err := os.Remove(f.path(key)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("delete key failed: %w", err)
+	}
+	return nil
+}
+
+// List 列出指定前缀下的所有键
+func (f *FileKV) List(prefix string) ([]string, error) {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+
+	entries, err := os.ReadDir(f.dir)
+	if err != nil {
+		return nil, fmt.Errorf("list keys failed: %w", err)
+	}
+
+	keys := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasSuffix(entry.Name(), ".tmp") {
+			continue
+		}
+		key, err := url.PathUnescape(entry.Name())
+		if err != nil {
+			continue
+		}
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Close 清理资源
+func (f *FileKV) Close() error {
+	return nil
+}
+
+// path 获取键对应的文件路径
+func (f *FileKV) path(key string) string {
+	return filepath.Join(f.dir, url.PathEscape(key))
+}
+
+// NewKV 根据存储类型创建键值存储
+// 支持memory和file两种类型，file类型需要指定目录
+func NewKV(storeType, dir string) (KV, error) {
+	switch storeType {
+	case "", "memory":
+		return NewMemoryKV(), nil
+	case "file":
+		return NewFileKV(dir)
+	default:
+		return nil, fmt.Errorf("unsupported storage type: %s", storeType)
+	}
+}
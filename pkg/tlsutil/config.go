@@ -0,0 +1,129 @@
+package tlsutil
+
+import (
+	"crypto/tls"
+	"fmt"
+	"strings"
+
+	"kai/kaigate/pkg/config"
+)
+
+// ServerConfig 根据监听器配置创建tls.Config
+// 返回的CertReloader用于热更新证书；enableHTTP2为false时只协商HTTP/1.1（WebSocket需要）
+func ServerConfig(cfg config.TLSConfig, enableHTTP2 bool) (*tls.Config, *CertReloader, error) {
+	files := []config.TLSCertificateConfig{}
+	if cfg.CertFile != "" || cfg.KeyFile != "" {
+		files = append(files, config.TLSCertificateConfig{CertFile: cfg.CertFile, KeyFile: cfg.KeyFile})
+	}
+	files = append(files, cfg.Certificates...)
+
+	minVersion, err := ParseVersion(cfg.MinVersion)
+	if err != nil {
+		return nil, nil, err
+	}
+	cipherSuites, err := ParseCipherSuites(cfg.CipherSuites)
+	if err != nil {
+		return nil, nil, err
+	}
+	clientAuth, err := ParseClientAuth(cfg.ClientAuth, cfg.ClientCAFile != "")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	reloader, err := NewCertReloader(files, cfg.ClientCAFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	nextProtos := []string{"http/1.1"}
+	if enableHTTP2 {
+		nextProtos = []string{"h2", "http/1.1"}
+	}
+
+	base := &tls.Config{
+		MinVersion:     minVersion,
+		CipherSuites:   cipherSuites,
+		ClientAuth:     clientAuth,
+		NextProtos:     nextProtos,
+		GetCertificate: reloader.GetCertificate,
+	}
+
+	// 每次握手使用最新的客户端CA，使CA文件也支持热更新
+	tlsConfig := base.Clone()
+	tlsConfig.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		connConfig := base.Clone()
+		connConfig.ClientCAs = reloader.ClientCAs()
+		return connConfig, nil
+	}
+	return tlsConfig, reloader, nil
+}
+
+// ParseVersion 解析TLS版本，为空时使用TLS 1.2
+func ParseVersion(version string) (uint16, error) {
+	switch strings.TrimPrefix(strings.ToLower(version), "tls") {
+	case "1.0", "10":
+		return tls.VersionTLS10, nil
+	case "1.1", "11":
+		return tls.VersionTLS11, nil
+	case "", "1.2", "12":
+		return tls.VersionTLS12, nil
+	case "1.3", "13":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unsupported tls version: %s", version)
+	}
+}
+
+// ParseCipherSuites 按IANA名称解析密码套件，为空时返回nil使用Go默认值
+func ParseCipherSuites(names []string) ([]uint16, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	available := make(map[string]uint16)
+	for _, suite := range tls.CipherSuites() {
+		available[suite.Name] = suite.ID
+	}
+	for _, suite := range tls.InsecureCipherSuites() {
+		available[suite.Name] = suite.ID
+	}
+
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, ok := available[strings.ToUpper(name)]
+		if !ok {
+			return nil, fmt.Errorf("unsupported cipher suite: %s", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// ParseClientAuth 解析客户端认证模式，未指定时配置了CA则要求并校验客户端证书
+// require等同于require_and_verify，校验客户端证书的模式必须配置客户端CA
+func ParseClientAuth(mode string, hasClientCA bool) (tls.ClientAuthType, error) {
+	switch strings.ToLower(mode) {
+	case "require", "verify_if_given", "require_and_verify":
+		if !hasClientCA {
+			return tls.NoClientCert, fmt.Errorf("client auth mode %s requires client_ca_file", mode)
+		}
+	}
+
+	switch strings.ToLower(mode) {
+	case "":
+		if hasClientCA {
+			return tls.RequireAndVerifyClientCert, nil
+		}
+		return tls.NoClientCert, nil
+	case "none":
+		return tls.NoClientCert, nil
+	case "request":
+		return tls.RequestClientCert, nil
+	case "verify_if_given":
+		return tls.VerifyClientCertIfGiven, nil
+	case "require", "require_and_verify":
+		return tls.RequireAndVerifyClientCert, nil
+	default:
+		return tls.NoClientCert, fmt.Errorf("unsupported client auth mode: %s", mode)
+	}
+}
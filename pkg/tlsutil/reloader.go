@@ -0,0 +1,226 @@
+package tlsutil
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"kai/kaigate/pkg/config"
+	"kai/kaigate/pkg/log"
+)
+
+// CertificateInfo 已加载证书的摘要信息
+type CertificateInfo struct {
+	CertFile  string    `json:"cert_file"`
+	Subject   string    `json:"subject"`
+	DNSNames  []string  `json:"dns_names"`
+	NotBefore time.Time `json:"not_before"`
+	NotAfter  time.Time `json:"not_after"`
+}
+
+// CertReloader 可热更新的证书集合
+// 按SNI主机名选择证书，文件变更后重新加载，加载失败时保留原有证书
+type CertReloader struct {
+	files        []config.TLSCertificateConfig // 第一个为默认证书
+	clientCAFile string
+
+	certificates []*tls.Certificate
+	byName       map[string]*tls.Certificate
+	clientCAs    *x509.CertPool
+	infos        []CertificateInfo
+	modTimes     map[string]time.Time
+	loadedAt     time.Time
+	mutex        sync.RWMutex
+	logger       log.Logger
+}
+
+// NewCertReloader 创建CertReloader实例并立即加载证书
+func NewCertReloader(files []config.TLSCertificateConfig, clientCAFile string) (*CertReloader, error) {
+	if len(files) == 0 {
+		return nil, errors.New("no certificate configured")
+	}
+
+	reloader := &CertReloader{
+		files:        files,
+		clientCAFile: clientCAFile,
+		logger:       log.GlobalLogger,
+	}
+	if err := reloader.Reload(); err != nil {
+		return nil, err
+	}
+	return reloader, nil
+}
+
+// Reload 从磁盘重新加载所有证书和客户端CA
+func (r *CertReloader) Reload() error {
+	certificates := make([]*tls.Certificate, 0, len(r.files))
+	byName := make(map[string]*tls.Certificate)
+	infos := make([]CertificateInfo, 0, len(r.files))
+
+	for _, file := range r.files {
+		certificate, err := tls.LoadX509KeyPair(file.CertFile, file.KeyFile)
+		if err != nil {
+			return fmt.Errorf("load certificate %s failed: %w", file.CertFile, err)
+		}
+		leaf, err := x509.ParseCertificate(certificate.Certificate[0])
+		if err != nil {
+			return fmt.Errorf("parse certificate %s failed: %w", file.CertFile, err)
+		}
+		certificate.Leaf = leaf
+		certificates = append(certificates, &certificate)
+
+		// 建立主机名索引，先配置的证书优先
+		names := leaf.DNSNames
+		if len(names) == 0 && leaf.Subject.CommonName != "" {
+			names = []string{leaf.Subject.CommonName}
+		}
+		for _, name := range names {
+			name = strings.ToLower(name)
+			if _, exists := byName[name]; !exists {
+				byName[name] = &certificate
+			}
+		}
+
+		infos = append(infos, CertificateInfo{
+			CertFile:  file.CertFile,
+			Subject:   leaf.Subject.String(),
+			DNSNames:  leaf.DNSNames,
+			NotBefore: leaf.NotBefore,
+			NotAfter:  leaf.NotAfter,
+		})
+	}
+
+	var clientCAs *x509.CertPool
+	if r.clientCAFile != "" {
+		content, err := os.ReadFile(r.clientCAFile)
+		if err != nil {
+			return fmt.Errorf("read client ca file failed: %w", err)
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(content) {
+			return fmt.Errorf("no certificate found in client ca file %s", r.clientCAFile)
+		}
+	}
+
+	r.mutex.Lock()
+	r.certificates = certificates
+	r.byName = byName
+	r.clientCAs = clientCAs
+	r.infos = infos
+	r.modTimes = r.currentModTimes()
+	r.loadedAt = time.Now()
+	r.mutex.Unlock()
+
+	r.logger.Info("TLS certificates loaded", zap.Int("certificates", len(certificates)))
+	return nil
+}
+
+// GetCertificate 根据SNI主机名选择证书，可直接用作tls.Config.GetCertificate
+// 依次匹配完整主机名和通配符证书，都不匹配时使用默认证书
+func (r *CertReloader) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	name := strings.ToLower(strings.TrimSuffix(hello.ServerName, "."))
+	if name != "" {
+		if certificate, ok := r.byName[name]; ok {
+			return certificate, nil
+		}
+		if index := strings.IndexByte(name, '.'); index > 0 {
+			if certificate, ok := r.byName["*"+name[index:]]; ok {
+				return certificate, nil
+			}
+		}
+	}
+	return r.certificates[0], nil
+}
+
+// ClientCAs 获取当前的客户端CA
+func (r *CertReloader) ClientCAs() *x509.CertPool {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.clientCAs
+}
+
+// Certificates 获取已加载证书的摘要信息
+func (r *CertReloader) Certificates() []CertificateInfo {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	infos := make([]CertificateInfo, len(r.infos))
+	copy(infos, r.infos)
+	return infos
+}
+
+// LoadedAt 获取最近一次加载成功的时间
+func (r *CertReloader) LoadedAt() time.Time {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.loadedAt
+}
+
+// Watch 按间隔检查证书文件的修改时间，变更后重新加载，直到ctx结束
+func (r *CertReloader) Watch(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !r.changed() {
+				continue
+			}
+			if err := r.Reload(); err != nil {
+				// 证书可能正在写入，保留原证书并在下次检查时重试
+				r.logger.Warn("Failed to reload TLS certificates, keeping previous ones", zap.Error(err))
+			}
+		}
+	}
+}
+
+// changed 检查证书文件是否有变更
+func (r *CertReloader) changed() bool {
+	current := r.currentModTimes()
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	for file, modTime := range current {
+		if !modTime.Equal(r.modTimes[file]) {
+			return true
+		}
+	}
+	return false
+}
+
+// currentModTimes 获取所有证书相关文件的修改时间
+func (r *CertReloader) currentModTimes() map[string]time.Time {
+	modTimes := make(map[string]time.Time)
+	files := []string{r.clientCAFile}
+	for _, file := range r.files {
+		files = append(files, file.CertFile, file.KeyFile)
+	}
+	for _, file := range files {
+		if file == "" {
+			continue
+		}
+		if info, err := os.Stat(file); err == nil {
+			modTimes[file] = info.ModTime()
+		}
+	}
+	return modTimes
+}
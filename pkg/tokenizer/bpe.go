@@ -0,0 +1,127 @@
+package tokenizer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+)
+
+// DefaultPattern 预分词的正则表达式
+// 近似cl100k_base的规则，RE2不支持前瞻断言，连续空白不单独保留最后一个空格
+const DefaultPattern = `(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+`
+
+// maxMergeBytes 执行字节对合并的最大片段长度
+// 合并的耗时随片段长度平方增长，超过该长度的片段（如很长的无空白字符串）使用启发式估算
+const maxMergeBytes = 512
+
+// BPE 按字节对编码(BPE)词表计算token数的分词器
+// 词表使用tiktoken格式：每行为base64编码的token和合并优先级(rank)，以空格分隔
+type BPE struct {
+	name    string
+	ranks   map[string]int
+	pattern *regexp.Regexp
+}
+
+// LoadBPE 从tiktoken格式的词表文件加载分词器，pattern为空时使用DefaultPattern
+func LoadBPE(name, path, pattern string) (*BPE, error) {
+	if pattern == "" {
+		pattern = DefaultPattern
+	}
+	regex, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tokenizer pattern: %w", err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open vocabulary file: %w", err)
+	}
+	defer file.Close()
+
+	ranks := make(map[string]int)
+	scanner := bufio.NewScanner(file)
+	line := 0
+	for scanner.Scan() {
+		line++
+		fields := bytes.Fields(scanner.Bytes())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid vocabulary line %d", line)
+		}
+		token, err := base64.StdEncoding.DecodeString(string(fields[0]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid token on vocabulary line %d: %w", line, err)
+		}
+		rank, err := strconv.Atoi(string(fields[1]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid rank on vocabulary line %d: %w", line, err)
+		}
+		ranks[string(token)] = rank
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read vocabulary file: %w", err)
+	}
+	if len(ranks) == 0 {
+		return nil, fmt.Errorf("vocabulary file is empty: %s", path)
+	}
+
+	return &BPE{name: name, ranks: ranks, pattern: regex}, nil
+}
+
+// Name 实现Tokenizer接口的Name方法
+func (b *BPE) Name() string {
+	return b.name
+}
+
+// Count 实现Tokenizer接口的Count方法
+func (b *BPE) Count(text string) int {
+	tokens := 0
+	for _, piece := range b.pattern.FindAllString(text, -1) {
+		if _, ok := b.ranks[piece]; ok {
+			tokens++
+			continue
+		}
+		if len(piece) > maxMergeBytes {
+			tokens += Heuristic.Count(piece)
+			continue
+		}
+		tokens += b.mergeCount([]byte(piece))
+	}
+	return tokens
+}
+
+// Size 返回词表大小
+func (b *BPE) Size() int {
+	return len(b.ranks)
+}
+
+// mergeCount 对单个预分词片段执行字节对合并，返回合并后的token数
+// 每轮合并优先级最高(rank最小)的相邻片段，直到没有可合并的片段
+func (b *BPE) mergeCount(piece []byte) int {
+	// parts[i]为第i个片段的起始位置，最后一个元素为片段结束位置
+	parts := make([]int, len(piece)+1)
+	for i := range parts {
+		parts[i] = i
+	}
+
+	for len(parts) > 2 {
+		best, bestRank := -1, math.MaxInt
+		for i := 0; i+2 < len(parts); i++ {
+			if rank, ok := b.ranks[string(piece[parts[i]:parts[i+2]])]; ok && rank < bestRank {
+				best, bestRank = i, rank
+			}
+		}
+		if best < 0 {
+			break
+		}
+		parts = append(parts[:best+1], parts[best+2:]...)
+	}
+	return len(parts) - 1
+}
@@ -0,0 +1,128 @@
+package tokenizer
+
+import (
+	"context"
+	"encoding/json"
+
+	"kai/kaigate/pkg/service/ai_agent"
+)
+
+// Name token计数中间件名称
+const Name = "tokenizer"
+
+// Middleware 为缺少用量的AI Agent响应补全Usage的中间件
+// 调用前按请求模型的分词器估算提示token数；非流式响应只补全为0的字段，上游返回的用量保持不变；
+// 流式响应逐块累计生成的token数，并将累计用量写入没有上游用量的数据块
+type Middleware struct {
+	registry *Registry
+}
+
+// NewMiddleware 创建token计数中间件
+func NewMiddleware(registry *Registry) *Middleware {
+	return &Middleware{registry: registry}
+}
+
+// Name 实现ai_agent.AIAgentMiddleware接口的Name方法
+func (m *Middleware) Name() string {
+	return Name
+}
+
+// Process 实现ai_agent.AIAgentMiddleware接口的Process方法
+func (m *Middleware) Process(ctx context.Context, req interface{}, next func(context.Context, interface{}) (interface{}, error)) (interface{}, error) {
+	var t Tokenizer
+	prompt := 0
+	switch r := req.(type) {
+	case *ai_agent.ChatRequest:
+		t = m.registry.For(r.Model)
+		prompt = CountMessages(t, r.Messages)
+		if len(r.Tools) > 0 {
+			if tools, err := json.Marshal(r.Tools); err == nil {
+				prompt += t.Count(string(tools))
+			}
+		}
+	case *ai_agent.CompletionRequest:
+		t = m.registry.For(r.Model)
+		prompt = t.Count(r.Prompt)
+	case *ai_agent.EmbeddingRequest:
+		t = m.registry.For(r.Model)
+		for _, input := range r.Input {
+			prompt += t.Count(input)
+		}
+	default:
+		return next(ctx, req)
+	}
+
+	resp, err := next(ctx, req)
+	if err != nil {
+		return resp, err
+	}
+
+	switch r := resp.(type) {
+	case *ai_agent.ChatResponse:
+		completion := 0
+		for _, choice := range r.Choices {
+			completion += CountMessage(t, choice.Message)
+		}
+		fillUsage(&r.Usage, prompt, completion)
+	case *ai_agent.CompletionResponse:
+		completion := 0
+		for _, choice := range r.Choices {
+			completion += t.Count(choice.Text)
+		}
+		fillUsage(&r.Usage, prompt, completion)
+	case *ai_agent.EmbeddingResponse:
+		if r.Usage.PromptTokens == 0 {
+			r.Usage.PromptTokens = prompt
+		}
+		if r.Usage.TotalTokens == 0 {
+			r.Usage.TotalTokens = r.Usage.PromptTokens
+		}
+	case *ai_agent.ChatStreamResult:
+		completion := 0
+		chunks, errs := ai_agent.ForwardStream(ctx, r.Chunks, r.Errors, ai_agent.StreamHooks[*ai_agent.ChatResponse]{Transform: func(chunk *ai_agent.ChatResponse) *ai_agent.ChatResponse {
+			for _, choice := range chunk.Choices {
+				completion += CountMessage(t, choice.Message)
+			}
+			if hasUsage(chunk.Usage) {
+				return chunk
+			}
+			counted := *chunk
+			fillUsage(&counted.Usage, prompt, completion)
+			return &counted
+		}})
+		return &ai_agent.ChatStreamResult{Chunks: chunks, Errors: errs}, nil
+	case *ai_agent.CompletionStreamResult:
+		completion := 0
+		chunks, errs := ai_agent.ForwardStream(ctx, r.Chunks, r.Errors, ai_agent.StreamHooks[*ai_agent.CompletionResponse]{Transform: func(chunk *ai_agent.CompletionResponse) *ai_agent.CompletionResponse {
+			for _, choice := range chunk.Choices {
+				completion += t.Count(choice.Text)
+			}
+			if hasUsage(chunk.Usage) {
+				return chunk
+			}
+			counted := *chunk
+			fillUsage(&counted.Usage, prompt, completion)
+			return &counted
+		}})
+		return &ai_agent.CompletionStreamResult{Chunks: chunks, Errors: errs}, nil
+	}
+	return resp, nil
+}
+
+// hasUsage 上游是否返回了用量
+func hasUsage(usage ai_agent.Usage) bool {
+	return usage.PromptTokens > 0 || usage.CompletionTokens > 0 || usage.TotalTokens > 0
+}
+
+// fillUsage 使用估算值补全为0的用量字段
+func fillUsage(usage *ai_agent.Usage, prompt, completion int) {
+	if usage.PromptTokens == 0 {
+		usage.PromptTokens = prompt
+	}
+	if usage.CompletionTokens == 0 {
+		usage.CompletionTokens = completion
+	}
+	if usage.TotalTokens == 0 {
+		usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+	}
+}
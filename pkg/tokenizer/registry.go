@@ -0,0 +1,101 @@
+package tokenizer
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Info 分词器及其适用的模型
+type Info struct {
+	Name   string   `json:"name"`
+	Models []string `json:"models"`
+	Size   int      `json:"size,omitempty"` // BPE词表大小
+}
+
+// Registry 按模型选择分词器
+// 模型名先精确匹配，再按最长前缀匹配"prefix*"形式的模型，都不匹配时使用默认分词器
+type Registry struct {
+	exact    map[string]Tokenizer
+	prefixes []prefixTokenizer
+	infos    map[string]*Info
+	fallback Tokenizer
+	mutex    sync.RWMutex
+}
+
+// prefixTokenizer 按模型名前缀匹配的分词器
+type prefixTokenizer struct {
+	prefix    string
+	tokenizer Tokenizer
+}
+
+// NewRegistry 创建默认使用启发式估算的注册表
+func NewRegistry() *Registry {
+	return &Registry{
+		exact:    make(map[string]Tokenizer),
+		infos:    make(map[string]*Info),
+		fallback: Heuristic,
+	}
+}
+
+// Register 注册分词器及其适用的模型，同一模型后注册的分词器生效
+func (r *Registry) Register(t Tokenizer, models ...string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	info, exists := r.infos[t.Name()]
+	if !exists {
+		info = &Info{Name: t.Name(), Models: []string{}}
+		if bpe, ok := t.(*BPE); ok {
+			info.Size = bpe.Size()
+		}
+		r.infos[t.Name()] = info
+	}
+	for _, model := range models {
+		info.Models = append(info.Models, model)
+		if strings.HasSuffix(model, "*") {
+			r.prefixes = append(r.prefixes, prefixTokenizer{prefix: strings.TrimSuffix(model, "*"), tokenizer: t})
+			continue
+		}
+		r.exact[model] = t
+	}
+	sort.SliceStable(r.prefixes, func(i, j int) bool {
+		return len(r.prefixes[i].prefix) > len(r.prefixes[j].prefix)
+	})
+}
+
+// SetDefault 设置未匹配模型时使用的分词器
+func (r *Registry) SetDefault(t Tokenizer) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.fallback = t
+}
+
+// For 获取模型使用的分词器
+func (r *Registry) For(model string) Tokenizer {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	if t, ok := r.exact[model]; ok {
+		return t
+	}
+	for _, entry := range r.prefixes {
+		if strings.HasPrefix(model, entry.prefix) {
+			return entry.tokenizer
+		}
+	}
+	return r.fallback
+}
+
+// List 列出已注册的分词器，按名称排列，第二个返回值为默认分词器名称
+func (r *Registry) List() ([]Info, string) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	infos := make([]Info, 0, len(r.infos))
+	for _, info := range r.infos {
+		infos = append(infos, *info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos, r.fallback.Name()
+}
@@ -0,0 +1,67 @@
+// Package tokenizer 估算文本和消息的token数
+// 模型配置了BPE词表时按词表精确计算，否则使用按字符类型估算的启发式方法；
+// 用于在调用前估算提示token数，以及为没有返回用量的响应（如流式响应）补全Usage
+package tokenizer
+
+import (
+	"unicode"
+
+	"kai/kaigate/pkg/service/ai_agent"
+)
+
+// HeuristicName 启发式估算的名称
+const HeuristicName = "heuristic"
+
+// MessageOverhead 每条消息的角色和格式开销(token)
+const MessageOverhead = 4
+
+// Tokenizer 计算文本的token数
+type Tokenizer interface {
+	// 获取分词器名称
+	Name() string
+
+	// 计算文本的token数
+	Count(text string) int
+}
+
+// heuristic 启发式估算：中日韩字符每个计1个token，其余字符每4个计1个token
+type heuristic struct{}
+
+// Heuristic 启发式估算的分词器，没有配置词表的模型使用
+var Heuristic Tokenizer = heuristic{}
+
+// Name 实现Tokenizer接口的Name方法
+func (heuristic) Name() string {
+	return HeuristicName
+}
+
+// Count 实现Tokenizer接口的Count方法
+func (heuristic) Count(text string) int {
+	wide, other := 0, 0
+	for _, r := range text {
+		if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) {
+			wide++
+		} else {
+			other++
+		}
+	}
+	return wide + (other+3)/4
+}
+
+// CountMessages 计算消息列表的token数，包括每条消息的格式开销和工具调用
+func CountMessages(t Tokenizer, messages []ai_agent.Message) int {
+	tokens := 0
+	for _, message := range messages {
+		tokens += MessageOverhead + CountMessage(t, message)
+	}
+	return tokens
+}
+
+// CountMessage 计算单条消息内容和工具调用的token数，不含格式开销
+func CountMessage(t Tokenizer, message ai_agent.Message) int {
+	tokens := t.Count(message.Text())
+	for _, call := range message.ToolCalls {
+		tokens += t.Count(call.Function.Name) + t.Count(call.Function.Arguments)
+	}
+	return tokens
+}
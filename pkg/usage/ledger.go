@@ -0,0 +1,368 @@
+package usage
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"kai/kaigate/pkg/config"
+	"kai/kaigate/pkg/log"
+)
+
+// 查询支持的汇总维度
+const (
+	DimensionCaller    = "caller"
+	DimensionAgent     = "agent"
+	DimensionModel     = "model"
+	DimensionOperation = "operation"
+	DimensionStatus    = "status"
+	DimensionHour      = "hour"
+	DimensionDay       = "day"
+)
+
+// ErrInvalidQuery 查询参数无效
+var ErrInvalidQuery = errors.New("invalid usage query")
+
+// Totals 用量合计
+type Totals struct {
+	Calls            int64   `json:"calls"`
+	Errors           int64   `json:"errors"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	TotalTokens      int64   `json:"total_tokens"`
+	Cost             float64 `json:"cost"`
+	AvgLatencyMs     float64 `json:"avg_latency_ms"`
+	latencyMs        float64
+}
+
+// rollupKey 按小时汇总的键
+type rollupKey struct {
+	hour      int64 // 小时起始时间的Unix秒
+	caller    string
+	agent     string
+	model     string
+	operation string
+	status    string
+}
+
+// Query 用量查询条件
+type Query struct {
+	From    time.Time // 开始时间(含)，按小时向下取整
+	To      time.Time // 结束时间(不含)
+	GroupBy []string  // 汇总维度，为空时只返回合计
+	Caller  string    // 只统计指定调用方，为空表示全部
+	Agent   string    // 只统计指定AI Agent
+	Model   string    // 只统计指定模型
+}
+
+// Row 按维度汇总的一行结果，未参与汇总的维度为空
+type Row struct {
+	Period    string `json:"period,omitempty"` // hour或day维度的时间段起点
+	Caller    string `json:"caller,omitempty"`
+	Agent     string `json:"agent,omitempty"`
+	Model     string `json:"model,omitempty"`
+	Operation string `json:"operation,omitempty"`
+	Status    string `json:"status,omitempty"`
+	Totals
+}
+
+// Report 用量查询结果
+type Report struct {
+	From     time.Time `json:"from"`
+	To       time.Time `json:"to"`
+	Currency string    `json:"currency"`
+	GroupBy  []string  `json:"group_by"`
+	Rows     []Row     `json:"rows"`
+	Total    Totals    `json:"total"`
+}
+
+// Ledger 用量账本
+// 记录写入Sink后按小时和调用方、Agent、模型、调用类型、状态汇总在内存中，超过保留时间的汇总被清理
+type Ledger struct {
+	sink      Sink
+	pricing   *Pricing
+	currency  string
+	retention time.Duration
+	rollups   map[rollupKey]*Totals
+	pruned    time.Time
+	mutex     sync.RWMutex
+	logger    log.Logger
+}
+
+// Option Ledger配置选项
+type Option func(*Ledger)
+
+// WithSink 设置用量记录的输出，为空时只在内存中汇总
+func WithSink(sink Sink) Option {
+	return func(l *Ledger) {
+		l.sink = sink
+	}
+}
+
+// WithPricing 设置模型价格和货币
+func WithPricing(prices map[string]config.ModelPriceConfig, currency string) Option {
+	return func(l *Ledger) {
+		l.pricing = NewPricing(prices)
+		l.currency = currency
+	}
+}
+
+// WithRetention 设置内存中汇总的保留时间
+func WithRetention(retention time.Duration) Option {
+	return func(l *Ledger) {
+		l.retention = retention
+	}
+}
+
+// WithLogger 设置日志记录器
+func WithLogger(logger log.Logger) Option {
+	return func(l *Ledger) {
+		l.logger = logger
+	}
+}
+
+// NewLedger 创建用量账本，Sink支持回放时恢复保留时间内的汇总
+func NewLedger(options ...Option) *Ledger {
+	l := &Ledger{
+		pricing:   NewPricing(nil),
+		retention: time.Duration(config.DefaultUsageRetentionDays) * 24 * time.Hour,
+		rollups:   make(map[rollupKey]*Totals),
+		pruned:    time.Now(),
+		logger:    log.GlobalLogger,
+	}
+	for _, option := range options {
+		option(l)
+	}
+
+	if replayer, ok := l.sink.(Replayer); ok {
+		replayed := 0
+		err := replayer.Replay(time.Now().Add(-l.retention), func(record Record) {
+			l.add(record)
+			replayed++
+		})
+		if err != nil {
+			l.logger.Warn("Failed to replay usage records", zap.Error(err))
+		} else {
+			l.logger.Info("Usage records replayed", zap.Int("records", replayed))
+		}
+	}
+	return l
+}
+
+// SetPricing 替换模型价格和货币，只影响之后的记录
+func (l *Ledger) SetPricing(prices map[string]config.ModelPriceConfig, currency string) {
+	pricing := NewPricing(prices)
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.pricing = pricing
+	l.currency = currency
+}
+
+// Pricing 返回当前的模型价格和货币
+func (l *Ledger) Pricing() (map[string]config.ModelPriceConfig, string) {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	return l.pricing.Prices(), l.currency
+}
+
+// Record 记录一次调用，补全总token数和费用后写入Sink并计入汇总
+func (l *Ledger) Record(record Record) {
+	if record.Time.IsZero() {
+		record.Time = time.Now()
+	}
+	if record.TotalTokens == 0 {
+		record.TotalTokens = record.PromptTokens + record.CompletionTokens
+	}
+
+	l.mutex.RLock()
+	record.Cost = l.pricing.Cost(record.Model, record.PromptTokens, record.CompletionTokens)
+	l.mutex.RUnlock()
+
+	if l.sink != nil {
+		if err := l.sink.Write(record); err != nil {
+			l.logger.Error("Failed to write usage record",
+				zap.String("agent", record.Agent),
+				zap.Error(err),
+			)
+		}
+	}
+	l.add(record)
+}
+
+// add 将记录计入按小时的汇总，每小时最多清理一次过期汇总
+func (l *Ledger) add(record Record) {
+	key := rollupKey{
+		hour:      record.Time.Truncate(time.Hour).Unix(),
+		caller:    record.Caller,
+		agent:     record.Agent,
+		model:     record.Model,
+		operation: record.Operation,
+		status:    record.Status,
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	totals, exists := l.rollups[key]
+	if !exists {
+		totals = &Totals{}
+		l.rollups[key] = totals
+	}
+	totals.addRecord(record)
+
+	if now := time.Now(); now.Sub(l.pruned) >= time.Hour {
+		cutoff := now.Add(-l.retention).Truncate(time.Hour).Unix()
+		for key := range l.rollups {
+			if key.hour < cutoff {
+				delete(l.rollups, key)
+			}
+		}
+		l.pruned = now
+	}
+}
+
+// addRecord 将单条记录计入合计
+func (t *Totals) addRecord(record Record) {
+	t.Calls++
+	if record.Status == StatusError {
+		t.Errors++
+	}
+	t.PromptTokens += int64(record.PromptTokens)
+	t.CompletionTokens += int64(record.CompletionTokens)
+	t.TotalTokens += int64(record.TotalTokens)
+	t.Cost += record.Cost
+	t.latencyMs += record.LatencyMs
+}
+
+// merge 合并另一份合计
+func (t *Totals) merge(other *Totals) {
+	t.Calls += other.Calls
+	t.Errors += other.Errors
+	t.PromptTokens += other.PromptTokens
+	t.CompletionTokens += other.CompletionTokens
+	t.TotalTokens += other.TotalTokens
+	t.Cost += other.Cost
+	t.latencyMs += other.latencyMs
+}
+
+// finish 计算平均耗时
+func (t *Totals) finish() {
+	if t.Calls > 0 {
+		t.AvgLatencyMs = t.latencyMs / float64(t.Calls)
+	}
+}
+
+// Query 按时间范围和维度汇总用量，时间精度为小时
+func (l *Ledger) Query(query Query) (*Report, error) {
+	if query.To.IsZero() {
+		query.To = time.Now()
+	}
+	if query.From.IsZero() {
+		query.From = query.To.Add(-24 * time.Hour)
+	}
+	if !query.From.Before(query.To) {
+		return nil, fmt.Errorf("%w: from must be before to", ErrInvalidQuery)
+	}
+	for _, dimension := range query.GroupBy {
+		switch dimension {
+		case DimensionCaller, DimensionAgent, DimensionModel, DimensionOperation, DimensionStatus, DimensionHour, DimensionDay:
+		default:
+			return nil, fmt.Errorf("%w: unknown dimension %q", ErrInvalidQuery, dimension)
+		}
+	}
+
+	from := query.From.Truncate(time.Hour).Unix()
+	to := query.To.Unix()
+	report := &Report{From: query.From, To: query.To, GroupBy: query.GroupBy}
+	rows := make(map[Row]*Totals)
+
+	l.mutex.RLock()
+	report.Currency = l.currency
+	for key, totals := range l.rollups {
+		if key.hour < from || key.hour >= to {
+			continue
+		}
+		if (query.Caller != "" && key.caller != query.Caller) ||
+			(query.Agent != "" && key.agent != query.Agent) ||
+			(query.Model != "" && key.model != query.Model) {
+			continue
+		}
+
+		row := groupRow(key, query.GroupBy)
+		merged, exists := rows[row]
+		if !exists {
+			merged = &Totals{}
+			rows[row] = merged
+		}
+		merged.merge(totals)
+		report.Total.merge(totals)
+	}
+	l.mutex.RUnlock()
+
+	report.Rows = make([]Row, 0, len(rows))
+	for row, totals := range rows {
+		totals.finish()
+		row.Totals = *totals
+		report.Rows = append(report.Rows, row)
+	}
+	report.Total.finish()
+
+	// 按时间段排列，同一时间段内费用和token数高的在前
+	sort.Slice(report.Rows, func(i, j int) bool {
+		a, b := report.Rows[i], report.Rows[j]
+		if a.Period != b.Period {
+			return a.Period < b.Period
+		}
+		if a.Cost != b.Cost {
+			return a.Cost > b.Cost
+		}
+		if a.TotalTokens != b.TotalTokens {
+			return a.TotalTokens > b.TotalTokens
+		}
+		return rowName(a) < rowName(b)
+	})
+	return report, nil
+}
+
+// groupRow 按汇总维度生成结果行的键
+func groupRow(key rollupKey, dimensions []string) Row {
+	var row Row
+	for _, dimension := range dimensions {
+		switch dimension {
+		case DimensionCaller:
+			row.Caller = key.caller
+		case DimensionAgent:
+			row.Agent = key.agent
+		case DimensionModel:
+			row.Model = key.model
+		case DimensionOperation:
+			row.Operation = key.operation
+		case DimensionStatus:
+			row.Status = key.status
+		case DimensionHour:
+			row.Period = time.Unix(key.hour, 0).UTC().Format(time.RFC3339)
+		case DimensionDay:
+			row.Period = time.Unix(key.hour, 0).UTC().Truncate(24 * time.Hour).Format(time.RFC3339)
+		}
+	}
+	return row
+}
+
+// rowName 结果行的维度组合，用于稳定排序
+func rowName(row Row) string {
+	return strings.Join([]string{row.Caller, row.Agent, row.Model, row.Operation, row.Status}, "/")
+}
+
+// Close 关闭Sink
+func (l *Ledger) Close() error {
+	if l.sink == nil {
+		return nil
+	}
+	return l.sink.Close()
+}
@@ -0,0 +1,145 @@
+package usage
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"kai/kaigate/pkg/auth"
+	"kai/kaigate/pkg/router"
+	"kai/kaigate/pkg/service/ai_agent"
+)
+
+// Name 用量统计中间件名称
+const Name = "usage"
+
+// callMark 标记中间件链中的一次调用，嵌套调用开始时设置外层调用的nested
+type callMark struct {
+	nested atomic.Bool
+}
+
+// callMarkKey callMark在上下文中的键
+type callMarkKey struct{}
+
+// Middleware 记录AI Agent调用用量的中间件
+// 嵌套调用（如Agent内部再通过管理器调用其他Agent）只由最内层的调用记录，避免重复计费；流式调用使用数据块中最后出现的用量
+type Middleware struct {
+	ledger *Ledger
+}
+
+// NewMiddleware 创建用量统计中间件
+func NewMiddleware(ledger *Ledger) *Middleware {
+	return &Middleware{ledger: ledger}
+}
+
+// Name 实现ai_agent.AIAgentMiddleware接口的Name方法
+func (m *Middleware) Name() string {
+	return Name
+}
+
+// Process 实现ai_agent.AIAgentMiddleware接口的Process方法
+func (m *Middleware) Process(ctx context.Context, req interface{}, next func(context.Context, interface{}) (interface{}, error)) (interface{}, error) {
+	if parent, ok := ctx.Value(callMarkKey{}).(*callMark); ok {
+		parent.nested.Store(true)
+	}
+	mark := &callMark{}
+	ctx = context.WithValue(ctx, callMarkKey{}, mark)
+	// 逻辑模型的调用按实际处理请求的目标记录Agent
+	ctx, servedBy := router.EnsureServedBy(ctx)
+
+	info, _ := ai_agent.CallInfoFromContext(ctx)
+	record := Record{
+		Agent:     info.Agent,
+		Operation: info.Operation,
+		Model:     requestModel(req),
+		Caller:    auth.IdentityTypeAnonymous,
+	}
+	if identity, ok := auth.IdentityFromContext(ctx); ok && !identity.IsAnonymous() {
+		record.Caller = identity.ID
+		record.CallerName = identity.Name
+	}
+	start := time.Now()
+
+	done := func(usage ai_agent.Usage, model string, err error) {
+		if mark.nested.Load() {
+			return
+		}
+		if servedBy.Route == record.Agent && servedBy.Agent != "" {
+			record.Agent = servedBy.Agent
+		}
+		record.Time = start
+		record.LatencyMs = float64(time.Since(start)) / float64(time.Millisecond)
+		record.PromptTokens = usage.PromptTokens
+		record.CompletionTokens = usage.CompletionTokens
+		record.TotalTokens = usage.TotalTokens
+		if model != "" {
+			record.Model = model
+		}
+		record.Status = StatusSuccess
+		if err != nil {
+			record.Status = StatusError
+			record.Error = err.Error()
+		}
+		m.ledger.Record(record)
+	}
+
+	resp, err := next(ctx, req)
+	if err != nil {
+		done(ai_agent.Usage{}, "", err)
+		return resp, err
+	}
+
+	switch r := resp.(type) {
+	case *ai_agent.ChatResponse:
+		done(r.Usage, r.Model, nil)
+	case *ai_agent.CompletionResponse:
+		done(r.Usage, r.Model, nil)
+	case *ai_agent.EmbeddingResponse:
+		done(ai_agent.Usage{PromptTokens: r.Usage.PromptTokens, TotalTokens: r.Usage.TotalTokens}, r.Model, nil)
+	case *ai_agent.ChatStreamResult:
+		chunks, errs := observeStream(ctx, r.Chunks, r.Errors, func(chunk *ai_agent.ChatResponse) (ai_agent.Usage, string) {
+			return chunk.Usage, chunk.Model
+		}, done)
+		return &ai_agent.ChatStreamResult{Chunks: chunks, Errors: errs}, nil
+	case *ai_agent.CompletionStreamResult:
+		chunks, errs := observeStream(ctx, r.Chunks, r.Errors, func(chunk *ai_agent.CompletionResponse) (ai_agent.Usage, string) {
+			return chunk.Usage, chunk.Model
+		}, done)
+		return &ai_agent.CompletionStreamResult{Chunks: chunks, Errors: errs}, nil
+	default:
+		done(ai_agent.Usage{}, "", nil)
+	}
+	return resp, nil
+}
+
+// requestModel 获取请求中的模型名称
+func requestModel(req interface{}) string {
+	switch r := req.(type) {
+	case *ai_agent.ChatRequest:
+		return r.Model
+	case *ai_agent.CompletionRequest:
+		return r.Model
+	case *ai_agent.EmbeddingRequest:
+		return r.Model
+	}
+	return ""
+}
+
+// observeStream 转发数据流并记录数据块中最后出现的用量和模型，数据流结束后调用done
+func observeStream[T any](ctx context.Context, chunks <-chan T, errs <-chan error, inspect func(T) (ai_agent.Usage, string), done func(ai_agent.Usage, string, error)) (<-chan T, <-chan error) {
+	var usage ai_agent.Usage
+	var model string
+	return ai_agent.ForwardStream(ctx, chunks, errs, ai_agent.StreamHooks[T]{
+		Transform: func(chunk T) T {
+			chunkUsage, chunkModel := inspect(chunk)
+			if chunkUsage.TotalTokens > 0 || chunkUsage.PromptTokens > 0 || chunkUsage.CompletionTokens > 0 {
+				usage = chunkUsage
+			}
+			if model == "" {
+				model = chunkModel
+			}
+			return chunk
+		},
+		Done: func(_ int, err error) { done(usage, model, err) },
+	})
+}
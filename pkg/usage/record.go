@@ -0,0 +1,175 @@
+// Package usage 记录AI Agent调用的token用量、耗时和费用，用于按调用方、Agent和模型统计和分摊成本
+// 每次调用生成一条Record写入Sink（默认追加到JSON Lines文件），同时在内存中按小时汇总以支持按时间范围查询
+package usage
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"kai/kaigate/pkg/config"
+)
+
+// 调用状态
+const (
+	StatusSuccess = "success"
+	StatusError   = "error"
+)
+
+// Record 一次AI Agent调用的用量记录
+type Record struct {
+	Time             time.Time `json:"time"`
+	Caller           string    `json:"caller"`                // 调用方身份标识
+	CallerName       string    `json:"caller_name,omitempty"` // 调用方身份名称
+	Agent            string    `json:"agent"`
+	Operation        string    `json:"operation"`
+	Model            string    `json:"model"`
+	PromptTokens     int       `json:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+	TotalTokens      int       `json:"total_tokens"`
+	LatencyMs        float64   `json:"latency_ms"`
+	Status           string    `json:"status"` // 状态: success, error
+	Error            string    `json:"error,omitempty"`
+	Cost             float64   `json:"cost"` // 按记录时的价格计算的费用
+}
+
+// Sink 用量记录的输出
+type Sink interface {
+	// 写入一条记录
+	Write(record Record) error
+
+	// 关闭输出
+	Close() error
+}
+
+// Replayer 可以回放已写入记录的Sink，用于启动时恢复内存中的汇总
+type Replayer interface {
+	// 按写入顺序回放since之后的记录
+	Replay(since time.Time, fn func(Record)) error
+}
+
+// FileSink 将记录逐行追加到JSON Lines文件的Sink
+type FileSink struct {
+	path  string
+	file  *os.File
+	mutex sync.Mutex
+}
+
+// NewFileSink 打开或创建用量记录文件，目录不存在时自动创建
+func NewFileSink(path string) (*FileSink, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create usage directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open usage file: %w", err)
+	}
+	return &FileSink{path: path, file: file}, nil
+}
+
+// Write 实现Sink接口的Write方法
+func (s *FileSink) Write(record Record) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	_, err = s.file.Write(append(data, '\n'))
+	return err
+}
+
+// Close 实现Sink接口的Close方法
+func (s *FileSink) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.file.Close()
+}
+
+// Replay 实现Replayer接口的Replay方法，跳过无法解析的行
+func (s *FileSink) Replay(since time.Time, fn func(Record)) error {
+	file, err := os.Open(s.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue
+		}
+		if !record.Time.Before(since) {
+			fn(record)
+		}
+	}
+	return scanner.Err()
+}
+
+// Pricing 模型价格表
+// 模型名先精确匹配，再按最长前缀匹配"prefix*"形式的键
+type Pricing struct {
+	exact    map[string]config.ModelPriceConfig
+	prefixes []string
+	prices   map[string]config.ModelPriceConfig
+}
+
+// NewPricing 根据配置创建价格表
+func NewPricing(prices map[string]config.ModelPriceConfig) *Pricing {
+	pricing := &Pricing{
+		exact:  make(map[string]config.ModelPriceConfig),
+		prices: make(map[string]config.ModelPriceConfig, len(prices)),
+	}
+	for model, price := range prices {
+		pricing.prices[model] = price
+		if strings.HasSuffix(model, "*") {
+			pricing.prefixes = append(pricing.prefixes, strings.TrimSuffix(model, "*"))
+			continue
+		}
+		pricing.exact[model] = price
+	}
+	sort.Slice(pricing.prefixes, func(i, j int) bool {
+		return len(pricing.prefixes[i]) > len(pricing.prefixes[j])
+	})
+	return pricing
+}
+
+// Lookup 获取模型价格
+func (p *Pricing) Lookup(model string) (config.ModelPriceConfig, bool) {
+	if price, ok := p.exact[model]; ok {
+		return price, true
+	}
+	for _, prefix := range p.prefixes {
+		if strings.HasPrefix(model, prefix) {
+			return p.prices[prefix+"*"], true
+		}
+	}
+	return config.ModelPriceConfig{}, false
+}
+
+// Cost 计算一次调用的费用，没有价格的模型费用为0
+func (p *Pricing) Cost(model string, promptTokens, completionTokens int) float64 {
+	price, ok := p.Lookup(model)
+	if !ok {
+		return 0
+	}
+	return (float64(promptTokens)*price.Input + float64(completionTokens)*price.Output) / 1e6
+}
+
+// Prices 返回价格配置
+func (p *Pricing) Prices() map[string]config.ModelPriceConfig {
+	prices := make(map[string]config.ModelPriceConfig, len(p.prices))
+	for model, price := range p.prices {
+		prices[model] = price
+	}
+	return prices
+}
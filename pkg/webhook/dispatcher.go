@@ -0,0 +1,681 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"kai/kaigate/pkg/log"
+	"kai/kaigate/pkg/netutil"
+	"kai/kaigate/pkg/storage"
+)
+
+// Dispatcher Webhook分发器
+// 发布的事件按订阅生成投递，由固定数量的worker发送；失败的投递按指数退避重新排队，
+// 达到最大次数后写入死信列表
+type Dispatcher struct {
+	store          storage.KV
+	timeout        time.Duration
+	client         *http.Client   // 投递到订阅地址，订阅由管理员配置
+	callbackClient *http.Client   // 投递到调用方提供的回调地址，只连接guard允许的地址
+	guard          *netutil.Guard // 校验回调地址，拒绝内部网络地址
+	workers        int
+	queueSize      int
+	maxAttempts    int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	callbackSecret string
+	configured     map[string]*Subscriber // 配置文件中定义的订阅
+	registered     map[string]*Subscriber // 管理接口创建的订阅，与配置中的订阅同名时被覆盖
+	pending        map[string]*Delivery   // 等待投递或等待重试的投递
+	timers         map[string]*time.Timer // 等待重试的定时器
+	dead           map[string]*Delivery
+	stats          Stats
+	queue          chan *Delivery
+	ctx            context.Context // 关闭时取消，中断进行中的请求
+	stop           context.CancelFunc
+	closed         bool
+	closeOnce      sync.Once
+	closeErr       error
+	wg             sync.WaitGroup
+	mutex          sync.Mutex
+	logger         log.Logger
+}
+
+// Option Dispatcher配置选项
+type Option func(*Dispatcher)
+
+// WithWorkers 设置并发投递的worker数
+func WithWorkers(workers int) Option {
+	return func(d *Dispatcher) {
+		d.workers = workers
+	}
+}
+
+// WithQueueSize 设置等待投递的队列长度
+func WithQueueSize(size int) Option {
+	return func(d *Dispatcher) {
+		d.queueSize = size
+	}
+}
+
+// WithTimeout 设置单次投递请求的超时时间
+func WithTimeout(timeout time.Duration) Option {
+	return func(d *Dispatcher) {
+		d.timeout = timeout
+	}
+}
+
+// WithCallbackGuard 设置校验回调地址的Guard，Send拒绝解析到内部网络的地址
+func WithCallbackGuard(guard *netutil.Guard) Option {
+	return func(d *Dispatcher) {
+		d.guard = guard
+	}
+}
+
+// WithMaxAttempts 设置每个投递的最大请求次数，超过后进入死信列表
+func WithMaxAttempts(attempts int) Option {
+	return func(d *Dispatcher) {
+		d.maxAttempts = attempts
+	}
+}
+
+// WithBackoff 设置重试等待时间，首次重试等待initial，之后每次翻倍，不超过max
+func WithBackoff(initial, max time.Duration) Option {
+	return func(d *Dispatcher) {
+		d.initialBackoff = initial
+		d.maxBackoff = max
+	}
+}
+
+// WithCallbackSecret 设置Send直接发送的回调使用的签名密钥，支持${ENV}引用环境变量
+func WithCallbackSecret(secret string) Option {
+	return func(d *Dispatcher) {
+		d.callbackSecret = secret
+	}
+}
+
+// WithLogger 设置日志器
+func WithLogger(logger log.Logger) Option {
+	return func(d *Dispatcher) {
+		d.logger = logger
+	}
+}
+
+// NewDispatcher 创建Dispatcher实例，加载存储中的订阅和死信并启动worker
+func NewDispatcher(store storage.KV, options ...Option) (*Dispatcher, error) {
+	if store == nil {
+		return nil, errors.New("webhook store cannot be nil")
+	}
+
+	d := &Dispatcher{
+		store:          store,
+		timeout:        10 * time.Second,
+		workers:        1,
+		queueSize:      1000,
+		maxAttempts:    5,
+		initialBackoff: time.Second,
+		maxBackoff:     5 * time.Minute,
+		configured:     make(map[string]*Subscriber),
+		registered:     make(map[string]*Subscriber),
+		pending:        make(map[string]*Delivery),
+		timers:         make(map[string]*time.Timer),
+		dead:           make(map[string]*Delivery),
+		logger:         log.GlobalLogger,
+	}
+	for _, option := range options {
+		option(d)
+	}
+	if d.workers <= 0 {
+		return nil, fmt.Errorf("invalid webhook worker count: %d", d.workers)
+	}
+	if d.queueSize <= 0 {
+		return nil, fmt.Errorf("invalid webhook queue size: %d", d.queueSize)
+	}
+	if d.maxAttempts <= 0 {
+		return nil, fmt.Errorf("invalid webhook max attempts: %d", d.maxAttempts)
+	}
+	if d.guard == nil {
+		d.guard, _ = netutil.NewGuard(nil)
+	}
+	d.client = &http.Client{Timeout: d.timeout}
+	d.callbackClient = d.guard.Client(d.timeout)
+	d.queue = make(chan *Delivery, d.queueSize)
+	d.ctx, d.stop = context.WithCancel(context.Background())
+
+	if err := d.load(); err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < d.workers; i++ {
+		d.wg.Add(1)
+		go d.work()
+	}
+	return d, nil
+}
+
+// SetConfigSubscribers 替换配置文件中定义的订阅
+func (d *Dispatcher) SetConfigSubscribers(subscribers []Subscriber) error {
+	configured := make(map[string]*Subscriber, len(subscribers))
+	for _, sub := range subscribers {
+		sub.Source = SourceConfig
+		if err := validate(&sub); err != nil {
+			return err
+		}
+		if _, exists := configured[sub.Name]; exists {
+			return fmt.Errorf("%w: duplicate name %q", ErrInvalid, sub.Name)
+		}
+		configured[sub.Name] = &sub
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	for name := range configured {
+		if _, exists := d.registered[name]; exists {
+			d.logger.Warn("Webhook defined in config overrides the one created via API", zap.String("name", name))
+		}
+	}
+	d.configured = configured
+	return nil
+}
+
+// PutSubscriber 创建或更新管理接口定义的订阅
+func (d *Dispatcher) PutSubscriber(sub Subscriber) error {
+	sub.Source = SourceAPI
+	if err := validate(&sub); err != nil {
+		return err
+	}
+	data, err := json.Marshal(&sub)
+	if err != nil {
+		return fmt.Errorf("encode webhook failed: %w", err)
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if _, exists := d.configured[sub.Name]; exists {
+		return ErrReadOnly
+	}
+	if err := d.store.Put(subscriberPrefix+sub.Name, data); err != nil {
+		return fmt.Errorf("save webhook failed: %w", err)
+	}
+	d.registered[sub.Name] = &sub
+	return nil
+}
+
+// DeleteSubscriber 删除管理接口定义的订阅
+func (d *Dispatcher) DeleteSubscriber(name string) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if _, exists := d.configured[name]; exists {
+		return ErrReadOnly
+	}
+	if _, exists := d.registered[name]; !exists {
+		return ErrNotFound
+	}
+	if err := d.store.Delete(subscriberPrefix + name); err != nil {
+		return fmt.Errorf("delete webhook failed: %w", err)
+	}
+	delete(d.registered, name)
+	return nil
+}
+
+// Subscribers 列出生效的订阅，按名称排列
+func (d *Dispatcher) Subscribers() []SubscriberInfo {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	infos := make([]SubscriberInfo, 0, len(d.configured)+len(d.registered))
+	for _, sub := range d.subscribers() {
+		infos = append(infos, sub.Info())
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+// Publish 向订阅了该事件类型的订阅投递事件，返回创建的投递数
+// 不会阻塞，队列满时投递直接进入死信列表
+func (d *Dispatcher) Publish(eventType string, data interface{}) int {
+	event, err := newEvent(eventType, data)
+	if err != nil {
+		d.logger.Error("Failed to encode webhook event", zap.String("event", eventType), zap.Error(err))
+		return 0
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.closed {
+		return 0
+	}
+	count := 0
+	for _, sub := range d.subscribers() {
+		if !sub.Matches(eventType) {
+			continue
+		}
+		d.enqueue(d.newDelivery(sub.Name, sub.URL, event))
+		count++
+	}
+	return count
+}
+
+// Send 向调用方提供的地址投递事件，使用回调签名密钥，失败时同样重试并进入死信列表
+// 地址解析到内部网络且不在允许列表中时拒绝投递
+func (d *Dispatcher) Send(target, eventType string, data interface{}) error {
+	if err := d.guard.CheckURL(d.ctx, target); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	event, err := newEvent(eventType, data)
+	if err != nil {
+		return err
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.closed {
+		return errors.New("webhook dispatcher is closed")
+	}
+	return d.enqueue(d.newDelivery("", target, event))
+}
+
+// DeadLetters 列出死信，按创建时间倒序排列
+func (d *Dispatcher) DeadLetters() []Delivery {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	deliveries := make([]Delivery, 0, len(d.dead))
+	for _, delivery := range d.dead {
+		deliveries = append(deliveries, *delivery)
+	}
+	sort.Slice(deliveries, func(i, j int) bool {
+		return deliveries[i].CreatedAt.After(deliveries[j].CreatedAt)
+	})
+	return deliveries
+}
+
+// Replay 重新投递死信，请求次数从0开始计算，投递ID和事件不变
+func (d *Dispatcher) Replay(id string) (*Delivery, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	delivery, exists := d.dead[id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	if err := d.replay(delivery); err != nil {
+		return nil, err
+	}
+	result := *delivery
+	return &result, nil
+}
+
+// ReplayAll 重新投递全部死信，返回重新排队的投递数
+func (d *Dispatcher) ReplayAll() (int, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	count := 0
+	for _, delivery := range d.dead {
+		if err := d.replay(delivery); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// DeleteDeadLetter 删除死信
+func (d *Dispatcher) DeleteDeadLetter(id string) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if _, exists := d.dead[id]; !exists {
+		return ErrNotFound
+	}
+	if err := d.store.Delete(deadLetterPrefix + id); err != nil {
+		return fmt.Errorf("delete dead letter failed: %w", err)
+	}
+	delete(d.dead, id)
+	return nil
+}
+
+// Stats 获取投递统计
+func (d *Dispatcher) Stats() Stats {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	stats := d.stats
+	stats.Pending = len(d.pending)
+	stats.DeadLetters = len(d.dead)
+	return stats
+}
+
+// Close 停止worker并清理资源，未完成的投递写入死信列表，可以在下次启动后重放
+func (d *Dispatcher) Close() error {
+	d.closeOnce.Do(func() {
+		d.mutex.Lock()
+		d.closed = true
+		for id, timer := range d.timers {
+			timer.Stop()
+			delete(d.timers, id)
+		}
+		d.mutex.Unlock()
+
+		d.stop()
+		d.wg.Wait()
+
+		d.mutex.Lock()
+		for _, delivery := range d.pending {
+			if delivery.LastError == "" {
+				delivery.LastError = "interrupted by gateway shutdown"
+			}
+			d.bury(delivery)
+		}
+		d.mutex.Unlock()
+		d.closeErr = d.store.Close()
+	})
+	return d.closeErr
+}
+
+// load 加载存储中的订阅和死信
+func (d *Dispatcher) load() error {
+	keys, err := d.store.List(subscriberPrefix)
+	if err != nil {
+		return fmt.Errorf("list webhooks failed: %w", err)
+	}
+	for _, key := range keys {
+		var sub Subscriber
+		if err := d.loadKey(key, &sub); err != nil {
+			d.logger.Warn("Skipping unreadable webhook", zap.String("key", key), zap.Error(err))
+			continue
+		}
+		d.registered[sub.Name] = &sub
+	}
+
+	keys, err = d.store.List(deadLetterPrefix)
+	if err != nil {
+		return fmt.Errorf("list dead letters failed: %w", err)
+	}
+	for _, key := range keys {
+		var delivery Delivery
+		if err := d.loadKey(key, &delivery); err != nil {
+			d.logger.Warn("Skipping unreadable dead letter", zap.String("key", key), zap.Error(err))
+			continue
+		}
+		d.dead[delivery.ID] = &delivery
+	}
+	return nil
+}
+
+// loadKey 读取并解码存储中的值
+func (d *Dispatcher) loadKey(key string, value interface{}) error {
+	data, err := d.store.Get(key)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, value)
+}
+
+// work worker循环，依次发送队列中的投递
+func (d *Dispatcher) work() {
+	defer d.wg.Done()
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case delivery := <-d.queue:
+			d.attempt(delivery)
+		}
+	}
+}
+
+// attempt 发送一次投递，失败时安排重试或写入死信列表
+func (d *Dispatcher) attempt(delivery *Delivery) {
+	d.mutex.Lock()
+	if d.closed {
+		// 正在关闭，关闭时写入死信列表
+		d.mutex.Unlock()
+		return
+	}
+	target, secret, err := d.endpoint(delivery)
+	event := delivery.Event
+	d.mutex.Unlock()
+
+	status := 0
+	if err == nil {
+		client := d.client
+		if delivery.Subscriber == "" {
+			client = d.callbackClient
+		}
+		status, err = d.post(client, target, secret, delivery.ID, event)
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	now := time.Now()
+	delivery.Attempts++
+	delivery.LastAttemptAt = &now
+	delivery.LastStatus = status
+	if err == nil {
+		delivery.LastError = ""
+		delete(d.pending, delivery.ID)
+		d.stats.Delivered++
+		return
+	}
+	delivery.LastError = err.Error()
+	if d.closed {
+		return
+	}
+
+	if delivery.Attempts >= d.maxAttempts {
+		d.logger.Warn("Webhook delivery failed, moved to dead letters",
+			zap.String("delivery_id", delivery.ID),
+			zap.String("subscriber", delivery.Subscriber),
+			zap.String("url", delivery.URL),
+			zap.String("event", event.Type),
+			zap.Int("attempts", delivery.Attempts),
+			zap.Error(err),
+		)
+		d.bury(delivery)
+		return
+	}
+
+	backoff := d.backoff(delivery.Attempts)
+	d.logger.Info("Webhook delivery failed, retrying",
+		zap.String("delivery_id", delivery.ID),
+		zap.String("url", delivery.URL),
+		zap.Int("attempts", delivery.Attempts),
+		zap.Duration("backoff", backoff),
+		zap.Error(err),
+	)
+	d.stats.Retries++
+	d.timers[delivery.ID] = time.AfterFunc(backoff, func() {
+		d.mutex.Lock()
+		defer d.mutex.Unlock()
+		if _, waiting := d.timers[delivery.ID]; !waiting || d.closed {
+			return
+		}
+		delete(d.timers, delivery.ID)
+		d.requeue(delivery)
+	})
+}
+
+// endpoint 获取投递的目标地址和签名密钥，调用方需持有锁
+// 订阅的地址和密钥可能已更新，按订阅名称获取最新值
+func (d *Dispatcher) endpoint(delivery *Delivery) (string, string, error) {
+	if delivery.Subscriber == "" {
+		return delivery.URL, resolveSecret(d.callbackSecret), nil
+	}
+	for _, sub := range d.subscribers() {
+		if sub.Name == delivery.Subscriber {
+			delivery.URL = sub.URL
+			return sub.URL, resolveSecret(sub.Secret), nil
+		}
+	}
+	return "", "", fmt.Errorf("subscriber %q no longer exists", delivery.Subscriber)
+}
+
+// post 发送签名后的事件，返回HTTP状态码，非2xx状态码视为失败
+func (d *Dispatcher) post(client *http.Client, target, secret, deliveryID string, event Event) (int, error) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return 0, fmt.Errorf("encode event failed: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(d.ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("create request failed: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, event.Type)
+	req.Header.Set(HeaderDelivery, deliveryID)
+	if secret != "" {
+		req.Header.Set(HeaderSignature, Sign(secret, time.Now(), body))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("send request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("endpoint returned status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff 计算第attempts次失败后的重试等待时间
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	wait := d.initialBackoff
+	for i := 1; i < attempts && wait < d.maxBackoff; i++ {
+		wait *= 2
+	}
+	if d.maxBackoff > 0 && wait > d.maxBackoff {
+		wait = d.maxBackoff
+	}
+	return wait
+}
+
+// newDelivery 创建投递
+func (d *Dispatcher) newDelivery(subscriber, target string, event Event) *Delivery {
+	return &Delivery{
+		ID:         newID("whd_"),
+		Subscriber: subscriber,
+		URL:        target,
+		Event:      event,
+		CreatedAt:  time.Now(),
+	}
+}
+
+// enqueue 将新投递加入队列，调用方需持有锁
+func (d *Dispatcher) enqueue(delivery *Delivery) error {
+	d.stats.Published++
+	d.pending[delivery.ID] = delivery
+	return d.requeue(delivery)
+}
+
+// requeue 将投递加入队列，队列满时写入死信列表，调用方需持有锁
+func (d *Dispatcher) requeue(delivery *Delivery) error {
+	select {
+	case d.queue <- delivery:
+		return nil
+	default:
+		delivery.LastError = ErrQueueFull.Error()
+		d.logger.Warn("Webhook delivery queue is full, moved to dead letters",
+			zap.String("delivery_id", delivery.ID),
+			zap.String("url", delivery.URL),
+		)
+		d.bury(delivery)
+		return ErrQueueFull
+	}
+}
+
+// replay 将死信重新加入队列，调用方需持有锁
+func (d *Dispatcher) replay(delivery *Delivery) error {
+	if d.closed {
+		return errors.New("webhook dispatcher is closed")
+	}
+	if err := d.store.Delete(deadLetterPrefix + delivery.ID); err != nil {
+		return fmt.Errorf("delete dead letter failed: %w", err)
+	}
+	delete(d.dead, delivery.ID)
+	delivery.Attempts = 0
+	d.pending[delivery.ID] = delivery
+	d.logger.Info("Replaying webhook delivery", zap.String("delivery_id", delivery.ID), zap.String("url", delivery.URL))
+	return d.requeue(delivery)
+}
+
+// bury 将投递写入死信列表，调用方需持有锁
+func (d *Dispatcher) bury(delivery *Delivery) {
+	delete(d.pending, delivery.ID)
+	d.dead[delivery.ID] = delivery
+
+	data, err := json.Marshal(delivery)
+	if err == nil {
+		err = d.store.Put(deadLetterPrefix+delivery.ID, data)
+	}
+	if err != nil {
+		d.logger.Error("Failed to save dead letter", zap.String("delivery_id", delivery.ID), zap.Error(err))
+	}
+}
+
+// subscribers 获取生效的订阅，配置中的订阅覆盖同名的接口订阅，调用方需持有锁
+func (d *Dispatcher) subscribers() []*Subscriber {
+	subs := make([]*Subscriber, 0, len(d.configured)+len(d.registered))
+	for _, sub := range d.configured {
+		subs = append(subs, sub)
+	}
+	for name, sub := range d.registered {
+		if _, overridden := d.configured[name]; !overridden {
+			subs = append(subs, sub)
+		}
+	}
+	return subs
+}
+
+// validate 校验订阅参数
+func validate(sub *Subscriber) error {
+	if !namePattern.MatchString(sub.Name) {
+		return fmt.Errorf("%w: invalid name %q", ErrInvalid, sub.Name)
+	}
+	u, err := url.Parse(sub.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: url must be an absolute http or https URL", ErrInvalid)
+	}
+	return nil
+}
+
+// newEvent 创建事件
+func newEvent(eventType string, data interface{}) (Event, error) {
+	event := Event{ID: newID("evt_"), Type: eventType, Time: time.Now()}
+	if data != nil {
+		payload, err := json.Marshal(data)
+		if err != nil {
+			return event, fmt.Errorf("encode event data failed: %w", err)
+		}
+		event.Data = payload
+	}
+	return event, nil
+}
+
+// newID 生成带前缀的随机ID
+func newID(prefix string) string {
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Sprintf("%s%d", prefix, time.Now().UnixNano())
+	}
+	return prefix + hex.EncodeToString(buf)
+}
@@ -0,0 +1,113 @@
+package webhook
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"kai/kaigate/pkg/log"
+	"kai/kaigate/pkg/storage"
+)
+
+func TestMain(m *testing.M) {
+	if err := log.InitLogger("error", "json", "", false); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+func newTestDispatcher(t *testing.T, options ...Option) *Dispatcher {
+	t.Helper()
+	dispatcher, err := NewDispatcher(storage.NewMemoryKV(), options...)
+	if err != nil {
+		t.Fatalf("create dispatcher: %v", err)
+	}
+	t.Cleanup(func() { dispatcher.Close() })
+	return dispatcher
+}
+
+// waitFor 等待条件成立
+func waitFor(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestDeliverySignedForSubscriber(t *testing.T) {
+	verified := make(chan error, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get(HeaderEvent) != "job.finished" || r.Header.Get(HeaderDelivery) == "" {
+			t.Errorf("unexpected headers %v", r.Header)
+		}
+		verified <- Verify("s3cret", r.Header.Get(HeaderSignature), body, time.Minute)
+	}))
+	defer receiver.Close()
+
+	dispatcher := newTestDispatcher(t)
+	if err := dispatcher.PutSubscriber(Subscriber{Name: "jobs", URL: receiver.URL, Secret: "s3cret", Events: []string{"job.*"}}); err != nil {
+		t.Fatalf("PutSubscriber: %v", err)
+	}
+	if n := dispatcher.Publish("usage.recorded", nil); n != 0 {
+		t.Errorf("unsubscribed event published to %d subscribers", n)
+	}
+	if n := dispatcher.Publish("job.finished", map[string]string{"id": "job_1"}); n != 1 {
+		t.Fatalf("published to %d subscribers, want 1", n)
+	}
+
+	select {
+	case err := <-verified:
+		if err != nil {
+			t.Errorf("receiver could not verify signature: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("delivery not received")
+	}
+	waitFor(t, "delivery", func() bool { return dispatcher.Stats().Delivered == 1 })
+}
+
+func TestDeadLetterAndReplay(t *testing.T) {
+	var healthy atomic.Bool
+	var requests atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer receiver.Close()
+
+	dispatcher := newTestDispatcher(t, WithMaxAttempts(2), WithBackoff(time.Millisecond, time.Millisecond))
+	if err := dispatcher.PutSubscriber(Subscriber{Name: "flaky", URL: receiver.URL}); err != nil {
+		t.Fatalf("PutSubscriber: %v", err)
+	}
+	dispatcher.Publish("job.finished", nil)
+
+	// 达到最大尝试次数后进入死信列表
+	waitFor(t, "dead letter", func() bool { return len(dispatcher.DeadLetters()) == 1 })
+	dead := dispatcher.DeadLetters()[0]
+	if dead.Attempts != 2 || dead.LastStatus != http.StatusServiceUnavailable || requests.Load() != 2 {
+		t.Errorf("dead letter = %+v after %d requests", dead, requests.Load())
+	}
+	if stats := dispatcher.Stats(); stats.Retries != 1 || stats.Delivered != 0 {
+		t.Errorf("stats = %+v", stats)
+	}
+
+	healthy.Store(true)
+	if _, err := dispatcher.Replay(dead.ID); err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+	waitFor(t, "replayed delivery", func() bool { return dispatcher.Stats().Delivered == 1 })
+	if len(dispatcher.DeadLetters()) != 0 {
+		t.Error("replayed delivery still in dead letters")
+	}
+}
@@ -0,0 +1,72 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Webhook请求头
+const (
+	HeaderEvent     = "X-Kaigate-Event"     // 事件类型
+	HeaderDelivery  = "X-Kaigate-Delivery"  // 投递ID，重试和重放时不变
+	HeaderSignature = "X-Kaigate-Signature" // 签名，格式为"t=<unix秒>,v1=<hex>"
+)
+
+// ErrInvalidSignature 签名缺失、格式错误、不匹配或已过期
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// Sign 计算签名请求头的值
+// 签名为HMAC-SHA256(secret, "<timestamp>.<body>")的十六进制编码，时间戳用于接收方拒绝重放的旧请求
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	ts := strconv.FormatInt(timestamp.Unix(), 10)
+	return "t=" + ts + ",v1=" + signature(secret, ts, body)
+}
+
+// Verify 校验签名请求头，tolerance为允许的时间偏差，0表示不检查时间
+// 供接收方使用
+func Verify(secret, header string, body []byte, tolerance time.Duration) error {
+	var ts, sig string
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			ts = value
+		case "v1":
+			sig = value
+		}
+	}
+	if ts == "" || sig == "" {
+		return fmt.Errorf("%w: malformed header", ErrInvalidSignature)
+	}
+	if !hmac.Equal([]byte(sig), []byte(signature(secret, ts, body))) {
+		return fmt.Errorf("%w: signature mismatch", ErrInvalidSignature)
+	}
+	if tolerance > 0 {
+		unix, err := strconv.ParseInt(ts, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%w: invalid timestamp", ErrInvalidSignature)
+		}
+		if age := time.Since(time.Unix(unix, 0)); age > tolerance || age < -tolerance {
+			return fmt.Errorf("%w: timestamp outside tolerance", ErrInvalidSignature)
+		}
+	}
+	return nil
+}
+
+// signature 计算时间戳和请求体的HMAC-SHA256签名
+func signature(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,74 @@
+package webhook
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignVerifyRoundTrip(t *testing.T) {
+	body := []byte(`{"type":"job.finished"}`)
+	header := Sign("secret", time.Now(), body)
+	if !strings.HasPrefix(header, "t=") || !strings.Contains(header, ",v1=") {
+		t.Fatalf("unexpected header format %q", header)
+	}
+	if err := Verify("secret", header, body, 5*time.Minute); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		secret string
+		header string
+		body   []byte
+	}{
+		{"wrong secret", "other", header, body},
+		{"modified body", "secret", header, []byte(`{"type":"job.failed"}`)},
+		{"missing signature", "secret", strings.Split(header, ",")[0], body},
+		{"empty header", "secret", "", body},
+		{"garbage", "secret", "not a signature", body},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Verify(tt.secret, tt.header, tt.body, 5*time.Minute); !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("Verify err = %v, want ErrInvalidSignature", err)
+			}
+		})
+	}
+}
+
+func TestVerifyTolerance(t *testing.T) {
+	body := []byte("{}")
+	tests := []struct {
+		name      string
+		age       time.Duration
+		tolerance time.Duration
+		valid     bool
+	}{
+		{"within tolerance", 2 * time.Minute, 5 * time.Minute, true},
+		{"too old", 10 * time.Minute, 5 * time.Minute, false},
+		{"too far in the future", -10 * time.Minute, 5 * time.Minute, false},
+		{"tolerance disabled", 24 * time.Hour, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := Sign("secret", time.Now().Add(-tt.age), body)
+			err := Verify("secret", header, body, tt.tolerance)
+			if tt.valid && err != nil {
+				t.Errorf("Verify: %v", err)
+			}
+			if !tt.valid && !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("Verify err = %v, want ErrInvalidSignature", err)
+			}
+		})
+	}
+
+	// 时间戳属于签名内容，替换时间戳后签名不匹配
+	header := Sign("secret", time.Now().Add(-time.Hour), body)
+	forged := "t=" + strconv.FormatInt(time.Now().Unix(), 10) + header[strings.Index(header, ","):]
+	if err := Verify("secret", forged, body, 5*time.Minute); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Verify accepted a replaced timestamp: %v", err)
+	}
+}
@@ -0,0 +1,128 @@
+// Package webhook 向外部地址投递网关事件和异步任务结果
+// 请求体使用HMAC-SHA256签名；投递失败时按指数退避重试，超过最大次数后进入死信列表，
+// 死信保存在存储中，可以在问题修复后重放
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// 网关发布的事件类型
+const (
+	EventJobSucceeded   = "job.succeeded"   // 异步任务执行成功
+	EventJobFailed      = "job.failed"      // 异步任务执行失败
+	EventJobCanceled    = "job.canceled"    // 异步任务已取消
+	EventConfigReloaded = "config.reloaded" // 配置已重新加载
+)
+
+// 订阅来源
+const (
+	SourceConfig = "config" // 配置文件中定义，随配置重载更新
+	SourceAPI    = "api"    // 管理接口创建，保存在存储中
+)
+
+// 存储中的键前缀
+const (
+	subscriberPrefix = "webhook/subscriber/"
+	deadLetterPrefix = "webhook/dead/"
+)
+
+var (
+	// ErrNotFound 订阅或死信不存在
+	ErrNotFound = errors.New("webhook not found")
+	// ErrInvalid 订阅参数无效
+	ErrInvalid = errors.New("invalid webhook")
+	// ErrReadOnly 配置文件中定义的订阅不能通过接口修改
+	ErrReadOnly = errors.New("webhook is defined in config")
+	// ErrQueueFull 等待投递的请求已达到队列上限
+	ErrQueueFull = errors.New("webhook delivery queue is full")
+)
+
+// namePattern 订阅名称只允许字母、数字和常用分隔符
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9_.\-]{1,64}$`)
+
+// Event 投递的事件，作为请求体发送
+type Event struct {
+	ID   string          `json:"id"`
+	Type string          `json:"type"`
+	Time time.Time       `json:"time"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// Subscriber 事件订阅
+type Subscriber struct {
+	Name   string   `json:"name"`
+	URL    string   `json:"url"`
+	Secret string   `json:"secret,omitempty"` // 签名密钥，支持${ENV}引用环境变量，为空时不签名
+	Events []string `json:"events"`           // 订阅的事件类型，支持"prefix*"前缀匹配，为空表示全部事件
+	Source string   `json:"source"`
+}
+
+// SubscriberInfo 订阅列表中的信息，不包含密钥
+type SubscriberInfo struct {
+	Name   string   `json:"name"`
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	Source string   `json:"source"`
+	Signed bool     `json:"signed"` // 是否配置了签名密钥
+}
+
+// Delivery 一次事件投递
+type Delivery struct {
+	ID            string     `json:"id"`
+	Subscriber    string     `json:"subscriber,omitempty"` // 订阅名称，为空表示直接发送到URL的回调
+	URL           string     `json:"url"`
+	Event         Event      `json:"event"`
+	Attempts      int        `json:"attempts"`
+	LastStatus    int        `json:"last_status,omitempty"` // 最近一次请求的HTTP状态码
+	LastError     string     `json:"last_error,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	LastAttemptAt *time.Time `json:"last_attempt_at,omitempty"`
+}
+
+// Stats 投递统计
+type Stats struct {
+	Published   int64 `json:"published"`    // 创建的投递数
+	Delivered   int64 `json:"delivered"`    // 投递成功数
+	Retries     int64 `json:"retries"`      // 失败后重试的次数
+	Pending     int   `json:"pending"`      // 等待投递或等待重试的投递数
+	DeadLetters int   `json:"dead_letters"` // 死信数
+}
+
+// Matches 订阅是否包含事件类型
+func (s *Subscriber) Matches(eventType string) bool {
+	if len(s.Events) == 0 {
+		return true
+	}
+	for _, pattern := range s.Events {
+		if pattern == eventType || pattern == "*" {
+			return true
+		}
+		if strings.HasSuffix(pattern, "*") && strings.HasPrefix(eventType, strings.TrimSuffix(pattern, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// Info 获取不包含密钥的订阅信息
+func (s *Subscriber) Info() SubscriberInfo {
+	events := s.Events
+	if events == nil {
+		events = []string{}
+	}
+	return SubscriberInfo{Name: s.Name, URL: s.URL, Events: events, Source: s.Source, Signed: s.Secret != ""}
+}
+
+// resolveSecret 值为"${ENV}"格式时从环境变量读取密钥
+func resolveSecret(secret string) string {
+	if len(secret) > 3 && strings.HasPrefix(secret, "${") && strings.HasSuffix(secret, "}") {
+		return os.Getenv(secret[2 : len(secret)-1])
+	}
+	return secret
+}